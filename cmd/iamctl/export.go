@@ -0,0 +1,68 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/export"
+)
+
+// runExport writes the resources managing the IAM objects of an account, to
+// bring the account under the operator
+func runExport(args []string) error {
+	var acc account
+	flags := pflag.NewFlagSet("export", pflag.ContinueOnError)
+	acc.addFlags(flags)
+	namespace := flags.StringP("namespace", "n", "default", "Namespace of the resources")
+	output := flags.StringP("output", "o", "-", "File the resources are written to, - for the standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	clients, accountID, err := acc.connect()
+	if err != nil {
+		return err
+	}
+	result, err := export.Account(clients, accountID, *namespace)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := export.WriteYAML(w, result.Objects()); err != nil {
+		return err
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s\n", skipped)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d custom roles, %d access groups, %d access policies and %d authorization policies of account %s\n",
+		len(result.CustomRoles), len(result.AccessGroups), len(result.AccessPolicies), len(result.AuthorizationPolicies), accountID)
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command iamctl manages IBM Cloud IAM accounts with the custom resources of the
+// operator, outside of a cluster.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	bx "github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/spf13/pflag"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/endpoints"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+)
+
+// commands are the subcommands of iamctl, with their description
+var commands = []struct {
+	name        string
+	description string
+	run         func(args []string) error
+}{
+	{"export", "Export the IAM objects of an account into custom resources", runExport},
+	{"plan", "Plan the changes applying manifests makes to an account", runPlan},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: iamctl <command> [flags]\n\nCommands:\n")
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", command.name, command.description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun iamctl <command> --help for the flags of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, command := range commands {
+		if command.name != os.Args[1] {
+			continue
+		}
+		if err := command.run(os.Args[2:]); err != nil {
+			if err != pflag.ErrHelp {
+				fmt.Fprintf(os.Stderr, "iamctl %s: %v\n", command.name, err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+	usage()
+	os.Exit(2)
+}
+
+// account are the flags selecting the IAM account to connect to
+type account struct {
+	apiKey string
+	region string
+	id     string
+}
+
+func (a *account) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&a.apiKey, "api-key", "", "IBM Cloud API key (default $IBMCLOUD_API_KEY or $BLUEMIX_API_KEY)")
+	flags.StringVar(&a.region, "region", "us-south", "IBM Cloud region")
+	flags.StringVar(&a.id, "account", "", "ID of the account (default the account of the API key, if it has only one)")
+}
+
+// connect returns the IAM APIs of the account and its ID
+func (a *account) connect() (iamclient.Clients, string, error) {
+	if a.apiKey == "" { // Not the default of the flag, which help would print
+		a.apiKey = os.Getenv("IBMCLOUD_API_KEY")
+	}
+	if a.apiKey == "" {
+		a.apiKey = os.Getenv("BLUEMIX_API_KEY")
+	}
+	if a.apiKey == "" {
+		return nil, "", fmt.Errorf("an API key is required, set it with --api-key or $IBMCLOUD_API_KEY")
+	}
+	sess, err := session.New(&bx.Config{
+		BluemixAPIKey:   a.apiKey,
+		Region:          a.region,
+		EndpointLocator: endpoints.Locator(a.region),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	accountID := a.id
+	if accountID == "" {
+		accClient, err := accountv2.New(sess)
+		if err != nil {
+			return nil, "", err
+		}
+		accounts, err := accClient.Accounts().List()
+		if err != nil {
+			return nil, "", err
+		}
+		if len(accounts) != 1 {
+			return nil, "", fmt.Errorf("the API key has access to %d accounts, choose one with --account", len(accounts))
+		}
+		accountID = accounts[0].GUID
+	}
+	// Retry and rate limit the requests, which are many for a large account
+	resilience.Install(sess, accountID)
+	return iamclient.NewClients(sess, accountID), accountID, nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/manifest"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/plan"
+)
+
+// runPlan prints the changes applying the resources of manifests would make to
+// an account, to review them before they are applied, e.g. in CI
+func runPlan(args []string) error {
+	var acc account
+	flags := pflag.NewFlagSet("plan", pflag.ContinueOnError)
+	acc.addFlags(flags)
+	filenames := flags.StringArrayP("filename", "f", nil, "Manifest file, or directory of manifests, of the resources (repeatable)")
+	namespace := flags.StringP("namespace", "n", "default", "Namespace of the resources without one")
+	detailedExitCode := flags.Bool("detailed-exitcode", false, "Exit with 2 when there are changes, 0 when there are none")
+	instanceID := flags.String("instance-id", "", "Instance ID of the operator applying the resources, as in its instanceID setting")
+	prune := flags.Bool("prune", false, "Plan the deletion of the IAM objects of the operator instance that no resource manages, requires --instance-id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*filenames) == 0 {
+		return fmt.Errorf("manifests are required, set them with --filename")
+	}
+
+	resources, err := manifest.Load(*namespace, *filenames...)
+	if err != nil {
+		return err
+	}
+	clients, accountID, err := acc.connect()
+	if err != nil {
+		return err
+	}
+	changes, err := plan.Account(resources, clients, accountID, *instanceID, time.Now(), *prune)
+	if err != nil {
+		return err
+	}
+	if err := plan.Write(os.Stdout, changes); err != nil {
+		return err
+	}
+
+	changed := false
+	for _, change := range changes {
+		if change.Err != nil {
+			return fmt.Errorf("some resources cannot be planned")
+		}
+		if change.Action != plan.NoChange {
+			changed = true
+		}
+	}
+	if changed && *detailedExitCode {
+		os.Exit(2)
+	}
+	return nil
+}
@@ -5,30 +5,43 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 
 	"github.com/IBM/ibmcloud-iam-operator/pkg/apis"
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
 	"github.com/IBM/ibmcloud-iam-operator/pkg/controller"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/controller/accessrequest"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/election"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/health"
+	iammetrics "github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
 	"github.com/IBM/ibmcloud-iam-operator/version"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
-	"github.com/operator-framework/operator-sdk/pkg/leader"
 	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/restmapper"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 // Change below variables to serve metrics on different host or port.
@@ -37,6 +50,11 @@ var (
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
 )
+
+// leaderElectionID names the Lease held by the leader. It differs from the lock of the former
+// leader-for-life election, so replicas of both don't take each other's lock.
+const leaderElectionID = "ibmcloud-iam-operator-leader"
+
 var log = logf.Log.WithName("cmd")
 
 func printVersion() {
@@ -55,6 +73,23 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	// Add the flags of the settings tuning the reconciles
+	flagSettings := settings.Defaults
+	pflag.CommandLine.AddFlagSet(settings.FlagSet(&flagSettings))
+
+	// Add the flags of the leader election and health probes
+	leaderElect := pflag.Bool("leader-elect", true, "Elect a leader among the replicas, only the leader reconciles resources")
+	leaderElectionNamespace := pflag.String("leader-election-namespace", "", "Namespace of the leader Lease (default the operator namespace)")
+	leaseDuration := pflag.Duration("lease-duration", time.Second*15, "Time the other replicas wait before taking over the lease of a leader that stopped renewing it")
+	renewDeadline := pflag.Duration("renew-deadline", time.Second*10, "Time the leader keeps trying to renew its lease before giving up leadership")
+	retryPeriod := pflag.Duration("retry-period", time.Second*2, "Time between two attempts to acquire or renew the lease")
+	healthProbeAddress := pflag.String("health-probe-bind-address", ":8081", "Address serving the /healthz liveness and /readyz readiness probes")
+
+	// Add the flags of the admission webhook stamping the requester and reviewer of access requests
+	accessRequestWebhook := pflag.Bool("access-request-webhook", true, "Serve the admission webhook recording who requests and reviews access requests")
+	webhookPort := pflag.Int("webhook-port", 9443, "Port serving the admission webhook")
+	webhookCertDir := pflag.String("webhook-cert-dir", "", "Directory holding the tls.crt and tls.key of the admission webhook (default <temp-dir>/k8s-webhook-server/serving-certs)")
+
 	pflag.Parse()
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -82,20 +117,62 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the settings from the operator ConfigMap, overridden by the flags
+	reader, err := client.New(cfg, client.Options{})
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	settings.Current, err = settings.Load(reader, os.Getenv("CONTROLLER_NAMESPACE"), pflag.CommandLine, flagSettings)
+	if err != nil {
+		log.Error(err, "Failed to load the operator settings")
+		os.Exit(1)
+	}
+	log.Info("Operator settings", "syncPeriod", settings.Current.SyncPeriod, "syncJitter", settings.Current.SyncJitter,
+		"maxConcurrentReconciles", settings.Current.MaxConcurrentReconciles, "concurrency", settings.Current.Concurrency,
+		"watchNamespaces", settings.Current.Namespaces, "dryRun", settings.Current.DryRun, "instanceID", settings.Current.InstanceID)
+
 	ctx := context.TODO()
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "ibmcloud-iam-operator-lock")
+	// Elect the leader in the operator namespace, unless the operator runs outside of a cluster
+	if *leaderElect && *leaderElectionNamespace == "" {
+		*leaderElectionNamespace = os.Getenv("CONTROLLER_NAMESPACE")
+	}
+	if *leaderElect && *leaderElectionNamespace == "" {
+		*leaderElectionNamespace, err = k8sutil.GetOperatorNamespace()
+		if err == k8sutil.ErrNoNamespace || err == k8sutil.ErrRunLocal {
+			log.Info("Skipping leader election, not running in a cluster")
+			*leaderElect = false
+		} else if err != nil {
+			log.Error(err, "Failed to get the leader election namespace")
+			os.Exit(1)
+		}
+	}
+
+	// Export the spans of the reconciles and IAM requests to the OTLP collector, if one is configured
+	shutdownTracing, err := tracing.Setup(ctx)
 	if err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
-	// Create a new Cmd to provide shared dependencies and start components
-	mgr, err := manager.New(cfg, manager.Options{
-		Namespace:          namespace,
-		MapperProvider:     restmapper.NewDynamicRESTMapper,
-		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
-	})
+	// Create a new Cmd to provide shared dependencies and start components. The leader is elected
+	// with a Lease below, as the leader election of the manager only holds ConfigMap locks.
+	options := manager.Options{
+		MapperProvider:         restmapper.NewDynamicRESTMapper,
+		MetricsBindAddress:     fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		HealthProbeBindAddress: *healthProbeAddress,
+		Port:                   *webhookPort,
+		CertDir:                *webhookCertDir,
+	}
+	// Watch all namespaces, a single one, or several with a cache per namespace
+	switch namespaces := settings.Current.Namespaces; len(namespaces) {
+	case 0:
+	case 1:
+		options.Namespace = namespaces[0]
+	default:
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+	mgr, err := manager.New(cfg, options)
 	if err != nil {
 		log.Error(err, "")
 		os.Exit(1)
@@ -109,12 +186,79 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Start the controllers once the replica leads
+	elected := mgr
+	if *leaderElect {
+		campaign, err := election.New(mgr, cfg, election.Config{
+			Namespace:     *leaderElectionNamespace,
+			Name:          leaderElectionID,
+			LeaseDuration: *leaseDuration,
+			RenewDeadline: *renewDeadline,
+			RetryPeriod:   *retryPeriod,
+		})
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+		// The leader is restarted when it hangs without renewing its lease
+		if err := mgr.AddHealthzCheck("leader", campaign.Check); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+		elected = campaign
+	}
+
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr); err != nil {
+	if err := controller.AddToManager(elected); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// Serve the webhook on every replica, so that it is available while the replicas elect a new leader.
+	// Outside of a cluster the API server can't call it, and reviewed access requests are rejected. The
+	// certificate Secret is optional, as it is only issued when webhook.yaml is deployed with cert-manager.
+	certDir := *webhookCertDir
+	if certDir == "" {
+		certDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+	if _, err := k8sutil.GetOperatorNamespace(); err == k8sutil.ErrNoNamespace || err == k8sutil.ErrRunLocal {
+		log.Info("Skipping the access request webhook, not running in a cluster")
+	} else if *accessRequestWebhook && !servingCertificate(certDir) {
+		log.Info("Skipping the access request webhook, no serving certificate", "Directory", certDir)
+	} else if *accessRequestWebhook {
+		accessrequest.AddWebhook(mgr)
+	}
+
+	// The replica is alive while it answers and, leading, renews its lease. The leader and iam readiness checks
+	// report leadership and the availability of IAM, which the readiness probe of the pods excludes: every
+	// replica serves the webhook, so each is ready while it answers.
+	leader := &health.Leader{}
+	if err := elected.Add(leader); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	iammetrics.ReportUnavailableAccounts(health.UnavailableAccounts)
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	readyz := map[string]healthz.Checker{"ping": healthz.Ping, "leader": leader.Check, "iam": health.IAM}
+	for name, check := range readyz {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	// Count the custom resources per state with the operator metrics
+	crmetrics.Registry.MustRegister(iammetrics.NewStateCollector(mgr.GetClient(), map[string]k8sruntime.Object{
+		"AccessGroup":         &ibmcloudv1alpha1.AccessGroupList{},
+		"AccessPolicy":        &ibmcloudv1alpha1.AccessPolicyList{},
+		"AccessRequest":       &ibmcloudv1alpha1.AccessRequestList{},
+		"AuthorizationPolicy": &ibmcloudv1alpha1.AuthorizationPolicyList{},
+		"CustomRole":          &ibmcloudv1alpha1.CustomRoleList{},
+	}))
+
 	if err = serveCRMetrics(cfg); err != nil {
 		log.Info("Could not generate and serve custom resource metrics", "error", err.Error())
 	}
@@ -146,7 +290,11 @@ func main() {
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	err = mgr.Start(signals.SetupSignalHandler())
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Info("Could not export the remaining spans", "error", err.Error())
+	}
+	if err != nil {
 		log.Error(err, "Manager exited non-zero")
 		os.Exit(1)
 	}
@@ -175,3 +323,13 @@ func serveCRMetrics(cfg *rest.Config) error {
 	}
 	return nil
 }
+
+// servingCertificate returns whether the directory holds the certificate and key of the admission webhook
+func servingCertificate(dir string) bool {
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
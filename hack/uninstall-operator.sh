@@ -18,6 +18,8 @@
 
 # Delete the CRDs first, so controllers clean up their resources
 # TODO - should label CRDs - see if it can be done with kubebuilder
+kubectl delete --wait crd accessrequests.ibmcloud.ibm.com
+kubectl delete --wait crd iamorphanreports.ibmcloud.ibm.com
 kubectl delete --wait crd accessgroups.ibmcloud.ibm.com
 kubectl delete --wait crd customroles.ibmcloud.ibm.com
 kubectl delete --wait crd accesspolicies.ibmcloud.ibm.com
@@ -21,12 +21,26 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// TemporaryMember is an access group member that the operator removes once it expires
+type TemporaryMember struct {
+	UserEmail 		string 	 `json:"userEmail,omitempty"`
+	ServiceID 		string 	 `json:"serviceID,omitempty"`
+	// ExpiresAt is the time the member is removed from the access group
+	ExpiresAt 		*metav1.Time `json:"expiresAt,omitempty"`
+	// TTL removes the member after a duration from when it was added, e.g. 8h
+	TTL 			*metav1.Duration `json:"ttl,omitempty"`
+}
+
 // AccessGroupSpec defines the desired state of AccessGroup
 type AccessGroupSpec struct {
 	Name 			string 	 `json:"name"`
 	Description 	string   `json:"description"`
 	UserEmails    	[]string `json:"userEmails,omitempty"`
 	ServiceIDs    	[]string `json:"serviceIDs,omitempty"`
+	TemporaryMembers []TemporaryMember `json:"temporaryMembers,omitempty"`
+	// DriftPolicy is how changes made outside of the operator are handled: Enforce (default), Detect or Ignore
+	// +kubebuilder:validation:Enum=Enforce;Detect;Ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
 }
 
 // AccessGroupStatus defines the observed state of AccessGroup
@@ -37,6 +51,10 @@ type AccessGroupStatus struct {
 	Description 	string   `json:"description,omitempty"`
 	UserEmails    	[]string `json:"userEmails,omitempty"`
 	ServiceIDs    	[]string `json:"serviceIDs,omitempty"`
+	// TemporaryMembers are the temporary members with their resolved expiry time
+	TemporaryMembers []TemporaryMember `json:"temporaryMembers,omitempty"`
+	// ExpiredMembers lists the user emails and service IDs removed after they expired
+	ExpiredMembers 	[]string `json:"expiredMembers,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -33,15 +33,35 @@ type Subject struct {
 	AccessGroupDef AccessGroupDef `json:"accessGroupDef,omitempty"`
 }
 
+// Attribute is a resource attribute matched by a policy. Operator is one of
+// stringEquals (the default) or stringMatch, which accepts the wildcards '*' and '?'
+type Attribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// +kubebuilder:validation:Enum=stringEquals;stringMatch
+	Operator string `json:"operator,omitempty"`
+}
+
+// Tag is an access tag condition of a policy. A resource matches when it is tagged
+// Key:Value, compared with Operator (stringEquals by default, or stringMatch)
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// +kubebuilder:validation:Enum=stringEquals;stringMatch
+	Operator string `json:"operator,omitempty"`
+}
+
 type Target struct {
-	ResourceGroup string `json:"resourceGroup,omitempty"`
-	Region        string `json:"region,omitempty"`
-	ServiceClass  string `json:"serviceClass,omitempty"`
-	ServiceID     string `json:"serviceID,omitempty"`
-	ResourceName  string `json:"resourceName,omitempty"`
-	ResourceID    string `json:"resourceID,omitempty"`
-	ResourceKey   string `json:"resourceKey,omitempty"`
-	ResourceValue string `json:"resourceValue,omitempty"`
+	ResourceGroup string      `json:"resourceGroup,omitempty"`
+	Region        string      `json:"region,omitempty"`
+	ServiceClass  string      `json:"serviceClass,omitempty"`
+	ServiceID     string      `json:"serviceID,omitempty"`
+	ResourceName  string      `json:"resourceName,omitempty"`
+	ResourceID    string      `json:"resourceID,omitempty"`
+	ResourceKey   string      `json:"resourceKey,omitempty"`
+	ResourceValue string      `json:"resourceValue,omitempty"`
+	Attributes    []Attribute `json:"attributes,omitempty"`
+	Tags          []Tag       `json:"tags,omitempty"`
 }
 
 type CustomRolesDef struct {
@@ -55,20 +75,55 @@ type Roles struct {
 	CustomRolesDef   []CustomRolesDef `json:"customRolesDef,omitempty"`
 }
 
+// WeeklySchedule grants access on some days of the week, optionally between two times of the day
+type WeeklySchedule struct {
+	// Days of the week, e.g. Monday
+	Days []string `json:"days"`
+	// StartTime is the time of the day access starts, formatted as 15:04
+	StartTime string `json:"startTime,omitempty"`
+	// EndTime is the time of the day access ends, formatted as 15:04
+	EndTime string `json:"endTime,omitempty"`
+	// TimeZoneOffset of the schedule, e.g. -05:00. Defaults to UTC
+	TimeZoneOffset string `json:"timeZoneOffset,omitempty"`
+}
+
+// PolicyConditions restrict when an access policy grants access. They are enforced by IAM
+// with a v2 policy, so access stops even if the operator is not running.
+type PolicyConditions struct {
+	// NotBefore is the time access starts, defaults to the creation of the access policy
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// NotAfter is the time access ends, required unless Weekly is set
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+	// Weekly is a recurring access window, mutually exclusive with NotBefore and NotAfter
+	Weekly *WeeklySchedule `json:"weekly,omitempty"`
+}
+
 // AccessPolicySpec defines the desired state of AccessPolicy
 type AccessPolicySpec struct {
-	Subject Subject `json:"subject,required"`
-	Roles   Roles   `json:"roles,required"`
-	Target  Target  `json:"target,required"`
+	Subject    Subject           `json:"subject,required"`
+	Roles      Roles             `json:"roles,required"`
+	Target     Target            `json:"target,required"`
+	Conditions *PolicyConditions `json:"conditions,omitempty"`
+	// ExpiresAt is the time the operator revokes the access policy
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// TTL revokes the access policy after a duration from its creation, e.g. 72h
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// DeleteOnExpiry deletes the access policy resource once it has expired
+	DeleteOnExpiry bool `json:"deleteOnExpiry,omitempty"`
+	// DriftPolicy is how changes made outside of the operator are handled: Enforce (default), Detect or Ignore
+	// +kubebuilder:validation:Enum=Enforce;Detect;Ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
 }
 
 // AccessPolicyStatus defines the observed state of AccessPolicy
 type AccessPolicyStatus struct {
 	resv1.ResourceStatus `json:",inline"`
-	PolicyID             string  `json:"policyID,omitempty"`
-	Subject              Subject `json:"subject,omitempty"`
-	Roles                Roles   `json:"roles,omitempty"`
-	Target               Target  `json:"target,omitempty"`
+	PolicyID             string            `json:"policyID,omitempty"`
+	Subject              Subject           `json:"subject,omitempty"`
+	Roles                Roles             `json:"roles,omitempty"`
+	Target               Target            `json:"target,omitempty"`
+	PolicyConditions     *PolicyConditions `json:"policyConditions,omitempty"`
+	ExpiresAt            *metav1.Time      `json:"expiresAt,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ApprovedByAnnotation names the Kubernetes user approving an access request
+	ApprovedByAnnotation = "ibmcloud.ibm.com/approved-by"
+	// DeniedByAnnotation names the Kubernetes user denying an access request
+	DeniedByAnnotation = "ibmcloud.ibm.com/denied-by"
+)
+
+// AccessRequestSpec defines the desired state of AccessRequest
+type AccessRequestSpec struct {
+	// Requester is the Kubernetes user asking for access, who can't review the request. The admission
+	// webhook sets it to the user who creates the request.
+	Requester string  `json:"requester,omitempty"`
+	Subject   Subject `json:"subject"`
+	Roles     Roles   `json:"roles"`
+	Target    Target  `json:"target"`
+	// Justification tells the approver why access is needed
+	Justification string `json:"justification"`
+	// Duration of the access once approved, e.g. 8h
+	Duration metav1.Duration `json:"duration"`
+}
+
+// AccessRequestStatus defines the observed state of AccessRequest
+type AccessRequestStatus struct {
+	resv1.ResourceStatus `json:",inline"`
+	Requester            string `json:"requester,omitempty"`
+	// Approver is the Kubernetes user who approved or denied the request
+	Approver    string       `json:"approver,omitempty"`
+	RequestedAt *metav1.Time `json:"requestedAt,omitempty"`
+	ApprovedAt  *metav1.Time `json:"approvedAt,omitempty"`
+	// ApprovedGeneration is the generation of the spec that was approved
+	ApprovedGeneration int64        `json:"approvedGeneration,omitempty"`
+	ExpiresAt          *metav1.Time `json:"expiresAt,omitempty"`
+	// AccessPolicyName is the access policy granting the approved access
+	AccessPolicyName string `json:"accessPolicyName,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AccessRequest is the Schema for the accessrequests API
+// +kubebuilder:resource:path=accessrequests,scope=Namespaced
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Requester",type="string",JSONPath=".spec.requester"
+// +kubebuilder:printcolumn:name="Approver",type="string",JSONPath=".status.approver"
+// +kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.expiresAt"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AccessRequestSpec   `json:"spec,omitempty"`
+	Status AccessRequestStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AccessRequestList contains a list of AccessRequest
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AccessRequest `json:"items"`
+}
+
+// GetStatus returns the access request status
+func (s *AccessRequest) GetStatus() resv1.Status {
+	return &s.Status
+}
+
+func init() {
+	SchemeBuilder.Register(&AccessRequest{}, &AccessRequestList{})
+}
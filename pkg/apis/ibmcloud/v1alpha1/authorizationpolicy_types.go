@@ -22,13 +22,14 @@ import (
 )
 
 type Info struct {
-	ServiceClass  string `json:"serviceClass,required"`
-	ServiceID     string `json:"serviceID,omitempty"`
-	ResourceName  string `json:"resourceName,omitempty"`
-	ResourceID    string `json:"resourceID,omitempty"`
-	ResourceKey   string `json:"resourceKey,omitempty"`
-	ResourceValue string `json:"resourceValue,omitempty"`
-	ResourceGroup string `json:"resourceGroup,omitempty"` // mutually exclusive with ServiceID
+	ServiceClass  string      `json:"serviceClass,required"`
+	ServiceID     string      `json:"serviceID,omitempty"`
+	ResourceName  string      `json:"resourceName,omitempty"`
+	ResourceID    string      `json:"resourceID,omitempty"`
+	ResourceKey   string      `json:"resourceKey,omitempty"`
+	ResourceValue string      `json:"resourceValue,omitempty"`
+	ResourceGroup string      `json:"resourceGroup,omitempty"` // mutually exclusive with ServiceID
+	Attributes    []Attribute `json:"attributes,omitempty"`
 }
 
 // AuthorizationPolicySpec defines the desired state of AuthorizationPolicy
@@ -36,6 +37,9 @@ type AuthorizationPolicySpec struct {
 	Source Info     `json:"source,required"`
 	Roles  []string `json:"roles,required"`
 	Target Info     `json:"target,required"`
+	// DriftPolicy is how changes made outside of the operator are handled: Enforce (default), Detect or Ignore
+	// +kubebuilder:validation:Enum=Enforce;Detect;Ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
 }
 
 // AuthorizationPolicyStatus defines the observed state of AuthorizationPolicy
@@ -28,6 +28,9 @@ type CustomRoleSpec struct {
 	DisplayName string   `json:"displayName,required"`
 	Description string   `json:"description,required"`
 	Actions     []string `json:"actions,required"`
+	// DriftPolicy is how changes made outside of the operator are handled: Enforce (default), Detect or Ignore
+	// +kubebuilder:validation:Enum=Enforce;Detect;Ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
 }
 
 // CustomRoleStatus defines the observed state of CustomRole
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IAMOrphanReportSpec defines the desired state of IAMOrphanReport
+type IAMOrphanReportSpec struct {
+	// Interval between two sweeps of the account, defaults to 1h. Intervals shorter than 1m are raised to 1m
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// DeleteOrphans deletes orphaned IAM objects from the account once their grace period has passed
+	DeleteOrphans bool `json:"deleteOrphans,omitempty"`
+	// GracePeriod is how long an IAM object stays orphaned before it is deleted, defaults to 24h
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// Orphan is an operator owned IAM object that no custom resource manages
+type Orphan struct {
+	// Kind of the IAM object, AccessGroup, CustomRole or Policy
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// FirstSeen is when the sweeper first found the IAM object orphaned
+	FirstSeen metav1.Time `json:"firstSeen"`
+	// Reason is why the IAM object wasn't deleted once its grace period passed
+	Reason string `json:"reason,omitempty"`
+}
+
+// IAMOrphanReportStatus defines the observed state of IAMOrphanReport
+type IAMOrphanReportStatus struct {
+	resv1.ResourceStatus `json:",inline"`
+	LastSweep            *metav1.Time `json:"lastSweep,omitempty"`
+	Orphans              []Orphan     `json:"orphans,omitempty"`
+	// Deleted lists the IDs of the orphans deleted by the last sweep
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IAMOrphanReport is the Schema for the iamorphanreports API
+// +kubebuilder:resource:path=iamorphanreports,scope=Namespaced
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Last Sweep",type="date",JSONPath=".status.lastSweep"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+type IAMOrphanReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IAMOrphanReportSpec   `json:"spec,omitempty"`
+	Status IAMOrphanReportStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IAMOrphanReportList contains a list of IAMOrphanReport
+type IAMOrphanReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IAMOrphanReport `json:"items"`
+}
+
+// GetStatus returns the orphan report status
+func (s *IAMOrphanReport) GetStatus() resv1.Status {
+	return &s.Status
+}
+
+func init() {
+	SchemeBuilder.Register(&IAMOrphanReport{}, &IAMOrphanReportList{})
+}
@@ -24,7 +24,19 @@ import (
 	"errors"
 
 	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
-	common "github.com/IBM/ibmcloud-iam-operator/pkg/util"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/control"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/expiry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamcache"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/plan"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
 
  	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
 	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
@@ -34,7 +46,9 @@ import (
 
 	"k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -48,7 +62,7 @@ import (
 var log = logf.Log.WithName("controller_accessgroup")
 
 const accessgroupFinalizer = "accessgroup.ibmcloud.ibm.com"
-const syncPeriod = time.Second * 150
+const accessgroupKind = "AccessGroup"
 
 // ContainsFinalizer checks if the instance contains accessgroup finalizer
 func ContainsFinalizer(instance *ibmcloudv1alpha1.AccessGroup) bool {
@@ -75,18 +89,22 @@ func DeleteFinalizer(instance *ibmcloudv1alpha1.AccessGroup) []string {
 // Add creates a new AccessGroup Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	namespaces, err := control.Namespaces(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespaces))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileAccessGroup{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager, namespaces client.Reader) reconcile.Reconciler {
+	return &ReconcileAccessGroup{client: mgr.GetClient(), reader: namespaces, scheme: mgr.GetScheme(), iam: iamclient.New(), recorder: mgr.GetEventRecorderFor("accessgroup-controller")}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("accessgroup-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("accessgroup-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: settings.MaxConcurrentReconciles(accessgroupKind)})
 	if err != nil {
 		return err
 	}
@@ -116,7 +134,13 @@ type ReconcileAccessGroup struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads the namespaces, which the cache may not hold
+	reader client.Reader
 	scheme *runtime.Scheme
+	// iam connects to the IAM account of the namespace of an object
+	iam iamclient.Factory
+	// recorder records the events of drift in IAM
+	recorder record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a AccessGroup object and makes changes based on the state read
@@ -125,6 +149,14 @@ type ReconcileAccessGroup struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile("AccessGroup", request.NamespacedName)
+	result, err := r.reconcile(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile reconciles a AccessGroup within the span of the reconcile
+func (r *ReconcileAccessGroup) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling Access Group")
 
@@ -154,6 +186,32 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 		}
 	} 
 
+	// Leave a resource paused by its annotation or its namespace's alone, and record a resume or a forced resync
+	paused, reason, err := control.Paused(r.reader, instance)
+	if err != nil {
+		reqLogger.Info("Error getting pause annotations", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	if paused {
+		if control.Pause(instance, reason) {
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for pause", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		reqLogger.Info("Access Group reconciliation is paused", "Reason", reason)
+		return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+	}
+	resumed := control.Resume(instance)
+	resync := control.Resync(instance)
+	applied := !plan.Enabled(instance) && plan.Clear(instance)
+	if resumed || resync || applied {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for resume, resync or end of dry run", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
 	// Check that the spec is well-formed
 	if !isWellFormed(*instance) {
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -165,18 +223,21 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 			}
 			return reconcile.Result{}, nil
 		}
-		if instance.Status.State != "Failed" {
+		err := requeue.AsPermanent(fmt.Errorf("The spec is not well-formed"))
+		if requeue.Report(instance, err) || instance.Status.State != "Failed" {
 			instance.Status.State = "Failed"
-			instance.Status.Message = fmt.Errorf("The spec is not well-formed").Error()
+			instance.Status.Message = err.Error()
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for bad spec", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}	
 		}
-		return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+		return requeue.Result(err)
 	}
 
-	sess, myAccount, err := common.GetIAMAccountInfo(r.client, instance.ObjectMeta.Namespace)
+	_, credentials := tracing.Start(ctx, "LoadCredentials")
+	myAccount, iamClients, err := r.iam.Connect(ctx, r.client, instance.ObjectMeta.Namespace)
+	tracing.End(credentials, err)
 	if err != nil {
 		reqLogger.Info("Error getting IBM Cloud IAM account information", instance.Name, err.Error())
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -190,36 +251,63 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 		}
 		instance.Status.State = "Failed"
 		instance.Status.Message = "Error getting IBM Cloud IAM account information"
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for failing IAM account setup", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, err
+		return requeue.Result(err)
+	}
+
+	// Wait for IAM to recover rather than sending it more requests
+	unavailable := resilience.Unavailable(myAccount.GUID)
+	if resilience.Report(instance, unavailable) {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for IAM availability", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	if unavailable > 0 {
+		reqLogger.Info("IAM is unavailable, waiting for it to recover", "Retry after", unavailable.String())
+		return reconcile.Result{Requeue: true, RequeueAfter: unavailable}, nil
 	}
 
 	statusGroupID := instance.Status.GroupID
+	if statusGroupID == "" && !instance.ObjectMeta.DeletionTimestamp.IsZero() { // Status was lost, delete the access group recorded for this resource
+		statusGroupID = ownership.RecordedID(accessgroupKind, instance, myAccount.GUID)
+	}
 
-	iamClient, err := iamv1.New(sess)
+	serviceIDAPI, err := iamClients.ServiceIDs()
 	if err != nil {
 		reqLogger.Info("Error creating IAM Client", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	serviceIDAPI := iamClient.ServiceIds()
 
-	accClient1, err := accountv1.New(sess)
+	accountAPIV1, err := iamClients.Accounts()
 	if err != nil {
 		reqLogger.Info("Error creating account Client", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	accountAPIV1 := accClient1.Accounts()
 
-	iamuumClient, err := iamuumv2.New(sess)
+	accessGroupAPI, err := iamClients.AccessGroups()
 	if err != nil {
 		reqLogger.Info("Error creating iamuum Client", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	accessGroupAPI := iamuumClient.AccessGroup()
-	accessGroupMemAPI := iamuumClient.AccessGroupMember()
+	accessGroupMemAPI, err := iamClients.AccessGroupMembers()
+	if err != nil {
+		reqLogger.Info("Error creating iamuum Client", instance.Name, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	iamCache, err := iamClients.Cache()
+	if err != nil {
+		reqLogger.Info("Error getting IAM cache", instance.Name, err.Error())
+		return reconcile.Result{}, err
+	}
+	if resync { // Read IAM again rather than the cached state
+		iamCache.Expire()
+	}
 	
 	// Delete if necessary
  	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -234,8 +322,13 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 	} else {
 		// The object is being deleted
 		if ContainsFinalizer(instance) {
-			if (statusGroupID != "") { //Group must exist in IAM since status has an ID 
+			if statusGroupID != "" && plan.Enabled(instance) { // Record the deletion in an event, which outlives the resource
+				if err := r.recordPlan(instance, plan.Delete, nil, false); err != nil {
+					return reconcile.Result{}, err
+				}
+			} else if (statusGroupID != "") { //Group must exist in IAM since status has an ID 
 				err := deleteAccessGroup(statusGroupID, myAccount, accountAPIV1, accessGroupAPI)
+				iamCache.Invalidate(statusGroupID)
 				if err != nil {
 					if !strings.Contains(err.Error(), "Failed to find") {
 						reqLogger.Info("Error deleting access group", instance.Name, err.Error())
@@ -263,47 +356,106 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 		}
 	}  
 
+	// Temporary members are only part of the access group until they expire
+	now := time.Now()
+	temporaryMembers := resolveTemporaryMembers(instance, now)
+	userEmails, serviceIDs, expiredMembers := activeMembers(instance, temporaryMembers, now)
+
+	// Leave a spec that failed permanently alone until it changes, a temporary member expires or a resync is forced
+	if requeue.Stalled(instance) && len(expiredMembers) == len(instance.Status.ExpiredMembers) && !resync {
+		reqLogger.Info("Access Group failed permanently, waiting for its spec to change")
+		return requeue.Until(nextExpiry(temporaryMembers, now), now), nil
+	}
+
+	if statusGroupID == "" { // Status was lost, e.g. by a restore, look for the access group of this resource before creating one
+		statusGroupID, err = r.rediscoverAccessGroup(instance, myAccount.GUID, accessGroupAPI)
+		if err != nil {
+			reqLogger.Info("Error looking for existing access group", "Failed", err.Error())
+			instance.Status.State = "Failed"
+			instance.Status.Message = "Error looking for existing access group"
+			requeue.Report(instance, err)
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for failing access group lookup", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+			return requeue.ResultUntil(err, nextExpiry(temporaryMembers, now), now)
+		}
+		instance.Status.GroupID = statusGroupID
+		if statusGroupID != "" { // Compare the adopted access group with the spec as drift, so that its drift policy applies
+			instance.Status.Name = instance.Spec.Name
+			instance.Status.Description = instance.Spec.Description
+			instance.Status.UserEmails = instance.Spec.UserEmails
+			instance.Status.ServiceIDs = instance.Spec.ServiceIDs
+		}
+	}
+
 	if (statusGroupID != "") { //Group must exist in IAM since status has an ID 
-		retrievedGroup, etag, err := accessGroupAPI.Get(statusGroupID)
+		retrievedGroup, err := iamCache.AccessGroup(statusGroupID)
 		if err != nil {
 			reqLogger.Info("Error retrieving access group", "Failed", err.Error())
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error retrieving access group"
 			instance.Status.GroupID = ""   //clear out the group ID since group with this ID can't be retrieved
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing access group retrieval", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, err
+			return requeue.ResultUntil(err, nextExpiry(temporaryMembers, now), now)
 		}
 
-		retrievedMembers, err := accessGroupMemAPI.List(retrievedGroup.ID)
+		retrievedMembers, err := iamCache.Members(retrievedGroup.ID)
 		if err != nil {
 			reqLogger.Info("Error retrieving access group members", "Failed", err.Error())
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error retrieving access group members"
 			instance.Status.GroupID = ""   //clear out the group ID since group members with this ID can't be retrieved
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing access group members retrieval", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, err
+			return requeue.ResultUntil(err, nextExpiry(temporaryMembers, now), now)
 		}
 
-		if (specChanged(instance) || groupChanged(instance, retrievedGroup, retrievedMembers, myAccount, accountAPIV1, serviceIDAPI)) { // Spec change or a change via the IAM console means the acccess group needs an update
-			updatedgroup, err := updateAccessGroup(instance, myAccount, accountAPIV1, serviceIDAPI, accessGroupAPI, accessGroupMemAPI, etag, retrievedMembers)
+		changed := specChanged(instance) || temporaryMembersChanged(instance, temporaryMembers, expiredMembers)
+		var diffs []drift.Difference
+		if !changed {
+			diffs = groupDrift(instance, userEmails, serviceIDs, retrievedGroup, retrievedMembers, myAccount, accountAPIV1, iamCache)
+		}
+		mode := drift.Mode(instance.Spec.DriftPolicy, settings.Current.DriftPolicy)
+		driftReported := drift.Report(instance, mode, diffs)
+
+		if plan.Enabled(instance) {
+			requeueAfter := expiry.RequeueAfter(nextExpiry(temporaryMembers, now), now, settings.SyncPeriod(instance))
+			if changed || (len(diffs) > 0 && mode == drift.Enforce) {
+				diffs = groupDrift(instance, userEmails, serviceIDs, retrievedGroup, retrievedMembers, myAccount, accountAPIV1, iamCache)
+				return r.planAccessGroup(instance, plan.Update, diffs, driftReported, requeueAfter)
+			}
+			return r.planAccessGroup(instance, plan.NoChange, diffs, driftReported, requeueAfter)
+		}
+
+		drift.Event(r.recorder, instance, mode, diffs, driftReported)
+
+		if changed || (len(diffs) > 0 && mode == drift.Enforce) { // Spec change or an enforced change via the IAM console means the acccess group needs an update
+			updatedgroup, err := updateAccessGroup(instance, userEmails, serviceIDs, myAccount, accountAPIV1, serviceIDAPI, accessGroupAPI, accessGroupMemAPI)
+			iamCache.Invalidate(statusGroupID)
 			if err != nil {
 				reqLogger.Info("Error updating access group", instance.Name, err.Error())
 				instance.Status.State = "Failed"
 				instance.Status.Message = "Error updating access group"
+				requeue.Report(instance, err)
 				if err := r.client.Status().Update(context.Background(), instance); err != nil {
 					reqLogger.Info("Error updating status for failing access group update", "Failed", err.Error())
 					//TODO ??? delete access group
 					return reconcile.Result{}, err
 				}
-				return reconcile.Result{}, err
+				return requeue.ResultUntil(err, nextExpiry(temporaryMembers, now), now)
 			}
 			reqLogger.Info("Updated access group.","Group ID:",statusGroupID)
+			if !changed {
+				metrics.DriftCorrected(accessgroupKind)
+			}
 
 			instance.Status.State = "Online"
 			instance.Status.Message = "IAM access group updated"
@@ -312,23 +464,41 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 			instance.Status.Description = instance.Spec.Description
 			instance.Status.UserEmails = instance.Spec.UserEmails
 			instance.Status.ServiceIDs = instance.Spec.ServiceIDs
+			instance.Status.TemporaryMembers = temporaryMembers
+			instance.Status.ExpiredMembers = expiredMembers
+			requeue.Report(instance, nil)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for access group update", "Failed", err.Error())
 				//TODO ??? delete access group
 				return reconcile.Result{}, err
 			}
-		} 
+		} else if requeue.Report(instance, nil) || driftReported || instance.Status.State == "Pending" {
+			if instance.Status.State == "Pending" { // Resumed after a pause
+				instance.Status.State = "Online"
+			}
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for access group drift", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
 	} else { //Group doesn't exist in IAM
-		createdGroup, err := createAccessGroup(instance, myAccount, accountAPIV1, serviceIDAPI, accessGroupAPI, accessGroupMemAPI)
+		if plan.Enabled(instance) {
+			diffs := plan.Creation(compile.AccessGroup(instance, myAccount.GUID, settings.Current.InstanceID))
+			diffs = append(diffs, drift.Difference{Field: "members", Desired: append(append([]string{}, userEmails...), serviceIDs...)})
+			requeueAfter := expiry.RequeueAfter(nextExpiry(temporaryMembers, now), now, settings.SyncPeriod(instance))
+			return r.planAccessGroup(instance, plan.Create, diffs, false, requeueAfter)
+		}
+		createdGroup, err := createAccessGroup(instance, userEmails, serviceIDs, myAccount, accountAPIV1, serviceIDAPI, accessGroupAPI, accessGroupMemAPI)
 		if err != nil {
 			reqLogger.Info("Error creating access group", instance.Name, err.Error())
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error creating access group"
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing access group creation", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{}, err
+			return requeue.ResultUntil(err, nextExpiry(temporaryMembers, now), now)
 		}
 		reqLogger.Info("Created access group.","Group ID:",createdGroup.ID)
 
@@ -339,6 +509,9 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 		instance.Status.Description = instance.Spec.Description
 		instance.Status.UserEmails = instance.Spec.UserEmails
 		instance.Status.ServiceIDs = instance.Spec.ServiceIDs
+		instance.Status.TemporaryMembers = temporaryMembers
+		instance.Status.ExpiredMembers = expiredMembers
+		requeue.Report(instance, nil)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for access group creation", "Failed", err.Error())
 			errr := deleteAccessGroup(createdGroup.ID, myAccount, accountAPIV1, accessGroupAPI)
@@ -351,24 +524,106 @@ func (r *ReconcileAccessGroup) Reconcile(request reconcile.Request) (reconcile.R
 			reqLogger.Info("Deleted access group.","Group ID:", createdGroup.ID)
 			return reconcile.Result{}, err
 		}
+		metrics.Online(accessgroupKind, instance.ObjectMeta.CreationTimestamp.Time)
 	}	
-	return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+	if ownership.Record(accessgroupKind, instance, myAccount.GUID, instance.Status.GroupID) { // Annotate the resource with the ID of its access group, so it can be found without status
+		if err := r.client.Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error recording access group ID", instance.Name, err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: expiry.RequeueAfter(nextExpiry(temporaryMembers, now), now, settings.SyncPeriod(instance))}, nil
+}
+
+// planAccessGroup records the change the reconcile would make to the access group in IAM instead of making it
+func (r *ReconcileAccessGroup) planAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, action string, diffs []drift.Difference, statusChanged bool, requeueAfter time.Duration) (reconcile.Result, error) {
+	if err := r.recordPlan(instance, action, diffs, statusChanged); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
 }
 
-func groupChanged(instance *ibmcloudv1alpha1.AccessGroup, retrievedGroup *models.AccessGroupV2, retrievedMembers []models.AccessGroupMemberV2, myAccount *accountv2.Account, accountAPIV1 accountv1.Accounts, serviceIDAPI iamv1.ServiceIDRepository) bool {
-	description := "OPERATOR OWNED: "+instance.Spec.Description //Adding Operator owned TAG
+// recordPlan sets the planned change in the status of the resource and records it in an event when it changes
+func (r *ReconcileAccessGroup) recordPlan(instance *ibmcloudv1alpha1.AccessGroup, action string, diffs []drift.Difference, statusChanged bool) error {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	reqLogger.Info("Planned access group change", "Action", action, "Differences", drift.Summary(diffs))
+
+	planned := plan.Report(instance, action, "access group", diffs)
+	if requeue.Report(instance, nil) || planned || statusChanged {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for planned access group change", "Failed", err.Error())
+			return err
+		}
+	}
+	if planned {
+		if err := plan.Emit(r.client, instance, accessgroupKind); err != nil {
+			reqLogger.Info("Error recording planned access group change", "Failed", err.Error())
+		}
+	}
+	return nil
+}
+
+// rediscoverAccessGroup returns the ID of the access group recorded for the resource if it still exists in IAM,
+// else the ID of an operator owned access group with the name in the spec that no other resource recorded,
+// or "" if there is none
+func (r *ReconcileAccessGroup) rediscoverAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, accountID string, accessGroupAPI iamuumv2.AccessGroupRepository) (string, error) {
+	if recordedID := ownership.RecordedID(accessgroupKind, instance, accountID); recordedID != "" {
+		_, _, err := accessGroupAPI.Get(recordedID)
+		if err == nil {
+			log.Info("Recovered access group from annotations", "Group ID:", recordedID)
+			return recordedID, nil
+		}
+		if !iamclient.NotFound(err) { // The access group may still exist, don't create another one
+			return "", err
+		}
+	}
+
+	claimed, err := r.claimedGroups(instance, accountID)
+	if err != nil {
+		return "", err
+	}
+	groups, err := accessGroupAPI.FindByName(instance.Spec.Name, accountID)
+	if err != nil {
+		return "", err
+	}
+	for _, group := range groups {
+		if !claimed[group.AccessGroup.ID] && ownership.Adoptable(group.AccessGroup.Description, settings.Current.InstanceID) {
+			log.Info("Found existing access group", "Group ID:", group.AccessGroup.ID)
+			return group.AccessGroup.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// claimedGroups returns the IDs of the access groups recorded by the other access group resources
+func (r *ReconcileAccessGroup) claimedGroups(instance *ibmcloudv1alpha1.AccessGroup, accountID string) (map[string]bool, error) {
+	list := &ibmcloudv1alpha1.AccessGroupList{}
+	if err := r.client.List(context.Background(), list); err != nil {
+		return nil, err
+	}
+	objs := make([]metav1.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return ownership.Claimed(accessgroupKind, objs, instance, accountID), nil
+}
+
+// groupDrift returns the differences between the desired access group and the one in IAM
+func groupDrift(instance *ibmcloudv1alpha1.AccessGroup, userEmails []string, serviceIDs []string, retrievedGroup *models.AccessGroupV2, retrievedMembers []models.AccessGroupMemberV2, myAccount *accountv2.Account, accountAPIV1 accountv1.Accounts, iamCache *iamcache.Cache) []drift.Difference {
+	var diffs []drift.Difference
+	description := compile.Description(settings.Current.InstanceID, instance.Spec.Description)
 	if !reflect.DeepEqual(retrievedGroup.AccessGroup.Name,instance.Spec.Name) {
 		log.Info("Access group name in IAM has changed")
-		return true
+		diffs = append(diffs, drift.Difference{Field: "name", Desired: instance.Spec.Name, Actual: retrievedGroup.AccessGroup.Name})
 	}
 
 	if !reflect.DeepEqual(retrievedGroup.AccessGroup.Description, description) {
 		log.Info("Access group description in IAM has changed")
-		return true
+		diffs = append(diffs, drift.Difference{Field: "description", Desired: description, Actual: retrievedGroup.AccessGroup.Description})
 	}
 
 	var specMembers []models.AccessGroupMemberV2
-	for _, element := range instance.Spec.UserEmails {	
+	for _, element := range userEmails {	
 		userDetails, _ := accountAPIV1.FindAccountUserByUserId(myAccount.GUID, element)
 
 		if userDetails != nil && userDetails.UserId != "" && userDetails.IbmUniqueId != "" && userDetails.State != "PENDING" {
@@ -380,8 +635,8 @@ func groupChanged(instance *ibmcloudv1alpha1.AccessGroup, retrievedGroup *models
 		}
 	}	
 
-	for _, element := range instance.Spec.ServiceIDs {
-		sID, _ := serviceIDAPI.Get(element)
+	for _, element := range serviceIDs {
+		sID, _ := iamCache.ServiceID(element)
 		grpmem := models.AccessGroupMemberV2{
 			ID:   sID.IAMID,
 			Type: iamuumv2.AccessGroupMemberService,
@@ -389,17 +644,26 @@ func groupChanged(instance *ibmcloudv1alpha1.AccessGroup, retrievedGroup *models
 		specMembers= append(specMembers, grpmem)
 	}	
 
-	if (len(specMembers) != len(retrievedMembers)) {
-		return true
-	}
+	membersChanged := len(specMembers) != len(retrievedMembers)
 	for _, m := range specMembers {
 		if !contains(retrievedMembers,m) {
-			log.Info("Access group members in IAM has changed")
-			return true
+			membersChanged = true
 		}
 	}
+	if membersChanged {
+		log.Info("Access group members in IAM has changed")
+		diffs = append(diffs, drift.Difference{Field: "members", Desired: memberIDs(specMembers), Actual: memberIDs(retrievedMembers)})
+	}
 
-	return false
+	return diffs
+}
+
+func memberIDs(members []models.AccessGroupMemberV2) []string {
+	ids := []string{}
+	for _, m := range members {
+		ids = append(ids, m.ID)
+	}
+	return ids
 }
 
 func specChanged(instance *ibmcloudv1alpha1.AccessGroup) bool {
@@ -433,20 +697,12 @@ func specChanged(instance *ibmcloudv1alpha1.AccessGroup) bool {
 	return false
 }
 
-func createAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, myAccount *accountv2.Account, accountAPIV1 accountv1.Accounts, serviceIDAPI iamv1.ServiceIDRepository, accessGroupAPI iamuumv2.AccessGroupRepository, accessGroupMemAPI iamuumv2.AccessGroupMemberRepositoryV2) (*models.AccessGroupV2, error) {
+func createAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, userEmails []string, serviceIDs []string, myAccount *accountv2.Account, accountAPIV1 accountv1.Accounts, serviceIDAPI iamv1.ServiceIDRepository, accessGroupAPI iamuumv2.AccessGroupRepository, accessGroupMemAPI iamuumv2.AccessGroupMemberRepositoryV2) (*models.AccessGroupV2, error) {
 	var newaccessgroup *models.AccessGroupV2
 
 	accessgroups, err := accessGroupAPI.FindByName(instance.Spec.Name, myAccount.GUID)
 	if len(accessgroups) == 0 { //Access group by that name does not exist so create it
-		description := "OPERATOR OWNED: "+instance.Spec.Description //Adding Operator owned TAG
-		data := models.AccessGroupV2{
-			AccessGroup: models.AccessGroup{
-				Name: instance.Spec.Name,
-				Description: description,
-			},
-			AccountID: 	 myAccount.GUID,
-		}
-		newaccessgroup, err = accessGroupAPI.Create(data, myAccount.GUID)
+		newaccessgroup, err = accessGroupAPI.Create(compile.AccessGroup(instance, myAccount.GUID, settings.Current.InstanceID), myAccount.GUID)
 		if err != nil {
 			return nil, err
 		}
@@ -456,7 +712,7 @@ func createAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, myAccount *accoun
 
 	var members []models.AccessGroupMemberV2
 	log.Info("Adding members to the new Access Group")
-	for _, element := range instance.Spec.UserEmails {
+	for _, element := range userEmails {
 		_, err := accountAPIV1.InviteAccountUser(myAccount.GUID, element)
 		if err != nil {
 			_ = accessGroupAPI.Delete(newaccessgroup.ID,true)									
@@ -483,7 +739,7 @@ func createAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, myAccount *accoun
 		members = append(members, grpmem1)
 	}	
 
-	for _, element := range instance.Spec.ServiceIDs {
+	for _, element := range serviceIDs {
 		sID, err := serviceIDAPI.Get(element)
 		if err != nil {
 			_ = accessGroupAPI.Delete(newaccessgroup.ID,true)	
@@ -506,20 +762,23 @@ func createAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, myAccount *accoun
 	return newaccessgroup, nil
 }
 
-func updateAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, myAccount *accountv2.Account, accountAPIV1 accountv1.Accounts, serviceIDAPI iamv1.ServiceIDRepository, accessGroupAPI iamuumv2.AccessGroupRepository, accessGroupMemAPI iamuumv2.AccessGroupMemberRepositoryV2, etag string, currentMembers []models.AccessGroupMemberV2) (*models.AccessGroupV2, error) {
+func updateAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, userEmails []string, serviceIDs []string, myAccount *accountv2.Account, accountAPIV1 accountv1.Accounts, serviceIDAPI iamv1.ServiceIDRepository, accessGroupAPI iamuumv2.AccessGroupRepository, accessGroupMemAPI iamuumv2.AccessGroupMemberRepositoryV2) (*models.AccessGroupV2, error) {
 	accessgroupID := instance.Status.GroupID
-	description := "OPERATOR OWNED: "+instance.Spec.Description //Adding Operator owned TAG
-	data := iamuumv2.AccessGroupUpdateRequest {
-			Name: instance.Spec.Name,
-			Description: description,
+	_, etag, err := accessGroupAPI.Get(accessgroupID) // Cached groups have no etag and may have outdated members
+	if err != nil {
+		return nil, err
+	}
+	currentMembers, err := accessGroupMemAPI.List(accessgroupID)
+	if err != nil {
+		return nil, err
 	}
-	accessgroup, err := accessGroupAPI.Update(accessgroupID, data, etag)
+	accessgroup, err := accessGroupAPI.Update(accessgroupID, compile.AccessGroupUpdate(instance, settings.Current.InstanceID), etag)
 	if err != nil {
 		return nil, err
 	}
 
 	var newMembers []models.AccessGroupMemberV2
-	for _, element := range instance.Spec.UserEmails {
+	for _, element := range userEmails {
 		_, err := accountAPIV1.InviteAccountUser(myAccount.GUID, element)
 		if err != nil {									
 			return nil, err
@@ -543,7 +802,7 @@ func updateAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, myAccount *accoun
 		newMembers = append(newMembers, grpmem1)
 	}	
 
-	for _, element := range instance.Spec.ServiceIDs {
+	for _, element := range serviceIDs {
 		sID, err := serviceIDAPI.Get(element)
 		if err != nil {	
 			return nil, errors.New("Service ID is not valid:"+element)
@@ -604,6 +863,72 @@ func deleteAccessGroup(accessgroupID string, myAccount *accountv2.Account, accou
 	return nil
 }
 
+// resolveTemporaryMembers sets the expiry time of each temporary member. A TTL counts from when the member was first added.
+func resolveTemporaryMembers(instance *ibmcloudv1alpha1.AccessGroup, now time.Time) []ibmcloudv1alpha1.TemporaryMember {
+	var members []ibmcloudv1alpha1.TemporaryMember
+	for _, m := range instance.Spec.TemporaryMembers {
+		resolved := m
+		resolved.ExpiresAt = expiry.Deadline(metav1.NewTime(now), m.ExpiresAt, m.TTL)
+		if m.ExpiresAt == nil {
+			for _, s := range instance.Status.TemporaryMembers {
+				if s.UserEmail == m.UserEmail && s.ServiceID == m.ServiceID && reflect.DeepEqual(s.TTL, m.TTL) && s.ExpiresAt != nil {
+					resolved.ExpiresAt = s.ExpiresAt
+				}
+			}
+		}
+		members = append(members, resolved)
+	}
+	return members
+}
+
+// activeMembers returns the user emails and service IDs that belong in the access group, and the temporary members that have expired
+func activeMembers(instance *ibmcloudv1alpha1.AccessGroup, temporaryMembers []ibmcloudv1alpha1.TemporaryMember, now time.Time) ([]string, []string, []string) {
+	var userEmails, serviceIDs, expired []string
+	userEmails = append(userEmails, instance.Spec.UserEmails...)
+	serviceIDs = append(serviceIDs, instance.Spec.ServiceIDs...)
+	for _, m := range temporaryMembers {
+		if expiry.Expired(m.ExpiresAt, now) {
+			expired = append(expired, m.UserEmail+m.ServiceID)
+			continue
+		}
+		if m.UserEmail != "" {
+			userEmails = append(userEmails, m.UserEmail)
+		} else {
+			serviceIDs = append(serviceIDs, m.ServiceID)
+		}
+	}
+	return userEmails, serviceIDs, expired
+}
+
+func temporaryMembersChanged(instance *ibmcloudv1alpha1.AccessGroup, temporaryMembers []ibmcloudv1alpha1.TemporaryMember, expiredMembers []string) bool {
+	if len(temporaryMembers) != len(instance.Status.TemporaryMembers) {
+		log.Info("Access group temporary members in Spec has changed")
+		return true
+	}
+	for i, m := range temporaryMembers {
+		s := instance.Status.TemporaryMembers[i]
+		if m.UserEmail != s.UserEmail || m.ServiceID != s.ServiceID || !reflect.DeepEqual(m.TTL, s.TTL) || !expiry.Equal(m.ExpiresAt, s.ExpiresAt) {
+			log.Info("Access group temporary members in Spec has changed")
+			return true
+		}
+	}
+
+	if !reflect.DeepEqual(expiredMembers, instance.Status.ExpiredMembers) {
+		log.Info("Access group temporary members have expired")
+		return true
+	}
+	return false
+}
+
+// nextExpiry returns the time the next temporary member expires
+func nextExpiry(temporaryMembers []ibmcloudv1alpha1.TemporaryMember, now time.Time) *metav1.Time {
+	var deadlines []*metav1.Time
+	for _, m := range temporaryMembers {
+		deadlines = append(deadlines, m.ExpiresAt)
+	}
+	return expiry.Earliest(now, deadlines...)
+}
+
 func contains(s []models.AccessGroupMemberV2, e models.AccessGroupMemberV2) bool {
     for _, a := range s {
         if reflect.DeepEqual(a.ID,e.ID) {
@@ -614,8 +939,17 @@ func contains(s []models.AccessGroupMemberV2, e models.AccessGroupMemberV2) bool
 }
 
 func isWellFormed(instance ibmcloudv1alpha1.AccessGroup) bool {
-	if instance.Spec.Name != "" && (instance.Spec.UserEmails == nil && instance.Spec.ServiceIDs == nil) {
+	if instance.Spec.Name != "" && (instance.Spec.UserEmails == nil && instance.Spec.ServiceIDs == nil && instance.Spec.TemporaryMembers == nil) {
 		return false
 	}
+
+	for _, m := range instance.Spec.TemporaryMembers {
+		if (m.UserEmail == "") == (m.ServiceID == "") { // exactly one of user email or service ID
+			return false
+		}
+		if (m.ExpiresAt == nil) == (m.TTL == nil) { // exactly one of expiresAt or ttl
+			return false
+		}
+	}
 	return true
 }
\ No newline at end of file
@@ -0,0 +1,196 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessgroup
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+var request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "developers"}}
+
+// newTestReconciler returns a reconciler of an AccessGroup connected to an in-memory IAM account
+// with a user and a service ID
+func newTestReconciler(t *testing.T, spec ibmcloudv1alpha1.AccessGroupSpec) (*ReconcileAccessGroup, *iamfake.Factory) {
+	cluster := iamfake.NewCluster(&ibmcloudv1alpha1.AccessGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		Spec:       spec,
+	})
+	cluster.IAM.AddUser("jane@example.com")
+	cluster.IAM.AddServiceID("ServiceId-deployer", "deployer")
+	return &ReconcileAccessGroup{client: cluster.Client, reader: cluster.Client, scheme: cluster.Scheme, iam: cluster.IAM, recorder: cluster.Recorder}, cluster.IAM
+}
+
+func developersSpec() ibmcloudv1alpha1.AccessGroupSpec {
+	return ibmcloudv1alpha1.AccessGroupSpec{
+		Name:        "developers",
+		Description: "Developers",
+		UserEmails:  []string{"jane@example.com"},
+		ServiceIDs:  []string{"ServiceId-deployer"},
+	}
+}
+
+func getInstance(t *testing.T, r *ReconcileAccessGroup) *ibmcloudv1alpha1.AccessGroup {
+	instance := &ibmcloudv1alpha1.AccessGroup{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, instance))
+	return instance
+}
+
+func TestReconcileCreate(t *testing.T) {
+	r, iam := newTestReconciler(t, developersSpec())
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	groups := iam.AccessGroups()
+	require.Len(t, groups, 1)
+	assert.Equal(t, "OPERATOR OWNED: Developers", groups[0].Description)
+	assert.Len(t, iam.Members(groups[0].ID), 2)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, groups[0].ID, instance.Status.GroupID)
+}
+
+func TestReconcileInvalidUser(t *testing.T) {
+	spec := developersSpec()
+	spec.UserEmails = []string{"unknown@example.com"}
+	r, iam := newTestReconciler(t, spec)
+
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Failed", instance.Status.State)
+	assert.Equal(t, "Error creating access group", instance.Status.Message)
+	// The group and the invitation are rolled back
+	assert.Empty(t, iam.AccessGroups())
+	assert.Len(t, iam.Users(), 1)
+}
+
+func TestReconcileMemberDrift(t *testing.T) {
+	r, iam := newTestReconciler(t, developersSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	groupID := getInstance(t, r).Status.GroupID
+
+	// Remove a member as with the IAM console, the operator adds it back
+	require.NoError(t, iam.RemoveMember(groupID, "iam-ServiceId-deployer"))
+	iam.ResetCalls()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Contains(t, iam.Calls(), "AccessGroupMembers.Add")
+	assert.Len(t, iam.Members(groupID), 2)
+}
+
+func TestReconcileTemporaryMember(t *testing.T) {
+	spec := developersSpec()
+	spec.ServiceIDs = nil
+	expiresAt := metav1.NewTime(time.Now().Add(time.Hour).Truncate(time.Second))
+	spec.TemporaryMembers = []ibmcloudv1alpha1.TemporaryMember{{ServiceID: "ServiceId-deployer", ExpiresAt: &expiresAt}}
+	r, iam := newTestReconciler(t, spec)
+
+	result, err := r.Reconcile(request)
+	require.NoError(t, err)
+	groupID := getInstance(t, r).Status.GroupID
+	assert.Len(t, iam.Members(groupID), 2)
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Hour, "requeued after %v", result.RequeueAfter)
+
+	// The temporary member expires
+	instance := getInstance(t, r)
+	expired := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	instance.Spec.TemporaryMembers[0].ExpiresAt = &expired
+	require.NoError(t, r.client.Update(gocontext.Background(), instance))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	members := iam.Members(groupID)
+	require.Len(t, members, 1)
+	assert.Equal(t, "IBMid-user-1", members[0].ID)
+	instance = getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, []string{"ServiceId-deployer"}, instance.Status.ExpiredMembers)
+}
+
+func TestReconcileUnavailable(t *testing.T) {
+	r, iam := newTestReconciler(t, developersSpec())
+	iam.Fail("AccessGroups.FindByName", bmxerror.NewRequestFailure("service_unavailable", "IAM is unavailable", 503))
+
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	assert.Equal(t, "Failed", getInstance(t, r).Status.State)
+	assert.Empty(t, iam.AccessGroups())
+}
+
+func TestReconcileAdoptDetect(t *testing.T) {
+	spec := developersSpec()
+	spec.DriftPolicy = drift.Detect
+	r, iam := newTestReconciler(t, spec)
+	// Exported with a member the resource cannot express
+	group := iam.AddAccessGroup("developers", "OPERATOR OWNED: Developers")
+	jane := iam.Users()[0]
+	require.NoError(t, iam.AddMember(group.ID, models.AccessGroupMemberV2{ID: jane.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser}))
+	require.NoError(t, iam.AddMember(group.ID, models.AccessGroupMemberV2{ID: "iam-ServiceId-deployer", Type: iamuumv2.AccessGroupMemberService}))
+	require.NoError(t, iam.AddMember(group.ID, models.AccessGroupMemberV2{ID: "iam-Profile-1", Type: "Profile"}))
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, group.ID, instance.Status.GroupID)
+	assert.NotContains(t, iam.Calls(), "AccessGroups.Update")
+	assert.NotContains(t, iam.Calls(), "AccessGroupMembers.Remove")
+	assert.Len(t, iam.Members(group.ID), 3)
+	condition := resv1.GetCondition(instance, drift.ConditionType)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+}
+
+func TestReconcileClaimedGroup(t *testing.T) {
+	r, iam := newTestReconciler(t, developersSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	groupID := getInstance(t, r).Status.GroupID
+
+	// A copy of the resource in another namespace, restored without status, has the same name
+	require.NoError(t, r.client.Create(gocontext.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "restored"}}))
+	copied := getInstance(t, r).DeepCopy()
+	copied.ObjectMeta = metav1.ObjectMeta{Namespace: "restored", Name: request.Name, Annotations: copied.Annotations}
+	copied.Spec.ServiceIDs = nil
+	copied.Status = ibmcloudv1alpha1.AccessGroupStatus{}
+	require.NoError(t, r.client.Create(gocontext.Background(), copied))
+	copyRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: copied.Namespace, Name: copied.Name}}
+
+	// The group recorded by the first resource isn't adopted, and IAM rejects a second group with its name
+	_, err = r.Reconcile(copyRequest)
+	assert.Error(t, err)
+	require.NoError(t, r.client.Get(gocontext.Background(), copyRequest.NamespacedName, copied))
+	assert.Empty(t, copied.Status.GroupID)
+	assert.Equal(t, "Failed", copied.Status.State)
+	assert.Len(t, iam.AccessGroups(), 1)
+	assert.Len(t, iam.Members(groupID), 2)
+}
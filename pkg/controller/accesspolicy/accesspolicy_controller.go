@@ -24,21 +24,32 @@ import (
 	"time"
 
 	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
-	common "github.com/IBM/ibmcloud-iam-operator/pkg/util"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/control"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/expiry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamcache"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/plan"
+	poldiff "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/diff"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
 
 	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
-	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
-	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
-	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
-	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
-	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
-	"github.com/IBM-Cloud/bluemix-go/models"
-	"github.com/IBM-Cloud/bluemix-go/utils"
 
 	v1 "k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -51,7 +62,7 @@ import (
 var log = logf.Log.WithName("controller_accesspolicy")
 
 const accesspolicyFinalizer = "accesspolicy.ibmcloud.ibm.com"
-const syncPeriod = time.Second * 150
+const accesspolicyKind = "AccessPolicy"
 
 // ContainsFinalizer checks if the instance contains accesspolicy finalizer
 func ContainsFinalizer(instance *ibmcloudv1alpha1.AccessPolicy) bool {
@@ -78,18 +89,22 @@ func DeleteFinalizer(instance *ibmcloudv1alpha1.AccessPolicy) []string {
 // Add creates a new AccessPolicy Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	namespaces, err := control.Namespaces(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespaces))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileAccessPolicy{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager, namespaces client.Reader) reconcile.Reconciler {
+	return &ReconcileAccessPolicy{client: mgr.GetClient(), reader: namespaces, scheme: mgr.GetScheme(), iam: iamclient.New(), recorder: mgr.GetEventRecorderFor("accesspolicy-controller")}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("accesspolicy-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("accesspolicy-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: settings.MaxConcurrentReconciles(accesspolicyKind)})
 	if err != nil {
 		return err
 	}
@@ -108,9 +123,68 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the access groups and custom roles that access policies are waiting for
+	err = c.Watch(&source.Kind{Type: &ibmcloudv1alpha1.AccessGroup{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: waitingPolicies(mgr.GetClient(), refersToAccessGroup),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &ibmcloudv1alpha1.CustomRole{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: waitingPolicies(mgr.GetClient(), refersToCustomRole),
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// waitingPolicies maps a changed access group or custom role to the access policies waiting for it
+func waitingPolicies(c client.Client, refersTo func(ibmcloudv1alpha1.AccessPolicy, types.NamespacedName) bool) handler.ToRequestsFunc {
+	return func(o handler.MapObject) []reconcile.Request {
+		policies := &ibmcloudv1alpha1.AccessPolicyList{}
+		if err := c.List(context.Background(), policies); err != nil {
+			log.Info("Error listing access policies", "Failed", err.Error())
+			return nil
+		}
+
+		dependency := types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: o.Meta.GetName()}
+		var requests []reconcile.Request
+		for i := range policies.Items {
+			policy := &policies.Items[i]
+			if requeue.Waiting(policy) && refersTo(*policy, dependency) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}})
+			}
+		}
+		return requests
+	}
+}
+
+// refersToAccessGroup checks if the subject of the access policy is the access group resource
+func refersToAccessGroup(policy ibmcloudv1alpha1.AccessPolicy, accessGroup types.NamespacedName) bool {
+	accessGroupNameSpace := policy.ObjectMeta.Namespace
+	if policy.Spec.Subject.AccessGroupDef.AccessGroupNamespace != "" {
+		accessGroupNameSpace = policy.Spec.Subject.AccessGroupDef.AccessGroupNamespace
+	}
+	return policy.Spec.Subject.AccessGroupDef.AccessGroupName == accessGroup.Name && accessGroupNameSpace == accessGroup.Namespace
+}
+
+// refersToCustomRole checks if the access policy grants the custom role resource
+func refersToCustomRole(policy ibmcloudv1alpha1.AccessPolicy, customRole types.NamespacedName) bool {
+	for _, element := range policy.Spec.Roles.CustomRolesDef {
+		customRoleNameSpace := policy.ObjectMeta.Namespace
+		if element.CustomRoleNamespace != "" {
+			customRoleNameSpace = element.CustomRoleNamespace
+		}
+		if element.CustomRoleName == customRole.Name && customRoleNameSpace == customRole.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
 // blank assignment to verify that ReconcileAccessPolicy implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileAccessPolicy{}
 
@@ -119,7 +193,13 @@ type ReconcileAccessPolicy struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads the namespaces, which the cache may not hold
+	reader client.Reader
 	scheme *runtime.Scheme
+	// iam connects to the IAM account of the namespace of an object
+	iam iamclient.Factory
+	// recorder records the events of drift in IAM
+	recorder record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a AccessPolicy object and makes changes based on the state read
@@ -128,6 +208,14 @@ type ReconcileAccessPolicy struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileAccessPolicy) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile("AccessPolicy", request.NamespacedName)
+	result, err := r.reconcile(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile reconciles a AccessPolicy within the span of the reconcile
+func (r *ReconcileAccessPolicy) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling Access Policy")
 
@@ -157,6 +245,38 @@ func (r *ReconcileAccessPolicy) Reconcile(request reconcile.Request) (reconcile.
 		}
 	}
 
+	// Leave a resource paused by its annotation or its namespace's alone, and record a resume or a forced resync
+	paused, reason, err := control.Paused(r.reader, instance)
+	if err != nil {
+		reqLogger.Info("Error getting pause annotations", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	if paused {
+		if control.Pause(instance, reason) {
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for pause", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		reqLogger.Info("Access Policy reconciliation is paused", "Reason", reason)
+		return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+	}
+	resumed := control.Resume(instance)
+	resync := control.Resync(instance)
+	applied := !plan.Enabled(instance) && plan.Clear(instance)
+	if resumed || resync || applied {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for resume, resync or end of dry run", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Leave a spec that failed permanently alone until it changes, unless it is deleted, expires or a resync is forced
+	if requeue.Stalled(instance) && instance.ObjectMeta.DeletionTimestamp.IsZero() && !expiry.Expired(expiresAt(instance), time.Now()) && !resync {
+		reqLogger.Info("Access Policy failed permanently, waiting for its spec to change")
+		return requeue.Until(expiresAt(instance), time.Now()), nil
+	}
+
 	// Check that the spec is well-formed
 	if !isWellFormed(*instance) {
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -168,19 +288,22 @@ func (r *ReconcileAccessPolicy) Reconcile(request reconcile.Request) (reconcile.
 			}
 			return reconcile.Result{}, nil
 		}
-		if instance.Status.State != "Failed" {
+		err := requeue.AsPermanent(fmt.Errorf("The spec is not well-formed"))
+		if requeue.Report(instance, err) || instance.Status.State != "Failed" {
 			instance.Status.State = "Failed"
-			instance.Status.Message = fmt.Errorf("The spec is not well-formed").Error()
+			instance.Status.Message = err.Error()
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for bad spec", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
 
 		}
-		return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+		return requeue.ResultUntil(err, expiresAt(instance), time.Now())
 	}
 
-	sess, myAccount, err := common.GetIAMAccountInfo(r.client, instance.ObjectMeta.Namespace)
+	_, credentials := tracing.Start(ctx, "LoadCredentials")
+	myAccount, iamClients, err := r.iam.Connect(ctx, r.client, instance.ObjectMeta.Namespace)
+	tracing.End(credentials, err)
 	if err != nil {
 		reqLogger.Info("Error getting IBM Cloud IAM account information", instance.Name, err.Error())
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -194,51 +317,60 @@ func (r *ReconcileAccessPolicy) Reconcile(request reconcile.Request) (reconcile.
 		}
 		instance.Status.State = "Failed"
 		instance.Status.Message = "Error getting IBM Cloud IAM account information"
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for failing IAM account setup", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, err
+		return requeue.Result(err)
+	}
+
+	// Wait for IAM to recover rather than sending it more requests
+	unavailable := resilience.Unavailable(myAccount.GUID)
+	if resilience.Report(instance, unavailable) {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for IAM availability", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	if unavailable > 0 {
+		reqLogger.Info("IAM is unavailable, waiting for it to recover", "Retry after", unavailable.String())
+		return reconcile.Result{Requeue: true, RequeueAfter: unavailable}, nil
 	}
 
 	statusPolicyID := instance.Status.PolicyID
+	if statusPolicyID == "" && !instance.ObjectMeta.DeletionTimestamp.IsZero() { // Status was lost, delete the access policy recorded for this resource
+		statusPolicyID = ownership.RecordedID(accesspolicyKind, instance, myAccount.GUID)
+	}
 
-	iampapClient, err := iampapv1.New(sess)
+	policyAPI, err := iamClients.Policies()
 	if err != nil {
 		reqLogger.Info("Error getting iampap Client", instance.Name, err.Error())
 
 		return reconcile.Result{}, err
 	}
-	policyAPI := iampapClient.V1Policy()
 
-	iamClient, err := iamv1.New(sess)
+	accountAPIV1, err := iamClients.Accounts()
 	if err != nil {
-		reqLogger.Info("Error creating IAM Client", instance.Name, err.Error())
+		reqLogger.Info("Error getting account Client", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	serviceIDAPI := iamClient.ServiceIds()
-	serviceRolesAPI := iamClient.ServiceRoles()
 
-	roleClient, err := iampapv2.New(sess)
+	iamCache, err := iamClients.Cache()
 	if err != nil {
-		reqLogger.Info("Error creating Role Client", instance.Name, err.Error())
+		reqLogger.Info("Error getting IAM cache", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	customRolesAPI := roleClient.IAMRoles()
 
-	accClient1, err := accountv1.New(sess)
+	roleCatalog, err := iamClients.RoleCatalog()
 	if err != nil {
-		reqLogger.Info("Error getting account Client", instance.Name, err.Error())
+		reqLogger.Info("Error getting role catalog", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	accountAPIV1 := accClient1.Accounts()
-
-	iamuumClient, err := iamuumv2.New(sess)
-	if err != nil {
-		reqLogger.Info("Error getting iamuum Client", instance.Name, err.Error())
-		return reconcile.Result{}, err
+	if resync { // Read IAM and resolve roles and subjects again rather than using the cached state
+		iamCache.Expire()
+		roleCatalog.Expire()
 	}
-	accessGroupAPI := iamuumClient.AccessGroup()
 
 	// Delete if necessary
 	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -253,8 +385,15 @@ func (r *ReconcileAccessPolicy) Reconcile(request reconcile.Request) (reconcile.
 	} else {
 		// The object is being deleted
 		if ContainsFinalizer(instance) {
-			if statusPolicyID != "" { //Policy must exist in IAM since status has an ID
-				err := deleteAccessPolicy(statusPolicyID, policyAPI)
+			if statusPolicyID != "" && plan.Enabled(instance) { // Record the deletion in an event, which outlives the resource
+				if err := r.recordPlan(instance, plan.Delete, nil, false); err != nil {
+					return reconcile.Result{}, err
+				}
+			} else if statusPolicyID != "" { //Policy must exist in IAM since status has an ID
+				store, err := newPolicyStore(instance, polv1.Policy{}, iamClients, policyAPI, iamCache)
+				if err == nil {
+					err = store.delete(statusPolicyID)
+				}
 				if err != nil {
 					if !strings.Contains(err.Error(), "not found") {
 						reqLogger.Info("Error deleting access policy", instance.Name, err.Error())
@@ -282,151 +421,395 @@ func (r *ReconcileAccessPolicy) Reconcile(request reconcile.Request) (reconcile.
 		}
 	}
 
-	/* Setting roles, resource and subject in Policy */
-	policyRoles, err := getRoles(instance, r, myAccount, serviceRolesAPI, customRolesAPI)
-	if err != nil {
-		reqLogger.Info("Error getting roles for access policy", "Failed", err.Error())
-		instance.Status.State = "Failed"
-		instance.Status.Message = "Error getting roles for access policy"
-		if err := r.client.Status().Update(context.Background(), instance); err != nil {
-			reqLogger.Info("Error updating status for failing get roles", "Failed", err.Error())
+	// Revoke the access policy once it has expired
+	if expiry.Expired(expiresAt(instance), time.Now()) {
+		store, err := newPolicyStore(instance, polv1.Policy{}, iamClients, policyAPI, iamCache)
+		if err != nil {
+			reqLogger.Info("Error getting iampap v2 Client", instance.Name, err.Error())
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, err
+		return r.expireAccessPolicy(instance, store, myAccount.GUID)
 	}
 
-	policyResource, err := getResource(instance, serviceIDAPI)
+	/* Setting roles and subject in Policy */
+	policyResolver := resolver{Catalog: roleCatalog, client: r.client, accountID: myAccount.GUID, accounts: accountAPIV1, iamCache: iamCache, dryRun: plan.Enabled(instance)}
+	_, roles := tracing.Start(ctx, "ResolveRoles")
+	policyRoles, err := compile.AccessPolicyRoles(instance, policyResolver)
+	tracing.End(roles, err)
 	if err != nil {
-		reqLogger.Info("Error getting resource for access policy", "Failed", err.Error())
+		reqLogger.Info("Error getting roles for access policy", "Failed", err.Error())
 		instance.Status.State = "Failed"
-		instance.Status.Message = "Error getting resource for access policy"
+		instance.Status.Message = "Error getting roles for access policy"
+		if unknown, ok := err.(*rolecatalog.UnknownRolesError); ok {
+			instance.Status.Message = instance.Status.Message + ": " + unknown.Error()
+			err = requeue.AsPermanent(err)
+		} else if _, ok := err.(*compile.DependencyError); ok {
+			err = requeue.AsDependency(err)
+		}
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
-			reqLogger.Info("Error updating status for failing get resource", "Failed", err.Error())
+			reqLogger.Info("Error updating status for failing get roles", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, err
+		return requeue.ResultUntil(err, expiresAt(instance), time.Now())
 	}
 
-	policySubject, err := getSubject(instance, r, myAccount, accountAPIV1, serviceIDAPI, accessGroupAPI)
+	_, subject := tracing.Start(ctx, "ResolveSubject")
+	policySubject, err := compile.AccessPolicySubjects(instance, policyResolver)
+	tracing.End(subject, err)
 	if err != nil {
 		reqLogger.Info("Error getting subject for access policy", "Failed", err.Error())
 		instance.Status.State = "Failed"
 		instance.Status.Message = "Error getting subject for access policy"
+		if _, ok := err.(*compile.DependencyError); ok {
+			err = requeue.AsDependency(err)
+		}
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for failing get subject", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}
+		return requeue.ResultUntil(err, expiresAt(instance), time.Now())
+	}
+
+	policy := compile.NewAccessPolicy(instance, myAccount.GUID, settings.Current.InstanceID, policyRoles, policySubject)
+
+	if statusPolicyID == "" { // Status was lost, e.g. by a restore, look for the access policy of this resource before creating one
+		statusPolicyID, err = r.rediscoverAccessPolicy(instance, policy, myAccount.GUID, iamClients, policyAPI)
+		if err != nil {
+			reqLogger.Info("Error looking for existing access policy", "Failed", err.Error())
+			instance.Status.State = "Failed"
+			instance.Status.Message = "Error looking for existing access policy"
+			requeue.Report(instance, err)
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for failing access policy lookup", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+			return requeue.ResultUntil(err, expiresAt(instance), time.Now())
+		}
+		instance.Status.PolicyID = statusPolicyID
+	}
+
+	store, err := newPolicyStore(instance, policy, iamClients, policyAPI, iamCache)
+	if err != nil {
+		reqLogger.Info("Error getting iampap v2 Client", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
+	return r.reconcilePolicy(instance, store, myAccount.GUID)
+}
 
-	var policy = iampapv1.Policy{Roles: policyRoles, Resources: []iampapv1.Resource{policyResource}}
-	policy.Resources[0].SetAccountID(myAccount.GUID)
-	policy.Type = iampapv1.AccessPolicyType
-	policy.Subjects = policySubject
+// reconcilePolicy creates the access policy in IAM, or updates it when the spec changed or its drift is enforced
+func (r *ReconcileAccessPolicy) reconcilePolicy(instance *ibmcloudv1alpha1.AccessPolicy, store policyStore, accountID string) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	statusPolicyID := instance.Status.PolicyID
+	policy := store.desired()
 
 	if statusPolicyID != "" { //Policy must exist in IAM since status has an ID
-		retrievedPolicy, err := policyAPI.Get(statusPolicyID)
-		etag := retrievedPolicy.Version
+		retrievedPolicy, err := store.get(statusPolicyID)
 		if err != nil {
 			reqLogger.Info("Error retrieving policy", "Failed", err.Error())
-			instance.Status.State = "Failed"
-			instance.Status.Message = "Error retrieving policy"
 			instance.Status.PolicyID = "" //clear out the policy ID since policy with this ID can't be retrieved
-			if err := r.client.Status().Update(context.Background(), instance); err != nil {
-				reqLogger.Info("Error updating status for failing access policy retrieval", "Failed", err.Error())
-				return reconcile.Result{}, err
+			return r.fail(instance, "Error retrieving policy", err)
+		}
+
+		changed := specChanged(instance)
+		var diffs, unmarked []drift.Difference
+		if !changed {
+			diffs = policyDrift(policy, retrievedPolicy)
+			unmarked = poldiff.Unmarked(policy, retrievedPolicy)
+		}
+		mode := drift.Mode(instance.Spec.DriftPolicy, settings.Current.DriftPolicy)
+		driftReported := drift.Report(instance, mode, diffs)
+		update := changed || (len(diffs) > 0 && mode == drift.Enforce)
+
+		if plan.Enabled(instance) {
+			if update {
+				return r.planAccessPolicy(instance, plan.Update, poldiff.Policies(policy, retrievedPolicy), driftReported)
+			}
+			if len(unmarked) > 0 {
+				return r.planAccessPolicy(instance, plan.Update, unmarked, driftReported)
+			}
+			return r.planAccessPolicy(instance, plan.NoChange, diffs, driftReported)
+		}
+
+		drift.Event(r.recorder, instance, mode, diffs, driftReported)
+
+		if !update && len(unmarked) > 0 { // A policy created before the operator marked its policies only gets the marker
+			if err := store.stamp(statusPolicyID); err != nil {
+				reqLogger.Info("Error marking policy", "Failed", err.Error())
+				return r.fail(instance, "Error marking policy", err)
 			}
-			return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, err
+			reqLogger.Info("Marked access policy as owned.", "Policy ID:", statusPolicyID)
+			instance.Status.State = "Online"
+			instance.Status.Message = "IAM access policy marked as owned"
 		}
 
-		if specChanged(instance) || policyChanged(policy, retrievedPolicy) { // Spec change or a change via the IAM console means the acccess policy needs an update
-			updatedPolicy, err := updateAccessPolicy(statusPolicyID, policy, policyAPI, etag)
+		if update { // Spec change or an enforced change via the IAM console means the acccess policy needs an update
+			updatedID, err := store.update(statusPolicyID)
 			if err != nil {
 				reqLogger.Info("Error updating policy", "Failed", err.Error())
-				instance.Status.State = "Failed"
-				instance.Status.Message = "Error updating policy"
-				if err := r.client.Status().Update(context.Background(), instance); err != nil {
-					reqLogger.Info("Error updating status for failing access policy update", "Failed", err.Error())
-					return reconcile.Result{}, err
-				}
-				return reconcile.Result{}, err
+				return r.fail(instance, "Error updating policy", err)
+			}
+			reqLogger.Info("Updated access policy.", "Policy ID:", updatedID)
+			if !changed {
+				metrics.DriftCorrected(accesspolicyKind)
 			}
-			reqLogger.Info("Updated access policy.", "Policy ID:", updatedPolicy.ID, "Policy Href:", updatedPolicy.Href)
 
-			instance.Status.State = "Online"
-			instance.Status.Message = "IAM access policy updated"
-			instance.Status.PolicyID = updatedPolicy.ID
-			instance.Status.Subject = instance.Spec.Subject
-			instance.Status.Roles = instance.Spec.Roles
-			instance.Status.Target = instance.Spec.Target
+			setOnline(instance, "IAM access policy updated", updatedID)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for access policy update", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-		}
-	} else { //Policy doesn't exist in IAM
-		createdPolicy, err := createAccessPolicy(policy, policyAPI)
-		if err != nil {
-			reqLogger.Info("Error creating policy", "Failed", err.Error())
-			instance.Status.State = "Failed"
-			instance.Status.Message = "Error creating policy"
+		} else if requeue.Report(instance, nil) || driftReported || len(unmarked) > 0 || instance.Status.State == "Pending" {
+			if instance.Status.State == "Pending" { // Resumed after a pause
+				instance.Status.State = "Online"
+			}
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
-				reqLogger.Info("Error updating status for failing access policy creation", "Failed", err.Error())
+				reqLogger.Info("Error updating status for access policy drift", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
+		}
+	} else { //Policy doesn't exist in IAM
+		if plan.Enabled(instance) {
+			return r.planAccessPolicy(instance, plan.Create, poldiff.Policies(policy, polv2.Policy{}), false)
+		}
+		if err := r.recordIntent(instance, policy); err != nil {
 			return reconcile.Result{}, err
 		}
-		reqLogger.Info("Created access policy.", "Policy ID:", createdPolicy.ID, "Policy Href:", createdPolicy.Href)
+		createdID, err := store.create()
+		if err != nil {
+			reqLogger.Info("Error creating policy", "Failed", err.Error())
+			return r.fail(instance, "Error creating policy", err)
+		}
+		reqLogger.Info("Created access policy.", "Policy ID:", createdID)
 
-		instance.Status.State = "Online"
-		instance.Status.Message = "New IAM access policy created"
-		instance.Status.PolicyID = createdPolicy.ID
-		instance.Status.Subject = instance.Spec.Subject
-		instance.Status.Roles = instance.Spec.Roles
-		instance.Status.Target = instance.Spec.Target
+		setOnline(instance, "New IAM access policy created", createdID)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for access policy creation", "Failed", err.Error())
-			errr := deleteAccessPolicy(createdPolicy.ID, policyAPI)
+			errr := store.delete(createdID)
 			if errr != nil {
 				if !strings.Contains(errr.Error(), "not found") {
 					reqLogger.Info("Error deleting access policy", instance.Name, errr.Error())
 					return reconcile.Result{}, errr
 				}
 			}
-			reqLogger.Info("Deleted access policy.", "Policy ID:", createdPolicy.ID)
+			reqLogger.Info("Deleted access policy.", "Policy ID:", createdID)
 			return reconcile.Result{}, err
 		}
+		metrics.Online(accesspolicyKind, instance.ObjectMeta.CreationTimestamp.Time)
 	}
-	return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+	if err := r.recordPolicyID(instance, accountID); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: expiry.RequeueAfter(expiresAt(instance), time.Now(), settings.SyncPeriod(instance))}, nil
 }
 
-func policyChanged(policy iampapv1.Policy, retrievedPolicy iampapv1.Policy) bool {
-	if !reflect.DeepEqual(retrievedPolicy.Subjects, policy.Subjects) {
-		log.Info("Access policy subject in IAM has changed")
-		return true
+// fail sets the Failed state of the resource after an IAM request for the access policy failed, and requeues it
+func (r *ReconcileAccessPolicy) fail(instance *ibmcloudv1alpha1.AccessPolicy, message string, err error) (reconcile.Result, error) {
+	instance.Status.State = "Failed"
+	instance.Status.Message = message
+	requeue.Report(instance, err)
+	if err := r.client.Status().Update(context.Background(), instance); err != nil {
+		log.Info("Error updating status for failing access policy request", "Failed", err.Error())
+		return reconcile.Result{}, err
 	}
+	return requeue.ResultUntil(err, expiresAt(instance), time.Now())
+}
 
-	for _, m := range retrievedPolicy.Roles {
-		if !contains(policy.Roles, m) {
-			log.Info("Access policy roles in IAM has changed")
-			return true
+// setOnline records the spec the access policy in IAM was created or updated with in the status
+func setOnline(instance *ibmcloudv1alpha1.AccessPolicy, message string, policyID string) {
+	instance.Status.State = "Online"
+	instance.Status.Message = message
+	instance.Status.PolicyID = policyID
+	instance.Status.Subject = instance.Spec.Subject
+	instance.Status.Roles = instance.Spec.Roles
+	instance.Status.Target = instance.Spec.Target
+	instance.Status.ExpiresAt = expiresAt(instance)
+	instance.Status.PolicyConditions = instance.Spec.Conditions
+	requeue.Report(instance, nil)
+}
+
+// expireAccessPolicy revokes the access policy in IAM and marks the resource as expired
+func (r *ReconcileAccessPolicy) expireAccessPolicy(instance *ibmcloudv1alpha1.AccessPolicy, store policyStore, accountID string) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	statusPolicyID := instance.Status.PolicyID
+	if statusPolicyID == "" && instance.Status.State != "Expired" { // Status was lost, revoke the access policy recorded for this resource
+		statusPolicyID = ownership.RecordedID(accesspolicyKind, instance, accountID)
+	}
+
+	if plan.Enabled(instance) {
+		action := plan.NoChange
+		if statusPolicyID != "" {
+			action = plan.Delete
 		}
+		return reconcile.Result{}, r.recordPlan(instance, action, nil, false)
 	}
 
-	if !reflect.DeepEqual(retrievedPolicy.Resources, policy.Resources) {
-		log.Info("Access policy resource in IAM has changed")
-		return true
+	if statusPolicyID != "" { //Policy must exist in IAM since status has an ID
+		if err := store.delete(statusPolicyID); err != nil {
+			if !strings.Contains(err.Error(), "not found") {
+				reqLogger.Info("Error revoking expired access policy", instance.Name, err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		reqLogger.Info("Revoked expired access policy.", "Policy ID:", statusPolicyID)
 	}
-	return false
+
+	if instance.Status.State != "Expired" || statusPolicyID != "" {
+		instance.Status.State = "Expired"
+		instance.Status.Message = "IAM access policy expired"
+		instance.Status.PolicyID = "" //clear out the policy ID since policy with this ID has been revoked
+		instance.Status.ExpiresAt = expiresAt(instance)
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for access policy expiry", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
+	if instance.Spec.DeleteOnExpiry {
+		if err := r.client.Delete(context.Background(), instance); err != nil && !kerror.IsNotFound(err) {
+			reqLogger.Info("Error deleting expired access policy", instance.Name, err.Error())
+			return reconcile.Result{}, err
+		}
+		reqLogger.Info("Deleted expired access policy resource.")
+	}
+	// A later spec change that extends the expiry triggers a new reconcile
+	return reconcile.Result{}, nil
 }
 
-func contains(policyRoles []iampapv1.Role, e iampapv1.Role) bool {
-	for _, a := range policyRoles {
-		if reflect.DeepEqual(a.RoleID, e.RoleID) {
-			return true
+// planAccessPolicy records the change the reconcile would make to the access policy in IAM instead of making it
+func (r *ReconcileAccessPolicy) planAccessPolicy(instance *ibmcloudv1alpha1.AccessPolicy, action string, diffs []drift.Difference, statusChanged bool) (reconcile.Result, error) {
+	if err := r.recordPlan(instance, action, diffs, statusChanged); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: expiry.RequeueAfter(expiresAt(instance), time.Now(), settings.SyncPeriod(instance))}, nil
+}
+
+// recordPlan sets the planned change in the status of the resource and records it in an event when it changes
+func (r *ReconcileAccessPolicy) recordPlan(instance *ibmcloudv1alpha1.AccessPolicy, action string, diffs []drift.Difference, statusChanged bool) error {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	reqLogger.Info("Planned access policy change", "Action", action, "Differences", drift.Summary(diffs))
+
+	planned := plan.Report(instance, action, "access policy", diffs)
+	if requeue.Report(instance, nil) || planned || statusChanged {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for planned access policy change", "Failed", err.Error())
+			return err
 		}
 	}
-	return false
+	if planned {
+		if err := plan.Emit(r.client, instance, accesspolicyKind); err != nil {
+			reqLogger.Info("Error recording planned access policy change", "Failed", err.Error())
+		}
+	}
+	return nil
+}
+
+// recordIntent annotates the resource with the hash of the access policy before it is created, so that
+// the access policy is found rather than created again if the operator stops before recording its ID
+func (r *ReconcileAccessPolicy) recordIntent(instance *ibmcloudv1alpha1.AccessPolicy, policy polv2.Policy) error {
+	if !ownership.SetIntent(instance, ownership.PolicyHash(policy)) {
+		return nil
+	}
+	if err := r.client.Update(context.Background(), instance); err != nil {
+		log.Info("Error recording access policy creation", instance.Name, err.Error())
+		return err
+	}
+	return nil
+}
+
+// recordPolicyID annotates the resource with the ID of its access policy, so it can be found without status
+func (r *ReconcileAccessPolicy) recordPolicyID(instance *ibmcloudv1alpha1.AccessPolicy, accountID string) error {
+	recorded := ownership.Record(accesspolicyKind, instance, accountID, instance.Status.PolicyID)
+	if instance.Status.PolicyID == "" || !(ownership.ClearIntent(instance) || recorded) {
+		return nil
+	}
+	if err := r.client.Update(context.Background(), instance); err != nil {
+		log.Info("Error recording access policy ID", instance.Name, err.Error())
+		return err
+	}
+	return nil
+}
+
+// rediscoverAccessPolicy returns the ID of the access policy recorded for the resource if it still exists in IAM,
+// else the ID of an access policy in IAM that was about to be created for it and no other resource recorded, or "" if there is none
+func (r *ReconcileAccessPolicy) rediscoverAccessPolicy(instance *ibmcloudv1alpha1.AccessPolicy, policy polv1.Policy, accountID string, iamClients iamclient.Clients, policyAPI polv1.PolicyRepository) (string, error) {
+	var policyV2API polv2.PolicyRepository
+	if instance.Spec.Conditions != nil { //Policy with conditions is only visible to the v2 API
+		var err error
+		policyV2API, err = iamClients.PoliciesV2()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if recordedID := ownership.RecordedID(accesspolicyKind, instance, accountID); recordedID != "" {
+		var err error
+		if policyV2API != nil {
+			_, err = policyV2API.Get(recordedID)
+		} else {
+			_, err = policyAPI.Get(recordedID)
+		}
+		if err == nil {
+			log.Info("Recovered access policy from annotations", "Policy ID:", recordedID)
+			return recordedID, nil
+		}
+		if !iamclient.NotFound(err) { // The access policy may still exist, don't create another one
+			return "", err
+		}
+	}
+
+	intent := ownership.Intent(instance)
+	if intent == "" { // A policy with the same content isn't adopted, it may have been created by hand
+		return "", nil
+	}
+	claimed, err := r.claimedPolicies(instance, accountID)
+	if err != nil {
+		return "", err
+	}
+	var subject polv1.Subject
+	if len(policy.Subjects) > 0 {
+		subject = policy.Subjects[0]
+	}
+	var policyID string
+	if policyV2API != nil {
+		params := polv2.SearchParams{AccountID: accountID, IAMID: subject.GetAttribute("iam_id"), AccessGroupID: subject.GetAttribute("access_group_id"), Type: policy.Type}
+		policyID, err = ownership.FindV2Policy(policyV2API, params, intent, settings.Current.InstanceID, claimed)
+	} else {
+		params := polv1.SearchParams{AccountID: accountID, IAMID: subject.GetAttribute("iam_id"), AccessGroupID: subject.GetAttribute("access_group_id"), Type: policy.Type}
+		policyID, err = ownership.FindV1Policy(policyAPI, params, intent, settings.Current.InstanceID, claimed)
+	}
+	if policyID != "" {
+		log.Info("Found existing access policy", "Policy ID:", policyID)
+	}
+	return policyID, err
+}
+
+// claimedPolicies returns the IDs of the access policies recorded by the other access policy resources
+func (r *ReconcileAccessPolicy) claimedPolicies(instance *ibmcloudv1alpha1.AccessPolicy, accountID string) (map[string]bool, error) {
+	list := &ibmcloudv1alpha1.AccessPolicyList{}
+	if err := r.client.List(context.Background(), list); err != nil {
+		return nil, err
+	}
+	objs := make([]metav1.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return ownership.Claimed(accesspolicyKind, objs, instance, accountID), nil
+}
+
+// expiresAt returns the time the access policy expires, nil if it does not
+func expiresAt(instance *ibmcloudv1alpha1.AccessPolicy) *metav1.Time {
+	return expiry.Deadline(instance.ObjectMeta.CreationTimestamp, instance.Spec.ExpiresAt, instance.Spec.TTL)
+}
+
+// policyDrift returns the differences between the desired access policy and the one in IAM
+func policyDrift(policy polv2.Policy, retrievedPolicy polv2.Policy) []drift.Difference {
+	diffs := poldiff.Drift(policy, retrievedPolicy)
+	if len(diffs) > 0 {
+		log.Info("Access policy in IAM has changed", "Differences", drift.Summary(diffs))
+	}
+	return diffs
 }
 
 func specChanged(instance *ibmcloudv1alpha1.AccessPolicy) bool {
@@ -449,27 +832,99 @@ func specChanged(instance *ibmcloudv1alpha1.AccessPolicy) bool {
 		log.Info("Access policy resource in Spec has changed")
 		return true
 	}
+	if !reflect.DeepEqual(instance.Spec.Conditions, instance.Status.PolicyConditions) {
+		log.Info("Access policy conditions in Spec has changed")
+		return true
+	}
+	if !expiry.Equal(expiresAt(instance), instance.Status.ExpiresAt) {
+		log.Info("Access policy expiry in Spec has changed")
+		return true
+	}
 	return false
 }
 
-func createAccessPolicy(policy iampapv1.Policy, policyAPI iampapv1.V1PolicyRepository) (*iampapv1.Policy, error) {
-	createdPolicy, err := policyAPI.Create(policy)
+// policyStore manages the access policy of a resource in IAM with the v1 API, or with the v2 API for policies
+// with conditions, which only the v2 API supports. Policies are compared in their v2 form.
+type policyStore interface {
+	// desired returns the access policy compiled from the spec
+	desired() polv2.Policy
+	get(policyID string) (polv2.Policy, error)
+	// create creates the desired access policy and returns its ID
+	create() (string, error)
+	// update replaces the access policy with the desired one and returns its ID
+	update(policyID string) (string, error)
+	// stamp sets the description of the desired access policy on the one in IAM and keeps the rest of it
+	stamp(policyID string) error
+	delete(policyID string) error
+}
+
+// newPolicyStore returns the store of the access policy of a resource, which compiles to the v1 policy
+func newPolicyStore(instance *ibmcloudv1alpha1.AccessPolicy, policy polv1.Policy, iamClients iamclient.Clients, policyAPI polv1.PolicyRepository, iamCache *iamcache.Cache) (policyStore, error) {
+	if instance.Spec.Conditions == nil && instance.Status.PolicyConditions == nil {
+		return v1Store{policy: policy, policyAPI: policyAPI, iamCache: iamCache}, nil
+	}
+	policyV2API, err := iamClients.PoliciesV2()
 	if err != nil {
 		return nil, err
 	}
-	return &createdPolicy, nil
+	desired := polv2.ConvertV1Policy(policy)
+	desired.Rule, desired.Pattern = compile.AccessPolicyRule(instance)
+	return v2Store{policy: desired, policyAPI: policyV2API}, nil
+}
+
+// v1Store manages access policies without conditions, read through the IAM cache
+type v1Store struct {
+	policy    polv1.Policy
+	policyAPI polv1.PolicyRepository
+	iamCache  *iamcache.Cache
+}
+
+func (s v1Store) desired() polv2.Policy {
+	return polv2.ConvertV1Policy(s.policy)
+}
+
+func (s v1Store) get(policyID string) (polv2.Policy, error) {
+	retrievedPolicy, err := s.iamCache.Policy(policyID)
+	if err != nil {
+		return polv2.Policy{}, err
+	}
+	return polv2.ConvertV1Policy(retrievedPolicy), nil
 }
 
-func updateAccessPolicy(statusPolicyID string, policy iampapv1.Policy, policyAPI iampapv1.V1PolicyRepository, etag string) (*iampapv1.Policy, error) {
-	updatedPolicy, err := policyAPI.Update(statusPolicyID, policy, etag)
+func (s v1Store) create() (string, error) {
+	createdPolicy, err := s.policyAPI.Create(s.policy)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	return createdPolicy.ID, nil
+}
+
+func (s v1Store) update(policyID string) (string, error) {
+	defer s.iamCache.Invalidate(policyID)
+	retrievedPolicy, err := s.policyAPI.Get(policyID) // Cached policies have no etag
+	if err != nil {
+		return "", err
+	}
+	updatedPolicy, err := s.policyAPI.Update(policyID, s.policy, retrievedPolicy.Version)
+	if err != nil {
+		return "", err
+	}
+	return updatedPolicy.ID, nil
+}
+
+func (s v1Store) stamp(policyID string) error {
+	defer s.iamCache.Invalidate(policyID)
+	retrievedPolicy, err := s.policyAPI.Get(policyID)
+	if err != nil {
+		return err
 	}
-	return &updatedPolicy, nil
+	retrievedPolicy.Description = s.policy.Description
+	_, err = s.policyAPI.Update(policyID, retrievedPolicy, retrievedPolicy.Version)
+	return err
 }
 
-func deleteAccessPolicy(statusPolicyID string, policyAPI iampapv1.V1PolicyRepository) error {
-	err := policyAPI.Delete(statusPolicyID)
+func (s v1Store) delete(policyID string) error {
+	err := s.policyAPI.Delete(policyID)
 	if err != nil {
 		return err
 	}
@@ -491,226 +946,127 @@ func deleteAccessPolicy(statusPolicyID string, policyAPI iampapv1.V1PolicyReposi
 	return nil
 }
 
-func getSubject(instance *ibmcloudv1alpha1.AccessPolicy, r *ReconcileAccessPolicy, myAccount *accountv2.Account, account accountv1.Accounts, serviceIDAPI iamv1.ServiceIDRepository, accessGroupAPI iamuumv2.AccessGroupRepository) ([]iampapv1.Subject, error) {
-	/* Getting attributes for Subject depending on fields set in Subject spec */
-	if instance.Spec.Subject.UserEmail != "" {
-		_, err := account.InviteAccountUser(myAccount.GUID, instance.Spec.Subject.UserEmail)
-		if err != nil {
-			return nil, err
-		}
+// v2Store manages access policies with conditions, which are only visible to the v2 API
+type v2Store struct {
+	policy    polv2.Policy
+	policyAPI polv2.PolicyRepository
+}
 
-		userDetails, err := account.FindAccountUserByUserId(myAccount.GUID, instance.Spec.Subject.UserEmail)
-		if err != nil {
-			return nil, err
-		}
+func (s v2Store) desired() polv2.Policy {
+	return s.policy
+}
 
-		if userDetails == nil || userDetails.Id == "" {
-			return nil, errors.New("User email is not valid.")
-		}
+func (s v2Store) get(policyID string) (polv2.Policy, error) {
+	return s.policyAPI.Get(policyID)
+}
 
-		if (userDetails.UserId == "" || userDetails.IbmUniqueId == "" || userDetails.State == "PENDING") && (userDetails.Id != "") {
-			err = account.DeleteAccountUser(myAccount.GUID, userDetails.Id)
-			if err != nil {
-				return nil, err
-			}
-			return nil, errors.New("User email is not valid.")
-		}
-
-		return []iampapv1.Subject{
-			{
-				Attributes: []iampapv1.Attribute{
-					{
-						Name:  "iam_id",
-						Value: userDetails.IbmUniqueId,
-					},
-				},
-			},
-		}, nil
-	} else if instance.Spec.Subject.ServiceID != "" {
-
-		sID, err := serviceIDAPI.Get(instance.Spec.Subject.ServiceID)
-		if err != nil {
-			return nil, err
-		}
-
-		return []iampapv1.Subject{
-			{
-				Attributes: []iampapv1.Attribute{
-					{
-						Name:  "iam_id",
-						Value: sID.IAMID,
-					},
-				},
-			},
-		}, nil
-	} else if instance.Spec.Subject.AccessGroupID != "" {
-
-		ags, _, err := accessGroupAPI.Get(instance.Spec.Subject.AccessGroupID)
-		if err != nil {
-			return nil, err
-		}
-
-		return []iampapv1.Subject{
-			{
-				Attributes: []iampapv1.Attribute{
-					{
-						Name:  "access_group_id",
-						Value: ags.ID,
-					},
-				},
-			},
-		}, nil
-	} else if &instance.Spec.Subject.AccessGroupDef != nil {
-		accessgroup, err := r.getAccessGroupInstance(instance)
-		if err != nil {
-			log.Info("Access Policy could not read access group", instance.Spec.Subject.AccessGroupDef.AccessGroupName, err.Error())
-			return nil, err
-		}
+func (s v2Store) create() (string, error) {
+	createdPolicy, err := s.policyAPI.Create(s.policy)
+	if err != nil {
+		return "", err
+	}
+	return createdPolicy.ID, nil
+}
 
-		return []iampapv1.Subject{
-			{
-				Attributes: []iampapv1.Attribute{
-					{
-						Name:  "access_group_id",
-						Value: accessgroup.Status.GroupID,
-					},
-				},
-			},
-		}, nil
+func (s v2Store) update(policyID string) (string, error) {
+	retrievedPolicy, err := s.policyAPI.Get(policyID)
+	if err != nil {
+		return "", err
+	}
+	updatedPolicy, err := s.policyAPI.Update(policyID, s.policy, retrievedPolicy.Version)
+	if err != nil {
+		return "", err
 	}
-	return nil, nil
+	return updatedPolicy.ID, nil
 }
 
-func getRoles(instance *ibmcloudv1alpha1.AccessPolicy, r *ReconcileAccessPolicy, myAccount *accountv2.Account, serviceRolesAPI iamv1.ServiceRoleRepository, customRolesAPI iampapv2.RoleRepository) ([]iampapv1.Role, error) {
-	/* Getting roles for Subject */
-	var policyRoles []iampapv1.Role
+func (s v2Store) stamp(policyID string) error {
+	retrievedPolicy, err := s.policyAPI.Get(policyID)
+	if err != nil {
+		return err
+	}
+	retrievedPolicy.Description = s.policy.Description
+	_, err = s.policyAPI.Update(policyID, retrievedPolicy, retrievedPolicy.Version)
+	return err
+}
 
-	if instance.Spec.Roles.DefinedRoles != nil {
-		//log.Info("Spec contains defined roles")
-		var definedRoles []models.PolicyRole
-		var err error
-		if instance.Spec.Target.ServiceClass == "" {
-			definedRoles, err = serviceRolesAPI.ListSystemDefinedRoles()
-			if err != nil {
-				log.Info("Error getting defined system roles")
-				return nil, err
-			}
-		} else {
-			definedRoles, err = serviceRolesAPI.ListServiceRoles(instance.Spec.Target.ServiceClass)
-			if err != nil {
-				log.Info("Error getting defined system roles")
-				return nil, err
-			}
-		}
+func (s v2Store) delete(policyID string) error {
+	return s.policyAPI.Delete(policyID)
+}
 
-		filterDRoles, err := utils.GetRolesFromRoleNames(instance.Spec.Roles.DefinedRoles, definedRoles)
-		if err != nil {
-			log.Info("Error getting defined roles in spec")
-			return nil, err
-		}
-		policyRoles = iampapv1.ConvertRoleModels(filterDRoles)
-	}
+// resolver looks up the subjects and roles of access policies in the IAM account and the cluster
+type resolver struct {
+	*rolecatalog.Catalog
+	client    client.Client
+	accountID string
+	accounts  accountv1.Accounts
+	iamCache  *iamcache.Cache
+	// dryRun looks users up without inviting them, an invitation is pending and fails anyway
+	dryRun bool
+}
 
-	if instance.Spec.Roles.CustomRolesDName != nil {
-		//log.Info("Spec contains non-operator managed custom roles")
-		customCRoles, err := customRolesAPI.ListCustomRoles(myAccount.GUID, "")
+// User invites the user to the account and returns their IAM ID, removing the invitation of an unknown email
+func (r resolver) User(email string) (string, error) {
+	if !r.dryRun {
+		_, err := r.accounts.InviteAccountUser(r.accountID, email)
 		if err != nil {
-			log.Info("Error getting custom roles")
-			return nil, err
+			return "", err
 		}
+	}
 
-		filterCRoles, err := utils.GetRolesFromRoleNamesV2(instance.Spec.Roles.CustomRolesDName, customCRoles)
-		if err != nil {
-			log.Info("Error getting custom roles in spec")
-			return nil, err
-		}
-		policyRoles = append(policyRoles, iampapv1.ConvertV2RoleModels(filterCRoles)...)
+	userDetails, err := r.accounts.FindAccountUserByUserId(r.accountID, email)
+	if err != nil {
+		return "", err
 	}
 
-	if instance.Spec.Roles.CustomRolesDef != nil {
-		//log.Info("Spec contains operator managed custom roles")
-		var myCustomRoles []string
-		var customDefRoles []iampapv2.Role
-		for _, element := range instance.Spec.Roles.CustomRolesDef {
-			customRole, err := r.getCustomRoleInstance(instance, &element)
-			if err != nil {
-				log.Info("Access Policy could not read custom role", element.CustomRoleName, err.Error())
-				return nil, err
-			}
-			myCustomRoles = append(myCustomRoles, customRole.Spec.DisplayName)
+	if userDetails == nil || userDetails.Id == "" {
+		return "", requeue.AsPermanent(errors.New("User email is not valid."))
+	}
 
-			var croles []iampapv2.Role
-			croles, err = customRolesAPI.ListAll(iampapv2.RoleQuery{AccountID: myAccount.GUID, ServiceName: customRole.Spec.ServiceClass})
+	if userDetails.UserId == "" || userDetails.IbmUniqueId == "" || userDetails.State == "PENDING" {
+		if !r.dryRun {
+			err = r.accounts.DeleteAccountUser(r.accountID, userDetails.Id)
 			if err != nil {
-				log.Info("Error getting custom system roles")
-				return nil, err
+				return "", err
 			}
-			customDefRoles = append(customDefRoles, croles...)
 		}
-
-		filterDefRoles, err := utils.GetRolesFromRoleNamesV2(myCustomRoles, customDefRoles)
-		if err != nil {
-			log.Info("Error getting custom roles in spec")
-			return nil, err
-		}
-		policyRoles = append(policyRoles, iampapv1.ConvertV2RoleModels(filterDefRoles)...)
+		return "", requeue.AsPermanent(errors.New("User email is not valid."))
 	}
-	return policyRoles, nil
+	return userDetails.IbmUniqueId, nil
 }
 
-func getResource(instance *ibmcloudv1alpha1.AccessPolicy, serviceIDAPI iamv1.ServiceIDRepository) (iampapv1.Resource, error) {
-	/* Getting attributes for Resource */
-	policyResource := iampapv1.Resource{}
-
-	if instance.Spec.Target.ServiceClass != "" {
-		policyResource.SetAttribute("serviceName", instance.Spec.Target.ServiceClass)
-	}
-	if instance.Spec.Target.ServiceID != "" {
-		policyResource.SetAttribute("serviceInstance", instance.Spec.Target.ServiceID)
-	}
-	if instance.Spec.Target.ResourceName != "" {
-		policyResource.SetAttribute("resourceType", instance.Spec.Target.ResourceName)
-	}
-	if instance.Spec.Target.ResourceID != "" {
-		policyResource.SetAttribute("resource", instance.Spec.Target.ResourceID)
-	}
-	if instance.Spec.Target.ResourceGroup != "" {
-		policyResource.SetResourceGroupID(instance.Spec.Target.ResourceGroup)
-	}
-	if instance.Spec.Target.Region != "" {
-		policyResource.SetAttribute("region", instance.Spec.Target.Region)
-	}
-	if instance.Spec.Target.ResourceKey != "" && instance.Spec.Target.ResourceValue != "" {
-		policyResource.SetAttribute(instance.Spec.Target.ResourceKey, instance.Spec.Target.ResourceValue)
+func (r resolver) ServiceID(uuid string) (string, error) {
+	sID, err := r.iamCache.ServiceID(uuid)
+	if err != nil {
+		return "", err
 	}
-	//policyResource.SetServiceType("service")
-	return policyResource, nil
+	return sID.IAMID, nil
 }
 
-func (r *ReconcileAccessPolicy) getAccessGroupInstance(instance *ibmcloudv1alpha1.AccessPolicy) (*ibmcloudv1alpha1.AccessGroup, error) {
-	accessGroupNameSpace := instance.ObjectMeta.Namespace
-	if instance.Spec.Subject.AccessGroupDef.AccessGroupNamespace != "" {
-		accessGroupNameSpace = instance.Spec.Subject.AccessGroupDef.AccessGroupNamespace
+func (r resolver) AccessGroup(id string) (string, error) {
+	ags, err := r.iamCache.AccessGroup(id)
+	if err != nil {
+		return "", err
 	}
+	return ags.ID, nil
+}
+
+func (r resolver) AccessGroupResource(namespace string, name string) (*ibmcloudv1alpha1.AccessGroup, error) {
 	accessGroupInstance := &ibmcloudv1alpha1.AccessGroup{}
-	err := r.client.Get(context.Background(), types.NamespacedName{Name: instance.Spec.Subject.AccessGroupDef.AccessGroupName, Namespace: accessGroupNameSpace}, accessGroupInstance)
+	err := r.client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: namespace}, accessGroupInstance)
 	if err != nil {
-		log.Info("Error getting access group resource instance")
-		return &ibmcloudv1alpha1.AccessGroup{}, err
+		log.Info("Access Policy could not read access group", name, err.Error())
+		return nil, err
 	}
 	return accessGroupInstance, nil
 }
 
-func (r *ReconcileAccessPolicy) getCustomRoleInstance(instance *ibmcloudv1alpha1.AccessPolicy, roleinstance *ibmcloudv1alpha1.CustomRolesDef) (*ibmcloudv1alpha1.CustomRole, error) {
-	customRoleNameSpace := instance.ObjectMeta.Namespace
-	if roleinstance.CustomRoleNamespace != "" {
-		customRoleNameSpace = roleinstance.CustomRoleNamespace
-	}
+func (r resolver) CustomRoleResource(namespace string, name string) (*ibmcloudv1alpha1.CustomRole, error) {
 	customRoleInstance := &ibmcloudv1alpha1.CustomRole{}
-	err := r.client.Get(context.Background(), types.NamespacedName{Name: roleinstance.CustomRoleName, Namespace: customRoleNameSpace}, customRoleInstance)
+	err := r.client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: namespace}, customRoleInstance)
 	if err != nil {
-		log.Info("Error getting custom role resource instance")
-		return &ibmcloudv1alpha1.CustomRole{}, err
+		log.Info("Access Policy could not read custom role", name, err.Error())
+		return nil, err
 	}
 	return customRoleInstance, nil
 }
@@ -733,5 +1089,62 @@ func isWellFormed(instance ibmcloudv1alpha1.AccessPolicy) bool {
 		return false
 	}
 
+	if !compile.TargetWellFormed(instance.Spec.Target) {
+		return false
+	}
+
+	if !conditionsWellFormed(instance.Spec.Conditions) {
+		return false
+	}
+
+	if instance.Spec.ExpiresAt != nil && instance.Spec.TTL != nil {
+		return false
+	}
+
+	return true
+}
+
+func conditionsWellFormed(conditions *ibmcloudv1alpha1.PolicyConditions) bool {
+	if conditions == nil {
+		return true
+	}
+
+	if conditions.Weekly != nil {
+		if conditions.NotBefore != nil || conditions.NotAfter != nil || len(conditions.Weekly.Days) == 0 {
+			return false
+		}
+		for _, day := range conditions.Weekly.Days {
+			if _, ok := compile.Weekday(day); !ok {
+				return false
+			}
+		}
+		if (conditions.Weekly.StartTime == "") != (conditions.Weekly.EndTime == "") {
+			return false
+		}
+		if conditions.Weekly.StartTime != "" {
+			start, err := time.Parse("15:04", conditions.Weekly.StartTime)
+			if err != nil {
+				return false
+			}
+			end, err := time.Parse("15:04", conditions.Weekly.EndTime)
+			if err != nil || !end.After(start) {
+				return false
+			}
+		}
+		if conditions.Weekly.TimeZoneOffset != "" {
+			if _, err := time.Parse("-07:00", conditions.Weekly.TimeZoneOffset); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	// IAM requires an end to a time-based-conditions:once window
+	if conditions.NotAfter == nil {
+		return false
+	}
+	if conditions.NotBefore != nil && !conditions.NotAfter.After(conditions.NotBefore.Time) {
+		return false
+	}
 	return true
 }
@@ -37,6 +37,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	context "github.com/IBM/ibmcloud-iam-operator/pkg/context"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/control"
 	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
 
 	"github.com/IBM/ibmcloud-iam-operator/pkg/apis"
@@ -55,6 +56,9 @@ var (
 )
 
 func TestAccessPolicy(t *testing.T) {
+	if err := test.Configure(); err != nil {
+		t.Skip(err)
+	}
 	RegisterFailHandler(Fail)
 	SetDefaultEventuallyPollingInterval(20 * time.Second)
 	SetDefaultEventuallyTimeout(180 * time.Second)
@@ -64,7 +68,7 @@ func TestAccessPolicy(t *testing.T) {
 
 var _ = BeforeSuite(func() {
 	logf.SetLogger(logf.ZapLoggerTo(GinkgoWriter, true))
-	useExistingCluster := true
+	useExistingCluster := test.UseExistingCluster()
 
 	t = &envtest.Environment{
 		CRDDirectoryPaths:        []string{filepath.Join("..", "..", "..", "deploy", "crds")},
@@ -86,7 +90,9 @@ var _ = BeforeSuite(func() {
 
 	c = mgr.GetClient()
 
-	recFn := newReconciler(mgr)
+	namespaces, err := control.Namespaces(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	recFn := newReconciler(mgr, namespaces)
 	Expect(add(mgr, recFn)).NotTo(HaveOccurred())
 
 	stop = test.StartTestManager(mgr)
@@ -117,6 +123,9 @@ var _ = Describe("accesspolicy", func() {
 		Entry("string param", "cosuserpolicy.yaml"),
 		Entry("string param", "cosservicepolicy.yaml"),
 		Entry("string param", "cosgrouppolicy.yaml"),
+		Entry("string param", "cosprefixpolicy.yaml"),
+		Entry("string param", "cosweeklypolicy.yaml"),
+		Entry("string param", "costagpolicy.yaml"),
 	)
 
 	DescribeTable("should delete",
@@ -132,6 +141,20 @@ var _ = Describe("accesspolicy", func() {
 		Entry("string param", "cosgrouppolicy.yaml"),
 		Entry("string param", "cosservicepolicy.yaml"),
 		Entry("string param", "cosuserpolicy.yaml"),
+		Entry("string param", "cosprefixpolicy.yaml"),
+		Entry("string param", "cosweeklypolicy.yaml"),
+		Entry("string param", "costagpolicy.yaml"),
+	)
+
+	DescribeTable("should expire",
+		func(AccessPolicyfile string) {
+			ap := test.LoadAccessPolicy("aptestdata/" + AccessPolicyfile)
+			apobj := test.PostInNs(scontext, &ap, true, 0)
+
+			Eventually(test.GetState(scontext, apobj)).Should(Equal(resv1.ResourceStateExpired))
+		},
+
+		Entry("string param", "cosexpiredpolicy.yaml"),
 	)
 
 	DescribeTable("should fail",
@@ -147,6 +170,8 @@ var _ = Describe("accesspolicy", func() {
 		Entry("string param", "cosbadrole.yaml"),
 		Entry("string param", "cosbadaccessgroup.yaml"),
 		Entry("string param", "cosbadresource.yaml"),
+		Entry("string param", "cosbadconditions.yaml"),
+		Entry("string param", "cosbadtags.yaml"),
 		// Entry("string param", "cosbadspec_1.yaml"),
 		// Entry("string param", "cosbadspec_2.yaml"),
 		// Entry("string param", "cosbadspec_3.yaml"),
@@ -167,6 +192,8 @@ var _ = Describe("accesspolicy", func() {
 		Entry("string param", "cosbadrole.yaml"),
 		Entry("string param", "cosbadaccessgroup.yaml"),
 		Entry("string param", "cosbadresource.yaml"),
+		Entry("string param", "cosbadconditions.yaml"),
+		Entry("string param", "cosbadtags.yaml"),
 		// Entry("string param", "cosbadspec_1.yaml"),
 		// Entry("string param", "cosbadspec_2.yaml"),
 		// Entry("string param", "cosbadspec_3.yaml"),
@@ -0,0 +1,335 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package accesspolicy
+
+import (
+	gocontext "context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/registry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+var request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "kube-writer"}}
+
+// newTestReconciler returns a reconciler of an AccessPolicy connected to an in-memory IAM account
+// with a service ID
+func newTestReconciler(t *testing.T, spec ibmcloudv1alpha1.AccessPolicySpec) (*ReconcileAccessPolicy, *iamfake.Factory) {
+	cluster := iamfake.NewCluster(&ibmcloudv1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		Spec:       spec,
+	})
+	cluster.IAM.AddServiceID("ServiceId-deployer", "deployer")
+	return &ReconcileAccessPolicy{client: cluster.Client, reader: cluster.Client, scheme: cluster.Scheme, iam: cluster.IAM, recorder: cluster.Recorder}, cluster.IAM
+}
+
+func kubeWriterSpec() ibmcloudv1alpha1.AccessPolicySpec {
+	return ibmcloudv1alpha1.AccessPolicySpec{
+		Subject: ibmcloudv1alpha1.Subject{ServiceID: "ServiceId-deployer"},
+		Roles:   ibmcloudv1alpha1.Roles{DefinedRoles: []string{"Editor", "Writer"}},
+		Target:  ibmcloudv1alpha1.Target{ServiceClass: "containers-kubernetes", Region: "us-south"},
+	}
+}
+
+func getInstance(t *testing.T, r *ReconcileAccessPolicy) *ibmcloudv1alpha1.AccessPolicy {
+	instance := &ibmcloudv1alpha1.AccessPolicy{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, instance))
+	return instance
+}
+
+func TestReconcileCreate(t *testing.T) {
+	r, iam := newTestReconciler(t, kubeWriterSpec())
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	policies := iam.Policies()
+	require.Len(t, policies, 1)
+	assert.ElementsMatch(t, []string{"Editor", "Writer"}, iamfake.RoleNames(iam.Policies()[0]))
+	instance := getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, policies[0].ID, instance.Status.PolicyID)
+	assert.True(t, ContainsFinalizer(instance))
+
+	// A policy in sync with its spec is left alone
+	iam.ResetCalls()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.NotContains(t, iam.Calls(), "Policies.Create")
+	assert.NotContains(t, iam.Calls(), "Policies.Update")
+	assert.Len(t, iam.Policies(), 1)
+}
+
+func TestReconcileDrift(t *testing.T) {
+	for _, mode := range []string{drift.Enforce, drift.Detect} {
+		spec := kubeWriterSpec()
+		spec.DriftPolicy = mode
+		r, iam := newTestReconciler(t, spec)
+		_, err := r.Reconcile(request)
+		require.NoError(t, err)
+
+		// Remove a role as with the IAM console
+		policy := iam.Policies()[0]
+		for _, role := range policy.Control.Grant.Roles {
+			if strings.HasSuffix(role.RoleID, ":Editor") {
+				policy.Control.Grant.Roles = []polv2.Role{role}
+			}
+		}
+		require.NoError(t, iam.SetPolicy(policy))
+		_, err = r.Reconcile(request)
+		require.NoError(t, err)
+
+		condition := resv1.GetCondition(getInstance(t, r), drift.ConditionType)
+		require.NotNil(t, condition, mode)
+		events := r.recorder.(*record.FakeRecorder).Events
+		switch mode {
+		case drift.Enforce:
+			assert.ElementsMatch(t, []string{"Editor", "Writer"}, iamfake.RoleNames(iam.Policies()[0]))
+			assert.Equal(t, corev1.ConditionFalse, condition.Status)
+			assert.Contains(t, <-events, "Warning DriftReverted")
+		default:
+			assert.Equal(t, []string{"Editor"}, iamfake.RoleNames(iam.Policies()[0]))
+			assert.Equal(t, corev1.ConditionTrue, condition.Status)
+			assert.Contains(t, <-events, "Warning DriftDetected")
+		}
+	}
+}
+
+func TestReconcileUnmarkedPolicy(t *testing.T) {
+	spec := kubeWriterSpec()
+	spec.DriftPolicy = drift.Detect
+	r, iam := newTestReconciler(t, spec)
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	description := iam.Policies()[0].Description
+
+	// A policy created before the operator marked its policies, with a role removed in the IAM console
+	policy := iam.Policies()[0]
+	policy.Description = ""
+	for _, role := range policy.Control.Grant.Roles {
+		if strings.HasSuffix(role.RoleID, ":Editor") {
+			policy.Control.Grant.Roles = []polv2.Role{role}
+		}
+	}
+	require.NoError(t, iam.SetPolicy(policy))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+
+	// The policy gets the marker, and the detected drift is kept
+	assert.Equal(t, description, iam.Policies()[0].Description)
+	assert.Equal(t, []string{"Editor"}, iamfake.RoleNames(iam.Policies()[0]))
+	instance := getInstance(t, r)
+	condition := resv1.GetCondition(instance, drift.ConditionType)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.NotContains(t, condition.Message, "description")
+	assert.Equal(t, "Online", instance.Status.State)
+
+	// Once marked, the policy has only drifted on its roles
+	iam.ResetCalls()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.NotContains(t, iam.Calls(), "Policies.Update")
+}
+
+func TestReconcileUnknownRole(t *testing.T) {
+	spec := kubeWriterSpec()
+	spec.Roles.DefinedRoles = []string{"Entertainer"}
+	r, iam := newTestReconciler(t, spec)
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err) // A spec that can't work isn't retried
+	instance := getInstance(t, r)
+	assert.Equal(t, "Failed", instance.Status.State)
+	assert.Contains(t, instance.Status.Message, "Error getting roles for access policy")
+	assert.Contains(t, instance.Status.Message, "Entertainer")
+	assert.Empty(t, iam.Policies())
+}
+
+func TestReconcileOnceConditions(t *testing.T) {
+	notBefore := metav1.NewTime(time.Now().Add(time.Hour).Truncate(time.Second))
+	notAfter := metav1.NewTime(notBefore.Add(24 * time.Hour))
+	cases := []struct {
+		name       string
+		conditions ibmcloudv1alpha1.PolicyConditions
+		state      string
+	}{
+		{name: "window", conditions: ibmcloudv1alpha1.PolicyConditions{NotBefore: &notBefore, NotAfter: &notAfter}, state: "Online"},
+		{name: "end only", conditions: ibmcloudv1alpha1.PolicyConditions{NotAfter: &notAfter}, state: "Online"},
+		{name: "start only", conditions: ibmcloudv1alpha1.PolicyConditions{NotBefore: &notBefore}, state: "Failed"},
+		{name: "empty window", conditions: ibmcloudv1alpha1.PolicyConditions{NotBefore: &notBefore, NotAfter: &notBefore}, state: "Failed"},
+		{name: "end before start", conditions: ibmcloudv1alpha1.PolicyConditions{NotBefore: &notAfter, NotAfter: &notBefore}, state: "Failed"},
+	}
+
+	for _, c := range cases {
+		spec := kubeWriterSpec()
+		conditions := c.conditions
+		spec.Conditions = &conditions
+		r, iam := newTestReconciler(t, spec)
+
+		_, err := r.Reconcile(request)
+		require.NoError(t, err, c.name)
+		instance := getInstance(t, r)
+		assert.Equal(t, c.state, instance.Status.State, c.name)
+		if c.state == "Failed" {
+			assert.Equal(t, "The spec is not well-formed", instance.Status.Message, c.name)
+			assert.Empty(t, iam.Policies(), c.name)
+		} else {
+			assert.Len(t, iam.Policies(), 1, c.name)
+		}
+	}
+}
+
+func TestReconcileIAMError(t *testing.T) {
+	r, iam := newTestReconciler(t, kubeWriterSpec())
+	iam.Fail("Policies.Create", bmxerror.NewRequestFailure("forbidden", "Not authorized to create policies", 403))
+
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	assert.Equal(t, "Failed", getInstance(t, r).Status.State)
+	assert.Empty(t, iam.Policies())
+
+	// The policy is created once IAM recovers
+	iam.Fail("Policies.Create", nil)
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, "Online", getInstance(t, r).Status.State)
+	assert.Len(t, iam.Policies(), 1)
+}
+
+func TestReconcileDelete(t *testing.T) {
+	r, iam := newTestReconciler(t, kubeWriterSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	require.Len(t, iam.Policies(), 1)
+
+	instance := getInstance(t, r)
+	now := metav1.Now()
+	instance.ObjectMeta.DeletionTimestamp = &now
+	require.NoError(t, r.client.Update(gocontext.Background(), instance))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Empty(t, iam.Policies())
+	assert.False(t, ContainsFinalizer(getInstance(t, r)))
+}
+
+func TestReconcileExpiryLostStatus(t *testing.T) {
+	r, iam := newTestReconciler(t, kubeWriterSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	require.Len(t, iam.Policies(), 1)
+
+	// The resource is restored without status, then expires
+	instance := getInstance(t, r)
+	instance.Status = ibmcloudv1alpha1.AccessPolicyStatus{}
+	require.NoError(t, r.client.Status().Update(gocontext.Background(), instance))
+	expired := metav1.NewTime(time.Now().Add(-time.Minute))
+	instance.Spec.ExpiresAt = &expired
+	require.NoError(t, r.client.Update(gocontext.Background(), instance))
+
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Empty(t, iam.Policies())
+	assert.Equal(t, "Expired", getInstance(t, r).Status.State)
+
+	// The revoked policy isn't looked for again
+	iam.ResetCalls()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.NotContains(t, iam.Calls(), "Policies.Delete")
+}
+
+func TestReconcileRediscoverUnavailable(t *testing.T) {
+	r, iam := newTestReconciler(t, kubeWriterSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	policyID := iam.Policies()[0].ID
+
+	// The resource is restored without status while IAM fails to answer
+	instance := getInstance(t, r)
+	instance.Status = ibmcloudv1alpha1.AccessPolicyStatus{}
+	require.NoError(t, r.client.Status().Update(gocontext.Background(), instance))
+	iam.Fail("Policies.Get", bmxerror.NewRequestFailure("service_unavailable", "IAM is unavailable", 503))
+
+	// The recorded policy may still exist, so no other policy is created
+	_, err = r.Reconcile(request)
+	assert.Error(t, err)
+	assert.Len(t, iam.Policies(), 1)
+	assert.Equal(t, "Failed", getInstance(t, r).Status.State)
+
+	// The recorded policy is recovered once IAM answers
+	iam.Fail("Policies.Get", nil)
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Len(t, iam.Policies(), 1)
+	assert.Equal(t, policyID, getInstance(t, r).Status.PolicyID)
+}
+
+func TestReconcileBreakerOpens(t *testing.T) {
+	r, iam := newTestReconciler(t, kubeWriterSpec())
+	transport := resilience.ForAccount(&session.Session{Config: &bluemix.Config{}}, iamfake.AccountID)
+	defer resilience.Evict(iamfake.AccountID, registry.Key(iamfake.AccountID, ""))
+
+	// Other requests open the circuit breaker while the policy is created
+	iam.Fail("Policies.Create", resilience.ErrUnavailable)
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Failed", instance.Status.State)
+	assert.NotEmpty(t, ownership.Intent(instance))
+	assert.Empty(t, iam.Policies())
+
+	// IAM isn't called until the circuit breaker lets a probe through
+	for i := 0; i < resilience.DefaultOptions.FailureThreshold; i++ {
+		transport.Breaker.Failure()
+	}
+	iam.ResetCalls()
+	result, err := r.Reconcile(request)
+	require.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 0)
+	assert.Equal(t, []string{"Connect"}, iam.Calls())
+	condition := resv1.GetCondition(getInstance(t, r), resilience.ConditionType)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+
+	// The policy is created once, when IAM recovers
+	transport.Breaker.Success()
+	iam.Fail("Policies.Create", nil)
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Len(t, iam.Policies(), 1)
+	instance = getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, corev1.ConditionFalse, resv1.GetCondition(instance, resilience.ConditionType).Status)
+}
@@ -0,0 +1,393 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/expiry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
+
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_accessrequest")
+
+// approveVerb is the RBAC verb on accessrequests that allows a user to review access requests
+const approveVerb = "approve"
+
+// stateRejected is the final state of the requests whose review can't be trusted or no longer applies,
+// a new request is needed
+const stateRejected = "Rejected"
+
+const (
+	// preReviewedMessage is the status message of requests created with an approval or denial annotation
+	preReviewedMessage = "An access request can't be created already reviewed"
+	// specChangedMessage is the status message of requests whose spec changed after they were reviewed
+	specChangedMessage = "The spec of an access request can't change once it is reviewed, create a new access request"
+	// selfReviewMessage is the status message of requests reviewed by their requester
+	selfReviewMessage = "An access request can't be reviewed by its requester"
+	// malformedTargetMessage is the admission message of requests whose target has malformed attributes or tags
+	malformedTargetMessage = "The target of an access request has malformed attributes or tags"
+	// unstampedMessage is the status message of requests reviewed while the admission webhook doesn't record reviewers
+	unstampedMessage = "An access request can't be reviewed without the admission webhook recording its reviewer"
+)
+
+// Add creates a new AccessRequest Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileAccessRequest{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("accessrequest-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: settings.MaxConcurrentReconciles("AccessRequest")})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource AccessRequest
+	err = c.Watch(&source.Kind{Type: &ibmcloudv1alpha1.AccessRequest{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the access policies granting approved requests
+	err = c.Watch(&source.Kind{Type: &ibmcloudv1alpha1.AccessPolicy{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &ibmcloudv1alpha1.AccessRequest{},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileAccessRequest implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileAccessRequest{}
+
+// ReconcileAccessRequest reconciles a AccessRequest object
+type ReconcileAccessRequest struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile reads that state of the cluster for a AccessRequest object and makes changes based on the state read
+// and what is in the AccessRequest.Spec
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *ReconcileAccessRequest) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile("AccessRequest", request.NamespacedName)
+	result, err := r.reconcile(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile reconciles a AccessRequest within the span of the reconcile
+func (r *ReconcileAccessRequest) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling Access Request")
+
+	// Fetch the AccessRequest instance
+	instance := &ibmcloudv1alpha1.AccessRequest{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected, which revokes the access policy.
+			// Return and don't requeue
+			reqLogger.Info("Access Request resource not found. Ignoring since object must be deleted")
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		reqLogger.Error(err, "Failed to get Access Request")
+		return reconcile.Result{}, err
+	}
+
+	// Set the Status field for the first time
+	if reflect.DeepEqual(instance.Status, ibmcloudv1alpha1.AccessRequestStatus{}) {
+		instance.Status.State = "Pending"
+		instance.Status.Message = "Waiting for approval"
+		instance.Status.Requester = instance.Spec.Requester
+		instance.Status.RequestedAt = &instance.ObjectMeta.CreationTimestamp
+		if reviewer(instance) != "" { // A review must come after the request, not with it
+			instance.Status.State = stateRejected
+			instance.Status.Message = preReviewedMessage
+		}
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating initial status", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// Check that the spec is well-formed
+	if !isWellFormed(*instance) {
+		err := requeue.AsPermanent(fmt.Errorf("The spec is not well-formed"))
+		if requeue.Report(instance, err) || instance.Status.State != "Failed" {
+			instance.Status.State = "Failed"
+			instance.Status.Message = err.Error()
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for bad spec", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		return requeue.Result(err)
+	}
+
+	switch instance.Status.State {
+	case "Denied", "Expired", stateRejected: // Reviewed requests are final, a new request is needed for more access
+		return reconcile.Result{}, nil
+	case "Approved":
+		if instance.Status.ApprovedGeneration != 0 && instance.Status.ApprovedGeneration != instance.ObjectMeta.Generation {
+			return r.revoke(instance)
+		}
+		return r.syncAccessPolicy(instance)
+	}
+
+	user := reviewer(instance)
+	if user == "" { // An annotation update triggers a new reconcile
+		return reconcile.Result{}, nil
+	}
+
+	// Only the admission webhook makes sure that the reviewer is the user who set the annotation, and that
+	// Kubernetes RBAC allows them to approve access requests, otherwise anyone could name an approver
+	if !stamping {
+		return r.reject(instance, unstampedMessage)
+	}
+	if user == instance.Spec.Requester {
+		return r.reject(instance, selfReviewMessage)
+	}
+
+	now := metav1.Now()
+	instance.Status.Approver = user
+	if _, denied := instance.ObjectMeta.Annotations[ibmcloudv1alpha1.DeniedByAnnotation]; denied {
+		reqLogger.Info("Access request denied.", "Approver:", user)
+		instance.Status.State = "Denied"
+		instance.Status.Message = "Access request denied"
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for access request denial", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	reqLogger.Info("Access request approved.", "Approver:", user)
+	expiresAt := metav1.NewTime(now.Add(instance.Spec.Duration.Duration))
+	instance.Status.State = "Approved"
+	instance.Status.Message = "Access request approved"
+	instance.Status.ApprovedAt = &now
+	instance.Status.ApprovedGeneration = instance.ObjectMeta.Generation
+	instance.Status.ExpiresAt = &expiresAt
+	instance.Status.AccessPolicyName = instance.Name
+	if err := r.client.Status().Update(context.Background(), instance); err != nil {
+		reqLogger.Info("Error updating status for access request approval", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	return r.syncAccessPolicy(instance)
+}
+
+// syncAccessPolicy makes sure the access policy of an approved request exists until the request expires
+func (r *ReconcileAccessRequest) syncAccessPolicy(instance *ibmcloudv1alpha1.AccessRequest) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Status.AccessPolicyName}, policy)
+	if err != nil {
+		if !kerror.IsNotFound(err) {
+			reqLogger.Info("Error retrieving access policy", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		if expiry.Expired(instance.Status.ExpiresAt, time.Now()) {
+			return r.setExpired(instance)
+		}
+
+		policy = newAccessPolicy(instance)
+		if err := r.client.Create(context.Background(), policy); err != nil {
+			reqLogger.Info("Error creating access policy", "Failed", err.Error())
+			instance.Status.Message = "Error creating access policy"
+			requeue.Report(instance, err)
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for failing access policy creation", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+			return requeue.Result(err)
+		}
+		reqLogger.Info("Created access policy.", "AccessPolicy:", policy.Name)
+		// Changes to the access policy are watched, so the request only needs to be requeued when it expires
+		return requeue.Until(instance.Status.ExpiresAt, time.Now()), nil
+	}
+
+	if !metav1.IsControlledBy(policy, instance) { // Never grant or report an access policy the request didn't create
+		err := requeue.AsPermanent(fmt.Errorf("access policy %s already exists and isn't owned by the access request", policy.Name))
+		reqLogger.Info("Error granting access request", "Failed", err.Error())
+		message := "Access request approved, but " + err.Error()
+		if requeue.Report(instance, err) || instance.Status.Message != message {
+			instance.Status.Message = message
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for access policy conflict", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		// The access policy isn't watched, check again whether it was deleted
+		return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+	}
+
+	if policy.Status.State == "Expired" {
+		return r.setExpired(instance)
+	}
+
+	// Undo changes to the access policy, it grants what was approved and no more
+	desired := newAccessPolicy(instance)
+	if !reflect.DeepEqual(policy.Spec, desired.Spec) {
+		policy.Spec = desired.Spec
+		if err := r.client.Update(context.Background(), policy); err != nil {
+			reqLogger.Info("Error restoring access policy", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		reqLogger.Info("Restored access policy to the approved spec.", "AccessPolicy:", policy.Name)
+	}
+
+	message := fmt.Sprintf("Access request approved, access policy is %s", policy.Status.State)
+	if policy.Status.State == "Failed" {
+		message = fmt.Sprintf("Access request approved, access policy failed: %s", policy.Status.Message)
+	}
+	if requeue.Report(instance, nil) || instance.Status.Message != message {
+		instance.Status.Message = message
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for access policy state", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	return requeue.Until(instance.Status.ExpiresAt, time.Now()), nil
+}
+
+// revoke deletes the access policy of an approved request whose spec changed since, and fails the request
+func (r *ReconcileAccessRequest) revoke(instance *ibmcloudv1alpha1.AccessRequest) (reconcile.Result, error) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Status.AccessPolicyName}, policy)
+	if err != nil && !kerror.IsNotFound(err) {
+		log.Info("Error retrieving access policy", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	if err == nil && metav1.IsControlledBy(policy, instance) {
+		if err := r.client.Delete(context.Background(), policy); err != nil && !kerror.IsNotFound(err) {
+			log.Info("Error revoking access policy", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		log.Info("Revoked access policy of a changed access request.", "AccessPolicy:", policy.Name)
+	}
+	return r.reject(instance, specChangedMessage)
+}
+
+func (r *ReconcileAccessRequest) setExpired(instance *ibmcloudv1alpha1.AccessRequest) (reconcile.Result, error) {
+	log.Info("Access request expired.", "Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	instance.Status.State = "Expired"
+	instance.Status.Message = "Access request expired"
+	if err := r.client.Status().Update(context.Background(), instance); err != nil {
+		log.Info("Error updating status for access request expiry", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// reject ends a request whose review can't be trusted or no longer applies
+func (r *ReconcileAccessRequest) reject(instance *ibmcloudv1alpha1.AccessRequest, message string) (reconcile.Result, error) {
+	log.Info("Access request rejected.", "Request.Namespace", instance.Namespace, "Request.Name", instance.Name, "Reason", message)
+	requeue.Report(instance, requeue.AsPermanent(errors.New(message)))
+	instance.Status.State = stateRejected
+	instance.Status.Message = message
+	if err := r.client.Status().Update(context.Background(), instance); err != nil {
+		log.Info("Error updating status for rejected access request", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// reviewer returns the user named by the approval or denial annotation, a denial wins over an approval. The
+// admission webhook replaces the name set with the user who set the annotation.
+func reviewer(instance *ibmcloudv1alpha1.AccessRequest) string {
+	if user := instance.ObjectMeta.Annotations[ibmcloudv1alpha1.DeniedByAnnotation]; user != "" {
+		return user
+	}
+	return instance.ObjectMeta.Annotations[ibmcloudv1alpha1.ApprovedByAnnotation]
+}
+
+// newAccessPolicy returns the access policy granting an approved request, owned by the request
+func newAccessPolicy(instance *ibmcloudv1alpha1.AccessRequest) *ibmcloudv1alpha1.AccessPolicy {
+	return &ibmcloudv1alpha1.AccessPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Status.AccessPolicyName,
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"ibmcloud.ibm.com/access-request": instance.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(instance, ibmcloudv1alpha1.SchemeGroupVersion.WithKind("AccessRequest")),
+			},
+		},
+		Spec: ibmcloudv1alpha1.AccessPolicySpec{
+			Subject:   instance.Spec.Subject,
+			Roles:     instance.Spec.Roles,
+			Target:    instance.Spec.Target,
+			ExpiresAt: instance.Status.ExpiresAt,
+		},
+	}
+}
+
+func isWellFormed(instance ibmcloudv1alpha1.AccessRequest) bool {
+	if instance.Spec.Requester == "" || instance.Spec.Justification == "" {
+		return false
+	}
+
+	if instance.Spec.Duration.Duration <= 0 {
+		return false
+	}
+
+	if !compile.TargetWellFormed(instance.Spec.Target) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package accessrequest
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+)
+
+var request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "debugcosaccess"}}
+
+// newTestReconciler returns a reconciler of an AccessRequest of jane, reviewed by bob
+func newTestReconciler(t *testing.T) *ReconcileAccessRequest {
+	instance := &ibmcloudv1alpha1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   request.Namespace,
+			Name:        request.Name,
+			Annotations: map[string]string{ibmcloudv1alpha1.ApprovedByAnnotation: "bob"},
+		},
+		Spec: ibmcloudv1alpha1.AccessRequestSpec{
+			Requester:     "jane",
+			Subject:       ibmcloudv1alpha1.Subject{UserEmail: "jane@example.com"},
+			Roles:         ibmcloudv1alpha1.Roles{DefinedRoles: []string{"Reader"}},
+			Target:        ibmcloudv1alpha1.Target{ServiceClass: "cloud-object-storage"},
+			Justification: "debug",
+			Duration:      metav1.Duration{Duration: time.Hour},
+		},
+		Status: ibmcloudv1alpha1.AccessRequestStatus{Requester: "jane"},
+	}
+	instance.Status.State = "Pending"
+	cluster := iamfake.NewCluster(instance)
+	return &ReconcileAccessRequest{client: cluster.Client, scheme: cluster.Scheme}
+}
+
+func getInstance(t *testing.T, r *ReconcileAccessRequest) *ibmcloudv1alpha1.AccessRequest {
+	instance := &ibmcloudv1alpha1.AccessRequest{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, instance))
+	return instance
+}
+
+func TestReconcileApproval(t *testing.T) {
+	defer func() { stamping = false }()
+	stamping = true
+	r := newTestReconciler(t)
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Approved", instance.Status.State)
+	assert.Equal(t, "bob", instance.Status.Approver)
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, policy))
+	assert.True(t, metav1.IsControlledBy(policy, instance))
+}
+
+func TestReconcileUnstampedApproval(t *testing.T) {
+	r := newTestReconciler(t)
+
+	// Without the webhook, anyone could name an approver
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, stateRejected, instance.Status.State)
+	assert.Equal(t, unstampedMessage, instance.Status.Message)
+	assert.Empty(t, instance.Status.Approver)
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	assert.Error(t, r.client.Get(gocontext.Background(), request.NamespacedName, policy))
+
+	// A rejected request stays rejected once the webhook runs
+	stamping = true
+	defer func() { stamping = false }()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, stateRejected, getInstance(t, r).Status.State)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+)
+
+// WebhookPath is the path the operator serves the admission webhook of access requests at
+const WebhookPath = "/mutate-ibmcloud-ibm-com-v1alpha1-accessrequest"
+
+// stamping is set once the admission webhook is registered, before the Manager starts. Without it, the
+// controller doesn't trust the reviewers that annotations name.
+var stamping bool
+
+// AddWebhook registers the admission webhook of access requests with the webhook server of the Manager
+func AddWebhook(mgr manager.Manager) {
+	mgr.GetWebhookServer().Register(WebhookPath, &webhook.Admission{Handler: &stamper{client: mgr.GetClient()}})
+	stamping = true
+}
+
+// stamper is the admission webhook recording who requests and who reviews access requests. Annotations
+// and the spec are free text, so it replaces the requester and the reviewer they name with the user the
+// API server authenticated, refuses reviewers Kubernetes RBAC doesn't allow to approve access requests,
+// and refuses the changes that would let a review apply to another request. It also refuses the targets
+// the compile package rejects, so a malformed request fails when it is applied rather than once approved.
+type stamper struct {
+	// client creates the SubjectAccessReviews of the reviewers
+	client client.Client
+}
+
+var _ admission.Handler = &stamper{}
+
+// Handle stamps the requester of a created access request and the reviewer of an updated one
+func (s *stamper) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &ibmcloudv1alpha1.AccessRequest{}
+	if err := json.Unmarshal(req.Object.Raw, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	user := req.UserInfo.Username
+
+	switch req.Operation {
+	case admissionv1beta1.Create:
+		if reviewer(instance) != "" {
+			return admission.Denied(preReviewedMessage)
+		}
+		if !compile.TargetWellFormed(instance.Spec.Target) {
+			return admission.Denied(malformedTargetMessage)
+		}
+		instance.Spec.Requester = user
+	case admissionv1beta1.Update:
+		old := &ibmcloudv1alpha1.AccessRequest{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if instance.Spec.Requester != old.Spec.Requester {
+			return admission.Denied("The requester of an access request can't change")
+		}
+		if reviewer(old) != "" {
+			if !reflect.DeepEqual(instance.Spec, old.Spec) {
+				return admission.Denied(specChangedMessage)
+			}
+			if reviewAnnotations(instance) != reviewAnnotations(old) {
+				return admission.Denied("An access request can only be reviewed once")
+			}
+			return admission.Allowed("")
+		}
+		if !compile.TargetWellFormed(instance.Spec.Target) {
+			return admission.Denied(malformedTargetMessage)
+		}
+		if reviewer(instance) == "" {
+			return admission.Allowed("")
+		}
+		if !reflect.DeepEqual(instance.Spec, old.Spec) {
+			return admission.Denied("An access request can't be reviewed and changed at once")
+		}
+		if user == instance.Spec.Requester {
+			return admission.Denied(selfReviewMessage)
+		}
+		allowed, err := s.canReview(ctx, instance, req.UserInfo)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if !allowed {
+			return admission.Denied(fmt.Sprintf("%s is not allowed to %s access requests", user, approveVerb))
+		}
+		for _, annotation := range []string{ibmcloudv1alpha1.ApprovedByAnnotation, ibmcloudv1alpha1.DeniedByAnnotation} {
+			if _, ok := instance.Annotations[annotation]; ok {
+				instance.Annotations[annotation] = user
+			}
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	stamped, err := json.Marshal(instance)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, stamped)
+}
+
+// canReview checks with a SubjectAccessReview that Kubernetes RBAC allows a user, with their groups, to approve
+// access requests in the namespace
+func (s *stamper) canReview(ctx context.Context, instance *ibmcloudv1alpha1.AccessRequest, user authenticationv1.UserInfo) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: instance.Namespace,
+				Verb:      approveVerb,
+				Group:     ibmcloudv1alpha1.SchemeGroupVersion.Group,
+				Resource:  "accessrequests",
+				Name:      instance.Name,
+			},
+		},
+	}
+	if err := s.client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// reviewAnnotations returns the approval and denial annotations of an access request, to compare them
+func reviewAnnotations(instance *ibmcloudv1alpha1.AccessRequest) string {
+	return fmt.Sprintf("%q %q", instance.Annotations[ibmcloudv1alpha1.ApprovedByAnnotation], instance.Annotations[ibmcloudv1alpha1.DeniedByAnnotation])
+}
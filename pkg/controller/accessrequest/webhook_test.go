@@ -0,0 +1,171 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+)
+
+// reviewClient answers SubjectAccessReviews, allowing the approvers, users or groups, to approve access requests
+type reviewClient struct {
+	client.Client
+	approvers map[string]bool
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	review := obj.(*authorizationv1.SubjectAccessReview)
+	if review.Spec.ResourceAttributes.Verb != approveVerb {
+		return nil
+	}
+	review.Status.Allowed = c.approvers[review.Spec.User]
+	for _, group := range review.Spec.Groups {
+		review.Status.Allowed = review.Status.Allowed || c.approvers[group]
+	}
+	return nil
+}
+
+// newStamper returns the webhook with bob and the approvers group allowed to approve access requests
+func newStamper() *stamper {
+	return &stamper{client: &reviewClient{approvers: map[string]bool{"bob": true, "approvers": true}}}
+}
+
+func webhookRequest(operation admissionv1beta1.Operation, user string, old, instance *ibmcloudv1alpha1.AccessRequest) admission.Request {
+	req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: operation,
+		UserInfo:  authenticationv1.UserInfo{Username: user},
+	}}
+	if user == "carol" {
+		req.UserInfo.Groups = []string{"approvers"}
+	}
+	req.Object = runtime.RawExtension{Raw: marshal(instance)}
+	if old != nil {
+		req.OldObject = runtime.RawExtension{Raw: marshal(old)}
+	}
+	return req
+}
+
+func marshal(instance *ibmcloudv1alpha1.AccessRequest) []byte {
+	raw, err := json.Marshal(instance)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func accessRequest(requester string, annotations map[string]string, justification string) *ibmcloudv1alpha1.AccessRequest {
+	return &ibmcloudv1alpha1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "debugcosaccess", Namespace: "default", Annotations: annotations},
+		Spec:       ibmcloudv1alpha1.AccessRequestSpec{Requester: requester, Justification: justification},
+	}
+}
+
+// patched returns the value set at path by the patch of a response, and whether it was set
+func patched(resp admission.Response, path string) (interface{}, bool) {
+	for _, op := range resp.Patches {
+		if op.Path == path {
+			return op.Value, true
+		}
+	}
+	return nil, false
+}
+
+func TestStamperCreate(t *testing.T) {
+	s := newStamper()
+
+	resp := s.Handle(context.TODO(), webhookRequest(admissionv1beta1.Create, "jane", nil, accessRequest("alice", nil, "debug")))
+	if !resp.Allowed {
+		t.Fatalf("creation denied: %v", resp.Result)
+	}
+	if value, ok := patched(resp, "/spec/requester"); !ok || value != "jane" {
+		t.Errorf("requester patched to %v, want jane", value)
+	}
+
+	approved := map[string]string{ibmcloudv1alpha1.ApprovedByAnnotation: "alice"}
+	resp = s.Handle(context.TODO(), webhookRequest(admissionv1beta1.Create, "jane", nil, accessRequest("", approved, "debug")))
+	if resp.Allowed {
+		t.Error("creation of a reviewed access request allowed")
+	}
+}
+
+func TestStamperUpdate(t *testing.T) {
+	approved := map[string]string{ibmcloudv1alpha1.ApprovedByAnnotation: "jane"}
+	approvedByBob := map[string]string{ibmcloudv1alpha1.ApprovedByAnnotation: "bob"}
+	denied := map[string]string{ibmcloudv1alpha1.DeniedByAnnotation: "alice"}
+
+	cases := []struct {
+		name     string
+		user     string
+		old      *ibmcloudv1alpha1.AccessRequest
+		instance *ibmcloudv1alpha1.AccessRequest
+		allowed  bool
+		stamped  string
+	}{
+		{"approval by another user", "bob", accessRequest("jane", nil, "debug"), accessRequest("jane", approved, "debug"), true, "bob"},
+		{"approval by a group member", "carol", accessRequest("jane", nil, "debug"), accessRequest("jane", approved, "debug"), true, "carol"},
+		{"approval without permission", "dave", accessRequest("jane", nil, "debug"), accessRequest("jane", approved, "debug"), false, ""},
+		{"approval by the requester", "jane", accessRequest("jane", nil, "debug"), accessRequest("jane", approvedByBob, "debug"), false, ""},
+		{"approval and change", "bob", accessRequest("jane", nil, "debug"), accessRequest("jane", approvedByBob, "more"), false, ""},
+		{"change before review", "jane", accessRequest("jane", nil, "debug"), accessRequest("jane", nil, "more"), true, ""},
+		{"change of requester", "jane", accessRequest("jane", nil, "debug"), accessRequest("bob", nil, "debug"), false, ""},
+		{"change after review", "bob", accessRequest("jane", approvedByBob, "debug"), accessRequest("jane", approvedByBob, "more"), false, ""},
+		{"second review", "alice", accessRequest("jane", approvedByBob, "debug"), accessRequest("jane", denied, "debug"), false, ""},
+		{"label after review", "jane", accessRequest("jane", approvedByBob, "debug"), accessRequest("jane", approvedByBob, "debug"), true, ""},
+	}
+	s := newStamper()
+	for _, c := range cases {
+		resp := s.Handle(context.TODO(), webhookRequest(admissionv1beta1.Update, c.user, c.old, c.instance))
+		if resp.Allowed != c.allowed {
+			t.Errorf("%s: allowed %t, want %t", c.name, resp.Allowed, c.allowed)
+			continue
+		}
+		value, ok := patched(resp, "/metadata/annotations/ibmcloud.ibm.com~1approved-by")
+		if c.stamped == "" && ok {
+			t.Errorf("%s: reviewer patched to %v", c.name, value)
+		}
+		if c.stamped != "" && value != c.stamped {
+			t.Errorf("%s: reviewer patched to %v, want %s", c.name, value, c.stamped)
+		}
+	}
+}
+
+func TestStamperMalformedTarget(t *testing.T) {
+	s := newStamper()
+	malformed := accessRequest("jane", nil, "debug")
+	malformed.Spec.Target.Tags = []ibmcloudv1alpha1.Tag{{Key: "env"}}
+
+	resp := s.Handle(context.TODO(), webhookRequest(admissionv1beta1.Create, "jane", nil, malformed))
+	if resp.Allowed {
+		t.Error("creation of an access request with a malformed target allowed")
+	}
+
+	resp = s.Handle(context.TODO(), webhookRequest(admissionv1beta1.Update, "jane", accessRequest("jane", nil, "debug"), malformed))
+	if resp.Allowed {
+		t.Error("change of an access request to a malformed target allowed")
+	}
+}
@@ -0,0 +1,10 @@
+package controller
+
+import (
+	"github.com/IBM/ibmcloud-iam-operator/pkg/controller/accessrequest"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, accessrequest.Add)
+}
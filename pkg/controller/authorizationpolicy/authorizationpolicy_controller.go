@@ -21,19 +21,29 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"time"
 
 	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
-	common "github.com/IBM/ibmcloud-iam-operator/pkg/util"
-
-    "github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
-    "github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
-    "github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
-    "github.com/IBM-Cloud/bluemix-go/utils"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/control"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/plan"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	poldiff "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/diff"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
 
     "k8s.io/api/core/v1"
     kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -46,7 +56,7 @@ import (
 var log = logf.Log.WithName("controller_authorizationpolicy")
 
 const authorizationpolicyFinalizer = "authorizationpolicy.ibmcloud.ibm.com"
-const syncPeriod = time.Second * 150
+const authorizationpolicyKind = "AuthorizationPolicy"
 
 // ContainsFinalizer checks if the instance contains authorizationpolicy finalizer
 func ContainsFinalizer(instance *ibmcloudv1alpha1.AuthorizationPolicy) bool {
@@ -73,18 +83,22 @@ func DeleteFinalizer(instance *ibmcloudv1alpha1.AuthorizationPolicy) []string {
 // Add creates a new AuthorizationPolicy Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	namespaces, err := control.Namespaces(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespaces))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileAuthorizationPolicy{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager, namespaces client.Reader) reconcile.Reconciler {
+	return &ReconcileAuthorizationPolicy{client: mgr.GetClient(), reader: namespaces, scheme: mgr.GetScheme(), iam: iamclient.New(), recorder: mgr.GetEventRecorderFor("authorizationpolicy-controller")}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("authorizationpolicy-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("authorizationpolicy-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: settings.MaxConcurrentReconciles(authorizationpolicyKind)})
 	if err != nil {
 		return err
 	}
@@ -114,7 +128,13 @@ type ReconcileAuthorizationPolicy struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads the namespaces, which the cache may not hold
+	reader client.Reader
 	scheme *runtime.Scheme
+	// iam connects to the IAM account of the namespace of an object
+	iam iamclient.Factory
+	// recorder records the events of drift in IAM
+	recorder record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a AuthorizationPolicy object and makes changes based on the state read
@@ -125,6 +145,14 @@ type ReconcileAuthorizationPolicy struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile("AuthorizationPolicy", request.NamespacedName)
+	result, err := r.reconcile(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile reconciles a AuthorizationPolicy within the span of the reconcile
+func (r *ReconcileAuthorizationPolicy) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling AuthorizationPolicy")
 
@@ -154,6 +182,38 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 		}
 	} 
 
+	// Leave a resource paused by its annotation or its namespace's alone, and record a resume or a forced resync
+	paused, reason, err := control.Paused(r.reader, instance)
+	if err != nil {
+		reqLogger.Info("Error getting pause annotations", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	if paused {
+		if control.Pause(instance, reason) {
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for pause", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		reqLogger.Info("Authorization Policy reconciliation is paused", "Reason", reason)
+		return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+	}
+	resumed := control.Resume(instance)
+	resync := control.Resync(instance)
+	applied := !plan.Enabled(instance) && plan.Clear(instance)
+	if resumed || resync || applied {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for resume, resync or end of dry run", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Leave a spec that failed permanently alone until it changes, unless it is deleted or a resync is forced
+	if requeue.Stalled(instance) && instance.ObjectMeta.DeletionTimestamp.IsZero() && !resync {
+		reqLogger.Info("Authorization Policy failed permanently, waiting for its spec to change")
+		return reconcile.Result{}, nil
+	}
+
 	// Check that the spec is well-formed
 	if !isWellFormed(*instance) {
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -165,19 +225,22 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 			}
 			return reconcile.Result{}, nil
 		}
-		if instance.Status.State != "Failed" {
+		err := requeue.AsPermanent(fmt.Errorf("The spec is not well-formed"))
+		if requeue.Report(instance, err) || instance.Status.State != "Failed" {
 			instance.Status.State = "Failed"
-			instance.Status.Message = fmt.Errorf("The spec is not well-formed").Error()
+			instance.Status.Message = err.Error()
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for bad spec", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
 	
 		}
-		return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+		return requeue.Result(err)
 	}
 
-	sess, myAccount, err := common.GetIAMAccountInfo(r.client, instance.ObjectMeta.Namespace)
+	_, credentials := tracing.Start(ctx, "LoadCredentials")
+	myAccount, iamClients, err := r.iam.Connect(ctx, r.client, instance.ObjectMeta.Namespace)
+	tracing.End(credentials, err)
 	if err != nil {
 		reqLogger.Info("Error getting IBM Cloud IAM account information", instance.Name, err.Error())
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -191,30 +254,54 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 		}
 		instance.Status.State = "Failed"
 		instance.Status.Message = "Error getting IBM Cloud IAM account information"
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for failing IAM account setup", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, err
+		return requeue.Result(err)
+	}
+
+	// Wait for IAM to recover rather than sending it more requests
+	unavailable := resilience.Unavailable(myAccount.GUID)
+	if resilience.Report(instance, unavailable) {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for IAM availability", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	if unavailable > 0 {
+		reqLogger.Info("IAM is unavailable, waiting for it to recover", "Retry after", unavailable.String())
+		return reconcile.Result{Requeue: true, RequeueAfter: unavailable}, nil
 	}
 	
 	statusPolicyID := instance.Status.PolicyID
+	if statusPolicyID == "" && !instance.ObjectMeta.DeletionTimestamp.IsZero() { // Status was lost, delete the authorization policy recorded for this resource
+		statusPolicyID = ownership.RecordedID(authorizationpolicyKind, instance, myAccount.GUID)
+	}
 
-	iampapClient, err := iampapv1.New(sess)
+	policyAPI, err := iamClients.Policies()
 	if err != nil {
 		reqLogger.Info("Error getting iampap Client", instance.Name, err.Error())
 
 		return reconcile.Result{}, err
 	}
-	policyAPI := iampapClient.V1Policy()
 
-	iamClient, err := iamv1.New(sess)
+	iamCache, err := iamClients.Cache()
 	if err != nil {
-		reqLogger.Info("Error creating IAM Client", instance.Name, err.Error())
+		reqLogger.Info("Error getting IAM cache", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	serviceIDAPI := iamClient.ServiceIds()
-	serviceRolesAPI := iamClient.ServiceRoles()
+
+	roleCatalog, err := iamClients.RoleCatalog()
+	if err != nil {
+		reqLogger.Info("Error getting role catalog", instance.Name, err.Error())
+		return reconcile.Result{}, err
+	}
+	if resync { // Read IAM and resolve roles and subjects again rather than using the cached state
+		iamCache.Expire()
+		roleCatalog.Expire()
+	}
 	
 	// Delete if necessary
  	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -229,7 +316,11 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 	} else {
 		// The object is being deleted
 		if ContainsFinalizer(instance) {
-			if (statusPolicyID != "") { //Policy must exist in IAM since status has an ID 
+			if statusPolicyID != "" && plan.Enabled(instance) { // Record the deletion in an event, which outlives the resource
+				if err := r.recordPlan(instance, plan.Delete, nil, false); err != nil {
+					return reconcile.Result{}, err
+				}
+			} else if (statusPolicyID != "") { //Policy must exist in IAM since status has an ID 
 				err := deleteAuthorizationPolicy(statusPolicyID, policyAPI)
 				if err != nil {			
 					if !strings.Contains(err.Error(), "not found") {
@@ -259,73 +350,115 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 	}  
 
 	/* Setting roles, resource and subject in Policy */
-	policyRoles, err := getRoles(instance, serviceRolesAPI)
+	_, roles := tracing.Start(ctx, "ResolveRoles")
+	policy, err := compile.AuthorizationPolicy(instance, myAccount.GUID, settings.Current.InstanceID, roleCatalog)
+	tracing.End(roles, err)
 	if err != nil {
 		reqLogger.Info("Error getting roles for authorization policy", "Failed", err.Error())
 		instance.Status.State = "Failed"
 		instance.Status.Message = "Error getting roles for authorization policy"
+		if unknown, ok := err.(*rolecatalog.UnknownRolesError); ok {
+			instance.Status.Message = instance.Status.Message + ": " + unknown.Error()
+			err = requeue.AsPermanent(err)
+		}
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for failing get roles", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}	
-		return reconcile.Result{}, err
+		return requeue.Result(err)
 	}
 	
-	policyResource, err := getResource(instance, myAccount, serviceIDAPI)
-	if err != nil {
-		reqLogger.Info("Error getting resource for authorization policy", "Failed", err.Error())
-		instance.Status.State = "Failed"
-		instance.Status.Message = "Error getting resource for authorization policy"
-		if err := r.client.Status().Update(context.Background(), instance); err != nil {
-			reqLogger.Info("Error updating status for failing get resource", "Failed", err.Error())
-			return reconcile.Result{}, err
-		}	
-		return reconcile.Result{}, err
-	}
-
-	policySubject, err  := getSubject(instance, myAccount, serviceIDAPI)
-	if err != nil {
-		reqLogger.Info("Error getting subject for authorization policy", "Failed", err.Error())
-		instance.Status.State = "Failed"
-		instance.Status.Message = "Error getting subject for authorization policy"
-		if err := r.client.Status().Update(context.Background(), instance); err != nil {
-			reqLogger.Info("Error updating status for failing get subject", "Failed", err.Error())
-			return reconcile.Result{}, err
-		}	
-		return reconcile.Result{}, err
+	if statusPolicyID == "" { // Status was lost, e.g. by a restore, look for the authorization policy of this resource before creating one
+		statusPolicyID, err = r.rediscoverAuthorizationPolicy(instance, policy, myAccount.GUID, policyAPI)
+		if err != nil {
+			reqLogger.Info("Error looking for existing authorization policy", "Failed", err.Error())
+			instance.Status.State = "Failed"
+			instance.Status.Message = "Error looking for existing authorization policy"
+			requeue.Report(instance, err)
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for failing authorization policy lookup", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+			return requeue.Result(err)
+		}
+		instance.Status.PolicyID = statusPolicyID
 	}
 
-	var policy = iampapv1.Policy{Roles: policyRoles, Resources: []iampapv1.Resource{policyResource}, Subjects: []iampapv1.Subject{policySubject}}
-	policy.Type = iampapv1.AuthorizationPolicyType
-
 	if (statusPolicyID != "") { //Policy must exist in IAM since status has an ID 
-		retrievedPolicy, err := policyAPI.Get(statusPolicyID)
-		etag := retrievedPolicy.Version
+		retrievedPolicy, err := iamCache.Policy(statusPolicyID)
 		if err != nil {
 			reqLogger.Info("Error retrieving policy", "Failed", err.Error())
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error retrieving policy"
 			instance.Status.PolicyID = ""   //clear out the policy ID since policy with this ID can't be retrieved
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing authorization policy retrieval", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, err
+			return requeue.Result(err)
 		}	
 
-		if (specChanged(instance) || policyChanged(policy, retrievedPolicy)) { // Spec change or a change via the IAM console means the authorization policy needs an update
-			updatedPolicy, err := updateAuthorizationPolicy(statusPolicyID, policy, policyAPI, etag)
+		changed := specChanged(instance)
+		var diffs, unmarked []drift.Difference
+		if !changed {
+			diffs = policyDrift(policy, retrievedPolicy)
+			unmarked = poldiff.V1Unmarked(policy, retrievedPolicy)
+		}
+		mode := drift.Mode(instance.Spec.DriftPolicy, settings.Current.DriftPolicy)
+		driftReported := drift.Report(instance, mode, diffs)
+		update := changed || (len(diffs) > 0 && mode == drift.Enforce)
+
+		if plan.Enabled(instance) {
+			if update {
+				return r.planAuthorizationPolicy(instance, plan.Update, poldiff.V1Policies(policy, retrievedPolicy), driftReported)
+			}
+			if len(unmarked) > 0 {
+				return r.planAuthorizationPolicy(instance, plan.Update, unmarked, driftReported)
+			}
+			return r.planAuthorizationPolicy(instance, plan.NoChange, diffs, driftReported)
+		}
+
+		drift.Event(r.recorder, instance, mode, diffs, driftReported)
+
+		if !update && len(unmarked) > 0 { // A policy created before the operator marked its policies only gets the marker
+			err := stampAuthorizationPolicy(statusPolicyID, policy.Description, policyAPI)
+			iamCache.Invalidate(statusPolicyID)
+			if err != nil {
+				reqLogger.Info("Error marking policy", "Failed", err.Error())
+				instance.Status.State = "Failed"
+				instance.Status.Message = "Error marking policy"
+				requeue.Report(instance, err)
+				if err := r.client.Status().Update(context.Background(), instance); err != nil {
+					reqLogger.Info("Error updating status for failing authorization policy marking", "Failed", err.Error())
+					return reconcile.Result{}, err
+				}
+				return requeue.Result(err)
+			}
+			reqLogger.Info("Marked authorization policy as owned.", "Policy ID:", statusPolicyID)
+			instance.Status.State = "Online"
+			instance.Status.Message = "IAM authorization policy marked as owned"
+		}
+
+		if update { // Spec change or an enforced change via the IAM console means the authorization policy needs an update
+			updatedPolicy, err := updateAuthorizationPolicy(statusPolicyID, policy, policyAPI)
+			iamCache.Invalidate(statusPolicyID)
 			if err != nil {
 				reqLogger.Info("Error updating policy", "Failed", err.Error())
 				instance.Status.State = "Failed"
 				instance.Status.Message = "Error updating policy"
+				requeue.Report(instance, err)
 				if err := r.client.Status().Update(context.Background(), instance); err != nil {
 					reqLogger.Info("Error updating status for failing authorization policy update", "Failed", err.Error())
 					return reconcile.Result{}, err
 				}
-				return reconcile.Result{}, err
+				return requeue.Result(err)
 			}
 			reqLogger.Info("Updated authorization policy.","Policy ID:",updatedPolicy.ID,"Policy Href:",updatedPolicy.Href)
+			if !changed {
+				metrics.DriftCorrected(authorizationpolicyKind)
+			}
 
 			instance.Status.State = "Online"
 			instance.Status.Message = "IAM authorization policy updated"
@@ -333,22 +466,42 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 			instance.Status.Source = instance.Spec.Source
 			instance.Status.Roles = instance.Spec.Roles
 			instance.Status.Target = instance.Spec.Target
+			requeue.Report(instance, nil)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for authorization policy update", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-		} 
+		} else if requeue.Report(instance, nil) || driftReported || len(unmarked) > 0 || instance.Status.State == "Pending" {
+			if instance.Status.State == "Pending" { // Resumed after a pause
+				instance.Status.State = "Online"
+			}
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for authorization policy drift", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
 	} else { //Policy doesn't exist in IAM
+		if plan.Enabled(instance) {
+			return r.planAuthorizationPolicy(instance, plan.Create, poldiff.V1Policies(policy, polv1.Policy{}), false)
+		}
+		// Record the intent first, so that the policy is found rather than created again if the operator stops before recording its ID
+		if ownership.SetIntent(instance, ownership.PolicyHash(polv2.ConvertV1Policy(policy))) {
+			if err := r.client.Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error recording authorization policy creation", instance.Name, err.Error())
+				return reconcile.Result{}, err
+			}
+		}
 		createdPolicy, err := createAuthorizationPolicy(policy, policyAPI)
 		if err != nil {
 			reqLogger.Info("Error creating policy", "Failed", err.Error())
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error creating policy"
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing authorization policy creation", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{}, err
+			return requeue.Result(err)
 		}
 		reqLogger.Info("Created authorization policy.","Policy ID:",createdPolicy.ID,"Policy Href:",createdPolicy.Href)
 
@@ -358,6 +511,7 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 		instance.Status.Source = instance.Spec.Source
 		instance.Status.Roles = instance.Spec.Roles
 		instance.Status.Target = instance.Spec.Target
+		requeue.Report(instance, nil)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for authorization policy creation", "Failed", err.Error())
 			errr := deleteAuthorizationPolicy(createdPolicy.ID, policyAPI)
@@ -370,38 +524,97 @@ func (r *ReconcileAuthorizationPolicy) Reconcile(request reconcile.Request) (rec
 			reqLogger.Info("Deleted authorization policy.","Policy ID:",createdPolicy.ID)
 			return reconcile.Result{}, err
 		}
+		metrics.Online(authorizationpolicyKind, instance.ObjectMeta.CreationTimestamp.Time)
 	}	
-	return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+	recorded := ownership.Record(authorizationpolicyKind, instance, myAccount.GUID, instance.Status.PolicyID)
+	if instance.Status.PolicyID != "" && (ownership.ClearIntent(instance) || recorded) { // Annotate the resource with the ID of its authorization policy, so it can be found without status
+		if err := r.client.Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error recording authorization policy ID", instance.Name, err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
 
 }
 
-func policyChanged(policy iampapv1.Policy, retrievedPolicy iampapv1.Policy) bool {
-	if !reflect.DeepEqual(retrievedPolicy.Subjects, policy.Subjects) {
-		log.Info("Authorization policy subject in IAM has changed")
-		return true
+// planAuthorizationPolicy records the change the reconcile would make to the authorization policy in IAM instead of making it
+func (r *ReconcileAuthorizationPolicy) planAuthorizationPolicy(instance *ibmcloudv1alpha1.AuthorizationPolicy, action string, diffs []drift.Difference, statusChanged bool) (reconcile.Result, error) {
+	if err := r.recordPlan(instance, action, diffs, statusChanged); err != nil {
+		return reconcile.Result{}, err
 	}
+	return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+}
 
-	for _, m := range retrievedPolicy.Roles {
-		if !contains(policy.Roles,m) {
-			log.Info("Authorization policy roles in IAM has changed")
-			return true
+// recordPlan sets the planned change in the status of the resource and records it in an event when it changes
+func (r *ReconcileAuthorizationPolicy) recordPlan(instance *ibmcloudv1alpha1.AuthorizationPolicy, action string, diffs []drift.Difference, statusChanged bool) error {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	reqLogger.Info("Planned authorization policy change", "Action", action, "Differences", drift.Summary(diffs))
+
+	planned := plan.Report(instance, action, "authorization policy", diffs)
+	if requeue.Report(instance, nil) || planned || statusChanged {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for planned authorization policy change", "Failed", err.Error())
+			return err
 		}
 	}
+	if planned {
+		if err := plan.Emit(r.client, instance, authorizationpolicyKind); err != nil {
+			reqLogger.Info("Error recording planned authorization policy change", "Failed", err.Error())
+		}
+	}
+	return nil
+}
 
-	if !reflect.DeepEqual(retrievedPolicy.Resources, policy.Resources) {
-		log.Info("Authorization policy resource in IAM has changed")
-		return true
-	}	
-	return false
+// rediscoverAuthorizationPolicy returns the ID of the authorization policy recorded for the resource if it still exists
+// in IAM, else the ID of an authorization policy in IAM that was about to be created for it and no other resource recorded,
+// or "" if there is none
+func (r *ReconcileAuthorizationPolicy) rediscoverAuthorizationPolicy(instance *ibmcloudv1alpha1.AuthorizationPolicy, policy polv1.Policy, accountID string, policyAPI polv1.PolicyRepository) (string, error) {
+	if recordedID := ownership.RecordedID(authorizationpolicyKind, instance, accountID); recordedID != "" {
+		_, err := policyAPI.Get(recordedID)
+		if err == nil {
+			log.Info("Recovered authorization policy from annotations", "Policy ID:", recordedID)
+			return recordedID, nil
+		}
+		if !iamclient.NotFound(err) { // The authorization policy may still exist, don't create another one
+			return "", err
+		}
+	}
+
+	intent := ownership.Intent(instance)
+	if intent == "" { // A policy with the same content isn't adopted, it may have been created by hand
+		return "", nil
+	}
+	claimed, err := r.claimedPolicies(instance, accountID)
+	if err != nil {
+		return "", err
+	}
+	policyID, err := ownership.FindV1Policy(policyAPI, polv1.SearchParams{AccountID: accountID, Type: policy.Type}, intent, settings.Current.InstanceID, claimed)
+	if policyID != "" {
+		log.Info("Found existing authorization policy", "Policy ID:", policyID)
+	}
+	return policyID, err
 }
 
-func contains(policyRoles []iampapv1.Role, e iampapv1.Role) bool {
-    for _, a := range policyRoles {
-        if reflect.DeepEqual(a.RoleID,e.RoleID) {
-            return true
-        }
-    }
-    return false
+// claimedPolicies returns the IDs of the authorization policies recorded by the other authorization policy resources
+func (r *ReconcileAuthorizationPolicy) claimedPolicies(instance *ibmcloudv1alpha1.AuthorizationPolicy, accountID string) (map[string]bool, error) {
+	list := &ibmcloudv1alpha1.AuthorizationPolicyList{}
+	if err := r.client.List(context.Background(), list); err != nil {
+		return nil, err
+	}
+	objs := make([]metav1.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return ownership.Claimed(authorizationpolicyKind, objs, instance, accountID), nil
+}
+
+// policyDrift returns the differences between the desired authorization policy and the one in IAM
+func policyDrift(policy polv1.Policy, retrievedPolicy polv1.Policy) []drift.Difference {
+	diffs := poldiff.V1Drift(policy, retrievedPolicy)
+	if len(diffs) > 0 {
+		log.Info("Authorization policy in IAM has changed", "Differences", drift.Summary(diffs))
+	}
+	return diffs
 }
 
 func specChanged(instance *ibmcloudv1alpha1.AuthorizationPolicy) bool {
@@ -427,7 +640,7 @@ func specChanged(instance *ibmcloudv1alpha1.AuthorizationPolicy) bool {
 	return false
 }
 
-func createAuthorizationPolicy(policy iampapv1.Policy, policyAPI iampapv1.V1PolicyRepository) (*iampapv1.Policy, error) {
+func createAuthorizationPolicy(policy polv1.Policy, policyAPI polv1.PolicyRepository) (*polv1.Policy, error) {
 	createdPolicy, err := policyAPI.Create(policy)
 	if err != nil {
 		return nil, err
@@ -435,92 +648,35 @@ func createAuthorizationPolicy(policy iampapv1.Policy, policyAPI iampapv1.V1Poli
 	return &createdPolicy, nil
 }
 
-func updateAuthorizationPolicy(statusPolicyID string, policy iampapv1.Policy, policyAPI iampapv1.V1PolicyRepository, etag string) (*iampapv1.Policy, error) {
-	updatedPolicy, err := policyAPI.Update(statusPolicyID, policy, etag)
+func updateAuthorizationPolicy(statusPolicyID string, policy polv1.Policy, policyAPI polv1.PolicyRepository) (*polv1.Policy, error) {
+	retrievedPolicy, err := policyAPI.Get(statusPolicyID) // Cached policies have no etag
+	if err != nil {
+		return nil, err
+	}
+	updatedPolicy, err := policyAPI.Update(statusPolicyID, policy, retrievedPolicy.Version)
 	if err != nil {
 		return nil, err
 	}
 	return &updatedPolicy, nil
 }
 
-func deleteAuthorizationPolicy(statusPolicyID string, policyAPI iampapv1.V1PolicyRepository) (error) {
-	err := policyAPI.Delete(statusPolicyID)
+// stampAuthorizationPolicy sets the description of the authorization policy in IAM and keeps the rest of it
+func stampAuthorizationPolicy(statusPolicyID string, description string, policyAPI polv1.PolicyRepository) error {
+	retrievedPolicy, err := policyAPI.Get(statusPolicyID) // Cached policies have no etag
 	if err != nil {
 		return err
 	}
-	return nil
+	retrievedPolicy.Description = description
+	_, err = policyAPI.Update(statusPolicyID, retrievedPolicy, retrievedPolicy.Version)
+	return err
 }
 
-func getSubject(instance *ibmcloudv1alpha1.AuthorizationPolicy, myAccount *accountv2.Account, serviceIDAPI iamv1.ServiceIDRepository) (iampapv1.Subject, error) {
-	/* Getting attributes for Resource */
-	policySubject := iampapv1.Subject{}
-
- 	if instance.Spec.Source.ServiceClass != "" {
-		policySubject.SetAttribute("serviceName",instance.Spec.Source.ServiceClass)
-	}
-	if instance.Spec.Source.ServiceID != "" {
-		policySubject.SetAttribute("serviceInstance",instance.Spec.Source.ServiceID)
-	}  
-	if instance.Spec.Source.ResourceName != "" {
-		policySubject.SetAttribute("resourceType",instance.Spec.Source.ResourceName)
-	} 
-	if instance.Spec.Source.ResourceID != "" {
-		policySubject.SetAttribute("resource",instance.Spec.Source.ResourceID)
-	} 
-  	if instance.Spec.Source.ResourceGroup != "" {
-		policySubject.SetResourceGroupID(instance.Spec.Source.ResourceGroup)
-	}
-	if instance.Spec.Source.ResourceKey != "" && instance.Spec.Source.ResourceValue != "" {
-		policySubject.SetAttribute(instance.Spec.Source.ResourceKey, instance.Spec.Source.ResourceValue)
-	}
-	policySubject.SetAttribute("accountId", myAccount.GUID)
-	return policySubject, nil
-}
-
-func getRoles(instance *ibmcloudv1alpha1.AuthorizationPolicy, serviceRolesAPI iamv1.ServiceRoleRepository) ([]iampapv1.Role, error) {
-	/* Getting roles for Source */
-	var policyRoles []iampapv1.Role
-
-	definedRoles, err := serviceRolesAPI.ListAuthorizationRoles(instance.Spec.Source.ServiceClass, instance.Spec.Target.ServiceClass)
-	if err != nil {
-		log.Info("Error getting defined system roles")
-		return nil, err
-	}
-
-	filterRoles, err := utils.GetRolesFromRoleNames(instance.Spec.Roles, definedRoles)
+func deleteAuthorizationPolicy(statusPolicyID string, policyAPI polv1.PolicyRepository) (error) {
+	err := policyAPI.Delete(statusPolicyID)
 	if err != nil {
-		log.Info("Error getting defined roles in spec")
-		return nil, err
-	}
-	policyRoles = iampapv1.ConvertRoleModels(filterRoles)
-
-	return policyRoles, nil
-}
-
-func getResource(instance *ibmcloudv1alpha1.AuthorizationPolicy, myAccount *accountv2.Account, serviceIDAPI iamv1.ServiceIDRepository) (iampapv1.Resource, error) {
-	/* Getting attributes for Resource */
-	policyResource := iampapv1.Resource{}
-
- 	if instance.Spec.Target.ServiceClass != "" {
-		policyResource.SetAttribute("serviceName",instance.Spec.Target.ServiceClass)
-	}
-	if instance.Spec.Target.ServiceID != "" {
-		policyResource.SetAttribute("serviceInstance",instance.Spec.Target.ServiceID)
-	}  
-	if instance.Spec.Target.ResourceName != "" {
-		policyResource.SetAttribute("resourceType",instance.Spec.Target.ResourceName)
-	} 
-	if instance.Spec.Target.ResourceID != "" {
-		policyResource.SetAttribute("resource",instance.Spec.Target.ResourceID)
-	} 
-  	if instance.Spec.Target.ResourceGroup != "" {
-		policyResource.SetResourceGroupID(instance.Spec.Target.ResourceGroup)
-	}
-	if instance.Spec.Target.ResourceKey != "" && instance.Spec.Target.ResourceValue != "" {
-		policyResource.SetAttribute(instance.Spec.Target.ResourceKey, instance.Spec.Target.ResourceValue)
+		return err
 	}
-	policyResource.SetAttribute("accountId", myAccount.GUID)
-	return policyResource, nil
+	return nil
 }
 
 func isWellFormed(instance ibmcloudv1alpha1.AuthorizationPolicy) bool {
@@ -528,7 +684,7 @@ func isWellFormed(instance ibmcloudv1alpha1.AuthorizationPolicy) bool {
 		return false
 	}
 
-	if (instance.Spec.Source.ResourceGroup == "" && instance.Spec.Source.ServiceID == "" && (instance.Spec.Source.ResourceName != "" || instance.Spec.Source.ResourceID != "" || instance.Spec.Source.ResourceKey != "" || instance.Spec.Source.ResourceValue != "")) { 
+	if (instance.Spec.Source.ResourceGroup == "" && instance.Spec.Source.ServiceID == "" && (instance.Spec.Source.ResourceName != "" || instance.Spec.Source.ResourceID != "" || instance.Spec.Source.ResourceKey != "" || instance.Spec.Source.ResourceValue != "" || len(instance.Spec.Source.Attributes) > 0)) { 
 		return false
 	}
 
@@ -536,9 +692,13 @@ func isWellFormed(instance ibmcloudv1alpha1.AuthorizationPolicy) bool {
 		return false
 	}
 
-	if (instance.Spec.Target.ResourceGroup == "" && instance.Spec.Target.ServiceID == "" && (instance.Spec.Target.ResourceName != "" || instance.Spec.Target.ResourceID != "" || instance.Spec.Target.ResourceKey != "" || instance.Spec.Target.ResourceValue != "")) { 
+	if (instance.Spec.Target.ResourceGroup == "" && instance.Spec.Target.ServiceID == "" && (instance.Spec.Target.ResourceName != "" || instance.Spec.Target.ResourceID != "" || instance.Spec.Target.ResourceKey != "" || instance.Spec.Target.ResourceValue != "" || len(instance.Spec.Target.Attributes) > 0)) { 
+		return false
+	}
+
+	if !compile.InfoWellFormed(instance.Spec.Source) || !compile.InfoWellFormed(instance.Spec.Target) {
 		return false
 	}
 
 	return true
-}
\ No newline at end of file
+}
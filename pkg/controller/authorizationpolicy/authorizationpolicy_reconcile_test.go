@@ -0,0 +1,167 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package authorizationpolicy
+
+import (
+	gocontext "context"
+	"strings"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+var request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "kms-reader"}}
+
+// newTestReconciler returns a reconciler of an AuthorizationPolicy connected to an in-memory IAM account
+func newTestReconciler(t *testing.T, spec ibmcloudv1alpha1.AuthorizationPolicySpec) (*ReconcileAuthorizationPolicy, *iamfake.Factory) {
+	cluster := iamfake.NewCluster(&ibmcloudv1alpha1.AuthorizationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		Spec:       spec,
+	})
+	return &ReconcileAuthorizationPolicy{client: cluster.Client, reader: cluster.Client, scheme: cluster.Scheme, iam: cluster.IAM, recorder: cluster.Recorder}, cluster.IAM
+}
+
+func kmsReaderSpec() ibmcloudv1alpha1.AuthorizationPolicySpec {
+	return ibmcloudv1alpha1.AuthorizationPolicySpec{
+		Source: ibmcloudv1alpha1.Info{ServiceClass: "cloud-object-storage", ServiceID: "1cdd19ff-c033-4767-b6b7-4fe2fc58c6a1"},
+		Roles:  []string{"Reader", "Writer"},
+		Target: ibmcloudv1alpha1.Info{ServiceClass: "kms"},
+	}
+}
+
+func getInstance(t *testing.T, r *ReconcileAuthorizationPolicy) *ibmcloudv1alpha1.AuthorizationPolicy {
+	instance := &ibmcloudv1alpha1.AuthorizationPolicy{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, instance))
+	return instance
+}
+
+func TestReconcileCreate(t *testing.T) {
+	r, iam := newTestReconciler(t, kmsReaderSpec())
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	policies := iam.Policies()
+	require.Len(t, policies, 1)
+	assert.Equal(t, "authorization", policies[0].Type)
+	assert.ElementsMatch(t, []string{"Reader", "Writer"}, iamfake.RoleNames(iam.Policies()[0]))
+	instance := getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, policies[0].ID, instance.Status.PolicyID)
+	assert.True(t, ContainsFinalizer(instance))
+
+	// A policy in sync with its spec is left alone
+	iam.ResetCalls()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.NotContains(t, iam.Calls(), "Policies.Create")
+	assert.NotContains(t, iam.Calls(), "Policies.Update")
+	assert.Len(t, iam.Policies(), 1)
+}
+
+func TestReconcileDrift(t *testing.T) {
+	for _, mode := range []string{drift.Enforce, drift.Detect} {
+		spec := kmsReaderSpec()
+		spec.DriftPolicy = mode
+		r, iam := newTestReconciler(t, spec)
+		_, err := r.Reconcile(request)
+		require.NoError(t, err)
+
+		// Remove a role as with the IAM console
+		policy := iam.Policies()[0]
+		for _, role := range policy.Control.Grant.Roles {
+			if strings.HasSuffix(role.RoleID, ":Reader") {
+				policy.Control.Grant.Roles = []polv2.Role{role}
+			}
+		}
+		require.NoError(t, iam.SetPolicy(policy))
+		_, err = r.Reconcile(request)
+		require.NoError(t, err)
+
+		condition := resv1.GetCondition(getInstance(t, r), drift.ConditionType)
+		require.NotNil(t, condition, mode)
+		events := r.recorder.(*record.FakeRecorder).Events
+		switch mode {
+		case drift.Enforce:
+			assert.ElementsMatch(t, []string{"Reader", "Writer"}, iamfake.RoleNames(iam.Policies()[0]))
+			assert.Equal(t, corev1.ConditionFalse, condition.Status)
+			assert.Contains(t, <-events, "Warning DriftReverted")
+		default:
+			assert.Equal(t, []string{"Reader"}, iamfake.RoleNames(iam.Policies()[0]))
+			assert.Equal(t, corev1.ConditionTrue, condition.Status)
+			assert.Contains(t, <-events, "Warning DriftDetected")
+		}
+	}
+}
+
+func TestReconcileUnknownRole(t *testing.T) {
+	spec := kmsReaderSpec()
+	spec.Roles = []string{"Entertainer"}
+	r, iam := newTestReconciler(t, spec)
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err) // A spec that can't work isn't retried
+	instance := getInstance(t, r)
+	assert.Equal(t, "Failed", instance.Status.State)
+	assert.Contains(t, instance.Status.Message, "Error getting roles for authorization policy")
+	assert.Contains(t, instance.Status.Message, "Entertainer")
+	assert.Empty(t, iam.Policies())
+}
+
+func TestReconcileIAMError(t *testing.T) {
+	r, iam := newTestReconciler(t, kmsReaderSpec())
+	iam.Fail("Policies.Create", bmxerror.NewRequestFailure("forbidden", "Not authorized to create policies", 403))
+
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	assert.Equal(t, "Failed", getInstance(t, r).Status.State)
+	assert.Empty(t, iam.Policies())
+
+	// The policy is created once IAM recovers
+	iam.Fail("Policies.Create", nil)
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, "Online", getInstance(t, r).Status.State)
+	assert.Len(t, iam.Policies(), 1)
+}
+
+func TestReconcileDelete(t *testing.T) {
+	r, iam := newTestReconciler(t, kmsReaderSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	require.Len(t, iam.Policies(), 1)
+
+	instance := getInstance(t, r)
+	now := metav1.Now()
+	instance.ObjectMeta.DeletionTimestamp = &now
+	require.NoError(t, r.client.Update(gocontext.Background(), instance))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Empty(t, iam.Policies())
+	assert.False(t, ContainsFinalizer(getInstance(t, r)))
+}
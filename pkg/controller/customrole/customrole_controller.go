@@ -20,18 +20,29 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"time"
 	"errors"
 
 	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
-	common "github.com/IBM/ibmcloud-iam-operator/pkg/util"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/control"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/plan"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
 
 	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
 	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
 
 	"k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -44,7 +55,7 @@ import (
 var log = logf.Log.WithName("controller_customrole")
 
 const customroleFinalizer = "customrole.ibmcloud.ibm.com"
-const syncPeriod = time.Second * 150
+const customroleKind = "CustomRole"
 
 // ContainsFinalizer checks if the instance contains customrole finalizer
 func ContainsFinalizer(instance *ibmcloudv1alpha1.CustomRole) bool {
@@ -71,18 +82,22 @@ func DeleteFinalizer(instance *ibmcloudv1alpha1.CustomRole) []string {
 // Add creates a new CustomRole Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	namespaces, err := control.Namespaces(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespaces))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileCustomRole{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager, namespaces client.Reader) reconcile.Reconciler {
+	return &ReconcileCustomRole{client: mgr.GetClient(), reader: namespaces, scheme: mgr.GetScheme(), iam: iamclient.New(), recorder: mgr.GetEventRecorderFor("customrole-controller")}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("customrole-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("customrole-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: settings.MaxConcurrentReconciles(customroleKind)})
 	if err != nil {
 		return err
 	}
@@ -112,7 +127,13 @@ type ReconcileCustomRole struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads the namespaces, which the cache may not hold
+	reader client.Reader
 	scheme *runtime.Scheme
+	// iam connects to the IAM account of the namespace of an object
+	iam iamclient.Factory
+	// recorder records the events of drift in IAM
+	recorder record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a CustomRole object and makes changes based on the state read
@@ -121,6 +142,14 @@ type ReconcileCustomRole struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile("CustomRole", request.NamespacedName)
+	result, err := r.reconcile(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile reconciles a CustomRole within the span of the reconcile
+func (r *ReconcileCustomRole) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling Custom Role")
 
@@ -150,6 +179,38 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 		}
 	} 
 
+	// Leave a resource paused by its annotation or its namespace's alone, and record a resume or a forced resync
+	paused, reason, err := control.Paused(r.reader, instance)
+	if err != nil {
+		reqLogger.Info("Error getting pause annotations", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	if paused {
+		if control.Pause(instance, reason) {
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for pause", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
+		reqLogger.Info("Custom Role reconciliation is paused", "Reason", reason)
+		return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+	}
+	resumed := control.Resume(instance)
+	resync := control.Resync(instance)
+	applied := !plan.Enabled(instance) && plan.Clear(instance)
+	if resumed || resync || applied {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for resume, resync or end of dry run", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
+	// Leave a spec that failed permanently alone until it changes, unless it is deleted or a resync is forced
+	if requeue.Stalled(instance) && instance.ObjectMeta.DeletionTimestamp.IsZero() && !resync {
+		reqLogger.Info("Custom Role failed permanently, waiting for its spec to change")
+		return reconcile.Result{}, nil
+	}
+
 	// Check that the spec is well-formed
 	if !isWellFormed(*instance) {
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -161,15 +222,16 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 			}
 			return reconcile.Result{}, nil
 		}
-		if instance.Status.State != "Failed" {
+		err := requeue.AsPermanent(fmt.Errorf("The spec is not well-formed"))
+		if requeue.Report(instance, err) || instance.Status.State != "Failed" {
 			instance.Status.State = "Failed"
-			instance.Status.Message = fmt.Errorf("The spec is not well-formed").Error()
+			instance.Status.Message = err.Error()
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for bad spec", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
 		}
-		return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+		return requeue.Result(err)
 	}
 
 	// Enforce immutability for role name and service class, restore the spec if it has changed
@@ -183,7 +245,9 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 		}
 	}
 
-	sess, myAccount, err := common.GetIAMAccountInfo(r.client, instance.ObjectMeta.Namespace)
+	_, credentials := tracing.Start(ctx, "LoadCredentials")
+	myAccount, iamClients, err := r.iam.Connect(ctx, r.client, instance.ObjectMeta.Namespace)
+	tracing.End(credentials, err)
 	if err != nil {
 		reqLogger.Info("Error getting IBM Cloud IAM account information", instance.Name, err.Error())
 		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -197,21 +261,53 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 		}
 		instance.Status.State = "Failed"
 		instance.Status.Message = "Error getting IBM Cloud IAM account information"
+		requeue.Report(instance, err)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for failing IAM account setup", "Failed", err.Error())
 			return reconcile.Result{}, err
 		}
-		return reconcile.Result{}, err
+		return requeue.Result(err)
+	}
+
+	// Wait for IAM to recover rather than sending it more requests
+	unavailable := resilience.Unavailable(myAccount.GUID)
+	if resilience.Report(instance, unavailable) {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for IAM availability", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	if unavailable > 0 {
+		reqLogger.Info("IAM is unavailable, waiting for it to recover", "Retry after", unavailable.String())
+		return reconcile.Result{Requeue: true, RequeueAfter: unavailable}, nil
 	}
 
 	statusRoleID := instance.Status.RoleID
+	if statusRoleID == "" && !instance.ObjectMeta.DeletionTimestamp.IsZero() { // Status was lost, delete the custom role recorded for this resource
+		statusRoleID = ownership.RecordedID(customroleKind, instance, myAccount.GUID)
+	}
 
-	roleClient, err := iampapv2.New(sess)
+	customRoleAPI, err := iamClients.CustomRoles()
 	if err != nil {
 		reqLogger.Info("Error creating Role Client", instance.Name, err.Error())
 		return reconcile.Result{}, err
 	}
-	customRoleAPI := roleClient.IAMRoles()
+
+	iamCache, err := iamClients.Cache()
+	if err != nil {
+		reqLogger.Info("Error getting IAM cache", instance.Name, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	roleCatalog, err := iamClients.RoleCatalog()
+	if err != nil {
+		reqLogger.Info("Error getting role catalog", instance.Name, err.Error())
+		return reconcile.Result{}, err
+	}
+	if resync { // Read IAM and resolve roles and subjects again rather than using the cached state
+		iamCache.Expire()
+		roleCatalog.Expire()
+	}
 	
 	// Delete if necessary
  	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -226,8 +322,14 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 	} else {
 		// The object is being deleted
 		if ContainsFinalizer(instance) {
-			if (statusRoleID != "") { //Role must exist in IAM since status has an ID 
+			if statusRoleID != "" && plan.Enabled(instance) { // Record the deletion in an event, which outlives the resource
+				if err := r.recordPlan(instance, plan.Delete, nil, false); err != nil {
+					return reconcile.Result{}, err
+				}
+			} else if (statusRoleID != "") { //Role must exist in IAM since status has an ID 
 				err := deleteCustomRole(statusRoleID, customRoleAPI)
+				iamCache.Invalidate(statusRoleID)
+				roleCatalog.Invalidate()
 				if err != nil {
 					if !strings.Contains(err.Error(), "not found") {
 						reqLogger.Info("Error deleting custom role", instance.Name, err.Error())
@@ -255,34 +357,80 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 		}
 	}  
 
+	if statusRoleID == "" { // Status was lost, e.g. by a restore, look for the custom role of this resource before creating one
+		existingRole, err := r.rediscoverCustomRole(instance, myAccount.GUID, customRoleAPI)
+		if err != nil {
+			reqLogger.Info("Error looking for existing custom role", "Failed", err.Error())
+			instance.Status.State = "Failed"
+			instance.Status.Message = "Error looking for existing custom role"
+			requeue.Report(instance, err)
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for failing custom role lookup", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+			return requeue.Result(err)
+		}
+		if existingRole != nil {
+			statusRoleID = existingRole.ID
+			instance.Status.RoleID = existingRole.ID
+			instance.Status.RoleCRN = existingRole.Crn
+			instance.Status.RoleName = existingRole.Name
+			instance.Status.ServiceClass = existingRole.ServiceName
+		}
+	}
+
 	if (statusRoleID != "") { //Role must exist in IAM since status has an ID 	
-		retrievedRole, etag, err := customRoleAPI.Get(statusRoleID)
+		retrievedRole, err := iamCache.CustomRole(statusRoleID)
 		if err != nil {
 			reqLogger.Info("Error retrieving custom role", "Failed", err.Error())
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error retrieving custom role"
 			instance.Status.RoleID = ""   //clear out the role ID since role with this ID can't be retrieved
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing custom role retrieval", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, err
+			return requeue.Result(err)
 		}
 		
-		if (mutableSpecChanged(instance) || roleChanged(instance, retrievedRole)) { // Spec change or a change via the IAM console means the custom role needs an update
-			updatedRole, err := updateCustomRole(instance, customRoleAPI, etag)
+		changed := mutableSpecChanged(instance)
+		var diffs []drift.Difference
+		if !changed {
+			diffs = roleDrift(instance, retrievedRole)
+		}
+		mode := drift.Mode(instance.Spec.DriftPolicy, settings.Current.DriftPolicy)
+		driftReported := drift.Report(instance, mode, diffs)
+
+		if plan.Enabled(instance) {
+			if changed || (len(diffs) > 0 && mode == drift.Enforce) {
+				return r.planCustomRole(instance, plan.Update, roleDrift(instance, retrievedRole), driftReported)
+			}
+			return r.planCustomRole(instance, plan.NoChange, diffs, driftReported)
+		}
+
+		drift.Event(r.recorder, instance, mode, diffs, driftReported)
+
+		if changed || (len(diffs) > 0 && mode == drift.Enforce) { // Spec change or an enforced change via the IAM console means the custom role needs an update
+			updatedRole, err := updateCustomRole(instance, customRoleAPI)
+			iamCache.Invalidate(statusRoleID)
+			roleCatalog.Invalidate()
 			if err != nil {
 				reqLogger.Info("Error updating custom role", instance.Name, err.Error())
 				instance.Status.State = "Failed"
 				instance.Status.Message = "Error updating custom role"
+				requeue.Report(instance, err)
 				if err := r.client.Status().Update(context.Background(), instance); err != nil {
 					reqLogger.Info("Error updating status for failing custom role update", "Failed", err.Error())
 					//TODO ??? delete custom role?
 					return reconcile.Result{}, err
 				}				
-				return reconcile.Result{}, err
+				return requeue.Result(err)
 			}
 			reqLogger.Info("Updated custom role.","Role ID:",statusRoleID)
+			if !changed {
+				metrics.DriftCorrected(customroleKind)
+			}
 
 			instance.Status.State = "Online"
 			instance.Status.Message = "IAM custom role updated"
@@ -291,23 +439,37 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 			instance.Status.DisplayName = instance.Spec.DisplayName
 			instance.Status.Description = instance.Spec.Description
 			instance.Status.Actions = instance.Spec.Actions
+			requeue.Report(instance, nil)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for custom role update", "Failed", err.Error())
 				//TODO ??? delete custom role?
 				return reconcile.Result{}, err
 			}
-		} 
+		} else if requeue.Report(instance, nil) || driftReported || instance.Status.State == "Pending" {
+			if instance.Status.State == "Pending" { // Resumed after a pause
+				instance.Status.State = "Online"
+			}
+			if err := r.client.Status().Update(context.Background(), instance); err != nil {
+				reqLogger.Info("Error updating status for custom role drift", "Failed", err.Error())
+				return reconcile.Result{}, err
+			}
+		}
 	} else { //Role doesn't exist in IAM
+		if plan.Enabled(instance) {
+			return r.planCustomRole(instance, plan.Create, plan.Creation(compile.CustomRole(instance, myAccount.GUID, settings.Current.InstanceID)), false)
+		}
 		createdRole, err := createCustomRole(instance, myAccount, customRoleAPI)
+		roleCatalog.Invalidate()
 		if err != nil {
 			reqLogger.Info("Error creating custom role", instance.Name, err.Error())	
 			instance.Status.State = "Failed"
 			instance.Status.Message = "Error creating custom role"
+			requeue.Report(instance, err)
 			if err := r.client.Status().Update(context.Background(), instance); err != nil {
 				reqLogger.Info("Error updating status for failing custom role creation", "Failed", err.Error())
 				return reconcile.Result{}, err
 			}
-			return reconcile.Result{}, err
+			return requeue.Result(err)
 		}
 		reqLogger.Info("Created custom role.","Role ID:",createdRole.ID)
 
@@ -320,6 +482,7 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 		instance.Status.DisplayName = instance.Spec.DisplayName
 		instance.Status.Description = instance.Spec.Description
 		instance.Status.Actions = instance.Spec.Actions
+		requeue.Report(instance, nil)
 		if err := r.client.Status().Update(context.Background(), instance); err != nil {
 			reqLogger.Info("Error updating status for custom role creation", "Failed", err.Error())
 			errr := deleteCustomRole(createdRole.ID, customRoleAPI)
@@ -332,29 +495,111 @@ func (r *ReconcileCustomRole) Reconcile(request reconcile.Request) (reconcile.Re
 			reqLogger.Info("Deleted custom role.","Role ID:", createdRole.ID)
 			return reconcile.Result{}, err
 		}
+		metrics.Online(customroleKind, instance.ObjectMeta.CreationTimestamp.Time)
 	}	
-	return reconcile.Result{Requeue: true, RequeueAfter: syncPeriod}, nil
+	if ownership.Record(customroleKind, instance, myAccount.GUID, instance.Status.RoleID) { // Annotate the resource with the ID of its custom role, so it can be found without status
+		if err := r.client.Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error recording custom role ID", instance.Name, err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
 }
 
-func roleChanged(instance *ibmcloudv1alpha1.CustomRole, retrievedRole iampapv2.Role) bool {
-	description := "OPERATOR OWNED: "+instance.Spec.Description //Adding Operator owned TAG
+// planCustomRole records the change the reconcile would make to the custom role in IAM instead of making it
+func (r *ReconcileCustomRole) planCustomRole(instance *ibmcloudv1alpha1.CustomRole, action string, diffs []drift.Difference, statusChanged bool) (reconcile.Result, error) {
+	if err := r.recordPlan(instance, action, diffs, statusChanged); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: settings.SyncPeriod(instance)}, nil
+}
+
+// recordPlan sets the planned change in the status of the resource and records it in an event when it changes
+func (r *ReconcileCustomRole) recordPlan(instance *ibmcloudv1alpha1.CustomRole, action string, diffs []drift.Difference, statusChanged bool) error {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+	reqLogger.Info("Planned custom role change", "Action", action, "Differences", drift.Summary(diffs))
+
+	planned := plan.Report(instance, action, "custom role", diffs)
+	if requeue.Report(instance, nil) || planned || statusChanged {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for planned custom role change", "Failed", err.Error())
+			return err
+		}
+	}
+	if planned {
+		if err := plan.Emit(r.client, instance, customroleKind); err != nil {
+			reqLogger.Info("Error recording planned custom role change", "Failed", err.Error())
+		}
+	}
+	return nil
+}
+
+// rediscoverCustomRole returns the custom role recorded for the resource if it still exists in IAM, else an
+// operator owned custom role with the name and service in the spec that no other resource recorded, or nil
+// if there is none
+func (r *ReconcileCustomRole) rediscoverCustomRole(instance *ibmcloudv1alpha1.CustomRole, accountID string, customRoleAPI iampapv2.RoleRepository) (*iampapv2.Role, error) {
+	if recordedID := ownership.RecordedID(customroleKind, instance, accountID); recordedID != "" {
+		role, _, err := customRoleAPI.Get(recordedID)
+		if err == nil {
+			log.Info("Recovered custom role from annotations", "Role ID:", recordedID)
+			return &role, nil
+		}
+		if !iamclient.NotFound(err) { // The custom role may still exist, don't create another one
+			return nil, err
+		}
+	}
+
+	claimed, err := r.claimedRoles(instance, accountID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := customRoleAPI.ListCustomRoles(accountID, instance.Spec.ServiceClass)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Name == instance.Spec.RoleName && !claimed[role.ID] && ownership.Adoptable(role.Description, settings.Current.InstanceID) {
+			log.Info("Found existing custom role", "Role ID:", role.ID)
+			return &role, nil
+		}
+	}
+	return nil, nil
+}
+
+// claimedRoles returns the IDs of the custom roles recorded by the other custom role resources
+func (r *ReconcileCustomRole) claimedRoles(instance *ibmcloudv1alpha1.CustomRole, accountID string) (map[string]bool, error) {
+	list := &ibmcloudv1alpha1.CustomRoleList{}
+	if err := r.client.List(context.Background(), list); err != nil {
+		return nil, err
+	}
+	objs := make([]metav1.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return ownership.Claimed(customroleKind, objs, instance, accountID), nil
+}
+
+// roleDrift returns the differences between the desired custom role and the one in IAM
+func roleDrift(instance *ibmcloudv1alpha1.CustomRole, retrievedRole iampapv2.Role) []drift.Difference {
+	var diffs []drift.Difference
+	description := compile.Description(settings.Current.InstanceID, instance.Spec.Description)
 	if !reflect.DeepEqual(retrievedRole.CreateRoleRequest.DisplayName,instance.Spec.DisplayName) {
 		log.Info("Custom role display name in IAM has changed")
-		return true
+		diffs = append(diffs, drift.Difference{Field: "displayName", Desired: instance.Spec.DisplayName, Actual: retrievedRole.CreateRoleRequest.DisplayName})
 	}
 
 	if !reflect.DeepEqual(retrievedRole.CreateRoleRequest.Description,description) {
 		log.Info("Custom role description in IAM has changed")
-		return true
+		diffs = append(diffs, drift.Difference{Field: "description", Desired: description, Actual: retrievedRole.CreateRoleRequest.Description})
 	}
 
 	if !reflect.DeepEqual(retrievedRole.CreateRoleRequest.Actions,instance.Spec.Actions) {
 		log.Info("Custom role actions in IAM has changed")
-		return true
+		diffs = append(diffs, drift.Difference{Field: "actions", Desired: instance.Spec.Actions, Actual: retrievedRole.CreateRoleRequest.Actions})
 	}
 
 	//Cannot update other fields of a Custom Role spec
-	return false
+	return diffs
 }
 
 func mutableSpecChanged(instance *ibmcloudv1alpha1.CustomRole) bool {
@@ -408,15 +653,7 @@ func immutableSpecChanged(instance *ibmcloudv1alpha1.CustomRole) bool {
 }
 
 func createCustomRole(instance *ibmcloudv1alpha1.CustomRole, myAccount *accountv2.Account, customRoleAPI iampapv2.RoleRepository) (*iampapv2.Role, error) {
-	description := "OPERATOR OWNED: "+instance.Spec.Description //Adding Operator owned TAG
-	roleReq := iampapv2.CreateRoleRequest{
-		Name:        instance.Spec.RoleName,
-		ServiceName: instance.Spec.ServiceClass,
-		AccountID: 	 myAccount.GUID,
-		DisplayName: instance.Spec.DisplayName,
-		Description: description,
-		Actions:     instance.Spec.Actions,
-	}
+	roleReq := compile.CustomRole(instance, myAccount.GUID, settings.Current.InstanceID)
 	
 	listresp, err := customRoleAPI.ListCustomRoles(myAccount.GUID, instance.Spec.ServiceClass)
 	if err != nil {
@@ -437,16 +674,13 @@ func createCustomRole(instance *ibmcloudv1alpha1.CustomRole, myAccount *accountv
 	return &customrole, nil
 }
 
-func updateCustomRole(instance *ibmcloudv1alpha1.CustomRole, customRoleAPI iampapv2.RoleRepository, etag string) (*iampapv2.Role, error) {
+func updateCustomRole(instance *ibmcloudv1alpha1.CustomRole, customRoleAPI iampapv2.RoleRepository) (*iampapv2.Role, error) {
 	customroleID := instance.Status.RoleID
-	description := "OPERATOR OWNED: "+instance.Spec.Description //Adding Operator owned TAG
-	updateReq := iampapv2.UpdateRoleRequest{
-		DisplayName: instance.Spec.DisplayName,
-		Description: description,
-		Actions: instance.Spec.Actions,
+	_, etag, err := customRoleAPI.Get(customroleID) // Cached roles have no etag
+	if err != nil {
+		return nil, err
 	}
-
-	updatedRole, err := customRoleAPI.Update(updateReq, customroleID, etag)
+	updatedRole, err := customRoleAPI.Update(compile.CustomRoleUpdate(instance, settings.Current.InstanceID), customroleID, etag)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +37,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	context "github.com/IBM/ibmcloud-iam-operator/pkg/context"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/control"
 	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
 
 	"github.com/IBM/ibmcloud-iam-operator/pkg/apis"
@@ -55,6 +56,9 @@ var (
 )
 
 func TestCustomRole(t *testing.T) {
+	if err := test.Configure(); err != nil {
+		t.Skip(err)
+	}
 	RegisterFailHandler(Fail)
 	SetDefaultEventuallyPollingInterval(20 * time.Second)
 	SetDefaultEventuallyTimeout(180 * time.Second)
@@ -64,7 +68,7 @@ func TestCustomRole(t *testing.T) {
 
 var _ = BeforeSuite(func() {
 	logf.SetLogger(logf.ZapLoggerTo(GinkgoWriter, true))
-	useExistingCluster := true
+	useExistingCluster := test.UseExistingCluster()
 
 	t = &envtest.Environment{
 		CRDDirectoryPaths:        []string{filepath.Join("..", "..", "..", "deploy", "crds")},
@@ -86,7 +90,9 @@ var _ = BeforeSuite(func() {
 
 	c = mgr.GetClient()
 
-	recFn := newReconciler(mgr)
+	namespaces, err := control.Namespaces(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	recFn := newReconciler(mgr, namespaces)
 	Expect(add(mgr, recFn)).NotTo(HaveOccurred())
 
 	stop = test.StartTestManager(mgr)
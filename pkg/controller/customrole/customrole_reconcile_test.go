@@ -0,0 +1,195 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customrole
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+var request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "deployer"}}
+
+// newTestReconciler returns a reconciler of a CustomRole connected to an in-memory IAM account
+func newTestReconciler(t *testing.T, spec ibmcloudv1alpha1.CustomRoleSpec) (*ReconcileCustomRole, *iamfake.Factory) {
+	cluster := iamfake.NewCluster(&ibmcloudv1alpha1.CustomRole{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		Spec:       spec,
+	})
+	return &ReconcileCustomRole{client: cluster.Client, reader: cluster.Client, scheme: cluster.Scheme, iam: cluster.IAM, recorder: cluster.Recorder}, cluster.IAM
+}
+
+func deployerSpec() ibmcloudv1alpha1.CustomRoleSpec {
+	return ibmcloudv1alpha1.CustomRoleSpec{
+		RoleName:     "Deployer",
+		ServiceClass: "kms",
+		DisplayName:  "Deployer",
+		Description:  "Deploys keys",
+		Actions:      []string{"kms.secrets.read"},
+	}
+}
+
+func getInstance(t *testing.T, r *ReconcileCustomRole) *ibmcloudv1alpha1.CustomRole {
+	instance := &ibmcloudv1alpha1.CustomRole{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, instance))
+	return instance
+}
+
+func TestReconcileCreate(t *testing.T) {
+	r, iam := newTestReconciler(t, deployerSpec())
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	roles := iam.CustomRoles()
+	require.Len(t, roles, 1)
+	assert.Equal(t, "OPERATOR OWNED: Deploys keys", roles[0].Description)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, roles[0].ID, instance.Status.RoleID)
+	assert.True(t, ContainsFinalizer(instance))
+
+	// A role in sync with its spec is left alone
+	iam.ResetCalls()
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.NotContains(t, iam.Calls(), "CustomRoles.Create")
+	assert.NotContains(t, iam.Calls(), "CustomRoles.Update")
+}
+
+func TestReconcileDrift(t *testing.T) {
+	for _, mode := range []string{drift.Enforce, drift.Detect, drift.Ignore} {
+		spec := deployerSpec()
+		spec.DriftPolicy = mode
+		r, iam := newTestReconciler(t, spec)
+		_, err := r.Reconcile(request)
+		require.NoError(t, err)
+		roleID := getInstance(t, r).Status.RoleID
+
+		// Change the role as with the IAM console
+		require.NoError(t, iam.SetCustomRole(roleID, iampapv2.UpdateRoleRequest{
+			DisplayName: "Changed",
+			Description: "OPERATOR OWNED: Deploys keys",
+			Actions:     spec.Actions,
+		}))
+		_, err = r.Reconcile(request)
+		require.NoError(t, err)
+
+		instance := getInstance(t, r)
+		condition := resv1.GetCondition(instance, drift.ConditionType)
+		require.NotNil(t, condition, mode)
+		events := r.recorder.(*record.FakeRecorder).Events
+		switch mode {
+		case drift.Enforce:
+			assert.Equal(t, "Deployer", iam.CustomRoles()[0].DisplayName)
+			assert.Equal(t, corev1.ConditionFalse, condition.Status)
+			assert.Contains(t, <-events, "Warning DriftReverted")
+		case drift.Detect:
+			assert.Equal(t, "Changed", iam.CustomRoles()[0].DisplayName)
+			assert.Equal(t, corev1.ConditionTrue, condition.Status)
+			assert.Contains(t, <-events, "Warning DriftDetected")
+		default:
+			assert.Equal(t, "Changed", iam.CustomRoles()[0].DisplayName)
+			assert.Equal(t, corev1.ConditionFalse, condition.Status)
+			assert.Equal(t, []resv1.AcceptedValue{{Field: "displayName", Value: `"Changed"`}}, instance.Status.AcceptedDrift)
+			assert.Empty(t, events)
+		}
+	}
+}
+
+func TestReconcileIAMError(t *testing.T) {
+	r, iam := newTestReconciler(t, deployerSpec())
+	iam.Fail("CustomRoles.Create", bmxerror.NewRequestFailure("forbidden", "Not authorized to create roles", 403))
+
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Failed", instance.Status.State)
+	assert.Equal(t, "Error creating custom role", instance.Status.Message)
+	assert.Empty(t, iam.CustomRoles())
+
+	// The role is created once IAM recovers
+	iam.Fail("CustomRoles.Create", nil)
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, "Online", getInstance(t, r).Status.State)
+	assert.Len(t, iam.CustomRoles(), 1)
+}
+
+func TestReconcileMissingCredentials(t *testing.T) {
+	r, iam := newTestReconciler(t, deployerSpec())
+	iam.Fail("Connect", bmxerror.New("ErrorMissingAPIKey", "No API key"))
+
+	_, err := r.Reconcile(request)
+	assert.Error(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Failed", instance.Status.State)
+	assert.Equal(t, "Error getting IBM Cloud IAM account information", instance.Status.Message)
+}
+
+func TestReconcileDelete(t *testing.T) {
+	r, iam := newTestReconciler(t, deployerSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	require.Len(t, iam.CustomRoles(), 1)
+
+	instance := getInstance(t, r)
+	now := metav1.Now()
+	instance.ObjectMeta.DeletionTimestamp = &now
+	require.NoError(t, r.client.Update(gocontext.Background(), instance))
+	_, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Empty(t, iam.CustomRoles())
+	assert.False(t, ContainsFinalizer(getInstance(t, r)))
+}
+
+func TestReconcileClaimedRole(t *testing.T) {
+	r, iam := newTestReconciler(t, deployerSpec())
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+
+	// A copy of the resource in another namespace, restored without status, has the same role name
+	require.NoError(t, r.client.Create(gocontext.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "restored"}}))
+	copied := getInstance(t, r).DeepCopy()
+	copied.ObjectMeta = metav1.ObjectMeta{Namespace: "restored", Name: request.Name, Annotations: copied.Annotations}
+	copied.Spec.Actions = []string{"kms.secrets.write"}
+	copied.Status = ibmcloudv1alpha1.CustomRoleStatus{}
+	require.NoError(t, r.client.Create(gocontext.Background(), copied))
+	copyRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: copied.Namespace, Name: copied.Name}}
+
+	// The role recorded by the first resource isn't adopted, and IAM rejects a second role with its name
+	_, err = r.Reconcile(copyRequest)
+	assert.Error(t, err)
+	require.NoError(t, r.client.Get(gocontext.Background(), copyRequest.NamespacedName, copied))
+	assert.Empty(t, copied.Status.RoleID)
+	assert.Equal(t, "Failed", copied.Status.State)
+	roles := iam.CustomRoles()
+	require.Len(t, roles, 1)
+	assert.Equal(t, []string{"kms.secrets.read"}, roles[0].Actions)
+}
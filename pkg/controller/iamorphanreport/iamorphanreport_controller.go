@@ -0,0 +1,483 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iamorphanreport
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/plan"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_iamorphanreport")
+
+const defaultInterval = time.Hour
+
+// minInterval is the shortest interval between two sweeps, each of which lists every IAM object of the account
+const minInterval = time.Minute
+const defaultGracePeriod = time.Hour * 24
+
+const (
+	accessGroupKind = "AccessGroup"
+	customRoleKind  = "CustomRole"
+	policyKind      = "Policy"
+)
+
+// Add creates a new IAMOrphanReport Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileIAMOrphanReport{client: mgr.GetClient(), scheme: mgr.GetScheme(), iam: iamclient.New()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("iamorphanreport-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: settings.MaxConcurrentReconciles("IAMOrphanReport")})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource IAMOrphanReport, but not to its status: writing the report
+	// doesn't start another sweep, the next one is requeued after the interval
+	err = c.Watch(&source.Kind{Type: &ibmcloudv1alpha1.IAMOrphanReport{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileIAMOrphanReport implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileIAMOrphanReport{}
+
+// ReconcileIAMOrphanReport reconciles a IAMOrphanReport object
+type ReconcileIAMOrphanReport struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+	// iam connects to the IAM account of the namespace of an object
+	iam iamclient.Factory
+}
+
+// Reconcile sweeps the account of the IAMOrphanReport namespace for operator owned IAM objects
+// that no custom resource of the cluster manages, and reports them in the IAMOrphanReport status
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *ReconcileIAMOrphanReport) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile("IAMOrphanReport", request.NamespacedName)
+	result, err := r.reconcile(ctx, request)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile reconciles a IAMOrphanReport within the span of the reconcile
+func (r *ReconcileIAMOrphanReport) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling IAM Orphan Report")
+
+	// Fetch the IAMOrphanReport instance
+	instance := &ibmcloudv1alpha1.IAMOrphanReport{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			reqLogger.Info("IAM Orphan Report resource not found. Ignoring since object must be deleted")
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		reqLogger.Error(err, "Failed to get IAM Orphan Report")
+		return reconcile.Result{}, err
+	}
+
+	// Set the Status field for the first time
+	if reflect.DeepEqual(instance.Status, ibmcloudv1alpha1.IAMOrphanReportStatus{}) {
+		instance.Status.State = "Pending"
+		instance.Status.Message = "Processing Resource"
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating initial status", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
+	interval := sweepInterval(instance)
+	now := time.Now()
+	if instance.Status.LastSweep != nil && now.Before(instance.Status.LastSweep.Add(interval)) { // Not time for a sweep yet
+		return reconcile.Result{Requeue: true, RequeueAfter: instance.Status.LastSweep.Add(interval).Sub(now)}, nil
+	}
+
+	_, credentials := tracing.Start(ctx, "LoadCredentials")
+	myAccount, iamClients, err := r.iam.Connect(ctx, r.client, instance.ObjectMeta.Namespace)
+	tracing.End(credentials, err)
+	if err != nil {
+		reqLogger.Info("Error getting IBM Cloud IAM account information", instance.Name, err.Error())
+		instance.Status.State = "Failed"
+		instance.Status.Message = "Error getting IBM Cloud IAM account information"
+		requeue.Report(instance, err)
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for failing IAM account setup", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		return requeue.Result(err)
+	}
+
+	// Wait for IAM to recover rather than sending it more requests
+	unavailable := resilience.Unavailable(myAccount.GUID)
+	if resilience.Report(instance, unavailable) {
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for IAM availability", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+	if unavailable > 0 {
+		reqLogger.Info("IAM is unavailable, waiting for it to recover", "Retry after", unavailable.String())
+		return reconcile.Result{Requeue: true, RequeueAfter: unavailable}, nil
+	}
+
+	objects, err := newIAMObjects(myAccount.GUID, iamClients)
+	if err != nil {
+		reqLogger.Info("Error creating IAM clients", instance.Name, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	managed, err := r.managedIDs()
+	if err != nil {
+		reqLogger.Info("Error listing custom resources", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+
+	owned, err := objects.owned(settings.Current.InstanceID)
+	if err != nil {
+		reqLogger.Info("Error listing operator owned IAM objects", "Failed", err.Error())
+		instance.Status.State = "Failed"
+		instance.Status.Message = "Error listing operator owned IAM objects"
+		requeue.Report(instance, err)
+		if err := r.client.Status().Update(context.Background(), instance); err != nil {
+			reqLogger.Info("Error updating status for failing IAM objects listing", "Failed", err.Error())
+			return reconcile.Result{}, err
+		}
+		return requeue.Result(err)
+	}
+
+	orphans := findOrphans(owned, managed, instance.Status.Orphans, metav1.NewTime(now))
+	found := len(orphans)
+
+	var deleted []string
+	state := "Online"
+	message := fmt.Sprintf("%d orphaned IAM objects found", found)
+	if instance.Spec.DeleteOrphans {
+		if refusal := sweepRefusal(); refusal != "" {
+			message += ", none deleted: " + refusal
+		} else {
+			gracePeriod := defaultGracePeriod
+			if instance.Spec.GracePeriod != nil {
+				gracePeriod = instance.Spec.GracePeriod.Duration
+			}
+			if plan.Enabled(instance) {
+				var planned []string
+				orphans, planned = objects.deleteOrphans(orphans, now, gracePeriod, true)
+				message += fmt.Sprintf(", dry run: %d would be deleted", len(planned))
+				state = plan.PlannedState
+			} else {
+				orphans, deleted = objects.deleteOrphans(orphans, now, gracePeriod, false)
+				message += fmt.Sprintf(", %d deleted", len(deleted))
+			}
+		}
+	}
+
+	sweep := metav1.NewTime(now)
+	instance.Status.State = state
+	instance.Status.Message = message
+	instance.Status.LastSweep = &sweep
+	instance.Status.Orphans = orphans
+	instance.Status.Deleted = deleted
+	requeue.Report(instance, nil)
+	if err := r.client.Status().Update(context.Background(), instance); err != nil {
+		reqLogger.Info("Error updating status for IAM orphan sweep", "Failed", err.Error())
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: interval}, nil
+}
+
+// sweepInterval returns the interval between two sweeps of a report, which is at least minInterval
+func sweepInterval(instance *ibmcloudv1alpha1.IAMOrphanReport) time.Duration {
+	if instance.Spec.Interval == nil {
+		return defaultInterval
+	}
+	if instance.Spec.Interval.Duration < minInterval {
+		return minInterval
+	}
+	return instance.Spec.Interval.Duration
+}
+
+// sweepRefusal returns why orphaned IAM objects can't be deleted safely, "" if they can
+func sweepRefusal() string {
+	if settings.Current.InstanceID == "" {
+		return "set the instanceID of the operator, so that it only deletes the IAM objects it marked"
+	}
+	if len(settings.Current.Namespaces) > 0 {
+		return "the operator only watches some namespaces, the custom resources of the others may manage the orphans"
+	}
+	return ""
+}
+
+// managedIDs returns the IDs of the IAM objects managed by custom resources in all namespaces,
+// from their status or, when status was lost, from their annotations
+func (r *ReconcileIAMOrphanReport) managedIDs() (map[string]bool, error) {
+	managed := map[string]bool{}
+
+	groups := &ibmcloudv1alpha1.AccessGroupList{}
+	if err := r.client.List(context.Background(), groups); err != nil {
+		return nil, err
+	}
+	for _, group := range groups.Items {
+		managed[group.Status.GroupID] = true
+		managed[group.ObjectMeta.Annotations[ownership.IAMIDAnnotation]] = true
+	}
+
+	roles := &ibmcloudv1alpha1.CustomRoleList{}
+	if err := r.client.List(context.Background(), roles); err != nil {
+		return nil, err
+	}
+	for _, role := range roles.Items {
+		managed[role.Status.RoleID] = true
+		managed[role.ObjectMeta.Annotations[ownership.IAMIDAnnotation]] = true
+	}
+
+	accessPolicies := &ibmcloudv1alpha1.AccessPolicyList{}
+	if err := r.client.List(context.Background(), accessPolicies); err != nil {
+		return nil, err
+	}
+	for _, policy := range accessPolicies.Items {
+		managed[policy.Status.PolicyID] = true
+		managed[policy.ObjectMeta.Annotations[ownership.IAMIDAnnotation]] = true
+	}
+
+	authorizationPolicies := &ibmcloudv1alpha1.AuthorizationPolicyList{}
+	if err := r.client.List(context.Background(), authorizationPolicies); err != nil {
+		return nil, err
+	}
+	for _, policy := range authorizationPolicies.Items {
+		managed[policy.Status.PolicyID] = true
+		managed[policy.ObjectMeta.Annotations[ownership.IAMIDAnnotation]] = true
+	}
+
+	delete(managed, "")
+	return managed, nil
+}
+
+// iamObjects lists and deletes the IAM objects of an account the sweeper looks at
+type iamObjects struct {
+	accountID string
+	groups    iamuumv2.AccessGroupRepository
+	members   iamuumv2.AccessGroupMemberRepositoryV2
+	roles     iampapv2.RoleRepository
+	policies  polv2.PolicyRepository
+}
+
+func newIAMObjects(accountID string, iamClients iamclient.Clients) (*iamObjects, error) {
+	objects := &iamObjects{accountID: accountID}
+	var err error
+	if objects.groups, err = iamClients.AccessGroups(); err != nil {
+		return nil, err
+	}
+	if objects.members, err = iamClients.AccessGroupMembers(); err != nil {
+		return nil, err
+	}
+	if objects.roles, err = iamClients.CustomRoles(); err != nil {
+		return nil, err
+	}
+	if objects.policies, err = iamClients.PoliciesV2(); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// owned lists the access groups, custom roles and policies of the account whose description marks them as owned
+// by the operator instance. Without instance ID, the objects of any instance are listed, and none is deleted.
+func (o *iamObjects) owned(instanceID string) ([]ibmcloudv1alpha1.Orphan, error) {
+	var owned []ibmcloudv1alpha1.Orphan
+	isOwned := func(description string) bool {
+		return ownership.Owned(description) && (instanceID == "" || ownership.Owner(description) == instanceID)
+	}
+
+	groups, err := o.groups.List(o.accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if isOwned(group.AccessGroup.Description) {
+			owned = append(owned, ibmcloudv1alpha1.Orphan{Kind: accessGroupKind, ID: group.AccessGroup.ID, Name: group.AccessGroup.Name})
+		}
+	}
+
+	roles, err := o.roles.ListCustomRoles(o.accountID, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if isOwned(role.Description) {
+			owned = append(owned, ibmcloudv1alpha1.Orphan{Kind: customRoleKind, ID: role.ID, Name: role.Name})
+		}
+	}
+
+	for _, policyType := range []string{iampapv1.AccessPolicyType, iampapv1.AuthorizationPolicyType} {
+		policies, err := o.policies.List(polv2.SearchParams{AccountID: o.accountID, Type: policyType})
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			if isOwned(policy.Description) {
+				owned = append(owned, ibmcloudv1alpha1.Orphan{Kind: policyKind, ID: policy.ID, Name: ownership.TrimMarker(policy.Description)})
+			}
+		}
+	}
+	return owned, nil
+}
+
+// deleteOrphans deletes the orphans whose grace period has passed, policies first so that the access groups
+// they grant access to can be deleted, and returns the remaining orphans and the IDs of the deleted ones.
+// In dry run, it deletes nothing and returns the IDs of the orphans it would delete, kept in the remaining ones.
+func (o *iamObjects) deleteOrphans(orphans []ibmcloudv1alpha1.Orphan, now time.Time, gracePeriod time.Duration, dryRun bool) ([]ibmcloudv1alpha1.Orphan, []string) {
+	sort.SliceStable(orphans, func(i, j int) bool { return orphans[i].Kind == policyKind && orphans[j].Kind != policyKind })
+
+	var remaining []ibmcloudv1alpha1.Orphan
+	var deleted []string
+	gone := map[string]bool{}
+	for _, orphan := range orphans {
+		orphan.Reason = ""
+		if now.Before(orphan.FirstSeen.Add(gracePeriod)) {
+			remaining = append(remaining, orphan)
+			continue
+		}
+		if err := o.deleteOrphan(orphan, gone, dryRun); err != nil {
+			log.Info("Error deleting orphaned IAM object", orphan.ID, err.Error())
+			orphan.Reason = err.Error()
+			remaining = append(remaining, orphan)
+			continue
+		}
+		gone[orphan.ID] = true
+		deleted = append(deleted, orphan.ID)
+		if dryRun {
+			log.Info("Dry run: would delete orphaned IAM object.", "Kind:", orphan.Kind, "ID:", orphan.ID, "Name:", orphan.Name)
+			orphan.Reason = "Dry run: would be deleted"
+			remaining = append(remaining, orphan)
+			continue
+		}
+		log.Info("Deleted orphaned IAM object.", "Kind:", orphan.Kind, "ID:", orphan.ID, "Name:", orphan.Name)
+	}
+	return remaining, deleted
+}
+
+// deleteOrphan deletes an orphaned IAM object, or only checks that it can be in dry run. An access group is only
+// deleted once it has neither members nor policies, besides the gone ones, since IAM would delete them with the
+// group, whoever manages them.
+func (o *iamObjects) deleteOrphan(orphan ibmcloudv1alpha1.Orphan, gone map[string]bool, dryRun bool) error {
+	switch orphan.Kind {
+	case accessGroupKind:
+		members, err := o.members.List(orphan.ID)
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			return fmt.Errorf("access group still has %d members", len(members))
+		}
+		policies, err := o.policies.List(polv2.SearchParams{AccountID: o.accountID, AccessGroupID: orphan.ID, Type: iampapv1.AccessPolicyType})
+		if err != nil {
+			return err
+		}
+		remaining := 0
+		for _, policy := range policies {
+			if !gone[policy.ID] {
+				remaining++
+			}
+		}
+		if remaining > 0 {
+			return fmt.Errorf("access group is still the subject of %d policies", remaining)
+		}
+		if dryRun {
+			return nil
+		}
+		return o.groups.Delete(orphan.ID, false)
+	case customRoleKind:
+		if dryRun {
+			return nil
+		}
+		return o.roles.Delete(orphan.ID)
+	case policyKind:
+		if dryRun {
+			return nil
+		}
+		return o.policies.Delete(orphan.ID)
+	}
+	return fmt.Errorf("Unknown kind of IAM object %s", orphan.Kind)
+}
+
+// findOrphans returns the owned IAM objects that are not managed, keeping when they were first seen by earlier sweeps
+func findOrphans(owned []ibmcloudv1alpha1.Orphan, managed map[string]bool, previous []ibmcloudv1alpha1.Orphan, now metav1.Time) []ibmcloudv1alpha1.Orphan {
+	firstSeen := map[string]metav1.Time{}
+	for _, orphan := range previous {
+		firstSeen[orphan.ID] = orphan.FirstSeen
+	}
+
+	var orphans []ibmcloudv1alpha1.Orphan
+	for _, object := range owned {
+		if managed[object.ID] {
+			continue
+		}
+		object.FirstSeen = now
+		if seen, ok := firstSeen[object.ID]; ok {
+			object.FirstSeen = seen
+		}
+		orphans = append(orphans, object)
+	}
+	return orphans
+}
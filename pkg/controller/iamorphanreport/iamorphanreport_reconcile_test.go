@@ -0,0 +1,189 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iamorphanreport
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+)
+
+var request = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "orphanreport"}}
+
+// newTestReconciler returns a reconciler of an IAMOrphanReport deleting orphans right away, connected to an
+// in-memory IAM account holding the IAM objects of the operator instance "cluster-a" and of another instance
+func newTestReconciler(t *testing.T, objs ...runtime.Object) (*ReconcileIAMOrphanReport, *iamfake.Factory) {
+	cluster := iamfake.NewCluster(&ibmcloudv1alpha1.IAMOrphanReport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+		Spec:       ibmcloudv1alpha1.IAMOrphanReportSpec{DeleteOrphans: true, GracePeriod: &metav1.Duration{}},
+	}, objs...)
+
+	iam := cluster.IAM
+	alice := iam.AddUser("alice@example.com")
+	iam.AddAccessGroup("Interns", ownership.Marker("cluster-a")+"No resource manages them anymore")
+	staff := iam.AddAccessGroup("Staff", ownership.Marker("cluster-a")+"Still has members")
+	require.NoError(t, iam.AddMember(staff.ID, models.AccessGroupMemberV2{ID: alice.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser}))
+	iam.AddAccessGroup("Testers", ownership.Marker("cluster-b")+"Managed by another cluster")
+	iam.AddAccessGroup("Support", "Not owned by the operator")
+	iam.AddCustomRole(iampapv2.CreateRoleRequest{
+		Name:        "Janitor",
+		ServiceName: "cloud-object-storage",
+		DisplayName: "Janitor",
+		Description: ownership.Marker("cluster-a") + "Cleans up buckets",
+		Actions:     []string{"cloud-object-storage.bucket.delete_bucket"},
+	})
+	policy := polv2.Policy{
+		Type:        "access",
+		Description: ownership.Marker("cluster-a") + "AccessPolicy default/interns",
+		Subject:     polv2.Subject{Attributes: []polv2.Attribute{{Key: "iam_id", Operator: "stringEquals", Value: alice.IbmUniqueId}}},
+	}
+	policy.Control.Grant.Roles = []polv2.Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Viewer"}}
+	policy.Resource.Attributes = []polv2.Attribute{{Key: "accountId", Operator: "stringEquals", Value: iamfake.AccountID}}
+	iam.AddPolicy(policy)
+	return &ReconcileIAMOrphanReport{client: cluster.Client, scheme: cluster.Scheme, iam: iam}, iam
+}
+
+func getInstance(t *testing.T, r *ReconcileIAMOrphanReport) *ibmcloudv1alpha1.IAMOrphanReport {
+	instance := &ibmcloudv1alpha1.IAMOrphanReport{}
+	require.NoError(t, r.client.Get(gocontext.Background(), request.NamespacedName, instance))
+	return instance
+}
+
+func groupNames(iam *iamfake.Factory) []string {
+	var names []string
+	for _, group := range iam.AccessGroups() {
+		names = append(names, group.Name)
+	}
+	return names
+}
+
+func TestReconcileSweep(t *testing.T) {
+	defer func() { settings.Current = settings.Defaults }()
+	settings.Current.InstanceID = "cluster-a"
+	r, iam := newTestReconciler(t)
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Online", instance.Status.State)
+	assert.Equal(t, "4 orphaned IAM objects found, 3 deleted", instance.Status.Message)
+	assert.Empty(t, iam.Policies())
+	assert.Empty(t, iam.CustomRoles())
+	assert.ElementsMatch(t, []string{"Staff", "Testers", "Support"}, groupNames(iam))
+	require.Len(t, instance.Status.Orphans, 1)
+	assert.Equal(t, "Staff", instance.Status.Orphans[0].Name)
+	assert.Equal(t, "access group still has 1 members", instance.Status.Orphans[0].Reason)
+}
+
+func TestReconcileMinimumInterval(t *testing.T) {
+	defer func() { settings.Current = settings.Defaults }()
+	settings.Current.InstanceID = "cluster-a"
+	r, iam := newTestReconciler(t)
+	instance := getInstance(t, r)
+	instance.Spec.Interval = &metav1.Duration{}
+	require.NoError(t, r.client.Update(gocontext.Background(), instance))
+
+	result, err := r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Equal(t, minInterval, result.RequeueAfter)
+
+	// The report written by the sweep doesn't start another one right away
+	iam.ResetCalls()
+	result, err = r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Empty(t, iam.Calls())
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= minInterval)
+}
+
+func TestReconcileManaged(t *testing.T) {
+	defer func() { settings.Current = settings.Defaults }()
+	settings.Current.InstanceID = "cluster-a"
+	r, iam := newTestReconciler(t, &ibmcloudv1alpha1.AccessGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: "interns"},
+	})
+	group := iam.AccessGroups()[0]
+	require.Equal(t, "Interns", group.Name)
+	managed := &ibmcloudv1alpha1.AccessGroup{}
+	require.NoError(t, r.client.Get(gocontext.Background(), types.NamespacedName{Namespace: request.Namespace, Name: "interns"}, managed))
+	managed.Status.GroupID = group.ID
+	require.NoError(t, r.client.Status().Update(gocontext.Background(), managed))
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	assert.Contains(t, groupNames(iam), "Interns")
+	assert.Equal(t, "3 orphaned IAM objects found, 2 deleted", getInstance(t, r).Status.Message)
+}
+
+func TestReconcileRefuseSweep(t *testing.T) {
+	for _, instanceID := range []string{"", "cluster-a"} {
+		settings.Current.InstanceID = instanceID
+		if instanceID != "" {
+			settings.Current.Namespaces = []string{"default"}
+		}
+		r, iam := newTestReconciler(t)
+
+		_, err := r.Reconcile(request)
+		settings.Current = settings.Defaults
+		require.NoError(t, err)
+		instance := getInstance(t, r)
+		assert.Contains(t, instance.Status.Message, "orphaned IAM objects found, none deleted: ", instanceID)
+		assert.Len(t, iam.Policies(), 1, instanceID)
+		assert.Len(t, iam.CustomRoles(), 1, instanceID)
+		assert.Len(t, iam.AccessGroups(), 4, instanceID)
+	}
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	defer func() { settings.Current = settings.Defaults }()
+	settings.Current.InstanceID = "cluster-a"
+	settings.Current.DryRun = true
+	r, iam := newTestReconciler(t)
+
+	_, err := r.Reconcile(request)
+	require.NoError(t, err)
+	instance := getInstance(t, r)
+	assert.Equal(t, "Planned", instance.Status.State)
+	assert.Equal(t, "4 orphaned IAM objects found, dry run: 3 would be deleted", instance.Status.Message)
+	assert.Empty(t, instance.Status.Deleted)
+	assert.Len(t, iam.Policies(), 1)
+	assert.Len(t, iam.CustomRoles(), 1)
+	assert.Len(t, iam.AccessGroups(), 4)
+	reasons := map[string]string{}
+	for _, orphan := range instance.Status.Orphans {
+		reasons[orphan.Name] = orphan.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"AccessPolicy default/interns": "Dry run: would be deleted",
+		"Janitor":                      "Dry run: would be deleted",
+		"Interns":                      "Dry run: would be deleted",
+		"Staff":                        "access group still has 1 members",
+	}, reasons)
+}
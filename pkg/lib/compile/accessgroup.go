@@ -0,0 +1,43 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compile
+
+import (
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+)
+
+// AccessGroup compiles an access group into the request creating it in the account
+func AccessGroup(instance *ibmcloudv1alpha1.AccessGroup, accountID string, instanceID string) models.AccessGroupV2 {
+	return models.AccessGroupV2{
+		AccessGroup: models.AccessGroup{
+			Name:        instance.Spec.Name,
+			Description: Description(instanceID, instance.Spec.Description),
+		},
+		AccountID: accountID,
+	}
+}
+
+// AccessGroupUpdate compiles an access group into the request updating it
+func AccessGroupUpdate(instance *ibmcloudv1alpha1.AccessGroup, instanceID string) iamuumv2.AccessGroupUpdateRequest {
+	return iamuumv2.AccessGroupUpdateRequest{
+		Name:        instance.Spec.Name,
+		Description: Description(instanceID, instance.Spec.Description),
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// dateTimeLayout is the format of date-time values in IAM policy rules
+const dateTimeLayout = "2006-01-02T15:04:05-07:00"
+
+// weekdays maps days of the week to their number in IAM policy rules
+var weekdays = map[string]int{
+	"monday":    1,
+	"tuesday":   2,
+	"wednesday": 3,
+	"thursday":  4,
+	"friday":    5,
+	"saturday":  6,
+	"sunday":    7,
+}
+
+// Weekday returns the number of a day of the week in IAM policy rules
+func Weekday(day string) (int, bool) {
+	number, ok := weekdays[strings.ToLower(day)]
+	return number, ok
+}
+
+// AccessPolicy compiles an access policy into the v1 policy granting it in the account
+func AccessPolicy(instance *ibmcloudv1alpha1.AccessPolicy, accountID string, instanceID string, resolver Resolver) (polv1.Policy, error) {
+	roles, err := AccessPolicyRoles(instance, resolver)
+	if err != nil {
+		return polv1.Policy{}, err
+	}
+	subjects, err := AccessPolicySubjects(instance, resolver)
+	if err != nil {
+		return polv1.Policy{}, err
+	}
+	return NewAccessPolicy(instance, accountID, instanceID, roles, subjects), nil
+}
+
+// NewAccessPolicy returns the v1 policy granting the resolved roles of an access
+// policy to its resolved subjects
+func NewAccessPolicy(instance *ibmcloudv1alpha1.AccessPolicy, accountID string, instanceID string, roles []iampapv1.Role, subjects []polv1.Subject) polv1.Policy {
+	policy := polv1.Policy{Roles: roles, Resources: []polv1.Resource{AccessPolicyResource(instance)}}
+	policy.Resources[0].SetAccountID(accountID)
+	policy.Type = iampapv1.AccessPolicyType
+	policy.Description = PolicyDescription(instanceID, "AccessPolicy", instance)
+	policy.Subjects = subjects
+	return policy
+}
+
+// AccessPolicySubjects resolves the subject of an access policy
+func AccessPolicySubjects(instance *ibmcloudv1alpha1.AccessPolicy, resolver Resolver) ([]polv1.Subject, error) {
+	subject := instance.Spec.Subject
+	if subject.UserEmail != "" {
+		iamID, err := resolver.User(subject.UserEmail)
+		if err != nil {
+			return nil, err
+		}
+		return subjectAttribute("iam_id", iamID), nil
+	} else if subject.ServiceID != "" {
+		iamID, err := resolver.ServiceID(subject.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+		return subjectAttribute("iam_id", iamID), nil
+	} else if subject.AccessGroupID != "" {
+		groupID, err := resolver.AccessGroup(subject.AccessGroupID)
+		if err != nil {
+			return nil, err
+		}
+		return subjectAttribute("access_group_id", groupID), nil
+	}
+
+	namespace := instance.ObjectMeta.Namespace
+	if subject.AccessGroupDef.AccessGroupNamespace != "" {
+		namespace = subject.AccessGroupDef.AccessGroupNamespace
+	}
+	accessgroup, err := resolver.AccessGroupResource(namespace, subject.AccessGroupDef.AccessGroupName)
+	if err != nil {
+		if kerror.IsNotFound(err) { // Reconciled again when the access group is created
+			return nil, &DependencyError{Err: err}
+		}
+		return nil, err
+	}
+	if accessgroup.Status.GroupID == "" { // Reconciled again when the access group is created in IAM
+		return nil, &DependencyError{Err: fmt.Errorf("Access group %s is not ready", accessgroup.Name)}
+	}
+	return subjectAttribute("access_group_id", accessgroup.Status.GroupID), nil
+}
+
+func subjectAttribute(name string, value string) []polv1.Subject {
+	return []polv1.Subject{
+		{
+			Attributes: []polv1.Attribute{
+				{
+					Name:  name,
+					Value: value,
+				},
+			},
+		},
+	}
+}
+
+// AccessPolicyRoles resolves the defined, custom and operator managed custom
+// roles of an access policy
+func AccessPolicyRoles(instance *ibmcloudv1alpha1.AccessPolicy, resolver Resolver) ([]iampapv1.Role, error) {
+	var policyRoles []iampapv1.Role
+
+	if instance.Spec.Roles.DefinedRoles != nil {
+		// System defined roles if there is no service class
+		roles, err := resolver.ResolveServiceRoles(instance.Spec.Target.ServiceClass, instance.Spec.Roles.DefinedRoles)
+		if err != nil {
+			return nil, err
+		}
+		policyRoles = rolecatalog.PolicyRoles(roles)
+	}
+
+	if instance.Spec.Roles.CustomRolesDName != nil {
+		roles, err := resolver.ResolveCustomRoles("", instance.Spec.Roles.CustomRolesDName)
+		if err != nil {
+			return nil, err
+		}
+		policyRoles = append(policyRoles, rolecatalog.PolicyRoles(roles)...)
+	}
+
+	for _, element := range instance.Spec.Roles.CustomRolesDef {
+		namespace := instance.ObjectMeta.Namespace
+		if element.CustomRoleNamespace != "" {
+			namespace = element.CustomRoleNamespace
+		}
+		customRole, err := resolver.CustomRoleResource(namespace, element.CustomRoleName)
+		if err != nil {
+			if kerror.IsNotFound(err) { // Reconciled again when the custom role is created
+				return nil, &DependencyError{Err: err}
+			}
+			return nil, err
+		}
+		if customRole.Status.RoleID == "" { // Reconciled again when the custom role is created in IAM
+			return nil, &DependencyError{Err: fmt.Errorf("Custom role %s is not ready", customRole.Name)}
+		}
+
+		roles, err := resolver.ResolveCustomRoles(customRole.Spec.ServiceClass, []string{customRole.Spec.DisplayName})
+		if err != nil {
+			return nil, err
+		}
+		policyRoles = append(policyRoles, rolecatalog.PolicyRoles(roles)...)
+	}
+	return policyRoles, nil
+}
+
+// AccessPolicyResource returns the resource an access policy grants access to
+func AccessPolicyResource(instance *ibmcloudv1alpha1.AccessPolicy) polv1.Resource {
+	target := instance.Spec.Target
+	policyResource := targetResource(target)
+	for _, attribute := range target.Attributes {
+		policyResource.AddAttribute(attribute.Name, attribute.Value, attribute.Operator)
+	}
+	for _, tag := range target.Tags {
+		policyResource.AddTag(tag.Key, tag.Value, tag.Operator)
+	}
+	return policyResource
+}
+
+// TargetWellFormed returns whether the custom attributes and tags of the target of an access
+// policy are well formed, and don't override the attributes set from its other fields
+func TargetWellFormed(target ibmcloudv1alpha1.Target) bool {
+	if !polv1.AttributesWellFormed(targetResource(target).Attributes, customAttributes(target.Attributes)) {
+		return false
+	}
+	for _, tag := range target.Tags {
+		if tag.Key == "" || tag.Value == "" || !polv1.OperatorWellFormed(tag.Operator) {
+			return false
+		}
+	}
+	return true
+}
+
+// targetResource returns the resource set from the fields of the target of an access policy,
+// without its custom attributes and tags
+func targetResource(target ibmcloudv1alpha1.Target) polv1.Resource {
+	policyResource := polv1.Resource{}
+
+	if target.ServiceClass != "" {
+		policyResource.SetAttribute("serviceName", target.ServiceClass)
+	}
+	if target.ServiceID != "" {
+		policyResource.SetAttribute("serviceInstance", target.ServiceID)
+	}
+	if target.ResourceName != "" {
+		policyResource.SetAttribute("resourceType", target.ResourceName)
+	}
+	if target.ResourceID != "" {
+		policyResource.SetAttribute("resource", target.ResourceID)
+	}
+	if target.ResourceGroup != "" {
+		policyResource.SetResourceGroupID(target.ResourceGroup)
+	}
+	if target.Region != "" {
+		policyResource.SetAttribute("region", target.Region)
+	}
+	if target.ResourceKey != "" && target.ResourceValue != "" {
+		policyResource.SetAttribute(target.ResourceKey, target.ResourceValue)
+	}
+	return policyResource
+}
+
+func customAttributes(attributes []ibmcloudv1alpha1.Attribute) []polv1.Attribute {
+	var result []polv1.Attribute
+	for _, a := range attributes {
+		result = append(result, polv1.Attribute{Name: a.Name, Value: a.Value, Operator: a.Operator})
+	}
+	return result
+}
+
+// AccessPolicyRule translates the conditions of an access policy into a v2
+// policy rule and pattern
+func AccessPolicyRule(instance *ibmcloudv1alpha1.AccessPolicy) (*polv2.Rule, string) {
+	conditions := instance.Spec.Conditions
+	if conditions == nil {
+		return nil, ""
+	}
+
+	if conditions.Weekly != nil {
+		offset := conditions.Weekly.TimeZoneOffset
+		if offset == "" {
+			offset = "+00:00"
+		}
+		var days []string
+		for _, day := range conditions.Weekly.Days {
+			number, _ := Weekday(day)
+			days = append(days, fmt.Sprintf("%d%s", number, offset))
+		}
+		rule := &polv2.Rule{
+			Operator: polv2.OperatorAnd,
+			Conditions: []polv2.Rule{
+				{Key: polv2.DayOfWeekKey, Operator: polv2.OperatorDayOfWeekAnyOf, Value: days},
+			},
+		}
+		if conditions.Weekly.StartTime == "" {
+			return rule, polv2.PatternWeeklyAllDay
+		}
+		rule.Conditions = append(rule.Conditions,
+			polv2.Rule{Key: polv2.CurrentTimeKey, Operator: polv2.OperatorTimeGreaterThanOrEquals, Value: conditions.Weekly.StartTime + ":00" + offset},
+			polv2.Rule{Key: polv2.CurrentTimeKey, Operator: polv2.OperatorTimeLessThanOrEquals, Value: conditions.Weekly.EndTime + ":00" + offset},
+		)
+		return rule, polv2.PatternWeeklyCustomHours
+	}
+
+	notBefore := instance.ObjectMeta.CreationTimestamp
+	if conditions.NotBefore != nil {
+		notBefore = *conditions.NotBefore
+	}
+	rule := &polv2.Rule{
+		Operator: polv2.OperatorAnd,
+		Conditions: []polv2.Rule{
+			{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeGreaterThanOrEquals, Value: notBefore.UTC().Format(dateTimeLayout)},
+		},
+	}
+	if conditions.NotAfter != nil {
+		rule.Conditions = append(rule.Conditions,
+			polv2.Rule{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeLessThanOrEquals, Value: conditions.NotAfter.UTC().Format(dateTimeLayout)},
+		)
+	}
+	return rule, polv2.PatternOnce
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compile
+
+import (
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// AuthorizationPolicy compiles an authorization policy into the v1 policy
+// granting it in the account
+func AuthorizationPolicy(instance *ibmcloudv1alpha1.AuthorizationPolicy, accountID string, instanceID string, resolver RoleResolver) (polv1.Policy, error) {
+	roles, err := resolver.ResolveAuthorizationRoles(instance.Spec.Source.ServiceClass, instance.Spec.Target.ServiceClass, instance.Spec.Roles)
+	if err != nil {
+		return polv1.Policy{}, err
+	}
+	policy := polv1.Policy{
+		Roles:     rolecatalog.PolicyRoles(roles),
+		Resources: []polv1.Resource{authorizationResource(instance.Spec.Target, accountID)},
+		Subjects:  []polv1.Subject{authorizationSubject(instance.Spec.Source, accountID)},
+	}
+	policy.Type = iampapv1.AuthorizationPolicyType
+	policy.Description = PolicyDescription(instanceID, "AuthorizationPolicy", instance)
+	return policy, nil
+}
+
+func authorizationSubject(source ibmcloudv1alpha1.Info, accountID string) polv1.Subject {
+	policySubject := polv1.Subject{Attributes: infoAttributes(source)}
+	for _, attribute := range source.Attributes {
+		policySubject.AddAttribute(attribute.Name, attribute.Value, attribute.Operator)
+	}
+	policySubject.SetAttribute("accountId", accountID)
+	return policySubject
+}
+
+func authorizationResource(target ibmcloudv1alpha1.Info, accountID string) polv1.Resource {
+	policyResource := polv1.Resource{Attributes: infoAttributes(target)}
+	for _, attribute := range target.Attributes {
+		policyResource.AddAttribute(attribute.Name, attribute.Value, attribute.Operator)
+	}
+	policyResource.SetAttribute("accountId", accountID)
+	return policyResource
+}
+
+// InfoWellFormed returns whether the custom attributes of the source or target of an
+// authorization policy are well formed, and don't override the attributes set from its other fields
+func InfoWellFormed(info ibmcloudv1alpha1.Info) bool {
+	return polv1.AttributesWellFormed(infoAttributes(info), customAttributes(info.Attributes))
+}
+
+// infoAttributes returns the attributes set from the fields of the source or target of an
+// authorization policy, without its custom attributes
+func infoAttributes(info ibmcloudv1alpha1.Info) []polv1.Attribute {
+	policyResource := polv1.Resource{}
+
+	if info.ServiceClass != "" {
+		policyResource.SetAttribute("serviceName", info.ServiceClass)
+	}
+	if info.ServiceID != "" {
+		policyResource.SetAttribute("serviceInstance", info.ServiceID)
+	}
+	if info.ResourceName != "" {
+		policyResource.SetAttribute("resourceType", info.ResourceName)
+	}
+	if info.ResourceID != "" {
+		policyResource.SetAttribute("resource", info.ResourceID)
+	}
+	if info.ResourceGroup != "" {
+		policyResource.SetResourceGroupID(info.ResourceGroup)
+	}
+	if info.ResourceKey != "" && info.ResourceValue != "" {
+		policyResource.SetAttribute(info.ResourceKey, info.ResourceValue)
+	}
+	return policyResource.Attributes
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package compile translates custom resources into the exact payloads sent to
+// IAM. Compiling reads neither IAM nor the cluster: the identities and roles a
+// resource refers to are looked up through a Resolver, so the same payloads can
+// be computed by reconcilers, admission checks, dry runs and offline tools.
+package compile
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// RoleResolver resolves role names to the roles of the account, as
+// *rolecatalog.Catalog does
+type RoleResolver interface {
+	ResolveServiceRoles(serviceClass string, names []string) ([]rolecatalog.Role, error)
+	ResolveCustomRoles(serviceClass string, names []string) ([]rolecatalog.Role, error)
+	ResolveAuthorizationRoles(source string, target string, names []string) ([]rolecatalog.Role, error)
+}
+
+// Resolver looks up the identities, roles and custom resources a resource
+// refers to
+type Resolver interface {
+	RoleResolver
+	// User returns the IAM ID of the account user with the email
+	User(email string) (string, error)
+	// ServiceID returns the IAM ID of the service ID with the UUID
+	ServiceID(uuid string) (string, error)
+	// AccessGroup returns the ID of the access group with the ID, failing if
+	// it does not exist
+	AccessGroup(id string) (string, error)
+	// AccessGroupResource returns the AccessGroup resource with the name
+	AccessGroupResource(namespace string, name string) (*ibmcloudv1alpha1.AccessGroup, error)
+	// CustomRoleResource returns the CustomRole resource with the name
+	CustomRoleResource(namespace string, name string) (*ibmcloudv1alpha1.CustomRole, error)
+}
+
+// DependencyError is returned when a resource refers to a custom resource that
+// does not exist yet or is not created in IAM yet
+type DependencyError struct {
+	Err error
+}
+
+func (e *DependencyError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error getting the dependency
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// Description returns the description of an IAM object owned by the operator instance
+func Description(instanceID string, description string) string {
+	return ownership.Marker(instanceID) + description
+}
+
+// PolicyDescription returns the description of the policy of a resource, which marks it as owned by the
+// operator instance, since policies have no description of their own
+func PolicyDescription(instanceID string, kind string, obj metav1.Object) string {
+	return Description(instanceID, kind+" "+obj.GetNamespace()+"/"+obj.GetName())
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compile
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+const (
+	accountID  = "fa4e0000000000000000000000000002"
+	instanceID = ""
+)
+
+// resolver resolves the identities, roles and resources used in testdata
+type resolver struct {
+	groups map[string]*ibmcloudv1alpha1.AccessGroup
+	roles  map[string]*ibmcloudv1alpha1.CustomRole
+}
+
+func newResolver() *resolver {
+	developers := &ibmcloudv1alpha1.AccessGroup{ObjectMeta: metav1.ObjectMeta{Name: "developers", Namespace: "default"}}
+	developers.Status.GroupID = "AccessGroupId-5678"
+	deployer := &ibmcloudv1alpha1.CustomRole{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "roles"}}
+	deployer.Spec.ServiceClass = "containers-kubernetes"
+	deployer.Spec.DisplayName = "Deployer"
+	deployer.Status.RoleID = "RoleId-1234"
+	return &resolver{
+		groups: map[string]*ibmcloudv1alpha1.AccessGroup{"default/developers": developers},
+		roles:  map[string]*ibmcloudv1alpha1.CustomRole{"roles/deployer": deployer},
+	}
+}
+
+func (r *resolver) User(email string) (string, error) {
+	if email != "alice@example.com" {
+		return "", errors.New("User email is not valid.")
+	}
+	return "IBMid-alice", nil
+}
+
+func (r *resolver) ServiceID(uuid string) (string, error) {
+	return "iam-" + uuid, nil
+}
+
+func (r *resolver) AccessGroup(id string) (string, error) {
+	return id, nil
+}
+
+func (r *resolver) AccessGroupResource(namespace string, name string) (*ibmcloudv1alpha1.AccessGroup, error) {
+	if group, ok := r.groups[namespace+"/"+name]; ok {
+		return group, nil
+	}
+	return nil, kerror.NewNotFound(schema.GroupResource{Group: "ibmcloud.ibm.com", Resource: "accessgroups"}, name)
+}
+
+func (r *resolver) CustomRoleResource(namespace string, name string) (*ibmcloudv1alpha1.CustomRole, error) {
+	if role, ok := r.roles[namespace+"/"+name]; ok {
+		return role, nil
+	}
+	return nil, kerror.NewNotFound(schema.GroupResource{Group: "ibmcloud.ibm.com", Resource: "customroles"}, name)
+}
+
+func (r *resolver) ResolveServiceRoles(serviceClass string, names []string) ([]rolecatalog.Role, error) {
+	return resolve(names, map[string]string{
+		"Viewer":        "crn:v1:bluemix:public:iam::::role:Viewer",
+		"Editor":        "crn:v1:bluemix:public:iam::::role:Editor",
+		"Administrator": "crn:v1:bluemix:public:iam::::role:Administrator",
+		"Reader":        "crn:v1:bluemix:public:iam::::serviceRole:Reader",
+		"Writer":        "crn:v1:bluemix:public:iam::::serviceRole:Writer",
+	})
+}
+
+func (r *resolver) ResolveCustomRoles(serviceClass string, names []string) ([]rolecatalog.Role, error) {
+	return resolve(names, map[string]string{
+		"Auditor":  fmt.Sprintf("crn:v1:bluemix:public:iam::a/%s::customRole:Auditor", accountID),
+		"Deployer": fmt.Sprintf("crn:v1:bluemix:public:containers-kubernetes::a/%s::customRole:Deployer", accountID),
+	})
+}
+
+func (r *resolver) ResolveAuthorizationRoles(source string, target string, names []string) ([]rolecatalog.Role, error) {
+	return resolve(names, map[string]string{
+		"Reader": "crn:v1:bluemix:public:iam::::serviceRole:Reader",
+	})
+}
+
+func resolve(names []string, crns map[string]string) ([]rolecatalog.Role, error) {
+	var roles []rolecatalog.Role
+	var unknown []string
+	for _, name := range names {
+		crn, ok := crns[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		roles = append(roles, rolecatalog.Role{Name: name, DisplayName: name, CRN: crn})
+	}
+	if len(unknown) > 0 {
+		return nil, &rolecatalog.UnknownRolesError{Unknown: unknown}
+	}
+	return roles, nil
+}
+
+// compileFile compiles the resource of a testdata file into the payloads sent to IAM
+func compileFile(t *testing.T, path string) interface{} {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var meta metav1.TypeMeta
+	require.NoError(t, yaml.Unmarshal(data, &meta))
+
+	switch meta.Kind {
+	case "AccessPolicy":
+		instance := &ibmcloudv1alpha1.AccessPolicy{}
+		require.NoError(t, yaml.Unmarshal(data, instance))
+		policy, err := AccessPolicy(instance, accountID, instanceID, newResolver())
+		require.NoError(t, err)
+		if instance.Spec.Conditions == nil {
+			return policy
+		}
+		conditional := polv2.ConvertV1Policy(policy)
+		conditional.Rule, conditional.Pattern = AccessPolicyRule(instance)
+		return conditional
+	case "AuthorizationPolicy":
+		instance := &ibmcloudv1alpha1.AuthorizationPolicy{}
+		require.NoError(t, yaml.Unmarshal(data, instance))
+		policy, err := AuthorizationPolicy(instance, accountID, instanceID, newResolver())
+		require.NoError(t, err)
+		return policy
+	case "AccessGroup":
+		instance := &ibmcloudv1alpha1.AccessGroup{}
+		require.NoError(t, yaml.Unmarshal(data, instance))
+		return map[string]interface{}{"create": AccessGroup(instance, accountID, instanceID), "update": AccessGroupUpdate(instance, instanceID)}
+	case "CustomRole":
+		instance := &ibmcloudv1alpha1.CustomRole{}
+		require.NoError(t, yaml.Unmarshal(data, instance))
+		return map[string]interface{}{"create": CustomRole(instance, accountID, instanceID), "update": CustomRoleUpdate(instance, instanceID)}
+	}
+	t.Fatalf("unsupported kind %q", meta.Kind)
+	return nil
+}
+
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			actual, err := json.MarshalIndent(compileFile(t, path), "", "  ")
+			require.NoError(t, err)
+			actual = append(actual, '\n')
+
+			golden := strings.TrimSuffix(path, ".yaml") + ".golden"
+			if *update {
+				require.NoError(t, ioutil.WriteFile(golden, actual, 0644))
+			}
+			expected, err := ioutil.ReadFile(golden)
+			require.NoError(t, err, "run go test with -update to create the golden file")
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestAccessPolicyErrors(t *testing.T) {
+	notReady := &ibmcloudv1alpha1.AccessGroup{ObjectMeta: metav1.ObjectMeta{Name: "testers", Namespace: "default"}}
+	pendingRole := &ibmcloudv1alpha1.CustomRole{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}}
+
+	cases := []struct {
+		name       string
+		spec       ibmcloudv1alpha1.AccessPolicySpec
+		dependency bool
+	}{
+		{
+			name: "unknown role",
+			spec: ibmcloudv1alpha1.AccessPolicySpec{Subject: ibmcloudv1alpha1.Subject{UserEmail: "alice@example.com"}, Roles: ibmcloudv1alpha1.Roles{DefinedRoles: []string{"Viewr"}}},
+		},
+		{
+			name: "invalid user",
+			spec: ibmcloudv1alpha1.AccessPolicySpec{Subject: ibmcloudv1alpha1.Subject{UserEmail: "bob@example.com"}, Roles: ibmcloudv1alpha1.Roles{DefinedRoles: []string{"Viewer"}}},
+		},
+		{
+			name:       "missing access group",
+			spec:       ibmcloudv1alpha1.AccessPolicySpec{Subject: ibmcloudv1alpha1.Subject{AccessGroupDef: ibmcloudv1alpha1.AccessGroupDef{AccessGroupName: "operators"}}, Roles: ibmcloudv1alpha1.Roles{DefinedRoles: []string{"Viewer"}}},
+			dependency: true,
+		},
+		{
+			name:       "access group not ready",
+			spec:       ibmcloudv1alpha1.AccessPolicySpec{Subject: ibmcloudv1alpha1.Subject{AccessGroupDef: ibmcloudv1alpha1.AccessGroupDef{AccessGroupName: "testers"}}, Roles: ibmcloudv1alpha1.Roles{DefinedRoles: []string{"Viewer"}}},
+			dependency: true,
+		},
+		{
+			name:       "custom role not ready",
+			spec:       ibmcloudv1alpha1.AccessPolicySpec{Subject: ibmcloudv1alpha1.Subject{UserEmail: "alice@example.com"}, Roles: ibmcloudv1alpha1.Roles{CustomRolesDef: []ibmcloudv1alpha1.CustomRolesDef{{CustomRoleName: "pending"}}}},
+			dependency: true,
+		},
+	}
+
+	for _, c := range cases {
+		resolver := newResolver()
+		resolver.groups["default/testers"] = notReady
+		resolver.roles["default/pending"] = pendingRole
+		instance := &ibmcloudv1alpha1.AccessPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default"}, Spec: c.spec}
+
+		_, err := AccessPolicy(instance, accountID, instanceID, resolver)
+		require.Error(t, err, c.name)
+		_, dependency := err.(*DependencyError)
+		assert.Equal(t, c.dependency, dependency, c.name)
+	}
+}
+
+func TestAuthorizationPolicyUnknownRole(t *testing.T) {
+	instance := &ibmcloudv1alpha1.AuthorizationPolicy{}
+	instance.Spec.Source.ServiceClass = "cloud-object-storage"
+	instance.Spec.Target.ServiceClass = "kms"
+	instance.Spec.Roles = []string{"Writer"}
+
+	_, err := AuthorizationPolicy(instance, accountID, instanceID, newResolver())
+	_, ok := err.(*rolecatalog.UnknownRolesError)
+	assert.True(t, ok)
+}
+
+func TestDescription(t *testing.T) {
+	assert.Equal(t, "OPERATOR OWNED: Developers", Description("", "Developers"))
+	assert.Equal(t, "OPERATOR OWNED: [prod] Developers", Description("prod", "Developers"))
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compile
+
+import (
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+)
+
+// CustomRole compiles a custom role into the request creating it in the account
+func CustomRole(instance *ibmcloudv1alpha1.CustomRole, accountID string, instanceID string) iampapv2.CreateRoleRequest {
+	return iampapv2.CreateRoleRequest{
+		Name:        instance.Spec.RoleName,
+		ServiceName: instance.Spec.ServiceClass,
+		AccountID:   accountID,
+		DisplayName: instance.Spec.DisplayName,
+		Description: Description(instanceID, instance.Spec.Description),
+		Actions:     instance.Spec.Actions,
+	}
+}
+
+// CustomRoleUpdate compiles a custom role into the request updating it
+func CustomRoleUpdate(instance *ibmcloudv1alpha1.CustomRole, instanceID string) iampapv2.UpdateRoleRequest {
+	return iampapv2.UpdateRoleRequest{
+		DisplayName: instance.Spec.DisplayName,
+		Description: Description(instanceID, instance.Spec.Description),
+		Actions:     instance.Spec.Actions,
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package control honours the operational annotations of custom resources: pausing their
+// reconciliation, e.g. during an incident, and forcing a full resync on demand.
+package control
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+const (
+	// PausedAnnotation pauses the reconciliation of a resource, or of all resources of a namespace, when "true"
+	PausedAnnotation = "ibmcloud.ibm.com/paused"
+	// ResyncAnnotation forces a full resync of a resource each time it is set to a new value, e.g. a timestamp
+	ResyncAnnotation = "ibmcloud.ibm.com/force-resync"
+)
+
+// PausedConditionType is the type of the status condition reporting a paused reconciliation
+const PausedConditionType = "Paused"
+
+// ResyncedConditionType is the type of the status condition recording the last forced resync
+const ResyncedConditionType = "Resynced"
+
+// PausedState is the state of a resource while its reconciliation is paused
+const PausedState = "Paused"
+
+const (
+	reasonResourcePaused  = "ResourcePaused"
+	reasonNamespacePaused = "NamespacePaused"
+	reasonResumed         = "Resumed"
+	reasonForceResync     = "ForceResync"
+)
+
+// Paused returns whether the reconciliation of obj is paused by its annotation or the annotation of its namespace,
+// and the reason of the pause. The namespace is read with r, e.g. the reader returned by Namespaces.
+func Paused(r client.Reader, obj runtime.Object) (bool, string, error) {
+	meta := resv1.ObjectMeta(obj)
+	if meta.GetAnnotations()[PausedAnnotation] == "true" {
+		return true, reasonResourcePaused, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: meta.GetNamespace()}, namespace); err != nil {
+		return false, "", err
+	}
+	if namespace.ObjectMeta.Annotations[PausedAnnotation] == "true" {
+		return true, reasonNamespacePaused, nil
+	}
+	return false, "", nil
+}
+
+// Pause sets the Paused state and condition of obj, and returns true if they changed
+func Pause(obj runtime.Object, reason string) bool {
+	message := "Reconciliation paused by the " + PausedAnnotation + " annotation of the resource"
+	if reason == reasonNamespacePaused {
+		message = "Reconciliation paused by the " + PausedAnnotation + " annotation of the namespace"
+	}
+	status := resv1.GetStatus(obj)
+	current := resv1.GetCondition(obj, PausedConditionType)
+	if status.GetState() == PausedState && current != nil && current.Status == corev1.ConditionTrue && current.Reason == reason {
+		return false
+	}
+	status.SetState(PausedState)
+	status.SetMessage(message)
+	resv1.SetCondition(obj, &resv1.Condition{Type: PausedConditionType, Status: corev1.ConditionTrue, Reason: reason, Message: message})
+	return true
+}
+
+// Resume clears the Paused state and condition of obj, if any, and returns true if they changed. A resource that
+// failed permanently before its pause is Failed again, any other is Pending until it is reconciled.
+func Resume(obj runtime.Object) bool {
+	current := resv1.GetCondition(obj, PausedConditionType)
+	if current == nil || current.Status != corev1.ConditionTrue {
+		return false
+	}
+	resv1.SetCondition(obj, &resv1.Condition{Type: PausedConditionType, Status: corev1.ConditionFalse, Reason: reasonResumed})
+	status := resv1.GetStatus(obj)
+	if stalled := resv1.GetCondition(obj, requeue.ConditionType); requeue.Stalled(obj) {
+		status.SetState(resv1.ResourceStateFailed)
+		status.SetMessage(stalled.Message)
+		return true
+	}
+	status.SetState(resv1.ResourceStatePending)
+	status.SetMessage("Reconciliation resumed")
+	return true
+}
+
+// Resync records the value of the ResyncAnnotation of obj in its Resynced condition, and returns true if
+// it is a new value, i.e. a full resync was requested since the last one.
+func Resync(obj runtime.Object) bool {
+	value, ok := resv1.ObjectMeta(obj).GetAnnotations()[ResyncAnnotation]
+	if !ok || value == "" {
+		return false
+	}
+	current := resv1.GetCondition(obj, ResyncedConditionType)
+	if current != nil && current.Message == value {
+		return false
+	}
+	resv1.SetCondition(obj, &resv1.Condition{Type: ResyncedConditionType, Status: corev1.ConditionTrue, Reason: reasonForceResync, Message: value})
+	return true
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package control
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/requeue"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+func namespace(name string, annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+}
+
+func TestPaused(t *testing.T) {
+	reader := fake.NewFakeClientWithScheme(scheme.Scheme,
+		namespace("team-a", nil),
+		namespace("team-b", map[string]string{PausedAnnotation: "true"}))
+
+	policy := &ibmcloudv1alpha1.AccessPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "viewer"}}
+	paused, _, err := Paused(reader, policy)
+	assert.NoError(t, err)
+	assert.False(t, paused)
+
+	policy.Annotations = map[string]string{PausedAnnotation: "true"}
+	paused, reason, err := Paused(reader, policy)
+	assert.NoError(t, err)
+	assert.True(t, paused)
+	assert.Equal(t, reasonResourcePaused, reason)
+
+	policy = &ibmcloudv1alpha1.AccessPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "viewer"}}
+	paused, reason, err = Paused(reader, policy)
+	assert.NoError(t, err)
+	assert.True(t, paused)
+	assert.Equal(t, reasonNamespacePaused, reason)
+
+	policy = &ibmcloudv1alpha1.AccessPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "team-c", Name: "viewer"}}
+	_, _, err = Paused(reader, policy)
+	assert.Error(t, err)
+}
+
+func TestPauseResume(t *testing.T) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	policy.Status.State = "Online"
+	assert.False(t, Resume(policy))
+
+	assert.True(t, Pause(policy, reasonResourcePaused))
+	assert.Equal(t, PausedState, policy.Status.State)
+	assert.Equal(t, corev1.ConditionTrue, resv1.GetCondition(policy, PausedConditionType).Status)
+	assert.False(t, Pause(policy, reasonResourcePaused))
+	assert.True(t, Pause(policy, reasonNamespacePaused))
+
+	assert.True(t, Resume(policy))
+	assert.Equal(t, resv1.ResourceStatePending, policy.Status.State)
+	assert.Equal(t, corev1.ConditionFalse, resv1.GetCondition(policy, PausedConditionType).Status)
+	assert.False(t, Resume(policy))
+
+	// A resource that failed permanently is Failed again, until its spec changes
+	requeue.Report(policy, requeue.AsPermanent(errors.New("The spec is not well-formed")))
+	assert.True(t, Pause(policy, reasonResourcePaused))
+	assert.True(t, Resume(policy))
+	assert.Equal(t, resv1.ResourceStateFailed, policy.Status.State)
+	assert.Equal(t, "The spec is not well-formed", policy.Status.Message)
+}
+
+func TestResync(t *testing.T) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	assert.False(t, Resync(policy))
+
+	policy.Annotations = map[string]string{ResyncAnnotation: "2020-06-01T10:00:00Z"}
+	assert.True(t, Resync(policy))
+	assert.Equal(t, "2020-06-01T10:00:00Z", resv1.GetCondition(policy, ResyncedConditionType).Message)
+	assert.False(t, Resync(policy))
+
+	policy.Annotations[ResyncAnnotation] = "2020-06-02T10:00:00Z"
+	assert.True(t, Resync(policy))
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package control
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var (
+	namespacesMu sync.Mutex
+	namespaces   = map[manager.Manager]client.Reader{}
+)
+
+// Namespaces returns a reader of the namespaces of the cluster, shared by the controllers of mgr. It reads them from
+// a cache watching all namespaces, started with mgr, since the cache of a manager watching some namespaces cannot
+// read them and reading them from the API server on every reconcile would load it on large clusters.
+func Namespaces(mgr manager.Manager) (client.Reader, error) {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	if reader, ok := namespaces[mgr]; ok {
+		return reader, nil
+	}
+
+	c, err := cache.New(mgr.GetConfig(), cache.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+	// Watch namespaces from the start, so that reads wait for them to be listed
+	if _, err := c.GetInformer(&corev1.Namespace{}); err != nil {
+		return nil, err
+	}
+	if err := mgr.Add(c); err != nil {
+		return nil, err
+	}
+	namespaces[mgr] = namespaceCache{c}
+	return namespaces[mgr], nil
+}
+
+// namespaceCache waits for the cache to be started and synced before reading it, since controllers may reconcile
+// before the manager starts it
+type namespaceCache struct {
+	cache.Cache
+}
+
+// Get implements client.Reader
+func (c namespaceCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if !c.WaitForCacheSync(ctx.Done()) {
+		return ctx.Err()
+	}
+	return c.Cache.Get(ctx, key, obj)
+}
+
+// List implements client.Reader
+func (c namespaceCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if !c.WaitForCacheSync(ctx.Done()) {
+		return ctx.Err()
+	}
+	return c.Cache.List(ctx, list, opts...)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package drift reports the differences between IAM objects and the resources managing them, and decides
+// from the drift policy of a resource whether they are reverted, only detected or accepted.
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+const (
+	// Enforce reverts changes made to IAM outside of the operator
+	Enforce = "Enforce"
+	// Detect reports changes made to IAM outside of the operator without reverting them
+	Detect = "Detect"
+	// Ignore accepts changes made to IAM outside of the operator
+	Ignore = "Ignore"
+)
+
+// ConditionType is the type of the status condition reporting drift
+const ConditionType = "DriftDetected"
+
+const (
+	reasonInSync   = "InSync"
+	reasonDetected = "Detected"
+	reasonReverted = "Reverted"
+	reasonAccepted = "Accepted"
+)
+
+// Difference is a field of an IAM object that does not match the desired state
+type Difference struct {
+	Field   string
+	Desired interface{}
+	Actual  interface{}
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: desired %s, actual %s", d.Field, format(d.Desired), format(d.Actual))
+}
+
+func format(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// Summary describes the differences on one line
+func Summary(diffs []Difference) string {
+	var parts []string
+	for _, d := range diffs {
+		parts = append(parts, d.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Mode returns the drift policy of a resource: its own policy, else the global one, else Enforce
+func Mode(policy string, global string) string {
+	for _, mode := range []string{policy, global} {
+		switch mode {
+		case Enforce, Detect, Ignore:
+			return mode
+		}
+	}
+	return Enforce
+}
+
+// Report sets the DriftDetected condition of obj for the differences found in IAM with the given mode, records
+// the values the Ignore mode accepts in its status, and returns true if the status changed
+func Report(obj runtime.Object, mode string, diffs []Difference) bool {
+	var accepted []resv1.AcceptedValue
+	if mode == Ignore {
+		for _, d := range diffs {
+			accepted = append(accepted, resv1.AcceptedValue{Field: d.Field, Value: format(d.Actual)})
+		}
+	}
+	acceptedChanged := !reflect.DeepEqual(accepted, resv1.AcceptedDrift(obj))
+	if acceptedChanged {
+		resv1.SetAcceptedDrift(obj, accepted)
+	}
+
+	condition := &resv1.Condition{Type: ConditionType, Status: corev1.ConditionFalse, Reason: reasonInSync}
+	if len(diffs) > 0 {
+		condition.Message = Summary(diffs)
+		switch mode {
+		case Detect:
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = reasonDetected
+		case Ignore:
+			condition.Reason = reasonAccepted
+		default:
+			condition.Reason = reasonReverted
+		}
+	}
+
+	current := resv1.GetCondition(obj, ConditionType)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message {
+		return acceptedChanged
+	}
+	if current == nil && len(diffs) == 0 { // No need for a condition until drift happens
+		return acceptedChanged
+	}
+	resv1.SetCondition(obj, condition)
+	return true
+}
+
+// Event records a Warning event on obj for the differences found in IAM that the mode doesn't accept. Detected
+// differences are recorded when reported is true, as the status changed, and enforced ones every time they are
+// reverted.
+func Event(recorder record.EventRecorder, obj runtime.Object, mode string, diffs []Difference, reported bool) {
+	if len(diffs) == 0 {
+		return
+	}
+	switch mode {
+	case Detect:
+		if reported {
+			recorder.Event(obj, corev1.EventTypeWarning, ConditionType, "Changed in IAM outside of the operator: "+Summary(diffs))
+		}
+	case Enforce:
+		recorder.Event(obj, corev1.EventTypeWarning, "DriftReverted", "Reverting changes made in IAM outside of the operator: "+Summary(diffs))
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package drift
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+func TestMode(t *testing.T) {
+	assert.Equal(t, Enforce, Mode("", ""))
+	assert.Equal(t, Detect, Mode("", Detect))
+	assert.Equal(t, Ignore, Mode(Ignore, Detect))
+	assert.Equal(t, Detect, Mode("Unknown", Detect))
+}
+
+func TestSummary(t *testing.T) {
+	diffs := []Difference{
+		{Field: "description", Desired: "OPERATOR OWNED: admins", Actual: "admins"},
+		{Field: "actions", Desired: []string{"iam.policy.read"}, Actual: []string{}},
+	}
+	assert.Equal(t, `description: desired "OPERATOR OWNED: admins", actual "admins"; actions: desired ["iam.policy.read"], actual []`, Summary(diffs))
+}
+
+func TestReport(t *testing.T) {
+	role := &ibmcloudv1alpha1.CustomRole{}
+	assert.False(t, Report(role, Enforce, nil))
+	assert.Nil(t, resv1.GetCondition(role, ConditionType))
+
+	diffs := []Difference{{Field: "displayName", Desired: "COS Admin", Actual: "Admin"}}
+	assert.True(t, Report(role, Detect, diffs))
+	condition := resv1.GetCondition(role, ConditionType)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, reasonDetected, condition.Reason)
+	assert.False(t, Report(role, Detect, diffs))
+
+	assert.True(t, Report(role, Ignore, diffs))
+	assert.Equal(t, corev1.ConditionFalse, resv1.GetCondition(role, ConditionType).Status)
+	assert.Equal(t, reasonAccepted, resv1.GetCondition(role, ConditionType).Reason)
+	assert.Equal(t, []resv1.AcceptedValue{{Field: "displayName", Value: `"Admin"`}}, role.Status.AcceptedDrift)
+	assert.False(t, Report(role, Ignore, diffs))
+
+	assert.True(t, Report(role, Enforce, nil))
+	assert.Equal(t, reasonInSync, resv1.GetCondition(role, ConditionType).Reason)
+	assert.Nil(t, role.Status.AcceptedDrift)
+}
+
+func TestEvent(t *testing.T) {
+	role := &ibmcloudv1alpha1.CustomRole{}
+	diffs := []Difference{{Field: "displayName", Desired: "COS Admin", Actual: "Admin"}}
+	recorder := record.NewFakeRecorder(10)
+
+	Event(recorder, role, Detect, diffs, true)
+	Event(recorder, role, Detect, diffs, false)
+	Event(recorder, role, Enforce, diffs, false)
+	Event(recorder, role, Ignore, diffs, true)
+	Event(recorder, role, Enforce, nil, true)
+
+	assert.Equal(t, 2, len(recorder.Events))
+	assert.Equal(t, `Warning DriftDetected Changed in IAM outside of the operator: displayName: desired "COS Admin", actual "Admin"`, <-recorder.Events)
+	assert.Equal(t, `Warning DriftReverted Reverting changes made in IAM outside of the operator: displayName: desired "COS Admin", actual "Admin"`, <-recorder.Events)
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package election elects the replica of the operator running the controllers with a Lease lock,
+// as the leader election of controller-runtime v0.5 only holds ConfigMap locks.
+package election
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ErrLost is returned when the replica stops leading, e.g. when it fails to renew its lease
+var ErrLost = errors.New("leader election lost")
+
+// Config names the Lease lock and tunes the election
+type Config struct {
+	// Namespace and Name of the Lease
+	Namespace string
+	Name      string
+	// LeaseDuration is the time the other replicas wait before taking over the lease of a leader
+	// that stopped renewing it
+	LeaseDuration time.Duration
+	// RenewDeadline is the time the leader keeps trying to renew its lease before giving up
+	RenewDeadline time.Duration
+	// RetryPeriod is the time between two attempts to acquire or renew the lease
+	RetryPeriod time.Duration
+}
+
+// Manager is a manager starting its runnables that need leader election, i.e. the controllers,
+// once the replica holds the Lease. The other runnables, such as the webhook server, are started
+// by every replica. The manager itself must be created without leader election.
+type Manager struct {
+	manager.Manager
+	lock     resourcelock.Interface
+	config   Config
+	watchdog *leaderelection.HealthzAdaptor
+
+	mu        sync.Mutex
+	runnables []manager.Runnable
+	// stop and fail are set once the replica leads
+	stop <-chan struct{}
+	fail func(error)
+}
+
+// New returns a manager electing the leader among the replicas with the Lease of config
+func New(mgr manager.Manager, cfg *rest.Config, config Config) (*Manager, error) {
+	// The identity of the replica must be unique, even if a pod is restarted with the same name
+	id, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	id = id + "_" + string(uuid.NewUUID())
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, config.Namespace, config.Name, client.CoreV1(), client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: id, EventRecorder: mgr.GetEventRecorderFor(id)})
+	if err != nil {
+		return nil, err
+	}
+	return newManager(mgr, lock, config)
+}
+
+func newManager(mgr manager.Manager, lock resourcelock.Interface, config Config) (*Manager, error) {
+	m := &Manager{Manager: mgr, lock: lock, config: config, watchdog: leaderelection.NewLeaderHealthzAdaptor(config.RenewDeadline)}
+	if err := mgr.Add(&campaign{m}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Add sets the dependencies of r, and starts it with the manager if it doesn't need leader
+// election, or else once the replica leads
+func (m *Manager) Add(r manager.Runnable) error {
+	if elected, ok := r.(manager.LeaderElectionRunnable); ok && !elected.NeedLeaderElection() {
+		return m.Manager.Add(r)
+	}
+	if err := m.Manager.SetFields(r); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runnables = append(m.runnables, r)
+	if m.stop != nil {
+		m.start(r)
+	}
+	return nil
+}
+
+// Check is a liveness check failing when the replica leads but did not renew its lease for
+// longer than the lease duration and renew deadline, so the kubelet restarts a stuck leader
+func (m *Manager) Check(req *http.Request) error {
+	return m.watchdog.Check(req)
+}
+
+// lead starts the runnables added so far, and those added later, until stop is closed
+func (m *Manager) lead(stop <-chan struct{}, fail func(error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stop, m.fail = stop, fail
+	for _, r := range m.runnables {
+		m.start(r)
+	}
+}
+
+// start runs r until the replica stops leading, m.mu must be held
+func (m *Manager) start(r manager.Runnable) {
+	stop, fail := m.stop, m.fail
+	go func() {
+		if err := r.Start(stop); err != nil {
+			fail(err)
+		}
+	}()
+}
+
+// campaign runs the election on every replica, until the manager stops or the replica stops leading
+type campaign struct {
+	m *Manager
+}
+
+// NeedLeaderElection returns false, the campaign runs before the replica leads
+func (c *campaign) NeedLeaderElection() bool {
+	return false
+}
+
+// Start runs the election until stop is closed. It returns ErrLost when the replica stops leading,
+// or the error of a runnable started as the leader, so the manager exits and another replica
+// takes over.
+func (c *campaign) Start(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	failed := make(chan error, 1)
+	fail := func(err error) {
+		select {
+		case failed <- err:
+		default:
+		}
+		cancel()
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          c.m.lock,
+		LeaseDuration: c.m.config.LeaseDuration,
+		RenewDeadline: c.m.config.RenewDeadline,
+		RetryPeriod:   c.m.config.RetryPeriod,
+		WatchDog:      c.m.watchdog,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				c.m.lead(ctx.Done(), fail)
+			},
+			OnStoppedLeading: func() {},
+		},
+		Name: c.m.config.Name,
+	})
+	if err != nil {
+		return err
+	}
+	elector.Run(ctx)
+
+	select {
+	case err := <-failed:
+		return err
+	case <-stop:
+		return nil
+	default:
+		return ErrLost
+	}
+}
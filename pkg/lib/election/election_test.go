@@ -0,0 +1,198 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package election
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var config = Config{Name: "leader", LeaseDuration: time.Millisecond * 300, RenewDeadline: time.Millisecond * 200, RetryPeriod: time.Millisecond * 20}
+
+// lease is an in-memory Lease shared by the replicas
+type lease struct {
+	mu     sync.Mutex
+	record *resourcelock.LeaderElectionRecord
+	// down holds the replicas that can't reach the API server
+	down map[string]bool
+}
+
+// lock is the lock of a replica on a lease
+type lock struct {
+	lease    *lease
+	identity string
+}
+
+func (l *lock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	l.lease.mu.Lock()
+	defer l.lease.mu.Unlock()
+	if l.lease.down[l.identity] {
+		return nil, errors.New("connection refused")
+	}
+	if l.lease.record == nil {
+		return nil, kerror.NewNotFound(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, config.Name)
+	}
+	record := *l.lease.record
+	return &record, nil
+}
+
+func (l *lock) Create(record resourcelock.LeaderElectionRecord) error {
+	return l.Update(record)
+}
+
+func (l *lock) Update(record resourcelock.LeaderElectionRecord) error {
+	l.lease.mu.Lock()
+	defer l.lease.mu.Unlock()
+	if l.lease.down[l.identity] {
+		return errors.New("connection refused")
+	}
+	l.lease.record = &record
+	return nil
+}
+
+func (l *lock) RecordEvent(string) {}
+
+func (l *lock) Identity() string {
+	return l.identity
+}
+
+func (l *lock) Describe() string {
+	return config.Name
+}
+
+// base is the manager of the controller-runtime, which starts the runnables added to it
+type base struct {
+	manager.Manager
+	runnables []manager.Runnable
+}
+
+func (b *base) Add(r manager.Runnable) error {
+	b.runnables = append(b.runnables, r)
+	return nil
+}
+
+func (b *base) SetFields(interface{}) error {
+	return nil
+}
+
+// runnable records when it runs
+type runnable struct {
+	elected bool
+	running chan bool
+}
+
+func newRunnable(elected bool) *runnable {
+	return &runnable{elected: elected, running: make(chan bool, 2)}
+}
+
+func (r *runnable) NeedLeaderElection() bool {
+	return r.elected
+}
+
+func (r *runnable) Start(stop <-chan struct{}) error {
+	r.running <- true
+	<-stop
+	r.running <- false
+	return nil
+}
+
+// replica starts the campaign of a replica, and returns the error it ends with on done
+func replica(t *testing.T, shared *lease, identity string, stop <-chan struct{}) (*Manager, *base, <-chan error) {
+	b := &base{}
+	m, err := newManager(b, &lock{lease: shared, identity: identity}, config)
+	require.NoError(t, err)
+	require.Len(t, b.runnables, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- b.runnables[0].Start(stop)
+	}()
+	return m, b, done
+}
+
+func TestLead(t *testing.T) {
+	stop := make(chan struct{})
+	m, b, done := replica(t, &lease{}, "a", stop)
+
+	controller := newRunnable(true)
+	require.NoError(t, m.Add(controller))
+	assert.True(t, <-controller.running)
+
+	// Runnables added to a leader start right away, webhooks are left to the manager
+	late := newRunnable(true)
+	require.NoError(t, m.Add(late))
+	assert.True(t, <-late.running)
+	webhook := newRunnable(false)
+	require.NoError(t, m.Add(webhook))
+	assert.Contains(t, b.runnables, webhook)
+
+	close(stop)
+	assert.NoError(t, <-done)
+	assert.False(t, <-controller.running)
+	assert.False(t, <-late.running)
+}
+
+func TestFailover(t *testing.T) {
+	shared := &lease{down: map[string]bool{}}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	a, _, doneA := replica(t, shared, "a", stop)
+	first := newRunnable(true)
+	require.NoError(t, a.Add(first))
+	assert.True(t, <-first.running)
+
+	b, _, doneB := replica(t, shared, "b", stop)
+	second := newRunnable(true)
+	require.NoError(t, b.Add(second))
+	select {
+	case <-second.running:
+		t.Fatal("the standby replica runs the controllers")
+	case <-time.After(config.LeaseDuration):
+	}
+
+	// The leader can't renew its lease: it stops its controllers and the standby replica takes over
+	shared.mu.Lock()
+	shared.down["a"] = true
+	shared.mu.Unlock()
+	assert.Equal(t, ErrLost, <-doneA)
+	assert.False(t, <-first.running)
+	assert.True(t, <-second.running)
+	assert.NoError(t, b.Check(nil))
+	select {
+	case err := <-doneB:
+		t.Fatalf("the new leader stopped: %v", err)
+	default:
+	}
+}
+
+func TestRunnableError(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	m, _, done := replica(t, &lease{}, "a", stop)
+
+	failure := errors.New("cache failed to sync")
+	require.NoError(t, m.Add(manager.RunnableFunc(func(<-chan struct{}) error { return failure })))
+	assert.Equal(t, failure, <-done)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package endpoints locates the IBM Cloud services called by the operator. All of them can be
+// redirected to a single URL, e.g. to run the operator or its tests against a fake IAM server.
+package endpoints
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	bxendpoints "github.com/IBM-Cloud/bluemix-go/endpoints"
+)
+
+// OverrideEnv is the environment variable redirecting all IBM Cloud services to one URL
+const OverrideEnv = "IBMCLOUD_IAM_OPERATOR_ENDPOINT"
+
+var (
+	mu       sync.RWMutex
+	override string
+)
+
+// Override redirects all IBM Cloud services to url until the returned function is called.
+// It takes precedence over OverrideEnv.
+func Override(url string) (restore func()) {
+	mu.Lock()
+	previous := override
+	override = strings.TrimSuffix(url, "/")
+	mu.Unlock()
+	return func() {
+		mu.Lock()
+		override = previous
+		mu.Unlock()
+	}
+}
+
+// Locator returns the locator of the services of region, or of the override URL if any
+func Locator(region string) bxendpoints.EndpointLocator {
+	mu.RLock()
+	url := override
+	mu.RUnlock()
+	if url == "" {
+		url = strings.TrimSuffix(os.Getenv(OverrideEnv), "/")
+	}
+	if url != "" {
+		return fixed(url)
+	}
+	return bxendpoints.NewEndpointLocator(region)
+}
+
+// fixed locates all services at the same URL
+type fixed string
+
+func (f fixed) AccountManagementEndpoint() (string, error)  { return string(f), nil }
+func (f fixed) CertificateManagerEndpoint() (string, error) { return string(f), nil }
+func (f fixed) CFAPIEndpoint() (string, error)              { return string(f), nil }
+func (f fixed) ContainerEndpoint() (string, error)          { return string(f), nil }
+func (f fixed) ContainerRegistryEndpoint() (string, error)  { return string(f), nil }
+func (f fixed) CisEndpoint() (string, error)                { return string(f), nil }
+func (f fixed) GlobalSearchEndpoint() (string, error)       { return string(f), nil }
+func (f fixed) GlobalTaggingEndpoint() (string, error)      { return string(f), nil }
+func (f fixed) IAMEndpoint() (string, error)                { return string(f), nil }
+func (f fixed) IAMPAPEndpoint() (string, error)             { return string(f), nil }
+func (f fixed) ICDEndpoint() (string, error)                { return string(f), nil }
+func (f fixed) MCCPAPIEndpoint() (string, error)            { return string(f), nil }
+func (f fixed) ResourceManagementEndpoint() (string, error) { return string(f), nil }
+func (f fixed) ResourceControllerEndpoint() (string, error) { return string(f), nil }
+func (f fixed) ResourceCatalogEndpoint() (string, error)    { return string(f), nil }
+func (f fixed) UAAEndpoint() (string, error)                { return string(f), nil }
+func (f fixed) CseEndpoint() (string, error)                { return string(f), nil }
+func (f fixed) SchematicsEndpoint() (string, error)         { return string(f), nil }
+func (f fixed) UserManagementEndpoint() (string, error)     { return string(f), nil }
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package endpoints
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocator(t *testing.T) {
+	iam, err := Locator("us-south").IAMEndpoint()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://iam.cloud.ibm.com", iam)
+
+	os.Setenv(OverrideEnv, "http://localhost:8080/")
+	defer os.Unsetenv(OverrideEnv)
+	iam, _ = Locator("us-south").IAMEndpoint()
+	assert.Equal(t, "http://localhost:8080", iam)
+
+	restore := Override("http://127.0.0.1:9999")
+	pap, _ := Locator("us-south").IAMPAPEndpoint()
+	account, _ := Locator("eu-de").AccountManagementEndpoint()
+	assert.Equal(t, "http://127.0.0.1:9999", pap)
+	assert.Equal(t, "http://127.0.0.1:9999", account)
+
+	restore()
+	iam, _ = Locator("us-south").IAMEndpoint()
+	assert.Equal(t, "http://localhost:8080", iam)
+}
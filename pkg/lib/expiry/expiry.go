@@ -0,0 +1,81 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expiry
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MinRequeue avoids busy requeues when a deadline is about to pass
+const MinRequeue = time.Second
+
+// Deadline returns the time a grant expires, either expiresAt or ttl after start. It returns nil for grants that never expire.
+func Deadline(start metav1.Time, expiresAt *metav1.Time, ttl *metav1.Duration) *metav1.Time {
+	if expiresAt != nil {
+		deadline := *expiresAt
+		return &deadline
+	}
+	if ttl != nil {
+		deadline := metav1.NewTime(start.Add(ttl.Duration))
+		return &deadline
+	}
+	return nil
+}
+
+// Equal checks if two deadlines are the same time, or both unset
+func Equal(a *metav1.Time, b *metav1.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
+
+// Expired checks if a deadline has passed
+func Expired(deadline *metav1.Time, now time.Time) bool {
+	return deadline != nil && !now.Before(deadline.Time)
+}
+
+// Earliest returns the first of the deadlines that has not passed yet, or nil if there is none
+func Earliest(now time.Time, deadlines ...*metav1.Time) *metav1.Time {
+	var earliest *metav1.Time
+	for _, deadline := range deadlines {
+		if deadline == nil || Expired(deadline, now) {
+			continue
+		}
+		if earliest == nil || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	return earliest
+}
+
+// RequeueAfter returns when a resource must be reconciled again: after period, or sooner if its deadline passes before
+func RequeueAfter(deadline *metav1.Time, now time.Time, period time.Duration) time.Duration {
+	if deadline == nil {
+		return period
+	}
+	until := deadline.Sub(now)
+	if until >= period {
+		return period
+	}
+	if until < MinRequeue {
+		return MinRequeue
+	}
+	return until
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeadline(t *testing.T) {
+	start := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiresAt := metav1.NewTime(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Nil(t, Deadline(start, nil, nil))
+	assert.Equal(t, expiresAt, *Deadline(start, &expiresAt, nil))
+	assert.Equal(t, start.Add(72*time.Hour), Deadline(start, nil, &metav1.Duration{Duration: 72 * time.Hour}).Time)
+	assert.True(t, Equal(Deadline(start, nil, &metav1.Duration{Duration: 744 * time.Hour}), &expiresAt))
+	assert.False(t, Equal(nil, &expiresAt))
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := metav1.NewTime(now.Add(-time.Minute))
+	future := metav1.NewTime(now.Add(time.Minute))
+
+	assert.False(t, Expired(nil, now))
+	assert.True(t, Expired(&past, now))
+	assert.False(t, Expired(&future, now))
+	assert.Equal(t, &future, Earliest(now, nil, &past, &future))
+	assert.Nil(t, Earliest(now, &past))
+}
+
+func TestRequeueAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	period := 150 * time.Second
+	soon := metav1.NewTime(now.Add(30 * time.Second))
+	later := metav1.NewTime(now.Add(time.Hour))
+	past := metav1.NewTime(now.Add(-time.Hour))
+
+	assert.Equal(t, period, RequeueAfter(nil, now, period))
+	assert.Equal(t, period, RequeueAfter(&later, now, period))
+	assert.Equal(t, 30*time.Second, RequeueAfter(&soon, now, period))
+	assert.Equal(t, time.Second, RequeueAfter(&past, now, period))
+}
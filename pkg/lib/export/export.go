@@ -0,0 +1,353 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package export translates the access groups, custom roles and policies of an
+// IAM account into the custom resources managing them, so that an account
+// configured by hand can be brought under the operator. The resources carry the
+// annotations binding them to their IAM objects, so applying them adopts the
+// objects instead of creating duplicates.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// The kinds of the exported resources, as recorded in their fingerprint
+const (
+	accessGroupKind         = "AccessGroup"
+	customRoleKind          = "CustomRole"
+	accessPolicyKind        = "AccessPolicy"
+	authorizationPolicyKind = "AuthorizationPolicy"
+)
+
+// publicAccessGroupID is the access group of all users, which IAM manages
+const publicAccessGroupID = "AccessGroupId-PublicAccess"
+
+// maxNameLength keeps the names of the resources valid labels
+const maxNameLength = 63
+
+// Result are the resources managing the IAM objects of an account
+type Result struct {
+	CustomRoles           []ibmcloudv1alpha1.CustomRole
+	AccessGroups          []ibmcloudv1alpha1.AccessGroup
+	AccessPolicies        []ibmcloudv1alpha1.AccessPolicy
+	AuthorizationPolicies []ibmcloudv1alpha1.AuthorizationPolicy
+	// Skipped tells which IAM objects, or parts of them, have no resource and why
+	Skipped []string
+}
+
+// Objects returns the resources in the order they are applied, the custom roles
+// and access groups before the policies referencing them
+func (r *Result) Objects() []runtime.Object {
+	var objects []runtime.Object
+	for i := range r.CustomRoles {
+		objects = append(objects, &r.CustomRoles[i])
+	}
+	for i := range r.AccessGroups {
+		objects = append(objects, &r.AccessGroups[i])
+	}
+	for i := range r.AccessPolicies {
+		objects = append(objects, &r.AccessPolicies[i])
+	}
+	for i := range r.AuthorizationPolicies {
+		objects = append(objects, &r.AuthorizationPolicies[i])
+	}
+	return objects
+}
+
+// exporter translates the IAM objects of an account, remembering the names of
+// the resources referenced by others
+type exporter struct {
+	clients   iamclient.Clients
+	accountID string
+	namespace string
+	catalog   *rolecatalog.Catalog
+	result    *Result
+	// names are the names taken by kind
+	names map[string]map[string]bool
+	// users maps the IAM IDs of the users of the account to their login
+	users map[string]string
+	// groups maps the IDs of the exported access groups to their resource names
+	groups map[string]string
+	// roles maps the CRNs of the exported custom roles to their resource names
+	roles map[string]string
+}
+
+// Account exports the custom roles, access groups, access and authorization
+// policies of an account into resources of a namespace
+func Account(clients iamclient.Clients, accountID string, namespace string) (*Result, error) {
+	catalog, err := clients.RoleCatalog()
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{
+		clients:   clients,
+		accountID: accountID,
+		namespace: namespace,
+		catalog:   catalog,
+		result:    &Result{},
+		names:     map[string]map[string]bool{},
+		groups:    map[string]string{},
+		roles:     map[string]string{},
+	}
+	// Policies reference the custom roles and access groups by resource name
+	for _, export := range []func() error{e.customRoles, e.accessGroups, e.policies} {
+		if err := export(); err != nil {
+			return nil, err
+		}
+	}
+	return e.result, nil
+}
+
+func (e *exporter) customRoles() error {
+	roleAPI, err := e.clients.CustomRoles()
+	if err != nil {
+		return err
+	}
+	roles, err := roleAPI.ListCustomRoles(e.accountID, "")
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	for _, role := range roles {
+		instance := ibmcloudv1alpha1.CustomRole{
+			TypeMeta:   typeMeta(customRoleKind),
+			ObjectMeta: e.objectMeta(customRoleKind, role.Name, role.ID),
+			Spec: ibmcloudv1alpha1.CustomRoleSpec{
+				RoleName:     role.Name,
+				ServiceClass: role.ServiceName,
+				DisplayName:  role.DisplayName,
+				Description:  description(role.Description),
+				Actions:      role.Actions,
+			},
+		}
+		e.roles[role.Crn] = instance.Name
+		e.result.CustomRoles = append(e.result.CustomRoles, instance)
+	}
+	return nil
+}
+
+func (e *exporter) accessGroups() error {
+	groupAPI, err := e.clients.AccessGroups()
+	if err != nil {
+		return err
+	}
+	memberAPI, err := e.clients.AccessGroupMembers()
+	if err != nil {
+		return err
+	}
+	groups, err := groupAPI.List(e.accountID)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	for _, group := range groups {
+		if group.ID == publicAccessGroupID {
+			continue
+		}
+		members, err := memberAPI.List(group.ID)
+		if err != nil {
+			return err
+		}
+		var userEmails, serviceIDs []string
+		partial := false
+		for _, member := range members {
+			switch member.Type {
+			case iamuumv2.AccessGroupMemberUser:
+				email, err := e.user(member.ID)
+				if err != nil {
+					return err
+				}
+				if email == "" {
+					e.skip("member %s of access group %q: not a user of the account", member.ID, group.Name)
+					partial = true
+					continue
+				}
+				userEmails = append(userEmails, email)
+			case iamuumv2.AccessGroupMemberService:
+				serviceIDs = append(serviceIDs, serviceID(member.ID))
+			default:
+				e.skip("member %s of access group %q: members of type %s are not supported", member.ID, group.Name, member.Type)
+				partial = true
+			}
+		}
+		if len(userEmails) == 0 && len(serviceIDs) == 0 { // Access group resources have members
+			e.skip("access group %q: it has no user or service ID member", group.Name)
+			continue
+		}
+
+		instance := ibmcloudv1alpha1.AccessGroup{
+			TypeMeta:   typeMeta(accessGroupKind),
+			ObjectMeta: e.objectMeta(accessGroupKind, group.Name, group.ID),
+			Spec: ibmcloudv1alpha1.AccessGroupSpec{
+				Name:        group.Name,
+				Description: description(group.Description),
+				UserEmails:  userEmails,
+				ServiceIDs:  serviceIDs,
+			},
+		}
+		if partial { // Enforcing the spec would remove the skipped members from the group
+			instance.Spec.DriftPolicy = drift.Detect
+		}
+		e.groups[group.ID] = instance.Name
+		e.result.AccessGroups = append(e.result.AccessGroups, instance)
+	}
+	return nil
+}
+
+// user returns the login of the user of the account with an IAM ID, or "" if
+// there is no such user
+func (e *exporter) user(iamID string) (string, error) {
+	if e.users == nil {
+		accountAPI, err := e.clients.Accounts()
+		if err != nil {
+			return "", err
+		}
+		users, err := accountAPI.GetAccountUsers(e.accountID)
+		if err != nil {
+			return "", err
+		}
+		e.users = map[string]string{}
+		for _, user := range users {
+			if user.IbmUniqueId != "" {
+				e.users[user.IbmUniqueId] = user.UserId
+			}
+		}
+	}
+	return e.users[iamID], nil
+}
+
+// serviceID returns the UUID of a service ID from its IAM ID
+func serviceID(iamID string) string {
+	return strings.TrimPrefix(iamID, "iam-")
+}
+
+// description strips the marker the operator adds to the description of the
+// IAM objects it owns
+func description(d string) string {
+	return ownership.TrimMarker(d)
+}
+
+func (e *exporter) skip(format string, args ...interface{}) {
+	e.result.Skipped = append(e.result.Skipped, fmt.Sprintf(format, args...))
+}
+
+func typeMeta(kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{APIVersion: ibmcloudv1alpha1.SchemeGroupVersion.String(), Kind: kind}
+}
+
+// objectMeta names the resource of an IAM object after a name of the object,
+// and binds the resource to the object
+func (e *exporter) objectMeta(kind string, name string, iamID string) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{Name: e.uniqueName(kind, name), Namespace: e.namespace}
+	ownership.Record(kind, &meta, e.accountID, iamID)
+	return meta
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// uniqueName turns name into a resource name not taken by another resource of a kind
+func (e *exporter) uniqueName(kind string, name string) string {
+	base := invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(base) > maxNameLength-3 { // Room for a suffix
+		base = base[:maxNameLength-3]
+	}
+	base = strings.Trim(base, "-")
+	if base == "" {
+		base = strings.ToLower(kind)
+	}
+	if e.names[kind] == nil {
+		e.names[kind] = map[string]bool{}
+	}
+	unique := base
+	for i := 2; e.names[kind][unique]; i++ {
+		unique = base + "-" + strconv.Itoa(i)
+	}
+	e.names[kind][unique] = true
+	return unique
+}
+
+// dropUnset removes the structs whose fields are all empty strings, such as the
+// access group reference of a subject which is not an access group
+func dropUnset(fields map[string]interface{}) {
+	for key, value := range fields {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		dropUnset(nested)
+		unset := len(nested) > 0
+		for _, v := range nested {
+			if v != "" {
+				unset = false
+			}
+		}
+		if unset {
+			delete(fields, key)
+		}
+	}
+}
+
+// WriteYAML writes resources as a stream of YAML documents, without their status
+func WriteYAML(w io.Writer, objects []runtime.Object) error {
+	for i, obj := range objects {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		delete(fields, "status")
+		if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+			delete(metadata, "creationTimestamp")
+		}
+		dropUnset(fields)
+		data, err = yaml.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	poldiff "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/diff"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+const (
+	viewer = "crn:v1:bluemix:public:iam::::role:Viewer"
+	reader = "crn:v1:bluemix:public:cloud-object-storage::::serviceRole:Reader"
+	writer = "crn:v1:bluemix:public:cloud-object-storage::::serviceRole:Writer"
+)
+
+func attr(key string, value string) polv2.Attribute {
+	return polv2.Attribute{Key: key, Operator: "stringEquals", Value: value}
+}
+
+func policy(policyType string, subject []polv2.Attribute, roles []string, resource ...polv2.Attribute) polv2.Policy {
+	p := polv2.Policy{Type: policyType, Subject: polv2.Subject{Attributes: subject}}
+	for _, role := range roles {
+		p.Control.Grant.Roles = append(p.Control.Grant.Roles, polv2.Role{RoleID: role})
+	}
+	p.Resource.Attributes = append(resource, attr("accountId", fake.AccountID))
+	return p
+}
+
+// account returns an account configured by hand, with an object of each kind
+// and objects the resources cannot express
+func account(t *testing.T) (*fake.Factory, iamclient.Clients) {
+	f := fake.NewFactory()
+	alice := f.AddUser("alice@example.com")
+	ci := f.AddServiceID("", "ci")
+	deployer := f.AddCustomRole(iampapv2.CreateRoleRequest{
+		Name:        "Deployer",
+		ServiceName: "containers-kubernetes",
+		DisplayName: "Deployer",
+		Description: "Deploys applications",
+		Actions:     []string{"containers-kubernetes.cluster.read"},
+	})
+	developers := f.AddAccessGroup("Developers", "OPERATOR OWNED: Application developers")
+	require.NoError(t, f.AddMember(developers.ID, models.AccessGroupMemberV2{ID: alice.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser}))
+	require.NoError(t, f.AddMember(developers.ID, models.AccessGroupMemberV2{ID: ci.IAMID, Type: iamuumv2.AccessGroupMemberService}))
+	auditors := f.AddAccessGroup("Auditors", "Members are added by dynamic rules")
+	operators := f.AddAccessGroup("Operators", "Cluster operators")
+	require.NoError(t, f.AddMember(operators.ID, models.AccessGroupMemberV2{ID: alice.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser}))
+	require.NoError(t, f.AddMember(operators.ID, models.AccessGroupMemberV2{ID: "iam-Profile-1", Type: "Profile"}))
+
+	f.AddPolicy(policy("access", []polv2.Attribute{attr("access_group_id", developers.ID)}, []string{viewer, deployer.Crn},
+		attr("serviceName", "containers-kubernetes"), attr("region", "us-south")))
+	f.AddPolicy(policy("access", []polv2.Attribute{attr("access_group_id", auditors.ID)}, []string{viewer}))
+
+	weekly := policy("access", []polv2.Attribute{attr("iam_id", alice.IbmUniqueId)}, []string{reader},
+		attr("serviceName", "cloud-object-storage"), attr("serviceInstance", "instance-1"))
+	weekly.Rule = &polv2.Rule{Operator: polv2.OperatorAnd, Conditions: []polv2.Rule{
+		{Key: polv2.DayOfWeekKey, Operator: polv2.OperatorDayOfWeekAnyOf, Value: []interface{}{"1-05:00", "7-05:00"}},
+		{Key: polv2.CurrentTimeKey, Operator: polv2.OperatorTimeGreaterThanOrEquals, Value: "09:00:00-05:00"},
+		{Key: polv2.CurrentTimeKey, Operator: polv2.OperatorTimeLessThanOrEquals, Value: "17:30:00-05:00"},
+	}}
+	weekly.Pattern = polv2.PatternWeeklyCustomHours
+	f.AddPolicy(weekly)
+
+	once := policy("access", []polv2.Attribute{attr("iam_id", ci.IAMID)}, []string{writer},
+		attr("serviceName", "cloud-object-storage"), attr("resourceGroupId", "rg-1"),
+		polv2.Attribute{Key: "resource", Operator: "stringMatch", Value: "logs-*"})
+	once.Resource.Tags = []polv2.Attribute{attr("env", "prod")}
+	once.Rule = &polv2.Rule{Operator: polv2.OperatorAnd, Conditions: []polv2.Rule{
+		{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeGreaterThanOrEquals, Value: "2020-01-01T00:00:00+00:00"},
+		{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeLessThanOrEquals, Value: "2020-06-30T12:00:00+00:00"},
+	}}
+	once.Pattern = polv2.PatternOnce
+	f.AddPolicy(once)
+
+	f.AddPolicy(policy("access", []polv2.Attribute{attr("iam_id", "IBMid-stranger")}, []string{viewer}))
+	f.AddPolicy(policy("authorization",
+		[]polv2.Attribute{attr("serviceName", "cloud-object-storage"), attr("accountId", fake.AccountID)},
+		[]string{"crn:v1:bluemix:public:kms::::serviceRole:Reader"},
+		attr("serviceName", "kms")))
+
+	_, clients, err := f.Connect(context.Background(), nil, "default")
+	require.NoError(t, err)
+	return f, clients
+}
+
+func TestAccount(t *testing.T) {
+	_, clients := account(t)
+	result, err := Account(clients, fake.AccountID, "iam")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteYAML(&out, result.Objects()))
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(&out, "# skipped %s\n", skipped)
+	}
+
+	golden := filepath.Join("testdata", "account.golden")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, out.Bytes(), 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), out.String())
+}
+
+// resolver resolves the exported resources against the fake account
+type resolver struct {
+	*rolecatalog.Catalog
+	f      *fake.Factory
+	result *Result
+}
+
+func (r resolver) User(email string) (string, error) {
+	for _, user := range r.f.Users() {
+		if user.UserId == email {
+			return user.IbmUniqueId, nil
+		}
+	}
+	return "", fmt.Errorf("no user %s", email)
+}
+
+func (r resolver) ServiceID(uuid string) (string, error) {
+	return "iam-" + uuid, nil
+}
+
+func (r resolver) AccessGroup(id string) (string, error) {
+	return id, nil
+}
+
+func (r resolver) AccessGroupResource(namespace string, name string) (*ibmcloudv1alpha1.AccessGroup, error) {
+	for _, group := range r.result.AccessGroups {
+		if group.Namespace == namespace && group.Name == name {
+			group.Status.GroupID = ownership.RecordedID(accessGroupKind, &group, fake.AccountID)
+			return &group, nil
+		}
+	}
+	return nil, kerror.NewNotFound(schema.GroupResource{Group: "ibmcloud.ibm.com", Resource: "accessgroups"}, name)
+}
+
+func (r resolver) CustomRoleResource(namespace string, name string) (*ibmcloudv1alpha1.CustomRole, error) {
+	for _, role := range r.result.CustomRoles {
+		if role.Namespace == namespace && role.Name == name {
+			role.Status.RoleID = ownership.RecordedID(customRoleKind, &role, fake.AccountID)
+			return &role, nil
+		}
+	}
+	return nil, kerror.NewNotFound(schema.GroupResource{Group: "ibmcloud.ibm.com", Resource: "customroles"}, name)
+}
+
+// TestRoundTrip checks that the exported resources compile into the policies
+// they were exported from, so that adopting them changes nothing in IAM
+// stamped returns the only difference between a policy exported from IAM and the policy compiled
+// from its resource: adopting the policy stamps the ownership marker in its description
+func stamped(desired polv2.Policy) []drift.Difference {
+	return []drift.Difference{{Field: "description", Desired: desired.Description}}
+}
+
+func TestRoundTrip(t *testing.T) {
+	f, clients := account(t)
+	result, err := Account(clients, fake.AccountID, "iam")
+	require.NoError(t, err)
+	catalog, err := clients.RoleCatalog()
+	require.NoError(t, err)
+	r := resolver{Catalog: catalog, f: f, result: result}
+
+	policies := map[string]polv2.Policy{}
+	for _, p := range f.Policies() {
+		policies[p.ID] = p
+	}
+
+	require.Len(t, result.AccessPolicies, 4)
+	for i := range result.AccessPolicies {
+		instance := &result.AccessPolicies[i]
+		v1, err := compile.AccessPolicy(instance, fake.AccountID, "", r)
+		require.NoError(t, err, instance.Name)
+		desired := polv2.ConvertV1Policy(v1)
+		desired.Rule, desired.Pattern = compile.AccessPolicyRule(instance)
+		actual := policies[ownership.RecordedID(accessPolicyKind, instance, fake.AccountID)]
+		assert.Equal(t, stamped(desired), poldiff.Policies(desired, actual), instance.Name)
+	}
+
+	require.Len(t, result.AuthorizationPolicies, 1)
+	instance := &result.AuthorizationPolicies[0]
+	v1, err := compile.AuthorizationPolicy(instance, fake.AccountID, "", r)
+	require.NoError(t, err)
+	actual := policies[ownership.RecordedID(authorizationPolicyKind, instance, fake.AccountID)]
+	assert.Equal(t, stamped(polv2.ConvertV1Policy(v1)), poldiff.Policies(polv2.ConvertV1Policy(v1), actual))
+
+	for _, group := range f.AccessGroups() {
+		if group.Name == "Developers" {
+			assert.Equal(t, compile.AccessGroup(&result.AccessGroups[0], fake.AccountID, "").Description, group.Description)
+		}
+	}
+	role := f.CustomRoles()[0]
+	request := compile.CustomRole(&result.CustomRoles[0], fake.AccountID, "")
+	assert.Equal(t, role.Actions, request.Actions)
+	assert.Equal(t, role.DisplayName, request.DisplayName)
+}
+
+func TestUniqueName(t *testing.T) {
+	e := &exporter{names: map[string]map[string]bool{}}
+	assert.Equal(t, "my-group", e.uniqueName(accessGroupKind, "My Group!"))
+	assert.Equal(t, "my-group-2", e.uniqueName(accessGroupKind, "my_group"))
+	assert.Equal(t, "my-group", e.uniqueName(customRoleKind, "My Group"))
+	assert.Equal(t, "accessgroup", e.uniqueName(accessGroupKind, "???"))
+	assert.Len(t, e.uniqueName(accessGroupKind, string(bytes.Repeat([]byte("a"), 100))), maxNameLength-3)
+}
@@ -0,0 +1,351 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// utcOffset is the time zone offset of weekly schedules by default
+const utcOffset = "+00:00"
+
+func (e *exporter) policies() error {
+	policyAPI, err := e.clients.PoliciesV2()
+	if err != nil {
+		return err
+	}
+	policies, err := policyAPI.List(polv2.SearchParams{AccountID: e.accountID, Type: iampapv1.AccessPolicyType})
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if !active(policy) {
+			continue
+		}
+		instance, reason := e.accessPolicy(policy)
+		if instance == nil {
+			e.skip("access policy %s: %s", policy.ID, reason)
+			continue
+		}
+		e.result.AccessPolicies = append(e.result.AccessPolicies, *instance)
+	}
+
+	policies, err = policyAPI.List(polv2.SearchParams{AccountID: e.accountID, Type: iampapv1.AuthorizationPolicyType})
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if !active(policy) {
+			continue
+		}
+		instance, reason := e.authorizationPolicy(policy)
+		if instance == nil {
+			e.skip("authorization policy %s: %s", policy.ID, reason)
+			continue
+		}
+		e.result.AuthorizationPolicies = append(e.result.AuthorizationPolicies, *instance)
+	}
+	return nil
+}
+
+// active tells whether a policy is in force, IAM keeping deleted policies for a while
+func active(policy polv2.Policy) bool {
+	return policy.State == "" || policy.State == "active"
+}
+
+// accessPolicy returns the resource of an access policy, or why there is none
+func (e *exporter) accessPolicy(policy polv2.Policy) (*ibmcloudv1alpha1.AccessPolicy, string) {
+	subject, subjectName, reason := e.accessPolicySubject(policy.Subject.Attributes)
+	if reason != "" {
+		return nil, reason
+	}
+	target := accessPolicyTarget(policy.Resource)
+	conditions, ok := accessPolicyConditions(policy.Rule, policy.Pattern)
+	if !ok {
+		return nil, fmt.Sprintf("conditions of pattern %q are not supported", policy.Pattern)
+	}
+
+	serviceName := target.ServiceClass
+	if serviceName == "" {
+		serviceName = "account"
+	}
+	return &ibmcloudv1alpha1.AccessPolicy{
+		TypeMeta:   typeMeta(accessPolicyKind),
+		ObjectMeta: e.objectMeta(accessPolicyKind, subjectName+"-"+serviceName, policy.ID),
+		Spec: ibmcloudv1alpha1.AccessPolicySpec{
+			Subject:    subject,
+			Roles:      e.accessPolicyRoles(target.ServiceClass, policy.Control.Grant.Roles),
+			Target:     target,
+			Conditions: conditions,
+		},
+	}, ""
+}
+
+// accessPolicySubject returns the subject of an access policy and a name for it,
+// referencing the exported access group when the subject is one
+func (e *exporter) accessPolicySubject(attributes []polv2.Attribute) (ibmcloudv1alpha1.Subject, string, string) {
+	if groupID := attributeValue(attributes, "access_group_id"); groupID != "" {
+		if name, ok := e.groups[groupID]; ok {
+			return ibmcloudv1alpha1.Subject{AccessGroupDef: ibmcloudv1alpha1.AccessGroupDef{AccessGroupName: name, AccessGroupNamespace: e.namespace}}, name, ""
+		}
+		return ibmcloudv1alpha1.Subject{AccessGroupID: groupID}, groupID, ""
+	}
+
+	iamID := attributeValue(attributes, "iam_id")
+	if strings.HasPrefix(iamID, "iam-ServiceId-") {
+		return ibmcloudv1alpha1.Subject{ServiceID: serviceID(iamID)}, serviceID(iamID), ""
+	}
+	if iamID != "" {
+		email, err := e.user(iamID)
+		if err != nil {
+			return ibmcloudv1alpha1.Subject{}, "", err.Error()
+		}
+		if email != "" {
+			return ibmcloudv1alpha1.Subject{UserEmail: email}, strings.SplitN(email, "@", 2)[0], ""
+		}
+	}
+	return ibmcloudv1alpha1.Subject{}, "", "the subject is not an access group, a service ID or a user of the account"
+}
+
+// accessPolicyTarget returns the target of an access policy
+func accessPolicyTarget(resource polv2.Resource) ibmcloudv1alpha1.Target {
+	target := ibmcloudv1alpha1.Target{}
+	for _, a := range resource.Attributes {
+		if !exact(a) {
+			target.Attributes = append(target.Attributes, attribute(a))
+			continue
+		}
+		switch a.Key {
+		case iampapv1.AccountIDAttribute:
+		case iampapv1.ServiceNameAttribute:
+			target.ServiceClass = a.Value
+		case iampapv1.ServiceInstanceAttribute:
+			target.ServiceID = a.Value
+		case iampapv1.ResourceTypeAttribute:
+			target.ResourceName = a.Value
+		case iampapv1.ResourceAttribute:
+			target.ResourceID = a.Value
+		case iampapv1.ResourceGroupIDAttribute:
+			target.ResourceGroup = a.Value
+		case iampapv1.RegionAttribute:
+			target.Region = a.Value
+		default:
+			target.Attributes = append(target.Attributes, attribute(a))
+		}
+	}
+	for _, t := range resource.Tags {
+		a := attribute(t)
+		target.Tags = append(target.Tags, ibmcloudv1alpha1.Tag{Key: a.Name, Value: a.Value, Operator: a.Operator})
+	}
+	return target
+}
+
+// accessPolicyRoles returns the roles of an access policy, referencing the
+// exported custom roles and naming the others after their display name
+func (e *exporter) accessPolicyRoles(serviceClass string, roles []polv2.Role) ibmcloudv1alpha1.Roles {
+	result := ibmcloudv1alpha1.Roles{}
+	catalogRoles, _ := e.catalog.ServiceRoles(serviceClass)
+	for _, role := range roles {
+		if name, ok := e.roles[role.RoleID]; ok {
+			result.CustomRolesDef = append(result.CustomRolesDef, ibmcloudv1alpha1.CustomRolesDef{CustomRoleName: name, CustomRoleNamespace: e.namespace})
+			continue
+		}
+		result.DefinedRoles = append(result.DefinedRoles, roleName(catalogRoles, role.RoleID))
+	}
+	return result
+}
+
+// roleName returns the display name of the role with a CRN, or the CRN, which
+// roles are also resolved by, if the role is unknown
+func roleName(roles []rolecatalog.Role, crn string) string {
+	for _, role := range roles {
+		if role.CRN == crn && role.DisplayName != "" {
+			return role.DisplayName
+		}
+	}
+	return crn
+}
+
+// accessPolicyConditions translates the rule of an access policy into its
+// conditions, the opposite of compile.AccessPolicyRule
+func accessPolicyConditions(rule *polv2.Rule, pattern string) (*ibmcloudv1alpha1.PolicyConditions, bool) {
+	if rule == nil {
+		return nil, true
+	}
+	conditions := &ibmcloudv1alpha1.PolicyConditions{}
+	switch pattern {
+	case polv2.PatternOnce:
+		for _, c := range rule.Conditions {
+			value, _ := c.Value.(string)
+			t, err := time.Parse(time.RFC3339, value)
+			if c.Key != polv2.CurrentDateTimeKey || err != nil {
+				return nil, false
+			}
+			at := metav1.NewTime(t.UTC())
+			switch c.Operator {
+			case polv2.OperatorDateTimeGreaterThanOrEquals:
+				conditions.NotBefore = &at
+			case polv2.OperatorDateTimeLessThanOrEquals:
+				conditions.NotAfter = &at
+			default:
+				return nil, false
+			}
+		}
+		return conditions, conditions.NotBefore != nil
+
+	case polv2.PatternWeeklyAllDay, polv2.PatternWeeklyCustomHours:
+		weekly := &ibmcloudv1alpha1.WeeklySchedule{}
+		offset := ""
+		for _, c := range rule.Conditions {
+			value, _ := c.Value.(string)
+			switch {
+			case c.Key == polv2.DayOfWeekKey && c.Operator == polv2.OperatorDayOfWeekAnyOf:
+				for _, day := range stringValues(c.Value) {
+					number := int(day[0] - '0')
+					if number < 1 || number > 7 {
+						return nil, false
+					}
+					weekly.Days = append(weekly.Days, time.Weekday(number%7).String())
+					offset = day[1:]
+				}
+			case c.Key == polv2.CurrentTimeKey && len(value) > len("15:04:05"):
+				if c.Operator == polv2.OperatorTimeGreaterThanOrEquals {
+					weekly.StartTime = value[:len("15:04")]
+				} else if c.Operator == polv2.OperatorTimeLessThanOrEquals {
+					weekly.EndTime = value[:len("15:04")]
+				} else {
+					return nil, false
+				}
+				offset = value[len("15:04:05"):]
+			default:
+				return nil, false
+			}
+		}
+		if offset != utcOffset {
+			weekly.TimeZoneOffset = offset
+		}
+		conditions.Weekly = weekly
+		return conditions, len(weekly.Days) > 0
+	}
+	return nil, false
+}
+
+// stringValues returns the strings of a rule value, decoded from JSON or not
+func stringValues(value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case []string:
+		values = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	var result []string
+	for _, s := range values {
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// authorizationPolicy returns the resource of an authorization policy, or why there is none
+func (e *exporter) authorizationPolicy(policy polv2.Policy) (*ibmcloudv1alpha1.AuthorizationPolicy, string) {
+	source := info(policy.Subject.Attributes)
+	target := info(policy.Resource.Attributes)
+	if source.ServiceClass == "" || target.ServiceClass == "" {
+		return nil, "the source or target has no service"
+	}
+
+	catalogRoles, _ := e.catalog.AuthorizationRoles(source.ServiceClass, target.ServiceClass)
+	var roles []string
+	for _, role := range policy.Control.Grant.Roles {
+		roles = append(roles, roleName(catalogRoles, role.RoleID))
+	}
+	return &ibmcloudv1alpha1.AuthorizationPolicy{
+		TypeMeta:   typeMeta(authorizationPolicyKind),
+		ObjectMeta: e.objectMeta(authorizationPolicyKind, source.ServiceClass+"-"+target.ServiceClass, policy.ID),
+		Spec: ibmcloudv1alpha1.AuthorizationPolicySpec{
+			Source: source,
+			Roles:  roles,
+			Target: target,
+		},
+	}, ""
+}
+
+// info returns the source or target of an authorization policy
+func info(attributes []polv2.Attribute) ibmcloudv1alpha1.Info {
+	result := ibmcloudv1alpha1.Info{}
+	for _, a := range attributes {
+		if !exact(a) {
+			result.Attributes = append(result.Attributes, attribute(a))
+			continue
+		}
+		switch a.Key {
+		case iampapv1.AccountIDAttribute:
+		case iampapv1.ServiceNameAttribute:
+			result.ServiceClass = a.Value
+		case iampapv1.ServiceInstanceAttribute:
+			result.ServiceID = a.Value
+		case iampapv1.ResourceTypeAttribute:
+			result.ResourceName = a.Value
+		case iampapv1.ResourceAttribute:
+			result.ResourceID = a.Value
+		case iampapv1.ResourceGroupIDAttribute:
+			result.ResourceGroup = a.Value
+		default:
+			result.Attributes = append(result.Attributes, attribute(a))
+		}
+	}
+	return result
+}
+
+// exact tells whether an attribute matches its value exactly
+func exact(a polv2.Attribute) bool {
+	return a.Operator == "" || a.Operator == polv1.OperatorStringEquals
+}
+
+// attribute returns a policy attribute as a resource attribute, without the default operator
+func attribute(a polv2.Attribute) ibmcloudv1alpha1.Attribute {
+	result := ibmcloudv1alpha1.Attribute{Name: a.Key, Value: a.Value}
+	if !exact(a) {
+		result.Operator = a.Operator
+	}
+	return result
+}
+
+func attributeValue(attributes []polv2.Attribute, key string) string {
+	for _, a := range attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package health reports whether the replica leads the other replicas and whether IAM is available, with
+// readiness checks and metrics. Every replica serves the access request webhook, so the readiness probe of
+// the pods excludes these checks: each replica is ready while it answers, whether it leads or IAM is failing.
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+)
+
+// ErrNotLeader is reported by the readiness check of a replica waiting to lead
+var ErrNotLeader = errors.New("waiting for the leader election")
+
+// Leader tracks whether the replica leads, and reports it with the leader metric. Added to a
+// manager, it is started with the controllers once the replica is elected, or right away
+// without leader election.
+type Leader struct {
+	leading int32
+}
+
+// Start marks the replica as leading until stop is closed
+func (l *Leader) Start(stop <-chan struct{}) error {
+	atomic.StoreInt32(&l.leading, 1)
+	metrics.Leading(true)
+	<-stop
+	atomic.StoreInt32(&l.leading, 0)
+	metrics.Leading(false)
+	return nil
+}
+
+// Check is a readiness check failing while the replica doesn't lead
+func (l *Leader) Check(req *http.Request) error {
+	if atomic.LoadInt32(&l.leading) == 0 {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// IAM is a readiness check failing while the circuit breaker of an account is open, i.e.
+// IAM kept failing with transient errors
+func IAM(req *http.Request) error {
+	if accounts := resilience.UnavailableAccounts(); len(accounts) > 0 {
+		return fmt.Errorf("IAM is unavailable for accounts %s", strings.Join(accounts, ", "))
+	}
+	return nil
+}
+
+// UnavailableAccounts counts the accounts whose circuit breaker is open, for the iam_unavailable_accounts metric
+func UnavailableAccounts() float64 {
+	return float64(len(resilience.UnavailableAccounts()))
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeader(t *testing.T) {
+	leader := &Leader{}
+	assert.Equal(t, ErrNotLeader, leader.Check(nil))
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		leader.Start(stop)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return leader.Check(nil) == nil }, time.Second, time.Millisecond*10)
+
+	close(stop)
+	<-done
+	assert.Equal(t, ErrNotLeader, leader.Check(nil))
+}
+
+func TestIAM(t *testing.T) {
+	assert.NoError(t, IAM(nil))
+	assert.Equal(t, float64(0), UnavailableAccounts())
+}
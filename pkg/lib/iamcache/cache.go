@@ -0,0 +1,512 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iamcache keeps an account-wide copy of IAM state, refreshed in bulk, so
+// controllers can look up policies, access groups, custom roles and service IDs
+// without calling IAM on every reconcile. The members of access groups are read
+// as they are looked up, since listing them takes a request per group.
+package iamcache
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/registry"
+)
+
+// DefaultPeriod is how long cached IAM state is used before it is listed again
+const DefaultPeriod = time.Minute * 2
+
+// Service IDs are listed by the CRN of the account they are bound to
+const (
+	cloudName = "bluemix"
+	cloudType = "public"
+)
+
+var clog = logf.Log.WithName("iamcache")
+
+// Clients are the IAM APIs the cache reads from
+type Clients struct {
+	Policies     polv1.PolicyRepository
+	AccessGroups iamuumv2.AccessGroupRepository
+	Members      iamuumv2.AccessGroupMemberRepositoryV2
+	CustomRoles  iampapv2.RoleRepository
+	ServiceIDs   iamv1.ServiceIDRepository
+}
+
+// NewClients creates the IAM APIs the cache reads from
+func NewClients(sess *session.Session) (Clients, error) {
+	policyAPI, err := polv1.New(sess)
+	if err != nil {
+		return Clients{}, err
+	}
+	iamClient, err := iamv1.New(sess)
+	if err != nil {
+		return Clients{}, err
+	}
+	iamuumClient, err := iamuumv2.New(sess)
+	if err != nil {
+		return Clients{}, err
+	}
+	roleClient, err := iampapv2.New(sess)
+	if err != nil {
+		return Clients{}, err
+	}
+	return Clients{
+		Policies:     policyAPI,
+		AccessGroups: iamuumClient.AccessGroup(),
+		Members:      iamuumClient.AccessGroupMember(),
+		CustomRoles:  roleClient.IAMRoles(),
+		ServiceIDs:   iamClient.ServiceIds(),
+	}, nil
+}
+
+var caches = registry.New()
+
+// ForAccount returns the cache shared by all controllers for an account and the
+// API key of the session, so that each API key only sees what it may read.
+func ForAccount(sess *session.Session, accountID string) (*Cache, error) {
+	cache, err := caches.Get(accountID, sess.Config.BluemixAPIKey, func() (interface{}, error) {
+		clients, err := NewClients(sess)
+		if err != nil {
+			return nil, err
+		}
+		cache := New(accountID, clients, DefaultPeriod)
+		cache.background = true
+		return cache, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cache.(*Cache), nil
+}
+
+// Evict drops the cache of a registry key, e.g. when its API key is no longer configured for any namespace
+func Evict(key string) {
+	caches.Evict(key)
+}
+
+// Cache holds the IAM state of an account. It is listed in bulk when it is older
+// than its period, and lookups that miss fall back to reading IAM directly.
+type Cache struct {
+	accountID string
+	period    time.Duration
+	// background lists the IAM state in a goroutine rather than in the lookup that finds it old,
+	// so that reconciles never wait for it
+	background bool
+	loading    sync.WaitGroup
+
+	mu          sync.Mutex
+	clients     Clients
+	state       *snapshot
+	refreshing  bool
+	refreshed   time.Time
+	invalidated map[string]time.Time
+}
+
+// New creates a cache of the IAM state of an account
+func New(accountID string, clients Clients, period time.Duration) *Cache {
+	return &Cache{
+		accountID:   accountID,
+		period:      period,
+		clients:     clients,
+		state:       newSnapshot(),
+		invalidated: map[string]time.Time{},
+	}
+}
+
+// Refresh lists the IAM state of the account if it is older than the period. If
+// listing fails, cached state is dropped so lookups read IAM directly.
+func (c *Cache) Refresh() {
+	if clients, ok := c.stale(); ok {
+		c.reload(clients)
+	}
+}
+
+// refresh refreshes the IAM state before a lookup, in a goroutine for a background cache
+func (c *Cache) refresh() {
+	clients, ok := c.stale()
+	if !ok {
+		return
+	}
+	if !c.background {
+		c.reload(clients)
+		return
+	}
+	c.loading.Add(1)
+	go func() {
+		defer c.loading.Done()
+		c.reload(clients)
+	}()
+}
+
+// stale returns the clients to list the IAM state with if it is older than the period, and no
+// refresh is under way, and marks the cache as refreshing
+func (c *Cache) stale() (Clients, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refreshing || (!c.refreshed.IsZero() && time.Since(c.refreshed) < c.period) {
+		return Clients{}, false
+	}
+	c.refreshing = true
+	return c.clients, true
+}
+
+// reload lists the IAM state of the account and replaces the cached state
+func (c *Cache) reload(clients Clients) {
+	start := time.Now()
+	state, err := load(c.accountID, clients)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	c.refreshed = time.Now()
+	if err != nil {
+		clog.Info("Error listing IAM state, reading IAM directly until the next refresh", "Account", c.accountID, "Failed", err.Error())
+		c.state = newSnapshot()
+		return
+	}
+	for id, at := range c.invalidated { // Don't resurrect objects written while listing
+		if !at.Before(start) {
+			state.remove(id)
+		} else {
+			delete(c.invalidated, id)
+		}
+	}
+	state.loaded = true
+	c.state = state
+	clog.V(1).Info("Refreshed IAM state", "Account", c.accountID, "Policies", len(state.policies), "Groups", len(state.groups), "Roles", len(state.roles), "Service IDs", len(state.serviceIDs))
+}
+
+// Invalidate drops IAM objects from the cache, e.g. after they were written, so the
+// next lookup reads them from IAM
+func (c *Cache) Invalidate(ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		c.state.remove(id)
+		c.invalidated[id] = now
+	}
+}
+
+// Expire drops the cached IAM state, so that lookups read IAM directly until it is listed again, e.g. on a
+// forced resync
+func (c *Cache) Expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshed = time.Time{}
+	c.state = newSnapshot()
+}
+
+// Policy returns an access or authorization policy. Cached policies have no
+// version, read the policy from IAM for the etag before updating it.
+func (c *Cache) Policy(id string) (polv1.Policy, error) {
+	c.refresh()
+	c.mu.Lock()
+	policy, ok := c.state.policies[id]
+	clients := c.clients
+	c.mu.Unlock()
+	metrics.CacheLookup("iam", "policy", ok)
+	if ok {
+		return policy, nil
+	}
+
+	policy, err := clients.Policies.Get(id)
+	if err != nil {
+		return policy, err
+	}
+	c.mu.Lock()
+	c.state.putPolicy(policy)
+	c.mu.Unlock()
+	return policy, nil
+}
+
+// AccessGroup returns an access group. Read the group from IAM for the etag before updating it.
+func (c *Cache) AccessGroup(id string) (*models.AccessGroupV2, error) {
+	c.refresh()
+	c.mu.Lock()
+	group, ok := c.state.groups[id]
+	clients := c.clients
+	c.mu.Unlock()
+	metrics.CacheLookup("iam", "accessgroup", ok)
+	if ok {
+		return &group, nil
+	}
+
+	retrieved, _, err := clients.AccessGroups.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.state.putGroup(*retrieved)
+	c.mu.Unlock()
+	return retrieved, nil
+}
+
+// Members returns the members of an access group
+func (c *Cache) Members(groupID string) ([]models.AccessGroupMemberV2, error) {
+	c.refresh()
+	c.mu.Lock()
+	members, ok := c.state.members[groupID]
+	clients := c.clients
+	c.mu.Unlock()
+	metrics.CacheLookup("iam", "members", ok)
+	if ok {
+		return members, nil
+	}
+
+	members, err := clients.Members.List(groupID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.state.members[groupID] = members
+	c.mu.Unlock()
+	return members, nil
+}
+
+// CustomRole returns a custom role. Read the role from IAM for the etag before updating it.
+func (c *Cache) CustomRole(id string) (iampapv2.Role, error) {
+	c.refresh()
+	c.mu.Lock()
+	role, ok := c.state.roles[id]
+	clients := c.clients
+	c.mu.Unlock()
+	metrics.CacheLookup("iam", "customrole", ok)
+	if ok {
+		return role, nil
+	}
+
+	role, _, err := clients.CustomRoles.Get(id)
+	if err != nil {
+		return role, err
+	}
+	c.mu.Lock()
+	c.state.putRole(role)
+	c.mu.Unlock()
+	return role, nil
+}
+
+// CustomRoles returns the custom roles of a service, or of all services if serviceName is ""
+func (c *Cache) CustomRoles(serviceName string) ([]iampapv2.Role, error) {
+	c.refresh()
+	c.mu.Lock()
+	var roles []iampapv2.Role
+	if c.state.loaded {
+		ids := c.state.byService.get(serviceName)
+		if serviceName == "" {
+			ids = c.state.byService.all()
+		}
+		for _, id := range ids {
+			roles = append(roles, c.state.roles[id])
+		}
+	}
+	clients := c.clients
+	c.mu.Unlock()
+	metrics.CacheLookup("iam", "customrole", len(roles) > 0)
+	if len(roles) > 0 {
+		return roles, nil
+	}
+
+	return c.listCustomRoles(clients, serviceName)
+}
+
+// ListCustomRoles lists the custom roles of a service, or of all services if serviceName is "", from IAM
+// and caches them, e.g. when a role name matches none of the cached roles
+func (c *Cache) ListCustomRoles(serviceName string) ([]iampapv2.Role, error) {
+	c.mu.Lock()
+	clients := c.clients
+	c.mu.Unlock()
+	return c.listCustomRoles(clients, serviceName)
+}
+
+func (c *Cache) listCustomRoles(clients Clients, serviceName string) ([]iampapv2.Role, error) {
+	roles, err := clients.CustomRoles.ListCustomRoles(c.accountID, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	for _, role := range roles {
+		c.state.putRole(role)
+	}
+	c.mu.Unlock()
+	return roles, nil
+}
+
+// ServiceID returns a service ID by UUID
+func (c *Cache) ServiceID(uuid string) (models.ServiceID, error) {
+	c.refresh()
+	c.mu.Lock()
+	serviceID, ok := c.state.serviceIDs[uuid]
+	clients := c.clients
+	c.mu.Unlock()
+	metrics.CacheLookup("iam", "serviceid", ok)
+	if ok {
+		return serviceID, nil
+	}
+
+	serviceID, err := clients.ServiceIDs.Get(uuid)
+	if err != nil {
+		return serviceID, err
+	}
+	c.mu.Lock()
+	c.state.serviceIDs[uuid] = serviceID
+	c.mu.Unlock()
+	return serviceID, nil
+}
+
+// load lists the IAM state of an account
+func load(accountID string, clients Clients) (*snapshot, error) {
+	state := newSnapshot()
+
+	for _, policyType := range []string{"access", "authorization"} {
+		policies, err := clients.Policies.List(polv1.SearchParams{AccountID: accountID, Type: policyType})
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			state.putPolicy(policy)
+		}
+	}
+
+	groups, err := clients.AccessGroups.List(accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		state.putGroup(group)
+	}
+
+	roles, err := clients.CustomRoles.ListCustomRoles(accountID, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		state.putRole(role)
+	}
+
+	boundTo := crn.New(cloudName, cloudType)
+	boundTo.ScopeType = crn.ScopeAccount
+	boundTo.Scope = accountID
+	serviceIDs, err := clients.ServiceIDs.List(boundTo.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, serviceID := range serviceIDs {
+		state.serviceIDs[serviceID.UUID] = serviceID
+	}
+	return state, nil
+}
+
+// snapshot is the IAM state of an account with its indexes
+type snapshot struct {
+	loaded     bool
+	policies   map[string]polv1.Policy
+	groups     map[string]models.AccessGroupV2
+	members    map[string][]models.AccessGroupMemberV2
+	roles      map[string]iampapv2.Role
+	serviceIDs map[string]models.ServiceID
+
+	byService index
+}
+
+func newSnapshot() *snapshot {
+	return &snapshot{
+		policies:   map[string]polv1.Policy{},
+		groups:     map[string]models.AccessGroupV2{},
+		members:    map[string][]models.AccessGroupMemberV2{},
+		roles:      map[string]iampapv2.Role{},
+		serviceIDs: map[string]models.ServiceID{},
+		byService:  index{},
+	}
+}
+
+func (s *snapshot) putPolicy(policy polv1.Policy) {
+	s.remove(policy.ID)
+	s.policies[policy.ID] = policy
+}
+
+func (s *snapshot) putGroup(group models.AccessGroupV2) {
+	s.remove(group.ID)
+	s.groups[group.ID] = group
+}
+
+func (s *snapshot) putRole(role iampapv2.Role) {
+	s.remove(role.ID)
+	s.roles[role.ID] = role
+	s.byService.add(role.ServiceName, role.ID)
+}
+
+// remove drops an IAM object of any kind, IDs are unique across kinds
+func (s *snapshot) remove(id string) {
+	delete(s.policies, id)
+	delete(s.groups, id)
+	delete(s.members, id)
+	if role, ok := s.roles[id]; ok {
+		s.byService.remove(role.ServiceName, id)
+		delete(s.roles, id)
+	}
+	delete(s.serviceIDs, id)
+}
+
+// index maps a key to a set of IDs
+type index map[string]map[string]bool
+
+func (i index) add(key string, id string) {
+	if i[key] == nil {
+		i[key] = map[string]bool{}
+	}
+	i[key][id] = true
+}
+
+func (i index) remove(key string, id string) {
+	delete(i[key], id)
+	if len(i[key]) == 0 {
+		delete(i, key)
+	}
+}
+
+// get returns the IDs of a key in order
+func (i index) get(key string) []string {
+	var ids []string
+	for id := range i[key] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// all returns the IDs of all keys in order
+func (i index) all() []string {
+	var ids []string
+	for key := range i {
+		ids = append(ids, i.get(key)...)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,374 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iamcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+)
+
+// fakeIAM serves the IAM state of an account and counts the calls made to it
+type fakeIAM struct {
+	policies   map[string]polv1.Policy
+	groups     map[string]models.AccessGroupV2
+	members    map[string][]models.AccessGroupMemberV2
+	roles      map[string]iampapv2.Role
+	serviceIDs map[string]models.ServiceID
+	listErr    error
+	onList     func()
+	lists      int
+	gets       int
+}
+
+func newFakeIAM() *fakeIAM {
+	subject := polv1.Subject{}
+	subject.SetAttribute("iam_id", "iam-ServiceId-123")
+	role := iampapv2.Role{ID: "role-1"}
+	role.ServiceName = "kms"
+	role.DisplayName = "Reader"
+	group := models.AccessGroupV2{}
+	group.ID = "AccessGroupId-1"
+	group.Name = "admins"
+	return &fakeIAM{
+		policies: map[string]polv1.Policy{
+			"policy-1": {ID: "policy-1", Type: "access", Subjects: []polv1.Subject{subject}},
+			"policy-2": {ID: "policy-2", Type: "authorization"},
+		},
+		groups:     map[string]models.AccessGroupV2{group.ID: group},
+		members:    map[string][]models.AccessGroupMemberV2{group.ID: {{ID: "iam-ServiceId-123", Type: "service"}}},
+		roles:      map[string]iampapv2.Role{role.ID: role},
+		serviceIDs: map[string]models.ServiceID{"ServiceId-123": {UUID: "ServiceId-123", IAMID: "iam-ServiceId-123"}},
+	}
+}
+
+func (f *fakeIAM) clients() Clients {
+	return Clients{
+		Policies:     fakePolicies{f},
+		AccessGroups: fakeGroups{fakeIAM: f},
+		Members:      fakeMembers{f},
+		CustomRoles:  fakeRoles{f},
+		ServiceIDs:   fakeServiceIDs{fakeIAM: f},
+	}
+}
+
+type fakePolicies struct{ *fakeIAM }
+
+func (f fakePolicies) List(params polv1.SearchParams) ([]polv1.Policy, error) {
+	f.lists++
+	if f.onList != nil {
+		f.onList()
+	}
+	var policies []polv1.Policy
+	for _, policy := range f.policies {
+		if params.Type == "" || policy.Type == params.Type {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, f.listErr
+}
+
+func (f fakePolicies) Get(id string) (polv1.Policy, error) {
+	f.gets++
+	policy, ok := f.policies[id]
+	if !ok {
+		return policy, errors.New("not found")
+	}
+	return policy, nil
+}
+
+func (f fakePolicies) Create(policy polv1.Policy) (polv1.Policy, error) { return policy, nil }
+func (f fakePolicies) Update(id string, policy polv1.Policy, version string) (polv1.Policy, error) {
+	return policy, nil
+}
+func (f fakePolicies) Delete(id string) error { return nil }
+
+type fakeGroups struct {
+	iamuumv2.AccessGroupRepository
+	*fakeIAM
+}
+
+func (f fakeGroups) List(accountID string) ([]models.AccessGroupV2, error) {
+	f.lists++
+	var groups []models.AccessGroupV2
+	for _, group := range f.groups {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (f fakeGroups) Get(id string) (*models.AccessGroupV2, string, error) {
+	f.gets++
+	group, ok := f.groups[id]
+	if !ok {
+		return nil, "", errors.New("Failed to find")
+	}
+	return &group, "etag", nil
+}
+
+func (f fakeGroups) FindByName(name string, accountID string) ([]models.AccessGroupV2, error) {
+	f.gets++
+	var groups []models.AccessGroupV2
+	for _, group := range f.groups {
+		if group.Name == name {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+type fakeMembers struct{ *fakeIAM }
+
+func (f fakeMembers) List(groupID string) ([]models.AccessGroupMemberV2, error) {
+	f.lists++
+	return f.members[groupID], nil
+}
+
+func (f fakeMembers) Add(groupID string, request iamuumv2.AddGroupMemberRequestV2) (iamuumv2.AddGroupMemberResponseV2, error) {
+	return iamuumv2.AddGroupMemberResponseV2{}, nil
+}
+
+func (f fakeMembers) Remove(groupID string, memberID string) error { return nil }
+
+type fakeRoles struct{ *fakeIAM }
+
+func (f fakeRoles) ListCustomRoles(accountID, serviceName string) ([]iampapv2.Role, error) {
+	f.lists++
+	var roles []iampapv2.Role
+	for _, role := range f.roles {
+		if serviceName == "" || role.ServiceName == serviceName {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (f fakeRoles) Get(id string) (iampapv2.Role, string, error) {
+	f.gets++
+	role, ok := f.roles[id]
+	if !ok {
+		return role, "", errors.New("not found")
+	}
+	return role, "etag", nil
+}
+
+func (f fakeRoles) Create(request iampapv2.CreateRoleRequest) (iampapv2.Role, error) {
+	return iampapv2.Role{}, nil
+}
+func (f fakeRoles) Update(request iampapv2.UpdateRoleRequest, roleID, etag string) (iampapv2.Role, error) {
+	return iampapv2.Role{}, nil
+}
+func (f fakeRoles) Delete(roleID string) error                                   { return nil }
+func (f fakeRoles) ListSystemDefinedRoles() ([]iampapv2.Role, error)             { return nil, nil }
+func (f fakeRoles) ListServiceRoles(serviceName string) ([]iampapv2.Role, error) { return nil, nil }
+func (f fakeRoles) ListAll(query iampapv2.RoleQuery) ([]iampapv2.Role, error)    { return nil, nil }
+
+type fakeServiceIDs struct {
+	iamv1.ServiceIDRepository
+	*fakeIAM
+}
+
+func (f fakeServiceIDs) List(boundTo string) ([]models.ServiceID, error) {
+	f.lists++
+	var serviceIDs []models.ServiceID
+	for _, serviceID := range f.serviceIDs {
+		serviceIDs = append(serviceIDs, serviceID)
+	}
+	return serviceIDs, nil
+}
+
+func (f fakeServiceIDs) Get(uuid string) (models.ServiceID, error) {
+	f.gets++
+	serviceID, ok := f.serviceIDs[uuid]
+	if !ok {
+		return serviceID, errors.New("not found")
+	}
+	return serviceID, nil
+}
+
+func TestCacheHit(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+
+	cache.Refresh()
+	// Two policy types, groups, roles and service IDs are listed once
+	assert.Equal(t, 5, iam.lists)
+	policy, err := cache.Policy("policy-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "authorization", policy.Type)
+	group, err := cache.AccessGroup("AccessGroupId-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "admins", group.Name)
+	// The members of a group are listed as they are looked up
+	members, err := cache.Members("AccessGroupId-1")
+	assert.NoError(t, err)
+	assert.Len(t, members, 1)
+	_, err = cache.Members("AccessGroupId-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, iam.lists)
+	role, err := cache.CustomRole("role-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Reader", role.DisplayName)
+	serviceID, err := cache.ServiceID("ServiceId-123")
+	assert.NoError(t, err)
+	assert.Equal(t, "iam-ServiceId-123", serviceID.IAMID)
+
+	assert.Equal(t, 6, iam.lists)
+	assert.Equal(t, 0, iam.gets)
+}
+
+func TestCacheIndexes(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+
+	roles, err := cache.CustomRoles("kms")
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	roles, err = cache.CustomRoles("")
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	assert.Equal(t, 5, iam.lists)
+
+	// Nothing cached for a key falls back to IAM
+	roles, err = cache.CustomRoles("messagehub")
+	assert.NoError(t, err)
+	assert.Len(t, roles, 0)
+	assert.Equal(t, 6, iam.lists)
+}
+
+func TestCacheMiss(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+	cache.Refresh()
+
+	// Objects created since the last refresh are read from IAM, then cached
+	iam.policies["policy-3"] = polv1.Policy{ID: "policy-3", Type: "access"}
+	_, err := cache.Policy("policy-3")
+	assert.NoError(t, err)
+	_, err = cache.Policy("policy-3")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, iam.gets)
+
+	_, err = cache.Policy("policy-4")
+	assert.Error(t, err)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+	cache.Refresh()
+
+	updated := iam.policies["policy-1"]
+	updated.Href = "updated"
+	iam.policies["policy-1"] = updated
+	cache.Invalidate("policy-1")
+	policy, err := cache.Policy("policy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", policy.Href)
+	assert.Equal(t, 1, iam.gets)
+}
+
+func TestCacheInvalidateWhileListing(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+	iam.onList = func() {
+		iam.onList = nil
+		cache.Invalidate("policy-1")
+	}
+	cache.Refresh()
+
+	// The policy listed before it was written isn't cached
+	_, err := cache.Policy("policy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, iam.gets)
+}
+
+func TestCacheListError(t *testing.T) {
+	iam := newFakeIAM()
+	iam.listErr = errors.New("rate limited")
+	cache := New("12345", iam.clients(), time.Hour)
+
+	// Lookups read IAM directly until the next refresh
+	policy, err := cache.Policy("policy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "policy-1", policy.ID)
+	group, err := cache.AccessGroup("AccessGroupId-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "admins", group.Name)
+	assert.Equal(t, 2, iam.gets)
+	assert.Equal(t, 1, iam.lists)
+}
+
+func TestCacheBackgroundRefresh(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+	cache.background = true
+
+	// The lookup doesn't wait for the account to be listed
+	role, err := cache.CustomRole("role-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Reader", role.DisplayName)
+	cache.loading.Wait()
+	assert.Equal(t, 5, iam.lists)
+
+	// Later lookups use the listed state
+	gets := iam.gets
+	_, err = cache.Policy("policy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, gets, iam.gets)
+}
+
+func TestCacheRefreshPeriod(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+	cache.Refresh()
+	cache.Refresh()
+	assert.Equal(t, 5, iam.lists)
+
+	cache = New("12345", iam.clients(), 0)
+	cache.Refresh()
+	cache.Refresh()
+	assert.Equal(t, 15, iam.lists)
+}
+
+func TestCacheExpire(t *testing.T) {
+	iam := newFakeIAM()
+	cache := New("12345", iam.clients(), time.Hour)
+	cache.Refresh()
+	cache.Expire()
+	cache.Refresh()
+	assert.Equal(t, 10, iam.lists)
+
+	// Lookups read IAM until the state is listed again
+	cache.background = true
+	listed := make(chan struct{})
+	iam.onList = func() { <-listed }
+	cache.Expire()
+	_, err := cache.Policy("policy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, iam.gets)
+	close(listed)
+	cache.loading.Wait()
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iamclient
+
+import (
+	"sync"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamcache"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// credential is an account and the registry key of the API key a namespace connects to it with
+type credential struct {
+	accountID string
+	key       string
+}
+
+var (
+	credentialsLock sync.Mutex
+	// credentials each namespace last connected with
+	credentials = map[string]credential{}
+)
+
+// connected records the credential a namespace connected with. The Secret and ConfigMap of the namespace are
+// read on each connection, so when the credential changes, e.g. because the API key was rotated, the cache,
+// catalog and transport shared for the previous one are evicted, unless another namespace still uses it.
+func connected(namespace string, current credential) {
+	credentialsLock.Lock()
+	previous, ok := credentials[namespace]
+	credentials[namespace] = current
+	unused := ok && previous != current && !inUse(previous)
+	credentialsLock.Unlock()
+	if unused {
+		evict(previous)
+	}
+}
+
+// forget drops the credential of a namespace whose Secret or ConfigMap was deleted, and evicts what was shared
+// for it unless another namespace still uses it
+func forget(namespace string) {
+	credentialsLock.Lock()
+	previous, ok := credentials[namespace]
+	delete(credentials, namespace)
+	unused := ok && !inUse(previous)
+	credentialsLock.Unlock()
+	if unused {
+		evict(previous)
+	}
+}
+
+// inUse returns true if a namespace connects with a credential, call it with credentialsLock held
+func inUse(c credential) bool {
+	for _, other := range credentials {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+func evict(c credential) {
+	iamcache.Evict(c.key)
+	rolecatalog.Evict(c.key)
+	resilience.Evict(c.accountID, c.key)
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iamclient
+
+import (
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/registry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+)
+
+func TestConnectedRotatedKey(t *testing.T) {
+	defer func() { credentials = map[string]credential{} }()
+	sess := func(apiKey string) *session.Session {
+		return &session.Session{Config: &bluemix.Config{BluemixAPIKey: apiKey}}
+	}
+	old := credential{accountID: "12345", key: registry.Key("12345", "key-1")}
+	transport := resilience.ForAccount(sess("key-1"), "12345")
+	connected("team-a", old)
+	connected("team-b", old)
+
+	// The key is still used by another namespace
+	connected("team-a", credential{accountID: "12345", key: registry.Key("12345", "key-2")})
+	assert.Same(t, transport, resilience.ForAccount(sess("key-1"), "12345"))
+
+	// The last namespace using the key rotated it
+	connected("team-b", credential{accountID: "12345", key: registry.Key("12345", "key-2")})
+	assert.NotSame(t, transport, resilience.ForAccount(sess("key-1"), "12345"))
+}
+
+func TestForget(t *testing.T) {
+	defer func() { credentials = map[string]credential{} }()
+	sess := &session.Session{Config: &bluemix.Config{BluemixAPIKey: "key-1"}}
+	transport := resilience.ForAccount(sess, "67890")
+	connected("team-a", credential{accountID: "67890", key: registry.Key("67890", "key-1")})
+
+	// The Secret of the namespace was deleted
+	forget("team-a")
+	assert.NotSame(t, transport, resilience.ForAccount(sess, "67890"))
+	assert.Empty(t, credentials)
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM-Cloud/bluemix-go/models"
+)
+
+// AddServiceID adds a service ID to the account, with a new UUID if uuid is empty
+func (f *Factory) AddServiceID(uuid string, name string) models.ServiceID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.createServiceID(models.ServiceID{UUID: uuid, Name: name})
+}
+
+// AddUser adds an active user to the account
+func (f *Factory) AddUser(email string) accountv1.AccountUser {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.createUser(email, "ACTIVE")
+}
+
+// Users returns the users of the account sorted by email
+func (f *Factory) Users() []accountv1.AccountUser {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	users := []accountv1.AccountUser{}
+	for _, user := range f.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users
+}
+
+// boundTo is the CRN of the account, which service IDs are bound to
+func boundTo() string {
+	account := crn.New("bluemix", "public")
+	account.ScopeType = crn.ScopeAccount
+	account.Scope = AccountID
+	return account.String()
+}
+
+func (f *Factory) createServiceID(serviceID models.ServiceID) *models.ServiceID {
+	if serviceID.UUID == "" {
+		serviceID.UUID = f.newID("ServiceId")
+	}
+	serviceID.IAMID = "iam-" + serviceID.UUID
+	serviceID.CRN = "crn:v1:bluemix:public:iam-identity::a/" + AccountID + "::serviceid:" + serviceID.UUID
+	serviceID.Version = "1-" + serviceID.UUID
+	serviceID.BoundTo = boundTo()
+	serviceID.CreatedAt = timestamp
+	serviceID.ModifiedAt = timestamp
+	f.serviceIDs[serviceID.UUID] = &serviceID
+	return &serviceID
+}
+
+func (f *Factory) listServiceIDs(boundTo string, name string) []models.ServiceID {
+	serviceIDs := []models.ServiceID{}
+	for _, serviceID := range f.serviceIDs {
+		if serviceID.BoundTo == boundTo && (name == "" || serviceID.Name == name) {
+			serviceIDs = append(serviceIDs, *serviceID)
+		}
+	}
+	sort.Slice(serviceIDs, func(i, j int) bool { return serviceIDs[i].Name < serviceIDs[j].Name })
+	return serviceIDs
+}
+
+func (f *Factory) createUser(email string, state string) *accountv1.AccountUser {
+	id := f.newID("user")
+	user := &accountv1.AccountUser{
+		UserId:      email,
+		Email:       email,
+		State:       state,
+		Id:          id,
+		IbmUniqueId: "IBMid-" + id,
+		AccountId:   AccountID,
+		Role:        "MEMBER",
+		CreatedOn:   timestamp,
+		InvitedOn:   timestamp,
+	}
+	if state == "ACTIVE" {
+		user.VerifiedOn = timestamp
+	}
+	f.users[email] = user
+	return user
+}
+
+// serviceIDs is the service IDs API of the account
+type serviceIDs struct{ f *Factory }
+
+func (r serviceIDs) Get(uuid string) (models.ServiceID, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceIDs.Get"); err != nil {
+		return models.ServiceID{}, err
+	}
+	serviceID, ok := r.f.serviceIDs[uuid]
+	if !ok {
+		return models.ServiceID{}, NotFound("Service ID", uuid)
+	}
+	return *serviceID, nil
+}
+
+func (r serviceIDs) List(boundTo string) ([]models.ServiceID, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceIDs.List"); err != nil {
+		return nil, err
+	}
+	return r.f.listServiceIDs(boundTo, ""), nil
+}
+
+func (r serviceIDs) FindByName(boundTo string, name string) ([]models.ServiceID, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceIDs.FindByName"); err != nil {
+		return nil, err
+	}
+	return r.f.listServiceIDs(boundTo, name), nil
+}
+
+func (r serviceIDs) Create(serviceID models.ServiceID) (models.ServiceID, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceIDs.Create"); err != nil {
+		return models.ServiceID{}, err
+	}
+	serviceID.UUID = ""
+	return *r.f.createServiceID(serviceID), nil
+}
+
+func (r serviceIDs) Update(uuid string, serviceID models.ServiceID, version string) (models.ServiceID, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceIDs.Update"); err != nil {
+		return models.ServiceID{}, err
+	}
+	existing, ok := r.f.serviceIDs[uuid]
+	if !ok {
+		return models.ServiceID{}, NotFound("Service ID", uuid)
+	}
+	if version != existing.Version {
+		return models.ServiceID{}, preconditionFailed()
+	}
+	existing.Name = serviceID.Name
+	existing.Description = serviceID.Description
+	return *existing, nil
+}
+
+func (r serviceIDs) Delete(uuid string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceIDs.Delete"); err != nil {
+		return err
+	}
+	if _, ok := r.f.serviceIDs[uuid]; !ok {
+		return NotFound("Service ID", uuid)
+	}
+	delete(r.f.serviceIDs, uuid)
+	return nil
+}
+
+// accounts is the users API of the account
+type accounts struct{ f *Factory }
+
+func (r accounts) GetAccountUsers(accountGUID string) ([]accountv1.AccountUser, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Accounts.GetAccountUsers"); err != nil {
+		return nil, err
+	}
+	if accountGUID != AccountID {
+		return nil, NotFound("Account", accountGUID)
+	}
+	users := []accountv1.AccountUser{}
+	for _, user := range r.f.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+func (r accounts) InviteAccountUser(accountGUID string, userEmail string) (accountv1.AccountInviteResponse, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Accounts.InviteAccountUser"); err != nil {
+		return accountv1.AccountInviteResponse{}, err
+	}
+	if accountGUID != AccountID {
+		return accountv1.AccountInviteResponse{}, NotFound("Account", accountGUID)
+	}
+	user, ok := r.f.users[userEmail]
+	if !ok {
+		user = r.f.createUser(userEmail, "PENDING")
+	}
+	return accountv1.AccountInviteResponse{Id: user.Id, Email: user.Email, State: user.State}, nil
+}
+
+// DeleteAccountUser removes a user from the account and its access groups
+func (r accounts) DeleteAccountUser(accountGUID string, userGUID string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Accounts.DeleteAccountUser"); err != nil {
+		return err
+	}
+	for email, user := range r.f.users {
+		if user.Id == userGUID && accountGUID == AccountID {
+			delete(r.f.users, email)
+			for _, g := range r.f.groups {
+				removeMember(g, user.IbmUniqueId)
+			}
+			return nil
+		}
+	}
+	return NotFound("User", userGUID)
+}
+
+// FindAccountUserByUserId returns nil without an error if the account has no such user, as IAM does
+func (r accounts) FindAccountUserByUserId(accountGUID string, userID string) (*accountv1.AccountUser, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Accounts.FindAccountUserByUserId"); err != nil {
+		return nil, err
+	}
+	if accountGUID != AccountID {
+		return nil, nil
+	}
+	for _, user := range r.f.users {
+		if strings.EqualFold(user.UserId, userID) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
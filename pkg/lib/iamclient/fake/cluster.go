@@ -0,0 +1,59 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/apis"
+)
+
+// Resource is a custom resource reconciled in a test
+type Resource interface {
+	metav1.Object
+	runtime.Object
+}
+
+// Cluster is what a reconciler depends on in a test: a Kubernetes client holding a custom resource and
+// its namespace, the IAM account of the namespace and an event recorder
+type Cluster struct {
+	Client   client.Client
+	Scheme   *runtime.Scheme
+	IAM      *Factory
+	Recorder *record.FakeRecorder
+}
+
+// NewCluster returns a cluster holding the custom resource, its namespace and the other objects, with an
+// empty IAM account
+func NewCluster(resource Resource, objs ...runtime.Object) *Cluster {
+	if err := apis.AddToScheme(scheme.Scheme); err != nil {
+		panic(err)
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: resource.GetNamespace()}}
+	return &Cluster{
+		Client:   crfake.NewFakeClientWithScheme(scheme.Scheme, append(objs, namespace, resource)...),
+		Scheme:   scheme.Scheme,
+		IAM:      NewFactory(),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fake is an in-memory IAM account for testing reconcilers without IBM
+// Cloud. The account keeps policies, access groups, custom roles, service IDs
+// and users in memory, records the operations called on it and fails the
+// operations chosen by a test, so that reconcile logic, error paths and drift
+// handling can be tested deterministically. NewCluster sets up the rest of what a
+// reconciler depends on: the custom resource, its namespace and an event recorder.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamcache"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// The fake account
+const (
+	AccountID   = "fa4e0000000000000000000000000002"
+	AccountName = "Fake Account"
+)
+
+// Objects created in the account are stamped with a fixed time and creator
+const (
+	timestamp = "2020-01-01T00:00:00Z"
+	creatorID = "iam-ServiceId-fake-operator"
+)
+
+// Factory is an in-memory IAM account, connected to reconcilers for every
+// namespace. A Factory is safe for concurrent use.
+type Factory struct {
+	mu           sync.Mutex
+	seq          int
+	failures     map[string]error
+	calls        []string
+	policies     map[string]*policy
+	groups       map[string]*group
+	roles        map[string]*role
+	serviceIDs   map[string]*models.ServiceID
+	serviceRoles map[string][]string
+	users        map[string]*accountv1.AccountUser
+	cache        *iamcache.Cache
+	catalog      *rolecatalog.Catalog
+}
+
+// NewFactory returns an empty account
+func NewFactory() *Factory {
+	f := &Factory{
+		failures:     map[string]error{},
+		policies:     map[string]*policy{},
+		groups:       map[string]*group{},
+		roles:        map[string]*role{},
+		serviceIDs:   map[string]*models.ServiceID{},
+		serviceRoles: map[string][]string{},
+		users:        map[string]*accountv1.AccountUser{},
+	}
+	// The cache and catalog list the account on every lookup so that tests
+	// see the changes they make to the account
+	f.cache = iamcache.New(AccountID, iamcache.Clients{
+		Policies:     policiesV1{f},
+		AccessGroups: accessGroups{f},
+		Members:      members{f},
+		CustomRoles:  customRoles{f},
+		ServiceIDs:   serviceIDs{f},
+	}, 0)
+	f.catalog = rolecatalog.New(AccountID, rolecatalog.Clients{
+		ServiceRoles: serviceRoles{f},
+		CustomRoles:  f.cache,
+	}, 0)
+	return f
+}
+
+var _ iamclient.Factory = &Factory{}
+
+// Connect returns the account, or the error set with Fail for "Connect"
+func (f *Factory) Connect(ctx context.Context, c client.Client, namespace string) (*accountv2.Account, iamclient.Clients, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Connect"); err != nil {
+		return nil, nil, err
+	}
+	return &accountv2.Account{GUID: AccountID, Name: AccountName, State: "ACTIVE"}, clients{f}, nil
+}
+
+// Fail makes an operation, e.g. "Connect" or "AccessGroups.Create", return err
+// until it is called again with a nil error. Operations are named after the
+// Clients method returning the API and the method of the API.
+func (f *Factory) Fail(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.failures, operation)
+		return
+	}
+	f.failures[operation] = err
+}
+
+// Calls returns the operations called on the account, in order
+func (f *Factory) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.calls...)
+}
+
+// ResetCalls forgets the operations called so far
+func (f *Factory) ResetCalls() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// call records an operation and returns the error it fails with, if any
+func (f *Factory) call(operation string) error {
+	f.calls = append(f.calls, operation)
+	return f.failures[operation]
+}
+
+// newID returns an ID unique within the account, IDs being numbered in the
+// order objects are created
+func (f *Factory) newID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s-%d", prefix, f.seq)
+}
+
+// NotFound returns the error of IAM for a missing object
+func NotFound(kind string, id string) error {
+	return bmxerror.NewRequestFailure("not_found", fmt.Sprintf("%s %s not found", kind, id), 404)
+}
+
+// Conflict returns the error of IAM for a request conflicting with the state of the account
+func Conflict(message string) error {
+	return bmxerror.NewRequestFailure("conflict", message, 409)
+}
+
+func preconditionFailed() error {
+	return bmxerror.NewRequestFailure("precondition_failed", "The revision does not match the current one", 412)
+}
+
+func badRequest(message string) error {
+	return bmxerror.NewRequestFailure("invalid_body", message, 400)
+}
+
+func etag(version int) string {
+	return fmt.Sprint(version)
+}
+
+// clients are the APIs of the account
+type clients struct{ f *Factory }
+
+func (c clients) Policies() (polv1.PolicyRepository, error) {
+	return policiesV1{c.f}, nil
+}
+
+func (c clients) PoliciesV2() (polv2.PolicyRepository, error) {
+	return policiesV2{c.f}, nil
+}
+
+func (c clients) ServiceIDs() (iamv1.ServiceIDRepository, error) {
+	return serviceIDs{c.f}, nil
+}
+
+func (c clients) AccessGroups() (iamuumv2.AccessGroupRepository, error) {
+	return accessGroups{c.f}, nil
+}
+
+func (c clients) AccessGroupMembers() (iamuumv2.AccessGroupMemberRepositoryV2, error) {
+	return members{c.f}, nil
+}
+
+func (c clients) CustomRoles() (iampapv2.RoleRepository, error) {
+	return customRoles{c.f}, nil
+}
+
+func (c clients) Accounts() (accountv1.Accounts, error) {
+	return accounts{c.f}, nil
+}
+
+func (c clients) Cache() (*iamcache.Cache, error) {
+	return c.f.cache, nil
+}
+
+func (c clients) RoleCatalog() (*rolecatalog.Catalog, error) {
+	return c.f.catalog, nil
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+func connect(t *testing.T, f *Factory) iamclient.Clients {
+	account, clients, err := f.Connect(context.Background(), nil, "default")
+	require.NoError(t, err)
+	require.Equal(t, AccountID, account.GUID)
+	return clients
+}
+
+func statusCode(err error) int {
+	if failure, ok := err.(bmxerror.RequestFailure); ok {
+		return failure.StatusCode()
+	}
+	return 0
+}
+
+func accessPolicy(subject string, role string) polv1.Policy {
+	p := polv1.Policy{
+		Type:      "access",
+		Subjects:  []polv1.Subject{{}},
+		Roles:     []iampapv1.Role{{RoleID: role}},
+		Resources: []polv1.Resource{{}},
+	}
+	p.Subjects[0].SetAttribute("iam_id", subject)
+	p.Resources[0].SetAccountID(AccountID)
+	p.Resources[0].SetAttribute("serviceName", "cloud-object-storage")
+	return p
+}
+
+func TestPolicies(t *testing.T) {
+	f := NewFactory()
+	clients := connect(t, f)
+	v1, err := clients.Policies()
+	require.NoError(t, err)
+	v2, err := clients.PoliciesV2()
+	require.NoError(t, err)
+
+	created, err := v1.Create(accessPolicy("IBMid-1", "crn:v1:bluemix:public:iam::::role:Viewer"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", created.Version)
+
+	conditional := polv2.ConvertV1Policy(accessPolicy("IBMid-2", "crn:v1:bluemix:public:iam::::role:Viewer"))
+	conditional.Rule = &polv2.Rule{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeLessThanOrEquals, Value: "2030-01-01T00:00:00+00:00"}
+	conditional.Pattern = polv2.PatternOnce
+	conditional, err = v2.Create(conditional)
+	require.NoError(t, err)
+
+	// Policies with conditions are only visible to the v2 API
+	listed, err := v1.List(polv1.SearchParams{AccountID: AccountID})
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+	_, err = v1.Get(conditional.ID)
+	assert.Equal(t, 404, statusCode(err))
+	listedV2, err := v2.List(polv2.SearchParams{AccountID: AccountID})
+	require.NoError(t, err)
+	assert.Len(t, listedV2, 2)
+	assert.Equal(t, created.ID, listedV2[0].ID)
+
+	_, err = v1.Update(created.ID, accessPolicy("IBMid-1", "crn:v1:bluemix:public:iam::::role:Editor"), "0")
+	assert.Equal(t, 412, statusCode(err))
+	updated, err := v1.Update(created.ID, accessPolicy("IBMid-1", "crn:v1:bluemix:public:iam::::role:Editor"), created.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "crn:v1:bluemix:public:iam::::role:Editor", updated.Roles[0].RoleID)
+	assert.Equal(t, "2", updated.Version)
+
+	_, err = v1.Create(polv1.Policy{Type: "access", Resources: []polv1.Resource{{}}})
+	assert.Equal(t, 400, statusCode(err))
+
+	require.NoError(t, v2.Delete(conditional.ID))
+	assert.Len(t, f.Policies(), 1)
+}
+
+func TestAccessGroups(t *testing.T) {
+	f := NewFactory()
+	user := f.AddUser("jane@example.com")
+	serviceID := f.AddServiceID("", "deployer")
+	clients := connect(t, f)
+	groups, err := clients.AccessGroups()
+	require.NoError(t, err)
+	members, err := clients.AccessGroupMembers()
+	require.NoError(t, err)
+
+	group, err := groups.Create(models.AccessGroupV2{AccessGroup: models.AccessGroup{Name: "developers"}}, AccountID)
+	require.NoError(t, err)
+	_, err = groups.Create(models.AccessGroupV2{AccessGroup: models.AccessGroup{Name: "developers"}}, AccountID)
+	assert.Equal(t, 409, statusCode(err))
+
+	response, err := members.Add(group.ID, iamuumv2.AddGroupMemberRequestV2{Members: []models.AccessGroupMemberV2{
+		{ID: user.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser},
+		{ID: serviceID.IAMID, Type: iamuumv2.AccessGroupMemberService},
+		{ID: "IBMid-unknown", Type: iamuumv2.AccessGroupMemberUser},
+	}})
+	require.NoError(t, err)
+	require.Len(t, response.Members, 3)
+	assert.Equal(t, 404, response.Members[2].StatusCode)
+	assert.Len(t, f.Members(group.ID), 2)
+
+	_, etag, err := groups.Get(group.ID)
+	require.NoError(t, err)
+	_, err = groups.Update(group.ID, iamuumv2.AccessGroupUpdateRequest{Description: "Developers"}, "0")
+	assert.Equal(t, 412, statusCode(err))
+	updated, err := groups.Update(group.ID, iamuumv2.AccessGroupUpdateRequest{Description: "Developers"}, etag)
+	require.NoError(t, err)
+	assert.Equal(t, "Developers", updated.Description)
+
+	policy := polv2.ConvertV1Policy(accessPolicy("", "crn:v1:bluemix:public:iam::::role:Viewer"))
+	policy.Subject.Attributes = []polv2.Attribute{{Key: "access_group_id", Operator: "stringEquals", Value: group.ID}}
+	f.AddPolicy(policy)
+
+	// Groups with members are deleted with their policies when forced
+	assert.Equal(t, 409, statusCode(groups.Delete(group.ID, false)))
+	require.NoError(t, groups.Delete(group.ID, true))
+	assert.Empty(t, f.AccessGroups())
+	assert.Empty(t, f.Policies())
+}
+
+func TestCustomRoles(t *testing.T) {
+	f := NewFactory()
+	clients := connect(t, f)
+	roles, err := clients.CustomRoles()
+	require.NoError(t, err)
+
+	request := iampapv2.CreateRoleRequest{Name: "Deployer", ServiceName: "kms", AccountID: AccountID, DisplayName: "Deployer", Actions: []string{"kms.secrets.read"}}
+	created, err := roles.Create(request)
+	require.NoError(t, err)
+	assert.Equal(t, "crn:v1:bluemix:public:kms::a/"+AccountID+"::customRole:Deployer", created.Crn)
+	_, err = roles.Create(request)
+	assert.Equal(t, 409, statusCode(err))
+
+	// The role catalog resolves custom roles and service roles of the account
+	catalog, err := clients.RoleCatalog()
+	require.NoError(t, err)
+	resolved, err := catalog.ResolveServiceRoles("kms", []string{"Reader", "Viewer"})
+	require.NoError(t, err)
+	assert.Len(t, resolved, 2)
+	resolved, err = catalog.ResolveCustomRoles("kms", []string{"Deployer"})
+	require.NoError(t, err)
+	require.Len(t, resolved, 1)
+	assert.Equal(t, created.Crn, resolved[0].CRN)
+
+	_, etag, err := roles.Get(created.ID)
+	require.NoError(t, err)
+	updated, err := roles.Update(iampapv2.UpdateRoleRequest{DisplayName: "Deployers", Actions: request.Actions}, created.ID, etag)
+	require.NoError(t, err)
+	assert.Equal(t, "Deployers", updated.DisplayName)
+	_, err = roles.Update(iampapv2.UpdateRoleRequest{DisplayName: "Deployer"}, created.ID, etag)
+	assert.Equal(t, 412, statusCode(err))
+
+	require.NoError(t, roles.Delete(created.ID))
+	assert.Equal(t, 404, statusCode(roles.Delete(created.ID)))
+}
+
+func TestUsers(t *testing.T) {
+	f := NewFactory()
+	f.AddUser("jane@example.com")
+	clients := connect(t, f)
+	accounts, err := clients.Accounts()
+	require.NoError(t, err)
+
+	found, err := accounts.FindAccountUserByUserId(AccountID, "JANE@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "ACTIVE", found.State)
+	missing, err := accounts.FindAccountUserByUserId(AccountID, "john@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	invited, err := accounts.InviteAccountUser(AccountID, "john@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING", invited.State)
+	require.NoError(t, accounts.DeleteAccountUser(AccountID, invited.Id))
+	assert.Len(t, f.Users(), 1)
+}
+
+func TestFail(t *testing.T) {
+	f := NewFactory()
+	unavailable := bmxerror.NewRequestFailure("service_unavailable", "IAM is unavailable", 503)
+
+	f.Fail("Connect", errors.New("no credentials"))
+	_, _, err := f.Connect(context.Background(), nil, "default")
+	assert.EqualError(t, err, "no credentials")
+	f.Fail("Connect", nil)
+
+	clients := connect(t, f)
+	groups, err := clients.AccessGroups()
+	require.NoError(t, err)
+	f.Fail("AccessGroups.List", unavailable)
+	_, err = groups.List(AccountID)
+	assert.Equal(t, 503, statusCode(err))
+	_, err = groups.FindByName("developers", AccountID)
+	assert.NoError(t, err)
+
+	f.Fail("AccessGroups.List", nil)
+	_, err = groups.List(AccountID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Connect", "Connect", "AccessGroups.List", "AccessGroups.FindByName", "AccessGroups.List"}, f.Calls())
+
+	f.ResetCalls()
+	assert.Empty(t, f.Calls())
+}
+
+func TestCache(t *testing.T) {
+	f := NewFactory()
+	clients := connect(t, f)
+	cache, err := clients.Cache()
+	require.NoError(t, err)
+
+	role := f.AddCustomRole(iampapv2.CreateRoleRequest{Name: "Deployer", ServiceName: "kms", DisplayName: "Deployer"})
+	cached, err := cache.CustomRole(role.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deployer", cached.DisplayName)
+
+	// Changes made to the account are seen without expiring the cache
+	require.NoError(t, f.SetCustomRole(role.ID, iampapv2.UpdateRoleRequest{DisplayName: "Changed"}))
+	cached, err = cache.CustomRole(role.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Changed", cached.DisplayName)
+}
@@ -0,0 +1,319 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"sort"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+)
+
+// group is an access group of the account and its members
+type group struct {
+	models.AccessGroupV2
+	version int
+	members []models.AccessGroupMemberV2
+}
+
+// AddAccessGroup adds an access group to the account, e.g. one created by hand before the operator
+func (f *Factory) AddAccessGroup(name string, description string) models.AccessGroupV2 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createGroup(name, description).AccessGroupV2
+}
+
+// AccessGroups returns the access groups of the account sorted by name
+func (f *Factory) AccessGroups() []models.AccessGroupV2 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.listGroups()
+}
+
+// Members returns the members of an access group
+func (f *Factory) Members(groupID string) []models.AccessGroupMemberV2 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if g, ok := f.groups[groupID]; ok {
+		return append([]models.AccessGroupMemberV2{}, g.members...)
+	}
+	return nil
+}
+
+// AddMember adds a member to an access group, e.g. as with the IAM console
+func (f *Factory) AddMember(groupID string, member models.AccessGroupMemberV2) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[groupID]
+	if !ok {
+		return NotFound("Access group", groupID)
+	}
+	if !hasMember(g, member.ID) {
+		g.members = append(g.members, member)
+	}
+	return nil
+}
+
+// RemoveMember removes a member from an access group, e.g. as with the IAM console
+func (f *Factory) RemoveMember(groupID string, iamID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[groupID]
+	if !ok {
+		return NotFound("Access group", groupID)
+	}
+	return removeMember(g, iamID)
+}
+
+func (f *Factory) createGroup(name string, description string) *group {
+	g := &group{
+		AccessGroupV2: models.AccessGroupV2{
+			AccessGroup: models.AccessGroup{
+				ID:          f.newID("AccessGroupId"),
+				Name:        name,
+				Description: description,
+			},
+			AccountID:        AccountID,
+			CreatedAt:        timestamp,
+			CreatedByID:      creatorID,
+			LastModifiedAt:   timestamp,
+			LastModifiedByID: creatorID,
+		},
+		version: 1,
+	}
+	f.groups[g.ID] = g
+	return g
+}
+
+func (f *Factory) listGroups() []models.AccessGroupV2 {
+	groups := []models.AccessGroupV2{}
+	for _, g := range f.groups {
+		groups = append(groups, g.AccessGroupV2)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+func (f *Factory) getGroup(groupID string) (*group, error) {
+	g, ok := f.groups[groupID]
+	if !ok {
+		return nil, NotFound("Access group", groupID)
+	}
+	return g, nil
+}
+
+// iamIDExists returns true if the account has a user or service ID with an IAM ID
+func (f *Factory) iamIDExists(iamID string, memberType string) bool {
+	if memberType == iamuumv2.AccessGroupMemberService {
+		for _, serviceID := range f.serviceIDs {
+			if serviceID.IAMID == iamID {
+				return true
+			}
+		}
+		return false
+	}
+	for _, user := range f.users {
+		if user.IbmUniqueId == iamID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMember(g *group, iamID string) bool {
+	for _, member := range g.members {
+		if member.ID == iamID {
+			return true
+		}
+	}
+	return false
+}
+
+func removeMember(g *group, iamID string) error {
+	for i, member := range g.members {
+		if member.ID == iamID {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			return nil
+		}
+	}
+	return NotFound("Member", iamID)
+}
+
+// accessGroups is the access groups API of the account
+type accessGroups struct{ f *Factory }
+
+func (r accessGroups) List(accountID string) ([]models.AccessGroupV2, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroups.List"); err != nil {
+		return nil, err
+	}
+	if accountID != AccountID {
+		return []models.AccessGroupV2{}, nil
+	}
+	return r.f.listGroups(), nil
+}
+
+func (r accessGroups) Create(request models.AccessGroupV2, accountID string) (*models.AccessGroupV2, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroups.Create"); err != nil {
+		return nil, err
+	}
+	if accountID != AccountID {
+		return nil, badRequest("Account " + accountID + " is not accessible")
+	}
+	for _, g := range r.f.groups {
+		if g.Name == request.Name {
+			return nil, Conflict("Group name " + request.Name + " already exists")
+		}
+	}
+	created := r.f.createGroup(request.Name, request.Description).AccessGroupV2
+	return &created, nil
+}
+
+func (r accessGroups) FindByName(name string, accountID string) ([]models.AccessGroupV2, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroups.FindByName"); err != nil {
+		return nil, err
+	}
+	var groups []models.AccessGroupV2
+	if accountID != AccountID {
+		return groups, nil
+	}
+	for _, g := range r.f.listGroups() {
+		if g.Name == name {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+func (r accessGroups) Delete(accessGroupID string, recursive bool) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroups.Delete"); err != nil {
+		return err
+	}
+	g, err := r.f.getGroup(accessGroupID)
+	if err != nil {
+		return err
+	}
+	if len(g.members) > 0 && !recursive {
+		return Conflict("Access group has members, delete it with force")
+	}
+	delete(r.f.groups, g.ID)
+	for id, p := range r.f.policies { // IAM deletes the policies of a deleted group
+		if attributeValue(p.Subject.Attributes, "access_group_id") == g.ID {
+			delete(r.f.policies, id)
+		}
+	}
+	return nil
+}
+
+func (r accessGroups) Update(accessGroupID string, request iamuumv2.AccessGroupUpdateRequest, revision string) (models.AccessGroupV2, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroups.Update"); err != nil {
+		return models.AccessGroupV2{}, err
+	}
+	g, err := r.f.getGroup(accessGroupID)
+	if err != nil {
+		return models.AccessGroupV2{}, err
+	}
+	if revision != etag(g.version) {
+		return models.AccessGroupV2{}, preconditionFailed()
+	}
+	if request.Name != "" {
+		g.Name = request.Name
+	}
+	if request.Description != "" {
+		g.Description = request.Description
+	}
+	g.version++
+	return g.AccessGroupV2, nil
+}
+
+func (r accessGroups) Get(accessGroupID string) (*models.AccessGroupV2, string, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroups.Get"); err != nil {
+		return nil, "", err
+	}
+	g, err := r.f.getGroup(accessGroupID)
+	if err != nil {
+		return nil, "", err
+	}
+	found := g.AccessGroupV2
+	return &found, etag(g.version), nil
+}
+
+// members is the access group members API of the account
+type members struct{ f *Factory }
+
+func (r members) List(groupID string) ([]models.AccessGroupMemberV2, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroupMembers.List"); err != nil {
+		return nil, err
+	}
+	g, err := r.f.getGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.AccessGroupMemberV2{}, g.members...), nil
+}
+
+// Add adds the members with an IAM ID of the account, and reports the others as not found
+func (r members) Add(groupID string, request iamuumv2.AddGroupMemberRequestV2) (iamuumv2.AddGroupMemberResponseV2, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroupMembers.Add"); err != nil {
+		return iamuumv2.AddGroupMemberResponseV2{}, err
+	}
+	g, err := r.f.getGroup(groupID)
+	if err != nil {
+		return iamuumv2.AddGroupMemberResponseV2{}, err
+	}
+	response := iamuumv2.AddGroupMemberResponseV2{}
+	for _, member := range request.Members {
+		added := iamuumv2.AddedGroupMemberV2{ID: member.ID, Type: member.Type, StatusCode: 200, CreatedAt: timestamp}
+		if !r.f.iamIDExists(member.ID, member.Type) {
+			added.StatusCode = 404
+			added.Errors = []iamuumv2.Error{{Code: "not_found", Message: "IAM ID " + member.ID + " not found"}}
+		} else if !hasMember(g, member.ID) {
+			member.CreatedAt = timestamp
+			g.members = append(g.members, member)
+		}
+		response.Members = append(response.Members, added)
+	}
+	return response, nil
+}
+
+func (r members) Remove(groupID string, memberID string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("AccessGroupMembers.Remove"); err != nil {
+		return err
+	}
+	g, err := r.f.getGroup(groupID)
+	if err != nil {
+		return err
+	}
+	return removeMember(g, memberID)
+}
@@ -0,0 +1,342 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+// policy is a policy of the account, stored as a v2 policy whatever the API
+// it was created with
+type policy struct {
+	polv2.Policy
+	version int
+	order   int
+}
+
+// AddPolicy adds a policy to the account, e.g. one created by hand before the operator
+func (f *Factory) AddPolicy(p polv2.Policy) polv2.Policy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createPolicy(p).Policy
+}
+
+// Policies returns the policies of the account in the order they were created
+func (f *Factory) Policies() []polv2.Policy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.listPolicies(polv2.SearchParams{AccountID: AccountID}, false)
+}
+
+// RoleNames returns the names of the roles a policy grants, the last segment of their CRN
+func RoleNames(p polv2.Policy) []string {
+	var names []string
+	for _, role := range p.Control.Grant.Roles {
+		names = append(names, role.RoleID[strings.LastIndex(role.RoleID, ":")+1:])
+	}
+	return names
+}
+
+// SetPolicy replaces a policy of the account, e.g. to change it as with the IAM console
+func (f *Factory) SetPolicy(p polv2.Policy) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.policies[p.ID]
+	if !ok {
+		return NotFound("Policy", p.ID)
+	}
+	f.updatePolicy(existing, p)
+	return nil
+}
+
+func (f *Factory) createPolicy(p polv2.Policy) *policy {
+	p.ID = f.newID("policy")
+	p.Href = "/v2/policies/" + p.ID
+	p.CreatedAt = timestamp
+	p.CreatedByID = creatorID
+	p.LastModifiedAt = p.CreatedAt
+	p.LastModifiedByID = p.CreatedByID
+	if p.State == "" {
+		p.State = "active"
+	}
+	created := &policy{Policy: p, version: 1, order: f.seq}
+	created.Version = etag(created.version)
+	f.policies[p.ID] = created
+	return created
+}
+
+func (f *Factory) updatePolicy(existing *policy, p polv2.Policy) {
+	existing.Type = p.Type
+	existing.Description = p.Description
+	existing.Subject = p.Subject
+	existing.Control = p.Control
+	existing.Resource = p.Resource
+	existing.Rule = p.Rule
+	existing.Pattern = p.Pattern
+	existing.version++
+	existing.Version = etag(existing.version)
+}
+
+func (f *Factory) listPolicies(params polv2.SearchParams, v1 bool) []polv2.Policy {
+	var matches []*policy
+	for _, p := range f.policies {
+		if v1 && !v1Visible(p.Policy) {
+			continue
+		}
+		if params.AccountID != attributeValue(p.Resource.Attributes, "accountId") ||
+			params.IAMID != "" && params.IAMID != attributeValue(p.Subject.Attributes, "iam_id") ||
+			params.AccessGroupID != "" && params.AccessGroupID != attributeValue(p.Subject.Attributes, "access_group_id") ||
+			params.Type != "" && params.Type != p.Type ||
+			params.State != "" && params.State != p.State {
+			continue
+		}
+		matches = append(matches, p)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].order < matches[j].order })
+	policies := []polv2.Policy{}
+	for _, p := range matches {
+		policies = append(policies, p.Policy)
+	}
+	return policies
+}
+
+// getPolicy returns a policy of the account, v1 policies being those without conditions
+func (f *Factory) getPolicy(policyID string, v1 bool) (*policy, error) {
+	p, ok := f.policies[policyID]
+	if !ok || v1 && !v1Visible(p.Policy) {
+		return nil, NotFound("Policy", policyID)
+	}
+	return p, nil
+}
+
+// validPolicy returns an error if a policy cannot be created in the account
+func (f *Factory) validPolicy(p polv2.Policy) error {
+	if p.Type != "access" && p.Type != "authorization" {
+		return badRequest("Policy type " + p.Type + " is not supported")
+	}
+	if len(p.Control.Grant.Roles) == 0 {
+		return badRequest("Policy must grant roles")
+	}
+	if groupID := attributeValue(p.Subject.Attributes, "access_group_id"); groupID != "" {
+		if _, ok := f.groups[groupID]; !ok {
+			return badRequest("Access group " + groupID + " not found")
+		}
+	}
+	return nil
+}
+
+// policiesV1 is the v1 policies API of the account. Policies with conditions
+// are only visible to the v2 API.
+type policiesV1 struct{ f *Factory }
+
+func (r policiesV1) List(params polv1.SearchParams) ([]polv1.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Policies.List"); err != nil {
+		return nil, err
+	}
+	policies := []polv1.Policy{}
+	for _, p := range r.f.listPolicies(polv2.SearchParams{
+		AccountID:     params.AccountID,
+		IAMID:         params.IAMID,
+		AccessGroupID: params.AccessGroupID,
+		Type:          params.Type,
+	}, true) {
+		policies = append(policies, toV1(p))
+	}
+	return policies, nil
+}
+
+func (r policiesV1) Get(policyID string) (polv1.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Policies.Get"); err != nil {
+		return polv1.Policy{}, err
+	}
+	p, err := r.f.getPolicy(policyID, true)
+	if err != nil {
+		return polv1.Policy{}, err
+	}
+	return toV1(p.Policy), nil
+}
+
+func (r policiesV1) Create(p polv1.Policy) (polv1.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Policies.Create"); err != nil {
+		return polv1.Policy{}, err
+	}
+	converted := polv2.ConvertV1Policy(p)
+	if err := r.f.validPolicy(converted); err != nil {
+		return polv1.Policy{}, err
+	}
+	return toV1(r.f.createPolicy(converted).Policy), nil
+}
+
+func (r policiesV1) Update(policyID string, p polv1.Policy, version string) (polv1.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Policies.Update"); err != nil {
+		return polv1.Policy{}, err
+	}
+	existing, err := r.f.getPolicy(policyID, true)
+	if err != nil {
+		return polv1.Policy{}, err
+	}
+	if version != existing.Version {
+		return polv1.Policy{}, preconditionFailed()
+	}
+	converted := polv2.ConvertV1Policy(p)
+	if err := r.f.validPolicy(converted); err != nil {
+		return polv1.Policy{}, err
+	}
+	r.f.updatePolicy(existing, converted)
+	return toV1(existing.Policy), nil
+}
+
+func (r policiesV1) Delete(policyID string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("Policies.Delete"); err != nil {
+		return err
+	}
+	if _, err := r.f.getPolicy(policyID, true); err != nil {
+		return err
+	}
+	delete(r.f.policies, policyID)
+	return nil
+}
+
+// policiesV2 is the v2 policies API of the account
+type policiesV2 struct{ f *Factory }
+
+func (r policiesV2) List(params polv2.SearchParams) ([]polv2.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("PoliciesV2.List"); err != nil {
+		return nil, err
+	}
+	return r.f.listPolicies(params, false), nil
+}
+
+func (r policiesV2) Get(policyID string) (polv2.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("PoliciesV2.Get"); err != nil {
+		return polv2.Policy{}, err
+	}
+	p, err := r.f.getPolicy(policyID, false)
+	if err != nil {
+		return polv2.Policy{}, err
+	}
+	return p.Policy, nil
+}
+
+func (r policiesV2) Create(p polv2.Policy) (polv2.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("PoliciesV2.Create"); err != nil {
+		return polv2.Policy{}, err
+	}
+	if err := r.f.validPolicy(p); err != nil {
+		return polv2.Policy{}, err
+	}
+	return r.f.createPolicy(p).Policy, nil
+}
+
+func (r policiesV2) Update(policyID string, p polv2.Policy, version string) (polv2.Policy, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("PoliciesV2.Update"); err != nil {
+		return polv2.Policy{}, err
+	}
+	existing, err := r.f.getPolicy(policyID, false)
+	if err != nil {
+		return polv2.Policy{}, err
+	}
+	if version != existing.Version {
+		return polv2.Policy{}, preconditionFailed()
+	}
+	if err := r.f.validPolicy(p); err != nil {
+		return polv2.Policy{}, err
+	}
+	r.f.updatePolicy(existing, p)
+	return existing.Policy, nil
+}
+
+func (r policiesV2) Delete(policyID string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("PoliciesV2.Delete"); err != nil {
+		return err
+	}
+	if _, err := r.f.getPolicy(policyID, false); err != nil {
+		return err
+	}
+	delete(r.f.policies, policyID)
+	return nil
+}
+
+// v1Visible returns true if a policy can be represented with the v1 API
+func v1Visible(p polv2.Policy) bool {
+	return p.Rule == nil && p.Pattern == ""
+}
+
+// toV1 converts a v2 policy without conditions to the v1 representation
+func toV1(p polv2.Policy) polv1.Policy {
+	result := polv1.Policy{
+		ID:               p.ID,
+		Type:             p.Type,
+		Description:      p.Description,
+		Subjects:         []polv1.Subject{{Attributes: toV1Attributes(p.Subject.Attributes)}},
+		Roles:            []iampapv1.Role{},
+		Resources:        []polv1.Resource{{Attributes: toV1Attributes(p.Resource.Attributes), Tags: toV1Attributes(p.Resource.Tags)}},
+		Href:             p.Href,
+		CreatedAt:        p.CreatedAt,
+		CreatedByID:      p.CreatedByID,
+		LastModifiedAt:   p.LastModifiedAt,
+		LastModifiedByID: p.LastModifiedByID,
+		Version:          p.Version,
+	}
+	for _, r := range p.Control.Grant.Roles {
+		result.Roles = append(result.Roles, iampapv1.Role{RoleID: r.RoleID})
+	}
+	return result
+}
+
+func toV1Attributes(attributes []polv2.Attribute) []polv1.Attribute {
+	var results []polv1.Attribute
+	for _, a := range attributes {
+		results = append(results, polv1.Attribute{Name: a.Key, Value: a.Value, Operator: a.Operator})
+	}
+	return results
+}
+
+func attributeValue(attributes []polv2.Attribute, key string) string {
+	for _, a := range attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return ""
+}
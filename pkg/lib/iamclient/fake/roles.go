@@ -0,0 +1,296 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fake
+
+import (
+	"sort"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM-Cloud/bluemix-go/models"
+)
+
+// platformRoles are the system defined roles, granted on any service
+var platformRoles = []string{"Viewer", "Operator", "Editor", "Administrator"}
+
+// defaultServiceRoles are the roles of the services without roles set by SetServiceRoles
+var defaultServiceRoles = []string{"Reader", "Writer", "Manager"}
+
+// role is a custom role of the account
+type role struct {
+	iampapv2.Role
+	version int
+}
+
+// AddCustomRole adds a custom role to the account, e.g. one created by hand before the operator
+func (f *Factory) AddCustomRole(request iampapv2.CreateRoleRequest) iampapv2.Role {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createRole(request).Role
+}
+
+// CustomRoles returns the custom roles of the account sorted by name
+func (f *Factory) CustomRoles() []iampapv2.Role {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.listRoles("")
+}
+
+// SetCustomRole replaces the display name, description and actions of a custom
+// role, e.g. to change them as with the IAM console
+func (f *Factory) SetCustomRole(roleID string, request iampapv2.UpdateRoleRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.roles[roleID]
+	if !ok {
+		return NotFound("Role", roleID)
+	}
+	updateRole(existing, request)
+	return nil
+}
+
+// SetServiceRoles sets the names of the service roles of a service, Reader, Writer and Manager by default
+func (f *Factory) SetServiceRoles(serviceName string, roles ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.serviceRoles[serviceName] = roles
+}
+
+func (f *Factory) createRole(request iampapv2.CreateRoleRequest) *role {
+	id := f.newID("role")
+	roleCRN := roleCRN(request.ServiceName, "customRole", request.Name)
+	roleCRN.ScopeType = crn.ScopeAccount
+	roleCRN.Scope = AccountID
+	request.AccountID = AccountID
+	r := &role{
+		Role: iampapv2.Role{
+			CreateRoleRequest: request,
+			ID:                id,
+			Crn:               roleCRN.String(),
+			CreatedAt:         timestamp,
+			CreatedByID:       creatorID,
+			LastModifiedAt:    timestamp,
+			LastModifiedByID:  creatorID,
+		},
+		version: 1,
+	}
+	f.roles[id] = r
+	return r
+}
+
+func updateRole(existing *role, request iampapv2.UpdateRoleRequest) {
+	existing.DisplayName = request.DisplayName
+	existing.Description = request.Description
+	existing.Actions = request.Actions
+	existing.version++
+}
+
+func (f *Factory) listRoles(serviceName string) []iampapv2.Role {
+	roles := []iampapv2.Role{}
+	for _, r := range f.roles {
+		if serviceName == "" || r.ServiceName == serviceName {
+			roles = append(roles, r.Role)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+func (f *Factory) serviceRoleNames(serviceName string) []string {
+	if names, ok := f.serviceRoles[serviceName]; ok {
+		return names
+	}
+	return defaultServiceRoles
+}
+
+// customRoles is the roles API of the account
+type customRoles struct{ f *Factory }
+
+func (r customRoles) Get(roleID string) (iampapv2.Role, string, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.Get"); err != nil {
+		return iampapv2.Role{}, "", err
+	}
+	existing, ok := r.f.roles[roleID]
+	if !ok {
+		return iampapv2.Role{}, "", NotFound("Role", roleID)
+	}
+	return existing.Role, etag(existing.version), nil
+}
+
+func (r customRoles) Create(request iampapv2.CreateRoleRequest) (iampapv2.Role, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.Create"); err != nil {
+		return iampapv2.Role{}, err
+	}
+	if request.AccountID != AccountID {
+		return iampapv2.Role{}, badRequest("Account " + request.AccountID + " is not accessible")
+	}
+	for _, existing := range r.f.roles {
+		if existing.Name == request.Name && existing.ServiceName == request.ServiceName {
+			return iampapv2.Role{}, Conflict("Role " + request.Name + " already exists")
+		}
+	}
+	return r.f.createRole(request).Role, nil
+}
+
+func (r customRoles) Update(request iampapv2.UpdateRoleRequest, roleID, etagValue string) (iampapv2.Role, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.Update"); err != nil {
+		return iampapv2.Role{}, err
+	}
+	existing, ok := r.f.roles[roleID]
+	if !ok {
+		return iampapv2.Role{}, NotFound("Role", roleID)
+	}
+	if etagValue != etag(existing.version) {
+		return iampapv2.Role{}, preconditionFailed()
+	}
+	updateRole(existing, request)
+	return existing.Role, nil
+}
+
+func (r customRoles) Delete(roleID string) error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.Delete"); err != nil {
+		return err
+	}
+	if _, ok := r.f.roles[roleID]; !ok {
+		return NotFound("Role", roleID)
+	}
+	delete(r.f.roles, roleID)
+	return nil
+}
+
+func (r customRoles) ListCustomRoles(accountID, serviceName string) ([]iampapv2.Role, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.ListCustomRoles"); err != nil {
+		return nil, err
+	}
+	if accountID != AccountID {
+		return []iampapv2.Role{}, nil
+	}
+	return r.f.listRoles(serviceName), nil
+}
+
+func (r customRoles) ListSystemDefinedRoles() ([]iampapv2.Role, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.ListSystemDefinedRoles"); err != nil {
+		return nil, err
+	}
+	return systemRoles(crn.ServiceIAM, crn.ResourceTypeRole, platformRoles), nil
+}
+
+func (r customRoles) ListServiceRoles(serviceName string) ([]iampapv2.Role, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.ListServiceRoles"); err != nil {
+		return nil, err
+	}
+	return systemRoles(serviceName, "serviceRole", r.f.serviceRoleNames(serviceName)), nil
+}
+
+func (r customRoles) ListAll(query iampapv2.RoleQuery) ([]iampapv2.Role, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("CustomRoles.ListAll"); err != nil {
+		return nil, err
+	}
+	roles := []iampapv2.Role{}
+	if query.AccountID == AccountID {
+		roles = append(roles, r.f.listRoles(query.ServiceName)...)
+	}
+	if query.ServiceName != "" {
+		roles = append(roles, systemRoles(query.ServiceName, "serviceRole", r.f.serviceRoleNames(query.ServiceName))...)
+	}
+	return append(roles, systemRoles(crn.ServiceIAM, crn.ResourceTypeRole, platformRoles)...), nil
+}
+
+// serviceRoles is the service roles API of the account
+type serviceRoles struct{ f *Factory }
+
+func (r serviceRoles) ListServiceRoles(serviceName string) ([]models.PolicyRole, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceRoles.ListServiceRoles"); err != nil {
+		return nil, err
+	}
+	roles := policyRoles(serviceName, "serviceRole", r.f.serviceRoleNames(serviceName))
+	return append(roles, policyRoles(crn.ServiceIAM, crn.ResourceTypeRole, platformRoles)...), nil
+}
+
+func (r serviceRoles) ListSystemDefinedRoles() ([]models.PolicyRole, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceRoles.ListSystemDefinedRoles"); err != nil {
+		return nil, err
+	}
+	return policyRoles(crn.ServiceIAM, crn.ResourceTypeRole, platformRoles), nil
+}
+
+func (r serviceRoles) ListServiceSpecificRoles(serviceName string) ([]models.PolicyRole, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceRoles.ListServiceSpecificRoles"); err != nil {
+		return nil, err
+	}
+	return policyRoles(serviceName, "serviceRole", r.f.serviceRoleNames(serviceName)), nil
+}
+
+func (r serviceRoles) ListAuthorizationRoles(sourceServiceName string, targetServiceName string) ([]models.PolicyRole, error) {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	if err := r.f.call("ServiceRoles.ListAuthorizationRoles"); err != nil {
+		return nil, err
+	}
+	return policyRoles(targetServiceName, "serviceRole", r.f.serviceRoleNames(targetServiceName)), nil
+}
+
+func roleCRN(serviceName string, resourceType string, name string) crn.CRN {
+	id := crn.New("bluemix", "public")
+	id.ServiceName = serviceName
+	id.ResourceType = resourceType
+	id.Resource = name
+	return id
+}
+
+func policyRoles(serviceName string, resourceType string, names []string) []models.PolicyRole {
+	roles := []models.PolicyRole{}
+	for _, name := range names {
+		roles = append(roles, models.PolicyRole{ID: roleCRN(serviceName, resourceType, name), DisplayName: name})
+	}
+	return roles
+}
+
+func systemRoles(serviceName string, resourceType string, names []string) []iampapv2.Role {
+	roles := []iampapv2.Role{}
+	for _, name := range names {
+		id := roleCRN(serviceName, resourceType, name).String()
+		roles = append(roles, iampapv2.Role{
+			CreateRoleRequest: iampapv2.CreateRoleRequest{Name: name, ServiceName: serviceName, DisplayName: name},
+			ID:                id,
+			Crn:               id,
+		})
+	}
+	return roles
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package iamclient connects reconcilers to the IAM account configured for a
+// namespace. Reconcilers get their IAM clients from a Factory so that tests can
+// replace IBM Cloud with the in-memory account of package fake.
+package iamclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamcache"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/registry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/resilience"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/tracing"
+	common "github.com/IBM/ibmcloud-iam-operator/pkg/util"
+)
+
+// Factory connects reconcilers to the IAM account of a namespace
+type Factory interface {
+	// Connect returns the IAM account configured for a namespace and the
+	// clients of its IAM APIs
+	Connect(ctx context.Context, c client.Client, namespace string) (*accountv2.Account, Clients, error)
+}
+
+// Clients are the IAM APIs of an account. Clients are created on first use, so a
+// reconcile only authenticates with the APIs it calls.
+type Clients interface {
+	Policies() (polv1.PolicyRepository, error)
+	PoliciesV2() (polv2.PolicyRepository, error)
+	ServiceIDs() (iamv1.ServiceIDRepository, error)
+	AccessGroups() (iamuumv2.AccessGroupRepository, error)
+	AccessGroupMembers() (iamuumv2.AccessGroupMemberRepositoryV2, error)
+	CustomRoles() (iampapv2.RoleRepository, error)
+	Accounts() (accountv1.Accounts, error)
+	// Cache returns the cache of the IAM state of the account
+	Cache() (*iamcache.Cache, error)
+	// RoleCatalog returns the catalog of the roles of the account
+	RoleCatalog() (*rolecatalog.Catalog, error)
+}
+
+// New returns the factory connecting to IBM Cloud with the credentials
+// configured for each namespace
+func New() Factory {
+	return factory{}
+}
+
+type factory struct{}
+
+func (factory) Connect(ctx context.Context, c client.Client, namespace string) (*accountv2.Account, Clients, error) {
+	sess, account, err := common.GetIAMAccountInfo(c, namespace)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			forget(namespace)
+		}
+		return nil, nil, err
+	}
+	connected(namespace, credential{accountID: account.GUID, key: registry.Key(account.GUID, sess.Config.BluemixAPIKey)})
+	// Route IAM clients through the account's rate limiter, retries and
+	// circuit breaker
+	resilience.Install(sess, account.GUID)
+	tracing.Instrument(sess)
+	return account, &clients{sess: tracing.WithContext(ctx, sess), shared: sess, accountID: account.GUID}, nil
+}
+
+// NewClients returns the IAM APIs of an account for a session, e.g. one created
+// outside of the cluster by a command line tool
+func NewClients(sess *session.Session, accountID string) Clients {
+	return &clients{sess: sess, shared: sess, accountID: accountID}
+}
+
+// clients creates the IAM APIs of a session, sharing the service clients
+// between the APIs of a service
+type clients struct {
+	// sess sends the requests within the span of the reconcile
+	sess *session.Session
+	// shared is the session of the cache and the catalog, which outlive the reconcile
+	shared    *session.Session
+	accountID string
+	iam       iamv1.IAMServiceAPI
+	iamuum    iamuumv2.IAMUUMServiceAPIv2
+}
+
+func (c *clients) Policies() (polv1.PolicyRepository, error) {
+	return polv1.New(c.sess)
+}
+
+func (c *clients) PoliciesV2() (polv2.PolicyRepository, error) {
+	return polv2.New(c.sess)
+}
+
+func (c *clients) ServiceIDs() (iamv1.ServiceIDRepository, error) {
+	if c.iam == nil {
+		iamClient, err := iamv1.New(c.sess)
+		if err != nil {
+			return nil, err
+		}
+		c.iam = iamClient
+	}
+	return c.iam.ServiceIds(), nil
+}
+
+func (c *clients) AccessGroups() (iamuumv2.AccessGroupRepository, error) {
+	if err := c.connectIAMUUM(); err != nil {
+		return nil, err
+	}
+	return c.iamuum.AccessGroup(), nil
+}
+
+func (c *clients) AccessGroupMembers() (iamuumv2.AccessGroupMemberRepositoryV2, error) {
+	if err := c.connectIAMUUM(); err != nil {
+		return nil, err
+	}
+	return c.iamuum.AccessGroupMember(), nil
+}
+
+func (c *clients) connectIAMUUM() error {
+	if c.iamuum != nil {
+		return nil
+	}
+	iamuumClient, err := iamuumv2.New(c.sess)
+	if err != nil {
+		return err
+	}
+	c.iamuum = iamuumClient
+	return nil
+}
+
+func (c *clients) CustomRoles() (iampapv2.RoleRepository, error) {
+	roleClient, err := iampapv2.New(c.sess)
+	if err != nil {
+		return nil, err
+	}
+	return roleClient.IAMRoles(), nil
+}
+
+func (c *clients) Accounts() (accountv1.Accounts, error) {
+	accClient, err := accountv1.New(c.sess)
+	if err != nil {
+		return nil, err
+	}
+	return accClient.Accounts(), nil
+}
+
+func (c *clients) Cache() (*iamcache.Cache, error) {
+	return iamcache.ForAccount(c.shared, c.accountID)
+}
+
+func (c *clients) RoleCatalog() (*rolecatalog.Catalog, error) {
+	cache, err := c.Cache()
+	if err != nil {
+		return nil, err
+	}
+	return rolecatalog.ForAccount(c.shared, c.accountID, cache)
+}
+
+// NotFound returns whether err is IAM answering that the object requested doesn't exist. Other errors, such as
+// IAM being unavailable, don't tell whether the object exists.
+func NotFound(err error) bool {
+	var failure bmxerror.RequestFailure
+	return errors.As(err, &failure) && failure.StatusCode() == http.StatusNotFound
+}
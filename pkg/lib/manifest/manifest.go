@@ -0,0 +1,165 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package manifest loads the custom resources of the operator from YAML or JSON
+// manifests, e.g. to plan their changes to IAM outside of a cluster.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+)
+
+// Resources are the custom resources of manifests managing IAM objects
+type Resources struct {
+	CustomRoles           []ibmcloudv1alpha1.CustomRole
+	AccessGroups          []ibmcloudv1alpha1.AccessGroup
+	AccessPolicies        []ibmcloudv1alpha1.AccessPolicy
+	AuthorizationPolicies []ibmcloudv1alpha1.AuthorizationPolicy
+}
+
+// extensions are the extensions of the manifests loaded from directories
+var extensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// Load loads the resources of manifest files, and of the manifests in
+// directories and their subdirectories. Resources without a namespace are put
+// in namespace.
+func Load(namespace string, paths ...string) (*Resources, error) {
+	resources := &Resources{}
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || file != path && !extensions[strings.ToLower(filepath.Ext(file))] {
+				return nil
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if err := resources.Decode(data, namespace); err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// Decode adds the resources of a stream of YAML documents, or of a JSON
+// document. Documents of other kinds, such as the secrets of the operator, are
+// ignored.
+func (r *Resources) Decode(data []byte, namespace string) error {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.decodeDocument(document, namespace); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Resources) decodeDocument(document []byte, namespace string) error {
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(document, &typeMeta); err != nil {
+		return err
+	}
+	if typeMeta.APIVersion != ibmcloudv1alpha1.SchemeGroupVersion.String() {
+		return nil
+	}
+
+	switch typeMeta.Kind {
+	case "CustomRole":
+		instance := ibmcloudv1alpha1.CustomRole{}
+		if err := DecodeObject(document, &instance, namespace); err != nil {
+			return err
+		}
+		r.CustomRoles = append(r.CustomRoles, instance)
+	case "AccessGroup":
+		instance := ibmcloudv1alpha1.AccessGroup{}
+		if err := DecodeObject(document, &instance, namespace); err != nil {
+			return err
+		}
+		r.AccessGroups = append(r.AccessGroups, instance)
+	case "AccessPolicy":
+		instance := ibmcloudv1alpha1.AccessPolicy{}
+		if err := DecodeObject(document, &instance, namespace); err != nil {
+			return err
+		}
+		r.AccessPolicies = append(r.AccessPolicies, instance)
+	case "AuthorizationPolicy":
+		instance := ibmcloudv1alpha1.AuthorizationPolicy{}
+		if err := DecodeObject(document, &instance, namespace); err != nil {
+			return err
+		}
+		r.AuthorizationPolicies = append(r.AuthorizationPolicies, instance)
+	}
+	return nil
+}
+
+// DecodeObject decodes a YAML or JSON document into a resource, in namespace if it has none
+func DecodeObject(document []byte, obj runtime.Object, namespace string) error {
+	if err := yaml.Unmarshal(document, obj); err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if accessor.GetName() == "" {
+		return fmt.Errorf("a resource has no name:\n%s", document)
+	}
+	if accessor.GetNamespace() == "" {
+		accessor.SetNamespace(namespace)
+	}
+	return nil
+}
+
+// LoadObject loads the resource of a manifest file holding a single document, in namespace if it has none
+func LoadObject(filename string, obj runtime.Object, namespace string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := DecodeObject(data, obj, namespace); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+)
+
+func TestLoad(t *testing.T) {
+	resources, err := Load("iam", filepath.Join("testdata", "manifests"))
+	require.NoError(t, err)
+
+	require.Len(t, resources.CustomRoles, 1)
+	assert.Equal(t, "deployer", resources.CustomRoles[0].Name)
+	assert.Equal(t, "roles", resources.CustomRoles[0].Namespace)
+	require.Len(t, resources.AccessGroups, 1)
+	assert.Equal(t, "iam", resources.AccessGroups[0].Namespace, "default namespace")
+	assert.Equal(t, []string{"alice@example.com"}, resources.AccessGroups[0].Spec.UserEmails)
+	require.Len(t, resources.AccessPolicies, 2)
+	assert.Equal(t, "developers", resources.AccessPolicies[0].Spec.Subject.AccessGroupDef.AccessGroupName)
+	assert.Equal(t, "alice@example.com", resources.AccessPolicies[1].Spec.Subject.UserEmail)
+	require.Len(t, resources.AuthorizationPolicies, 1)
+	assert.Equal(t, []string{"Reader"}, resources.AuthorizationPolicies[0].Spec.Roles)
+}
+
+func TestLoadFile(t *testing.T) {
+	resources, err := Load("iam", filepath.Join("testdata", "manifests", "policies.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, resources.AccessPolicies, 2)
+	assert.Empty(t, resources.AccessGroups)
+}
+
+func TestLoadObject(t *testing.T) {
+	role := &ibmcloudv1alpha1.CustomRole{}
+	require.NoError(t, LoadObject(filepath.Join("testdata", "manifests", "roles", "deployer.json"), role, "iam"))
+	assert.Equal(t, "deployer", role.Name)
+	assert.Equal(t, "roles", role.Namespace)
+
+	assert.Error(t, LoadObject(filepath.Join("testdata", "missing.yaml"), role, "iam"))
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	resources := &Resources{}
+	assert.Error(t, resources.Decode([]byte("apiVersion: ibmcloud.ibm.com/v1alpha1\nkind: AccessGroup\nspec: {}\n"), "iam"), "no name")
+	assert.Error(t, resources.Decode([]byte("apiVersion: ibmcloud.ibm.com/v1alpha1\nkind: AccessGroup\nmetadata: [\n"), "iam"))
+	assert.Error(t, resources.Decode([]byte("apiVersion: ibmcloud.ibm.com/v1alpha1\nkind: AccessGroup\nmetadata:\n  name: a\nspec:\n  userEmails: alice\n"), "iam"))
+	_, err := Load("iam", filepath.Join("testdata", "missing"))
+	assert.Error(t, err)
+}
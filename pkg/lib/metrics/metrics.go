@@ -0,0 +1,180 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package metrics defines the operator metrics on IAM requests, caches and custom resources,
+// registered with the controller-runtime metrics registry
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// namespace prefixes the names of the operator metrics
+const namespace = "ibmcloud_iam_operator"
+
+var (
+	iamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "iam_requests_total",
+		Help:      "Number of requests sent to IBM Cloud IAM, by service, operation and HTTP status code.",
+	}, []string{"service", "operation", "code"})
+
+	iamRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "iam_request_errors_total",
+		Help:      "Number of requests to IBM Cloud IAM that failed or returned an error status, by service and operation.",
+	}, []string{"service", "operation"})
+
+	iamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "iam_request_duration_seconds",
+		Help:      "Latency of the requests sent to IBM Cloud IAM, by service and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "operation"})
+
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_corrections_total",
+		Help:      "Number of IAM objects reverted to their spec after they were changed outside of the operator, by kind.",
+	}, []string{"kind"})
+
+	credentialFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "credential_lookup_failures_total",
+		Help:      "Number of failures to get the IBM Cloud credentials and account of a namespace, by namespace and reason.",
+	}, []string{"namespace", "reason"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Number of lookups in the caches of IAM state, by cache, kind and result (hit or miss).",
+	}, []string{"cache", "kind", "result"})
+
+	timeToOnline = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "time_to_online_seconds",
+		Help:      "Time from the creation of a resource to the creation of its IAM object, by kind.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"kind"})
+	leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Whether the replica leads the other replicas and reconciles the resources, 1 if it does.",
+	})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(iamRequests, iamRequestErrors, iamRequestDuration, driftCorrections, credentialFailures, cacheLookups, timeToOnline, leader)
+}
+
+// Leading records whether the replica leads
+func Leading(leading bool) {
+	if leading {
+		leader.Set(1)
+	} else {
+		leader.Set(0)
+	}
+}
+
+// ReportUnavailableAccounts registers the iam_unavailable_accounts metric, counted by count when the
+// metrics are collected
+func ReportUnavailableAccounts(count func() float64) {
+	crmetrics.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "iam_unavailable_accounts",
+		Help:      "Number of accounts IBM Cloud IAM is unavailable for, whose requests are paused after repeated transient errors.",
+	}, count))
+}
+
+// DriftCorrected counts an IAM object of kind reverted to its spec
+func DriftCorrected(kind string) {
+	driftCorrections.WithLabelValues(kind).Inc()
+}
+
+// CredentialLookupFailed counts a failure to get the credentials or account of a namespace
+func CredentialLookupFailed(namespace string, reason string) {
+	credentialFailures.WithLabelValues(namespace, reason).Inc()
+}
+
+// CacheLookup counts a lookup of kind in cache, and whether it was found
+func CacheLookup(cache string, kind string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, kind, result).Inc()
+}
+
+// Online records how long a resource of kind created at created took to have its IAM object
+func Online(kind string, created time.Time) {
+	timeToOnline.WithLabelValues(kind).Observe(time.Since(created).Seconds())
+}
+
+// Transport is an http.RoundTripper counting and timing the requests sent to IAM
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip sends the request with the base transport and records its outcome
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	service, operation := Operation(req)
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	iamRequestDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	iamRequests.WithLabelValues(service, operation, code).Inc()
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		iamRequestErrors.WithLabelValues(service, operation).Inc()
+	}
+	return resp, err
+}
+
+var (
+	// literal matches the path segments naming an API version or a collection, the others are IDs
+	literal = regexp.MustCompile(`^(v[0-9]+|[a-z_]+)$`)
+	// version matches the path segments naming an API version
+	version = regexp.MustCompile(`^v[0-9]+$`)
+)
+
+// Operation returns the service of a request, the collection it is about, and its operation, its method and path
+// with IDs left out, e.g. "policies" and "GET /v1/policies/{id}" so that metrics don't have a label per IAM object
+func Operation(req *http.Request) (string, string) {
+	service := req.URL.Host
+	var segments []string
+	for _, segment := range strings.Split(req.URL.Path, "/") {
+		switch {
+		case segment == "":
+		case !literal.MatchString(segment):
+			segments = append(segments, "{id}")
+		default:
+			if service == req.URL.Host && !version.MatchString(segment) {
+				service = segment
+			}
+			segments = append(segments, segment)
+		}
+	}
+	return service, req.Method + " /" + strings.Join(segments, "/")
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIAM answers requests with the status code, or an error if it is 0
+type fakeIAM struct {
+	code int
+}
+
+func (f *fakeIAM) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.code == 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+	return &http.Response{StatusCode: f.code, Body: http.NoBody, Request: req}, nil
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		method    string
+		url       string
+		service   string
+		operation string
+	}{
+		{"GET", "https://iam.cloud.ibm.com/v1/policies/6e1e0d5c-2d7e-4a6c-9e5b-8b1f0e7f2a31", "policies", "GET /v1/policies/{id}"},
+		{"GET", "https://iam.cloud.ibm.com/v1/policies?account_id=12ab34cd&type=access", "policies", "GET /v1/policies"},
+		{"PUT", "https://iam.cloud.ibm.com/v2/groups/AccessGroupId-8c3f1a2b/members", "groups", "PUT /v2/groups/{id}/members"},
+		{"GET", "https://iam.cloud.ibm.com/v1/serviceids/ServiceId-2f4e6a8c", "serviceids", "GET /v1/serviceids/{id}"},
+		{"POST", "https://iam.cloud.ibm.com/identity/token", "identity", "POST /identity/token"},
+		{"GET", "https://iam.cloud.ibm.com/", "iam.cloud.ibm.com", "GET /"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		assert.NoError(t, err)
+		service, operation := Operation(req)
+		assert.Equal(t, test.service, service, test.url)
+		assert.Equal(t, test.operation, operation, test.url)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	operation := "GET /v2/roles/{id}"
+	for _, code := range []int{200, 404, 0} {
+		req, _ := http.NewRequest("GET", "https://iam.cloud.ibm.com/v2/roles/0f1e2d3c", nil)
+		_, _ = (&Transport{Base: &fakeIAM{code: code}}).RoundTrip(req)
+	}
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(iamRequests.WithLabelValues("roles", operation, "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(iamRequests.WithLabelValues("roles", operation, "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(iamRequests.WithLabelValues("roles", operation, "error")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(iamRequestErrors.WithLabelValues("roles", operation)))
+}
+
+func TestCacheLookup(t *testing.T) {
+	CacheLookup("iam", "serviceid", true)
+	CacheLookup("iam", "serviceid", true)
+	CacheLookup("iam", "serviceid", false)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(cacheLookups.WithLabelValues("iam", "serviceid", "hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheLookups.WithLabelValues("iam", "serviceid", "miss")))
+}
+
+func TestLeading(t *testing.T) {
+	Leading(true)
+	assert.Equal(t, float64(1), testutil.ToFloat64(leader))
+	Leading(false)
+	assert.Equal(t, float64(0), testutil.ToFloat64(leader))
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("metrics")
+
+// collectTimeout bounds the time a scrape waits for the resources to be listed
+const collectTimeout = 10 * time.Second
+
+var resourcesDesc = prometheus.NewDesc(namespace+"_resources", "Number of custom resources, by kind, namespace and state.", []string{"kind", "namespace", "state"}, nil)
+
+// StateCollector is a prometheus.Collector counting the custom resources of each kind per namespace and state
+type StateCollector struct {
+	reader client.Reader
+	lists  map[string]runtime.Object
+}
+
+// NewStateCollector returns a collector counting the resources of the lists, by kind, read with reader
+func NewStateCollector(reader client.Reader, lists map[string]runtime.Object) *StateCollector {
+	return &StateCollector{reader: reader, lists: lists}
+}
+
+// Describe implements prometheus.Collector
+func (c *StateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourcesDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *StateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	for kind, prototype := range c.lists {
+		list := prototype.DeepCopyObject()
+		if err := c.reader.List(ctx, list); err != nil {
+			log.Info("Error listing resources for metrics", "Kind", kind, "Failed", err.Error())
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			log.Info("Error reading resources for metrics", "Kind", kind, "Failed", err.Error())
+			continue
+		}
+
+		counts := map[[2]string]int{}
+		for _, item := range items {
+			accessor, err := meta.Accessor(item)
+			if err != nil {
+				continue
+			}
+			counts[[2]string{accessor.GetNamespace(), state(item)}]++
+		}
+		for key, count := range counts {
+			ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, float64(count), kind, key[0], key[1])
+		}
+	}
+}
+
+// state returns the state in the status of a custom resource, or "Unknown" if it has none yet. The resource
+// types are not imported, as they depend on this package through the utilities.
+func state(obj runtime.Object) string {
+	status := reflect.Indirect(reflect.ValueOf(obj)).FieldByName("Status")
+	if !status.IsValid() || status.Kind() != reflect.Struct {
+		return "Unknown"
+	}
+	state := status.FieldByName("State")
+	if !state.IsValid() || state.Kind() != reflect.String || state.String() == "" {
+		return "Unknown"
+	}
+	return state.String()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+)
+
+func policy(namespace string, name string, state string) *ibmcloudv1alpha1.AccessPolicy {
+	policy := &ibmcloudv1alpha1.AccessPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	policy.Status.State = state
+	return policy
+}
+
+func TestStateCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, ibmcloudv1alpha1.SchemeBuilder.AddToScheme(scheme))
+	client := fake.NewFakeClientWithScheme(scheme,
+		policy("default", "viewer", "Online"),
+		policy("default", "editor", "Online"),
+		policy("default", "admin", "Failed"),
+		policy("team", "viewer", ""),
+	)
+
+	collector := metrics.NewStateCollector(client, map[string]runtime.Object{"AccessPolicy": &ibmcloudv1alpha1.AccessPolicyList{}})
+	expected := `
+# HELP ibmcloud_iam_operator_resources Number of custom resources, by kind, namespace and state.
+# TYPE ibmcloud_iam_operator_resources gauge
+ibmcloud_iam_operator_resources{kind="AccessPolicy",namespace="default",state="Failed"} 1
+ibmcloud_iam_operator_resources{kind="AccessPolicy",namespace="default",state="Online"} 2
+ibmcloud_iam_operator_resources{kind="AccessPolicy",namespace="team",state="Unknown"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ownership binds custom resources to the IAM objects the operator manages
+// for them, so the binding survives a loss of status, e.g. on backup and restore.
+package ownership
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+const (
+	// IAMIDAnnotation records the ID of the IAM object managed for a resource
+	IAMIDAnnotation = "ibmcloud.ibm.com/iam-id"
+	// FingerprintAnnotation records the resource and account the IAM ID belongs to
+	FingerprintAnnotation = "ibmcloud.ibm.com/iam-fingerprint"
+	// IntentAnnotation records the hash of a policy the operator is about to create, until its ID is recorded
+	IntentAnnotation = "ibmcloud.ibm.com/create-intent"
+	// DescriptionPrefix marks the description of IAM objects owned by the operator
+	DescriptionPrefix = "OPERATOR OWNED: "
+)
+
+// Fingerprint identifies a resource of a kind managed in an account. It doesn't
+// depend on the resource UID, which changes when the resource is re-created.
+func Fingerprint(kind string, obj metav1.Object, accountID string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{accountID, kind, obj.GetNamespace(), obj.GetName()}, "/")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Record sets the IAM ID and fingerprint annotations of a resource, and returns true if they changed
+func Record(kind string, obj metav1.Object, accountID string, iamID string) bool {
+	if iamID == "" {
+		return false
+	}
+	fingerprint := Fingerprint(kind, obj, accountID)
+	annotations := obj.GetAnnotations()
+	if annotations[IAMIDAnnotation] == iamID && annotations[FingerprintAnnotation] == fingerprint {
+		return false
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[IAMIDAnnotation] = iamID
+	annotations[FingerprintAnnotation] = fingerprint
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// RecordedID returns the IAM ID recorded on a resource, or "" if it wasn't
+// recorded for this resource and account, e.g. when annotations were copied
+func RecordedID(kind string, obj metav1.Object, accountID string) string {
+	annotations := obj.GetAnnotations()
+	if annotations[FingerprintAnnotation] != Fingerprint(kind, obj, accountID) {
+		return ""
+	}
+	return annotations[IAMIDAnnotation]
+}
+
+// SetIntent records that a policy with the hash is about to be created for a resource, and returns true if it changed
+func SetIntent(obj metav1.Object, hash string) bool {
+	annotations := obj.GetAnnotations()
+	if annotations[IntentAnnotation] == hash {
+		return false
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[IntentAnnotation] = hash
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// Intent returns the hash of the policy that was about to be created for a resource, or "" if there is none
+func Intent(obj metav1.Object) string {
+	return obj.GetAnnotations()[IntentAnnotation]
+}
+
+// ClearIntent removes the creation intent of a resource once the ID of its policy is recorded, and returns true if it changed
+func ClearIntent(obj metav1.Object) bool {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[IntentAnnotation]; !ok {
+		return false
+	}
+	delete(annotations, IntentAnnotation)
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// PolicyHash is a hash of the content of a policy that doesn't depend on the order of its attributes and roles
+func PolicyHash(policy polv2.Policy) string {
+	var lines []string
+	for _, a := range policy.Subject.Attributes {
+		lines = append(lines, attributeLine("subject", a))
+	}
+	for _, r := range policy.Control.Grant.Roles {
+		lines = append(lines, "role "+r.RoleID)
+	}
+	for _, a := range policy.Resource.Attributes {
+		lines = append(lines, attributeLine("resource", a))
+	}
+	for _, a := range policy.Resource.Tags {
+		lines = append(lines, attributeLine("tag", a))
+	}
+	sort.Strings(lines)
+	lines = append([]string{"type " + policy.Type, "pattern " + policy.Pattern}, lines...)
+	if policy.Rule != nil {
+		// Marshalling the rule as generic JSON sorts its keys, like when it is read back from IAM
+		var rule interface{}
+		if data, err := json.Marshal(policy.Rule); err == nil && json.Unmarshal(data, &rule) == nil {
+			data, _ = json.Marshal(rule)
+			lines = append(lines, "rule "+string(data))
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func attributeLine(kind string, a polv2.Attribute) string {
+	operator := a.Operator
+	if operator == "" {
+		operator = polv1.OperatorStringEquals
+	}
+	return strings.Join([]string{kind, a.Key, operator, a.Value}, " ")
+}
+
+// Owned returns true if a description of an IAM object marks it as owned by the operator
+func Owned(description string) bool {
+	return strings.HasPrefix(description, DescriptionPrefix)
+}
+
+// Marker returns the start of the description of the IAM objects owned by an operator instance: the
+// DescriptionPrefix followed by the instance ID in brackets, or the DescriptionPrefix alone without ID
+func Marker(instanceID string) string {
+	if instanceID == "" {
+		return DescriptionPrefix
+	}
+	return DescriptionPrefix + "[" + instanceID + "] "
+}
+
+// Owner returns the ID of the operator instance owning the IAM object with a description, "" if the
+// object isn't owned by the operator or its description doesn't name an instance
+func Owner(description string) string {
+	if !Owned(description) {
+		return ""
+	}
+	rest := strings.TrimPrefix(description, DescriptionPrefix)
+	end := strings.Index(rest, "] ")
+	if !strings.HasPrefix(rest, "[") || end < 0 {
+		return ""
+	}
+	return rest[1:end]
+}
+
+// TrimMarker returns a description without the marker of the operator instance owning its IAM object
+func TrimMarker(description string) string {
+	return strings.TrimPrefix(description, Marker(Owner(description)))
+}
+
+// Adoptable returns true if an IAM object with a description may be adopted by an operator instance: it is
+// owned by the operator, and not marked by another instance
+func Adoptable(description string, instanceID string) bool {
+	owner := Owner(description)
+	return Owned(description) && (owner == "" || owner == instanceID)
+}
+
+// Claimed returns the IAM IDs recorded on resources of a kind for an account, except the one on obj. The
+// operator never adopts a policy another resource recorded, even when it has the content obj wants.
+func Claimed(kind string, objs []metav1.Object, obj metav1.Object, accountID string) map[string]bool {
+	claimed := map[string]bool{}
+	for _, other := range objs {
+		if other.GetNamespace() == obj.GetNamespace() && other.GetName() == obj.GetName() {
+			continue
+		}
+		if id := RecordedID(kind, other, accountID); id != "" {
+			claimed[id] = true
+		}
+	}
+	return claimed
+}
+
+// FindV1Policy returns the ID of the policy listed with params that has the hash of the creation intent, the
+// marker of the operator instance in its description and isn't claimed, or "" if there is none. A policy with
+// the same content may have been created by hand, and given the marker when copied, so only the intent
+// recorded before creating a policy identifies it.
+func FindV1Policy(policyAPI polv1.PolicyRepository, params polv1.SearchParams, intent string, instanceID string, claimed map[string]bool) (string, error) {
+	if intent == "" {
+		return "", nil
+	}
+	policies, err := policyAPI.List(params)
+	if err != nil {
+		return "", err
+	}
+	for _, policy := range policies {
+		if !claimed[policy.ID] && Adoptable(policy.Description, instanceID) && PolicyHash(polv2.ConvertV1Policy(policy)) == intent {
+			return policy.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// FindV2Policy returns the ID of the v2 policy listed with params that has the hash of the creation intent, the
+// marker of the operator instance in its description and isn't claimed, or "" if there is none
+func FindV2Policy(policyAPI polv2.PolicyRepository, params polv2.SearchParams, intent string, instanceID string, claimed map[string]bool) (string, error) {
+	if intent == "" {
+		return "", nil
+	}
+	policies, err := policyAPI.List(params)
+	if err != nil {
+		return "", err
+	}
+	for _, policy := range policies {
+		if !claimed[policy.ID] && Adoptable(policy.Description, instanceID) && PolicyHash(policy) == intent {
+			return policy.ID, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ownership
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+func TestRecord(t *testing.T) {
+	group := &ibmcloudv1alpha1.AccessGroup{}
+	group.Name = "admins"
+	group.Namespace = "default"
+
+	assert.Equal(t, "", RecordedID("AccessGroup", group, "12345"))
+	assert.False(t, Record("AccessGroup", group, "12345", ""))
+	assert.True(t, Record("AccessGroup", group, "12345", "AccessGroupId-123"))
+	assert.False(t, Record("AccessGroup", group, "12345", "AccessGroupId-123"))
+	assert.Equal(t, "AccessGroupId-123", RecordedID("AccessGroup", group, "12345"))
+
+	// Annotations copied to another resource or account are not trusted
+	assert.Equal(t, "", RecordedID("AccessGroup", group, "67890"))
+	copied := &ibmcloudv1alpha1.AccessGroup{}
+	copied.Name = "admins"
+	copied.Namespace = "dev"
+	copied.Annotations = group.Annotations
+	assert.Equal(t, "", RecordedID("AccessGroup", copied, "12345"))
+}
+
+func TestOwned(t *testing.T) {
+	assert.True(t, Owned(DescriptionPrefix+"admins"))
+	assert.False(t, Owned("admins"))
+}
+
+func TestMarker(t *testing.T) {
+	assert.Equal(t, "OPERATOR OWNED: ", Marker(""))
+	assert.Equal(t, "OPERATOR OWNED: [cluster-a] ", Marker("cluster-a"))
+
+	assert.Equal(t, "cluster-a", Owner(Marker("cluster-a")+"admins"))
+	assert.Equal(t, "", Owner(Marker("")+"admins"))
+	assert.Equal(t, "", Owner(Marker("")+"[draft admins"))
+	assert.Equal(t, "", Owner("[cluster-a] admins"))
+
+	assert.Equal(t, "admins", TrimMarker(Marker("cluster-a")+"admins"))
+	assert.Equal(t, "admins", TrimMarker(Marker("")+"admins"))
+	assert.Equal(t, "admins", TrimMarker("admins"))
+
+	assert.True(t, Adoptable(Marker("")+"admins", "cluster-a"))
+	assert.True(t, Adoptable(Marker("cluster-a")+"admins", "cluster-a"))
+	assert.False(t, Adoptable(Marker("cluster-b")+"admins", "cluster-a"))
+	assert.False(t, Adoptable(Marker("cluster-b")+"admins", ""))
+	assert.False(t, Adoptable("admins", "cluster-a"))
+}
+
+type fakePolicyRepository struct {
+	polv1.PolicyRepository
+	policies []polv1.Policy
+	err      error
+}
+
+func (r fakePolicyRepository) List(params polv1.SearchParams) ([]polv1.Policy, error) {
+	return r.policies, r.err
+}
+
+func TestFindV1Policy(t *testing.T) {
+	desired := polv1.Policy{Type: "access", Roles: []iampapv1.Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Viewer"}}}
+	subject := polv1.Subject{}
+	subject.SetAttribute("iam_id", "iam-ServiceId-123")
+	desired.Subjects = []polv1.Subject{subject}
+	desired.Description = Marker("prod") + "AccessPolicy default/readers"
+
+	other := desired
+	other.ID = "policy-1"
+	other.Roles = []iampapv1.Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Editor"}}
+	same := desired
+	same.ID = "policy-2"
+
+	// A policy with the desired content may have been created by hand, it isn't adopted without an intent
+	id, err := FindV1Policy(fakePolicyRepository{policies: []polv1.Policy{other, same}}, polv1.SearchParams{}, "", "prod", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", id)
+
+	// A policy created from an earlier spec is found by the hash of the creation intent
+	intent := PolicyHash(polv2.ConvertV1Policy(other))
+	id, err = FindV1Policy(fakePolicyRepository{policies: []polv1.Policy{same, other}}, polv1.SearchParams{}, intent, "prod", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "policy-1", id)
+
+	// A policy without the marker of the operator instance isn't adopted, even with the content of the intent
+	for _, description := range []string{"", Marker("staging") + "AccessPolicy default/readers"} {
+		unmarked := other
+		unmarked.Description = description
+		id, err = FindV1Policy(fakePolicyRepository{policies: []polv1.Policy{unmarked}}, polv1.SearchParams{}, intent, "prod", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "", id, description)
+	}
+
+	// A policy recorded by another resource is never adopted
+	id, err = FindV1Policy(fakePolicyRepository{policies: []polv1.Policy{other}}, polv1.SearchParams{}, intent, "prod", map[string]bool{"policy-1": true})
+	assert.NoError(t, err)
+	assert.Equal(t, "", id)
+
+	_, err = FindV1Policy(fakePolicyRepository{err: errors.New("unauthorized")}, polv1.SearchParams{}, intent, "prod", nil)
+	assert.Error(t, err)
+}
+
+func TestClaimed(t *testing.T) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	policy.Name = "readers"
+	policy.Namespace = "default"
+	Record("AccessPolicy", policy, "12345", "policy-1")
+	other := &ibmcloudv1alpha1.AccessPolicy{}
+	other.Name = "writers"
+	other.Namespace = "default"
+	Record("AccessPolicy", other, "12345", "policy-2")
+	copied := &ibmcloudv1alpha1.AccessPolicy{}
+	copied.Name = "copied"
+	copied.Namespace = "default"
+	copied.Annotations = map[string]string{IAMIDAnnotation: "policy-3", FingerprintAnnotation: other.Annotations[FingerprintAnnotation]}
+
+	claimed := Claimed("AccessPolicy", []metav1.Object{policy, other, copied}, policy, "12345")
+	assert.Equal(t, map[string]bool{"policy-2": true}, claimed)
+}
+
+func TestIntent(t *testing.T) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	assert.Equal(t, "", Intent(policy))
+	assert.False(t, ClearIntent(policy))
+	assert.True(t, SetIntent(policy, "abc"))
+	assert.False(t, SetIntent(policy, "abc"))
+	assert.Equal(t, "abc", Intent(policy))
+	assert.True(t, ClearIntent(policy))
+	assert.Equal(t, "", Intent(policy))
+}
+
+func TestPolicyHash(t *testing.T) {
+	policy := polv2.Policy{
+		Type:    "access",
+		Subject: polv2.Subject{Attributes: []polv2.Attribute{{Key: "iam_id", Operator: "stringEquals", Value: "iam-ServiceId-123"}}},
+		Control: polv2.Control{Grant: polv2.Grant{Roles: []polv2.Role{{RoleID: "Viewer"}, {RoleID: "Writer"}}}},
+		Resource: polv2.Resource{Attributes: []polv2.Attribute{
+			{Key: "accountId", Operator: "stringEquals", Value: "12345"},
+			{Key: "serviceName", Operator: "stringEquals", Value: "cloud-object-storage"},
+		}},
+	}
+	reordered := policy
+	reordered.Control.Grant.Roles = []polv2.Role{{RoleID: "Writer"}, {RoleID: "Viewer"}}
+	reordered.Resource.Attributes = []polv2.Attribute{
+		{Key: "serviceName", Value: "cloud-object-storage"},
+		{Key: "accountId", Operator: "stringEquals", Value: "12345"},
+	}
+	assert.Equal(t, PolicyHash(policy), PolicyHash(reordered))
+
+	reordered.Control.Grant.Roles = []polv2.Role{{RoleID: "Writer"}}
+	assert.NotEqual(t, PolicyHash(policy), PolicyHash(reordered))
+}
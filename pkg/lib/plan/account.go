@@ -0,0 +1,653 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plan
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/compile"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/expiry"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/manifest"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	poldiff "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/diff"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/rolecatalog"
+)
+
+// KnownAfterApply stands for the IDs of the IAM objects created by applying the resources
+const KnownAfterApply = "(known after apply)"
+
+// The kinds of the planned resources, as recorded in their fingerprint
+const (
+	accessGroupKind         = "AccessGroup"
+	customRoleKind          = "CustomRole"
+	accessPolicyKind        = "AccessPolicy"
+	authorizationPolicyKind = "AuthorizationPolicy"
+)
+
+// publicAccessGroupID is the access group of all users, which IAM manages
+const publicAccessGroupID = "AccessGroupId-PublicAccess"
+
+// Change is the change applying a resource makes to its IAM object, or the
+// deletion of an IAM object owned by the operator that no resource manages
+type Change struct {
+	// Action is Create, Update, Delete or NoChange
+	Action string
+	Kind   string
+	// Name is the namespace/name of the resource, or the name of the IAM object
+	// to delete
+	Name string
+	// ID of the IAM object, "" if it is created
+	ID    string
+	Diffs []drift.Difference
+	// Err tells why the change of the resource cannot be planned
+	Err error
+	// Pruned is true for the deletion of an IAM object that no resource manages
+	Pruned bool
+}
+
+// planner plans the changes of resources to the IAM objects of an account. It
+// resolves the references of the resources to the other resources planned.
+type planner struct {
+	*rolecatalog.Catalog
+	clients   iamclient.Clients
+	accountID string
+	// instanceID is the instance ID of the operator applying the resources
+	instanceID string
+	now        time.Time
+	// users are the users of the account by login
+	users map[string]accountv1.AccountUser
+	// groups and roles are the resources by namespace/name, with the ID of their
+	// IAM object, or KnownAfterApply, in their status
+	groups map[string]*ibmcloudv1alpha1.AccessGroup
+	roles  map[string]*ibmcloudv1alpha1.CustomRole
+	// customRoles are the roles of the custom role resources
+	customRoles []rolecatalog.Role
+	changes     []Change
+	// prune deletes the IAM objects of the operator instance that no resource matches
+	prune bool
+}
+
+var _ compile.Resolver = &planner{}
+
+// Account plans the changes applying resources makes to the IAM objects of an
+// account, at time now, by the operator instance with instanceID. Resources are matched to
+// the IAM objects the operator would adopt: by their annotations, else by name for access
+// groups and custom roles owned by the operator, and by their creation intent for policies.
+// With prune, the access groups, custom roles and policies marked by the operator instance
+// that no resource matches are deleted, which requires its instance ID: the account may hold
+// the IAM objects of other clusters.
+func Account(resources *manifest.Resources, clients iamclient.Clients, accountID string, instanceID string, now time.Time, prune bool) ([]Change, error) {
+	if prune && instanceID == "" {
+		return nil, fmt.Errorf("pruning requires the instance ID of the operator, so that only the IAM objects it marked are deleted")
+	}
+	catalog, err := clients.RoleCatalog()
+	if err != nil {
+		return nil, err
+	}
+	p := &planner{
+		Catalog:    catalog,
+		clients:    clients,
+		accountID:  accountID,
+		instanceID: instanceID,
+		now:        now,
+		groups:     map[string]*ibmcloudv1alpha1.AccessGroup{},
+		roles:      map[string]*ibmcloudv1alpha1.CustomRole{},
+		prune:      prune,
+	}
+	// Policies reference the custom roles and access groups
+	if err := p.customRoleChanges(resources.CustomRoles); err != nil {
+		return nil, err
+	}
+	if err := p.accessGroupChanges(resources.AccessGroups); err != nil {
+		return nil, err
+	}
+	if err := p.accessPolicyChanges(resources.AccessPolicies); err != nil {
+		return nil, err
+	}
+	if err := p.authorizationPolicyChanges(resources.AuthorizationPolicies); err != nil {
+		return nil, err
+	}
+	if err := p.prunePolicies(); err != nil {
+		return nil, err
+	}
+	return p.changes, nil
+}
+
+// pruned returns true if the IAM object with a description is deleted when no resource matches it
+func (p *planner) pruned(description string) bool {
+	return p.prune && ownership.Owned(description) && ownership.Owner(description) == p.instanceID
+}
+
+func (p *planner) customRoleChanges(instances []ibmcloudv1alpha1.CustomRole) error {
+	roleAPI, err := p.clients.CustomRoles()
+	if err != nil {
+		return err
+	}
+	existing, err := roleAPI.ListCustomRoles(p.accountID, "")
+	if err != nil {
+		return err
+	}
+
+	objs := make([]metav1.Object, 0, len(instances))
+	for i := range instances {
+		objs = append(objs, &instances[i])
+	}
+	matched := map[string]bool{}
+	for i := range instances {
+		instance := instances[i].DeepCopy()
+		change := Change{Kind: customRoleKind, Name: key(instance.Namespace, instance.Name)}
+		crn := KnownAfterApply
+		if role := p.findCustomRole(instance, existing, ownership.Claimed(change.Kind, objs, instance, p.accountID)); role != nil {
+			matched[role.ID] = true
+			instance.Status.RoleID = role.ID
+			crn = role.Crn
+			change.ID = role.ID
+			change.Diffs = p.customRoleDiffs(instance, *role)
+			change.Action = action(change.Diffs)
+		} else {
+			instance.Status.RoleID = KnownAfterApply
+			change.Action = Create
+			change.Diffs = Creation(compile.CustomRole(instance, p.accountID, p.instanceID))
+		}
+		p.roles[change.Name] = instance
+		p.customRoles = append(p.customRoles, rolecatalog.Role{
+			Name:        instance.Spec.RoleName,
+			DisplayName: instance.Spec.DisplayName,
+			CRN:         crn,
+			ServiceName: instance.Spec.ServiceClass,
+			Custom:      true,
+		})
+		p.changes = append(p.changes, change)
+	}
+
+	for _, role := range existing {
+		if p.pruned(role.Description) && !matched[role.ID] {
+			p.changes = append(p.changes, Change{Action: Delete, Kind: customRoleKind, Name: role.Name, ID: role.ID, Pruned: true})
+		}
+	}
+	return nil
+}
+
+// findCustomRole returns the custom role the operator would adopt for a resource, or nil. Claimed roles
+// are recorded by other resources.
+func (p *planner) findCustomRole(instance *ibmcloudv1alpha1.CustomRole, roles []iampapv2.Role, claimed map[string]bool) *iampapv2.Role {
+	recordedID := ownership.RecordedID(customRoleKind, instance, p.accountID)
+	for i, role := range roles {
+		if recordedID != "" && role.ID == recordedID {
+			return &roles[i]
+		}
+	}
+	for i, role := range roles {
+		if role.Name == instance.Spec.RoleName && role.ServiceName == instance.Spec.ServiceClass && !claimed[role.ID] && ownership.Adoptable(role.Description, p.instanceID) {
+			return &roles[i]
+		}
+	}
+	return nil
+}
+
+// customRoleDiffs returns the differences between a custom role resource and the role in IAM
+func (p *planner) customRoleDiffs(instance *ibmcloudv1alpha1.CustomRole, role iampapv2.Role) []drift.Difference {
+	desired := compile.CustomRoleUpdate(instance, p.instanceID)
+	var diffs []drift.Difference
+	if desired.DisplayName != role.DisplayName {
+		diffs = append(diffs, drift.Difference{Field: "displayName", Desired: desired.DisplayName, Actual: role.DisplayName})
+	}
+	if desired.Description != role.Description {
+		diffs = append(diffs, drift.Difference{Field: "description", Desired: desired.Description, Actual: role.Description})
+	}
+	if !reflect.DeepEqual(desired.Actions, role.Actions) {
+		diffs = append(diffs, drift.Difference{Field: "actions", Desired: desired.Actions, Actual: role.Actions})
+	}
+	return diffs
+}
+
+func (p *planner) accessGroupChanges(instances []ibmcloudv1alpha1.AccessGroup) error {
+	groupAPI, err := p.clients.AccessGroups()
+	if err != nil {
+		return err
+	}
+	memberAPI, err := p.clients.AccessGroupMembers()
+	if err != nil {
+		return err
+	}
+	existing, err := groupAPI.List(p.accountID)
+	if err != nil {
+		return err
+	}
+
+	objs := make([]metav1.Object, 0, len(instances))
+	for i := range instances {
+		objs = append(objs, &instances[i])
+	}
+	matched := map[string]bool{}
+	for i := range instances {
+		instance := instances[i].DeepCopy()
+		change := Change{Kind: accessGroupKind, Name: key(instance.Namespace, instance.Name)}
+		group := p.findAccessGroup(instance, existing, ownership.Claimed(change.Kind, objs, instance, p.accountID))
+		if group != nil {
+			matched[group.ID] = true
+			instance.Status.GroupID = group.ID
+		} else {
+			instance.Status.GroupID = KnownAfterApply
+		}
+		p.groups[change.Name] = instance
+
+		members, err := p.members(instance)
+		if err != nil {
+			change.Err = err
+			p.changes = append(p.changes, change)
+			continue
+		}
+		if group == nil {
+			change.Action = Create
+			change.Diffs = Creation(compile.AccessGroup(instance, p.accountID, p.instanceID))
+			for _, m := range members {
+				change.Diffs = append(change.Diffs, drift.Difference{Field: "members", Desired: m.name})
+			}
+			p.changes = append(p.changes, change)
+			continue
+		}
+
+		actual, err := memberAPI.List(group.ID)
+		if err != nil {
+			return err
+		}
+		change.ID = group.ID
+		change.Diffs = p.accessGroupDiffs(instance, *group, members, actual)
+		change.Action = action(change.Diffs)
+		p.changes = append(p.changes, change)
+	}
+
+	for _, group := range existing {
+		if group.ID != publicAccessGroupID && p.pruned(group.Description) && !matched[group.ID] {
+			p.changes = append(p.changes, Change{Action: Delete, Kind: accessGroupKind, Name: group.Name, ID: group.ID, Pruned: true})
+		}
+	}
+	return nil
+}
+
+// findAccessGroup returns the access group the operator would adopt for a resource, or nil. Claimed groups
+// are recorded by other resources.
+func (p *planner) findAccessGroup(instance *ibmcloudv1alpha1.AccessGroup, groups []models.AccessGroupV2, claimed map[string]bool) *models.AccessGroupV2 {
+	recordedID := ownership.RecordedID(accessGroupKind, instance, p.accountID)
+	for i, group := range groups {
+		if recordedID != "" && group.ID == recordedID {
+			return &groups[i]
+		}
+	}
+	for i, group := range groups {
+		if group.Name == instance.Spec.Name && !claimed[group.ID] && ownership.Adoptable(group.Description, p.instanceID) {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+// member is a member of an access group, named as in the resource
+type member struct {
+	name  string
+	iamID string
+}
+
+// members returns the members of an access group resource, without the temporary members who expired
+func (p *planner) members(instance *ibmcloudv1alpha1.AccessGroup) ([]member, error) {
+	userEmails := append([]string{}, instance.Spec.UserEmails...)
+	serviceIDs := append([]string{}, instance.Spec.ServiceIDs...)
+	for _, m := range instance.Spec.TemporaryMembers {
+		if expiry.Expired(m.ExpiresAt, p.now) {
+			continue
+		}
+		if m.UserEmail != "" {
+			userEmails = append(userEmails, m.UserEmail)
+		} else {
+			serviceIDs = append(serviceIDs, m.ServiceID)
+		}
+	}
+
+	var members []member
+	for _, email := range userEmails {
+		iamID, err := p.User(email)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member{name: email, iamID: iamID})
+	}
+	for _, uuid := range serviceIDs {
+		iamID, err := p.ServiceID(uuid)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member{name: uuid, iamID: iamID})
+	}
+	return members, nil
+}
+
+// accessGroupDiffs returns the differences between an access group resource
+// and the access group in IAM, with a difference per member added or removed
+func (p *planner) accessGroupDiffs(instance *ibmcloudv1alpha1.AccessGroup, group models.AccessGroupV2, members []member, actual []models.AccessGroupMemberV2) []drift.Difference {
+	desired := compile.AccessGroupUpdate(instance, p.instanceID)
+	var diffs []drift.Difference
+	if desired.Name != group.Name {
+		diffs = append(diffs, drift.Difference{Field: "name", Desired: desired.Name, Actual: group.Name})
+	}
+	if desired.Description != group.Description {
+		diffs = append(diffs, drift.Difference{Field: "description", Desired: desired.Description, Actual: group.Description})
+	}
+
+	current := map[string]bool{}
+	for _, m := range actual {
+		current[m.ID] = true
+	}
+	wanted := map[string]bool{}
+	for _, m := range members {
+		wanted[m.iamID] = true
+		if !current[m.iamID] {
+			diffs = append(diffs, drift.Difference{Field: "members", Desired: m.name})
+		}
+	}
+	for _, m := range actual {
+		if !wanted[m.ID] {
+			diffs = append(diffs, drift.Difference{Field: "members", Actual: memberName(m)})
+		}
+	}
+	return diffs
+}
+
+// memberName returns the login of a user, else the name of a member as in resources
+func memberName(m models.AccessGroupMemberV2) string {
+	if m.Email != "" {
+		return m.Email
+	}
+	if m.Type == iamuumv2.AccessGroupMemberService {
+		return strings.TrimPrefix(m.ID, "iam-")
+	}
+	return m.ID
+}
+
+func (p *planner) accessPolicyChanges(instances []ibmcloudv1alpha1.AccessPolicy) error {
+	policyAPI, err := p.clients.PoliciesV2()
+	if err != nil {
+		return err
+	}
+	objs := make([]metav1.Object, 0, len(instances))
+	for i := range instances {
+		objs = append(objs, &instances[i])
+	}
+	for i := range instances {
+		instance := instances[i].DeepCopy()
+		if instance.CreationTimestamp.IsZero() { // Conditions start when the resource is created by default
+			instance.CreationTimestamp = metav1.NewTime(p.now)
+		}
+		change := Change{Kind: accessPolicyKind, Name: key(instance.Namespace, instance.Name)}
+		policy, err := compile.AccessPolicy(instance, p.accountID, p.instanceID, p)
+		if err != nil {
+			change.Err = err
+			p.changes = append(p.changes, change)
+			continue
+		}
+		desired := polv2.ConvertV1Policy(policy)
+		desired.Rule, desired.Pattern = compile.AccessPolicyRule(instance)
+
+		expired := expiry.Expired(expiry.Deadline(instance.CreationTimestamp, instance.Spec.ExpiresAt, instance.Spec.TTL), p.now)
+		if err := p.policyChange(&change, policyAPI, instance, desired, expired, ownership.Claimed(change.Kind, objs, instance, p.accountID)); err != nil {
+			return err
+		}
+		p.changes = append(p.changes, change)
+	}
+	return nil
+}
+
+func (p *planner) authorizationPolicyChanges(instances []ibmcloudv1alpha1.AuthorizationPolicy) error {
+	policyAPI, err := p.clients.PoliciesV2()
+	if err != nil {
+		return err
+	}
+	objs := make([]metav1.Object, 0, len(instances))
+	for i := range instances {
+		objs = append(objs, &instances[i])
+	}
+	for i := range instances {
+		instance := instances[i].DeepCopy()
+		change := Change{Kind: authorizationPolicyKind, Name: key(instance.Namespace, instance.Name)}
+		policy, err := compile.AuthorizationPolicy(instance, p.accountID, p.instanceID, p)
+		if err != nil {
+			change.Err = err
+			p.changes = append(p.changes, change)
+			continue
+		}
+		if err := p.policyChange(&change, policyAPI, instance, polv2.ConvertV1Policy(policy), false, ownership.Claimed(change.Kind, objs, instance, p.accountID)); err != nil {
+			return err
+		}
+		p.changes = append(p.changes, change)
+	}
+	return nil
+}
+
+// prunePolicies plans the deletion of the access and authorization policies that no resource matches
+func (p *planner) prunePolicies() error {
+	if !p.prune {
+		return nil
+	}
+	policyAPI, err := p.clients.PoliciesV2()
+	if err != nil {
+		return err
+	}
+	matched := map[string]bool{}
+	for _, change := range p.changes {
+		if change.Kind == accessPolicyKind || change.Kind == authorizationPolicyKind {
+			matched[change.ID] = true
+		}
+	}
+	kinds := map[string]string{iampapv1.AccessPolicyType: accessPolicyKind, iampapv1.AuthorizationPolicyType: authorizationPolicyKind}
+	for _, policyType := range []string{iampapv1.AccessPolicyType, iampapv1.AuthorizationPolicyType} {
+		policies, err := policyAPI.List(polv2.SearchParams{AccountID: p.accountID, Type: policyType})
+		if err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			if p.pruned(policy.Description) && !matched[policy.ID] {
+				name := ownership.TrimMarker(policy.Description)
+				p.changes = append(p.changes, Change{Action: Delete, Kind: kinds[policyType], Name: name, ID: policy.ID, Pruned: true})
+			}
+		}
+	}
+	return nil
+}
+
+// policyChange plans the change of a policy resource, which is deleted from IAM when it expired. Claimed
+// policies are recorded by other resources.
+func (p *planner) policyChange(change *Change, policyAPI polv2.PolicyRepository, obj metav1.Object, desired polv2.Policy, expired bool, claimed map[string]bool) error {
+	actual, err := p.findPolicy(policyAPI, change.Kind, obj, desired, claimed)
+	if err != nil {
+		return err
+	}
+	switch {
+	case actual == nil && expired:
+		change.Action = NoChange
+	case actual == nil:
+		change.Action = Create
+		change.Diffs = poldiff.Policies(desired, polv2.Policy{})
+	case expired:
+		change.Action = Delete
+		change.ID = actual.ID
+	default:
+		change.ID = actual.ID
+		change.Diffs = poldiff.Policies(desired, *actual)
+		change.Action = action(change.Diffs)
+	}
+	return nil
+}
+
+// findPolicy returns the policy the operator would adopt for a resource, or nil
+func (p *planner) findPolicy(policyAPI polv2.PolicyRepository, kind string, obj metav1.Object, desired polv2.Policy, claimed map[string]bool) (*polv2.Policy, error) {
+	if recordedID := ownership.RecordedID(kind, obj, p.accountID); recordedID != "" {
+		policy, err := policyAPI.Get(recordedID)
+		if err == nil {
+			return &policy, nil
+		}
+		if !iamclient.NotFound(err) { // The recorded policy may still exist, don't plan another one
+			return nil, err
+		}
+	}
+
+	params := polv2.SearchParams{AccountID: p.accountID, Type: desired.Type}
+	if desired.Type == iampapv1.AccessPolicyType {
+		for _, a := range desired.Subject.Attributes {
+			if a.Value == KnownAfterApply { // The subject is created by applying the resources
+				return nil, nil
+			}
+			switch a.Key {
+			case "iam_id":
+				params.IAMID = a.Value
+			case "access_group_id":
+				params.AccessGroupID = a.Value
+			}
+		}
+	}
+	policyID, err := ownership.FindV2Policy(policyAPI, params, ownership.Intent(obj), p.instanceID, claimed)
+	if err != nil || policyID == "" {
+		return nil, err
+	}
+	policy, err := policyAPI.Get(policyID)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// User returns the IAM ID of an active user of the account
+func (p *planner) User(email string) (string, error) {
+	if p.users == nil {
+		accountAPI, err := p.clients.Accounts()
+		if err != nil {
+			return "", err
+		}
+		users, err := accountAPI.GetAccountUsers(p.accountID)
+		if err != nil {
+			return "", err
+		}
+		p.users = map[string]accountv1.AccountUser{}
+		for _, user := range users {
+			p.users[user.UserId] = user
+		}
+	}
+	user, ok := p.users[email]
+	if !ok || user.IbmUniqueId == "" || user.State == "PENDING" {
+		return "", fmt.Errorf("user %s is not an active user of the account, the operator invites them and fails until they accept", email)
+	}
+	return user.IbmUniqueId, nil
+}
+
+// ServiceID returns the IAM ID of a service ID
+func (p *planner) ServiceID(uuid string) (string, error) {
+	serviceIDAPI, err := p.clients.ServiceIDs()
+	if err != nil {
+		return "", err
+	}
+	serviceID, err := serviceIDAPI.Get(uuid)
+	if err != nil {
+		return "", err
+	}
+	return serviceID.IAMID, nil
+}
+
+// AccessGroup returns the ID of an access group of the account
+func (p *planner) AccessGroup(id string) (string, error) {
+	groupAPI, err := p.clients.AccessGroups()
+	if err != nil {
+		return "", err
+	}
+	group, _, err := groupAPI.Get(id)
+	if err != nil {
+		return "", err
+	}
+	return group.ID, nil
+}
+
+// AccessGroupResource returns a planned access group resource
+func (p *planner) AccessGroupResource(namespace string, name string) (*ibmcloudv1alpha1.AccessGroup, error) {
+	if group, ok := p.groups[key(namespace, name)]; ok {
+		return group, nil
+	}
+	return nil, fmt.Errorf("access group %s is not in the manifests", key(namespace, name))
+}
+
+// CustomRoleResource returns a planned custom role resource
+func (p *planner) CustomRoleResource(namespace string, name string) (*ibmcloudv1alpha1.CustomRole, error) {
+	if role, ok := p.roles[key(namespace, name)]; ok {
+		return role, nil
+	}
+	return nil, fmt.Errorf("custom role %s is not in the manifests", key(namespace, name))
+}
+
+// ResolveCustomRoles returns the roles of the custom role resources with the
+// given names, as planned, else the custom roles of the account
+func (p *planner) ResolveCustomRoles(serviceClass string, names []string) ([]rolecatalog.Role, error) {
+	var resolved []rolecatalog.Role
+	var unknown []string
+	for _, name := range names {
+		role, ok := p.customRole(serviceClass, name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		resolved = append(resolved, role)
+	}
+	if len(unknown) == 0 {
+		return resolved, nil
+	}
+	roles, err := p.Catalog.ResolveCustomRoles(serviceClass, unknown)
+	if err != nil {
+		return nil, err
+	}
+	return append(resolved, roles...), nil
+}
+
+func (p *planner) customRole(serviceClass string, name string) (rolecatalog.Role, bool) {
+	for _, role := range p.customRoles {
+		if (serviceClass == "" || role.ServiceName == serviceClass) && (role.DisplayName == name || role.Name == name) {
+			return role, true
+		}
+	}
+	return rolecatalog.Role{}, false
+}
+
+// action returns the action applying differences to an IAM object
+func action(diffs []drift.Difference) string {
+	if len(diffs) == 0 {
+		return NoChange
+	}
+	return Update
+}
+
+func key(namespace string, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plan
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	iamfake "github.com/IBM/ibmcloud-iam-operator/pkg/lib/iamclient/fake"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/manifest"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func attr(key string, value string) polv2.Attribute {
+	return polv2.Attribute{Key: key, Operator: "stringEquals", Value: value}
+}
+
+func policy(policyType string, subject []polv2.Attribute, roles []string, resource ...polv2.Attribute) polv2.Policy {
+	p := polv2.Policy{Type: policyType, Subject: polv2.Subject{Attributes: subject}}
+	for _, role := range roles {
+		p.Control.Grant.Roles = append(p.Control.Grant.Roles, polv2.Role{RoleID: role})
+	}
+	p.Resource.Attributes = append(resource, attr("accountId", iamfake.AccountID))
+	return p
+}
+
+func TestAccount(t *testing.T) {
+	f := iamfake.NewFactory()
+	alice := f.AddUser("alice@example.com")
+	f.AddUser("bob@example.com")
+	f.AddServiceID("ServiceId-ci", "ci")
+
+	// Made by hand, and adopted by the resource annotated with its ID
+	operators := f.AddAccessGroup("Operators", "Cluster operators")
+	require.NoError(t, f.AddMember(operators.ID, models.AccessGroupMemberV2{ID: alice.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser}))
+	developers := f.AddAccessGroup("Developers", ownership.DescriptionPrefix+"Application developers")
+	require.NoError(t, f.AddMember(developers.ID, models.AccessGroupMemberV2{ID: alice.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser}))
+	f.AddAccessGroup("Interns", ownership.DescriptionPrefix+"No resource manages them anymore")
+	f.AddAccessGroup("Support", "Not owned by the operator")
+	f.AddCustomRole(iampapv2.CreateRoleRequest{
+		Name:        "Deployer",
+		ServiceName: "containers-kubernetes",
+		DisplayName: "Deployer",
+		Description: ownership.DescriptionPrefix + "Deploys applications",
+		Actions:     []string{"containers-kubernetes.cluster.read"},
+	})
+	f.AddCustomRole(iampapv2.CreateRoleRequest{
+		Name:        "Janitor",
+		ServiceName: "cloud-object-storage",
+		DisplayName: "Janitor",
+		Description: ownership.DescriptionPrefix + "Cleans up buckets",
+		Actions:     []string{"cloud-object-storage.bucket.delete_bucket"},
+	})
+
+	// Created by the operator, which stopped before recording its ID
+	created := policy("access", []polv2.Attribute{attr("access_group_id", developers.ID)},
+		[]string{"crn:v1:bluemix:public:iam::::role:Viewer"}, attr("serviceName", "containers-kubernetes"))
+	created.Description = ownership.DescriptionPrefix + "AccessPolicy iam/developers-viewer"
+	viewer := f.AddPolicy(created)
+	expired := f.AddPolicy(policy("access", []polv2.Attribute{attr("iam_id", alice.IbmUniqueId)},
+		[]string{"crn:v1:bluemix:public:cloud-object-storage::::serviceRole:Reader"}, attr("serviceName", "cloud-object-storage")))
+	kms := f.AddPolicy(policy("authorization",
+		[]polv2.Attribute{attr("serviceName", "cloud-object-storage"), attr("accountId", iamfake.AccountID)},
+		[]string{"crn:v1:bluemix:public:kms::::serviceRole:Reader"}, attr("serviceName", "kms")))
+	// Made by hand with the content of a resource, and not adopted
+	f.AddPolicy(policy("access", []polv2.Attribute{attr("access_group_id", operators.ID)},
+		[]string{"crn:v1:bluemix:public:iam::::role:Viewer", "crn:v1:bluemix:public:containers-kubernetes::a/" + iamfake.AccountID + "::customRole:Deployer"},
+		attr("serviceName", "containers-kubernetes"), attr("region", "us-south")))
+
+	resources, err := manifest.Load("default", filepath.Join("testdata", "manifests.yaml"))
+	require.NoError(t, err)
+	ownership.Record(accessGroupKind, &resources.AccessGroups[0], iamfake.AccountID, operators.ID)
+	ownership.Record(accessPolicyKind, &resources.AccessPolicies[4], iamfake.AccountID, expired.ID)
+	ownership.Record(authorizationPolicyKind, &resources.AuthorizationPolicies[0], iamfake.AccountID, kms.ID)
+	ownership.SetIntent(&resources.AccessPolicies[0], ownership.PolicyHash(viewer))
+
+	_, clients, err := f.Connect(context.Background(), nil, "default")
+	require.NoError(t, err)
+	changes, err := Account(resources, clients, iamfake.AccountID, "", time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), false)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, changes))
+	golden := filepath.Join("testdata", "account.golden")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, out.Bytes(), 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), out.String())
+
+	// Planning doesn't change the account
+	assert.Len(t, f.AccessGroups(), 4)
+	assert.Len(t, f.CustomRoles(), 2)
+	assert.Len(t, f.Policies(), 4)
+}
+
+func TestAccountPrune(t *testing.T) {
+	f := iamfake.NewFactory()
+	alice := f.AddUser("alice@example.com")
+	interns := f.AddAccessGroup("Interns", ownership.Marker("cluster-a")+"No resource manages them anymore")
+	f.AddAccessGroup("Testers", ownership.Marker("cluster-b")+"Managed by another cluster")
+	f.AddAccessGroup("Support", ownership.DescriptionPrefix+"Created before instance IDs")
+	janitor := f.AddCustomRole(iampapv2.CreateRoleRequest{
+		Name:        "Janitor",
+		ServiceName: "cloud-object-storage",
+		DisplayName: "Janitor",
+		Description: ownership.Marker("cluster-a") + "Cleans up buckets",
+		Actions:     []string{"cloud-object-storage.bucket.delete_bucket"},
+	})
+	viewer := policy("access", []polv2.Attribute{attr("iam_id", alice.IbmUniqueId)}, []string{"crn:v1:bluemix:public:iam::::role:Viewer"})
+	viewer.Description = ownership.Marker("cluster-a") + "AccessPolicy default/interns"
+	viewer = f.AddPolicy(viewer)
+	_, clients, err := f.Connect(context.Background(), nil, "default")
+	require.NoError(t, err)
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Without prune, only the resources are planned
+	changes, err := Account(&manifest.Resources{}, clients, iamfake.AccountID, "cluster-a", now, false)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Pruning requires an instance ID
+	_, err = Account(&manifest.Resources{}, clients, iamfake.AccountID, "", now, true)
+	assert.Error(t, err)
+
+	changes, err = Account(&manifest.Resources{}, clients, iamfake.AccountID, "cluster-a", now, true)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Action: Delete, Kind: customRoleKind, Name: "Janitor", ID: janitor.ID, Pruned: true},
+		{Action: Delete, Kind: accessGroupKind, Name: "Interns", ID: interns.ID, Pruned: true},
+		{Action: Delete, Kind: accessPolicyKind, Name: "AccessPolicy default/interns", ID: viewer.ID, Pruned: true},
+	}, changes)
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, changes[1:2]))
+	assert.Contains(t, out.String(), "# AccessGroup Interns will be deleted (pruned: owned by the operator instance, no resource manages it)")
+	assert.Len(t, f.AccessGroups(), 3)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package plan implements the dry run of reconciles: the operator resolves a resource and computes the
+// change it would make to IAM, then records it in the status and events of the resource instead of making it.
+// It also plans the changes of manifests to an account outside of a cluster, as iamctl plan does.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rcontext "github.com/IBM/ibmcloud-iam-operator/pkg/context"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/event"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+)
+
+// Annotation plans the changes of a resource when "true", and makes them when "false" even if the operator
+// runs in dry run
+const Annotation = "ibmcloud.ibm.com/dry-run"
+
+// ConditionType is the type of the status condition reporting the change planned for a resource
+const ConditionType = "Planned"
+
+// PlannedState is the state of a resource whose changes are planned but not made
+const PlannedState = "Planned"
+
+// Actions a reconcile plans on the IAM object of a resource
+const (
+	Create   = "Create"
+	Update   = "Update"
+	Delete   = "Delete"
+	NoChange = "NoChange"
+)
+
+const (
+	reasonApplied = "Applied"
+	component     = "ibmcloud-iam-operator"
+)
+
+// Enabled returns whether the changes of obj are planned only, by its annotation, else by the dry run setting
+func Enabled(obj runtime.Object) bool {
+	switch resv1.ObjectMeta(obj).GetAnnotations()[Annotation] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return settings.Current.DryRun
+}
+
+// Report sets the Planned state and condition of obj for the action planned on its IAM object, e.g. an
+// "access policy", and the differences between the IAM object and the desired one, and returns true if
+// they changed
+func Report(obj runtime.Object, action string, object string, diffs []drift.Difference) bool {
+	message := fmt.Sprintf("Dry run: would %s IAM %s", strings.ToLower(action), object)
+	if action == NoChange {
+		message = fmt.Sprintf("Dry run: IAM %s is up to date", object)
+	}
+	condition := &resv1.Condition{Type: ConditionType, Status: corev1.ConditionTrue, Reason: action, Message: drift.Summary(diffs)}
+
+	status := resv1.GetStatus(obj)
+	current := resv1.GetCondition(obj, ConditionType)
+	if status.GetState() == PlannedState && status.GetMessage() == message && current != nil &&
+		current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message {
+		return false
+	}
+	status.SetState(PlannedState)
+	status.SetMessage(message)
+	resv1.SetCondition(obj, condition)
+	return true
+}
+
+// Creation returns the fields of the payload creating an IAM object, such as a request compiled by package
+// compile, as differences from an object that does not exist
+func Creation(payload interface{}) []drift.Difference {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var diffs []drift.Difference
+	for _, name := range names {
+		diffs = append(diffs, drift.Difference{Field: name, Desired: fields[name]})
+	}
+	return diffs
+}
+
+// Clear clears the Planned state and condition of obj, if any, once its changes are made, and returns true
+// if they changed. The resource is Pending until it is reconciled.
+func Clear(obj runtime.Object) bool {
+	current := resv1.GetCondition(obj, ConditionType)
+	if current == nil || current.Status != corev1.ConditionTrue {
+		return false
+	}
+	resv1.SetCondition(obj, &resv1.Condition{Type: ConditionType, Status: corev1.ConditionFalse, Reason: reasonApplied})
+	status := resv1.GetStatus(obj)
+	status.SetState(resv1.ResourceStatePending)
+	status.SetMessage("Dry run ended")
+	return true
+}
+
+// Emit records the change planned for obj, a resource of a kind, in an event, so that plans can be reviewed
+// with kubectl get events even after a resource is deleted
+func Emit(c client.Client, obj runtime.Object, kind string) error {
+	meta := resv1.ObjectMeta(obj)
+	current := resv1.GetCondition(obj, ConditionType)
+	if current == nil {
+		return nil
+	}
+	message := resv1.GetStatus(obj).GetMessage()
+	if current.Message != "" {
+		message = message + ": " + current.Message
+	}
+	ctx := rcontext.New(c, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: meta.GetNamespace(), Name: meta.GetName()}})
+	_, err := event.CreateEvent(ctx, meta, current.Reason, message, kind, component)
+	return err
+}
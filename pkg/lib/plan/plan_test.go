@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/settings"
+)
+
+func TestEnabled(t *testing.T) {
+	defer func() { settings.Current = settings.Defaults }()
+
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	assert.False(t, Enabled(policy))
+	policy.Annotations = map[string]string{Annotation: "true"}
+	assert.True(t, Enabled(policy))
+
+	settings.Current.DryRun = true
+	policy.Annotations = nil
+	assert.True(t, Enabled(policy))
+	policy.Annotations = map[string]string{Annotation: "false"}
+	assert.False(t, Enabled(policy))
+}
+
+func TestReportClear(t *testing.T) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	policy.Status.State = "Online"
+	assert.False(t, Clear(policy))
+
+	assert.True(t, Report(policy, Create, "access policy", nil))
+	assert.Equal(t, PlannedState, policy.Status.State)
+	assert.Equal(t, "Dry run: would create IAM access policy", policy.Status.Message)
+	assert.Equal(t, Create, resv1.GetCondition(policy, ConditionType).Reason)
+	assert.False(t, Report(policy, Create, "access policy", nil))
+
+	diffs := []drift.Difference{{Field: "roles", Desired: []string{"Viewer"}, Actual: []string{"Editor"}}}
+	assert.True(t, Report(policy, Update, "access policy", diffs))
+	assert.Equal(t, `roles: desired ["Viewer"], actual ["Editor"]`, resv1.GetCondition(policy, ConditionType).Message)
+
+	assert.True(t, Report(policy, NoChange, "access policy", nil))
+	assert.Equal(t, "Dry run: IAM access policy is up to date", policy.Status.Message)
+
+	assert.True(t, Clear(policy))
+	assert.Equal(t, resv1.ResourceStatePending, policy.Status.State)
+	assert.Equal(t, corev1.ConditionFalse, resv1.GetCondition(policy, ConditionType).Status)
+	assert.False(t, Clear(policy))
+}
+
+func TestCreation(t *testing.T) {
+	group := models.AccessGroupV2{AccessGroup: models.AccessGroup{Name: "Developers", Description: "OPERATOR OWNED: Developers"}}
+	assert.Equal(t, `description: desired "OPERATOR OWNED: Developers", actual null; name: desired "Developers", actual null`, drift.Summary(Creation(group)))
+}
+
+func TestEmit(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	policy := &ibmcloudv1alpha1.AccessPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "viewer"}}
+	require.NoError(t, Emit(c, policy, "AccessPolicy"))
+
+	Report(policy, Update, "access policy", []drift.Difference{{Field: "roles", Desired: []string{"Viewer"}, Actual: []string{"Editor"}}})
+	require.NoError(t, Emit(c, policy, "AccessPolicy"))
+
+	events := &corev1.EventList{}
+	require.NoError(t, c.List(context.Background(), events))
+	require.Len(t, events.Items, 1)
+	assert.Equal(t, Update, events.Items[0].Reason)
+	assert.Equal(t, `Dry run: would update IAM access policy: roles: desired ["Viewer"], actual ["Editor"]`, events.Items[0].Message)
+	assert.Equal(t, "AccessPolicy", events.Items[0].InvolvedObject.Kind)
+	assert.Equal(t, "viewer", events.Items[0].InvolvedObject.Name)
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// symbols of the actions in a plan, as in Terraform
+var symbols = map[string]string{
+	Create: "+",
+	Update: "~",
+	Delete: "-",
+}
+
+// Write writes the changes of a plan, with a summary line, and leaves out the unchanged resources
+func Write(w io.Writer, changes []Change) error {
+	var created, updated, deleted, unchanged, failed int
+	for _, change := range changes {
+		var err error
+		switch {
+		case change.Err != nil:
+			failed++
+			_, err = fmt.Fprintf(w, "  # %s %s cannot be planned: %v\n  ! %s %s\n\n", change.Kind, change.Name, change.Err, change.Kind, change.Name)
+		case change.Action == NoChange:
+			unchanged++
+		default:
+			switch change.Action {
+			case Create:
+				created++
+			case Update:
+				updated++
+			case Delete:
+				deleted++
+			}
+			err = writeChange(w, change)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete, %d unchanged, %d failed.\n",
+		created, updated, deleted, unchanged, failed)
+	return err
+}
+
+func writeChange(w io.Writer, change Change) error {
+	verbs := map[string]string{Create: "created", Update: "updated in-place", Delete: "deleted"}
+	verb := verbs[change.Action]
+	if change.Pruned {
+		verb += " (pruned: owned by the operator instance, no resource manages it)"
+	}
+	if _, err := fmt.Fprintf(w, "  # %s %s will be %s\n", change.Kind, change.Name, verb); err != nil {
+		return err
+	}
+	header := fmt.Sprintf("  %s %s %s", symbols[change.Action], change.Kind, change.Name)
+	if change.ID != "" {
+		header += fmt.Sprintf(" (id: %s)", change.ID)
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+	for _, d := range change.Diffs {
+		var line string
+		switch {
+		case d.Actual == nil:
+			line = fmt.Sprintf("      + %s: %s", d.Field, format(d.Desired))
+		case d.Desired == nil:
+			line = fmt.Sprintf("      - %s: %s", d.Field, format(d.Actual))
+		default:
+			line = fmt.Sprintf("      ~ %s: %s -> %s", d.Field, format(d.Actual), format(d.Desired))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// format quotes strings and writes other values as JSON
+func format(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package diff compares IAM policies semantically: attribute order, the default
+// stringEquals operator and role names versus role CRNs don't make a difference.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/ownership"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+// V1Policies returns the differences between a desired v1 policy and the one in IAM
+func V1Policies(desired polv1.Policy, actual polv1.Policy) []drift.Difference {
+	return Policies(polv2.ConvertV1Policy(desired), polv2.ConvertV1Policy(actual))
+}
+
+// Policies returns the differences between a desired v2 policy and the one in IAM.
+// Fields are named after the policy JSON, e.g. resource.attributes[serviceName]. The
+// description is compared too, so that policies adopted without the ownership marker get it.
+// Policies returns every change an update makes, Drift the ones made outside of the operator.
+func Policies(desired polv2.Policy, actual polv2.Policy) []drift.Difference {
+	diffs := description(desired, actual)
+	diffs = append(diffs, attributes("subject.attributes", desired.Subject.Attributes, actual.Subject.Attributes)...)
+	diffs = append(diffs, roles(desired.Control.Grant.Roles, actual.Control.Grant.Roles)...)
+	diffs = append(diffs, attributes("resource.attributes", desired.Resource.Attributes, actual.Resource.Attributes)...)
+	diffs = append(diffs, tags(desired.Resource.Tags, actual.Resource.Tags)...)
+	if desired.Pattern != actual.Pattern {
+		diffs = append(diffs, drift.Difference{Field: "pattern", Desired: desired.Pattern, Actual: actual.Pattern})
+	}
+	if !polv2.RulesEqual(desired.Rule, actual.Rule) {
+		diffs = append(diffs, drift.Difference{Field: "rule", Desired: desired.Rule, Actual: actual.Rule})
+	}
+	return diffs
+}
+
+// V1Drift returns the differences between a desired v1 policy and the one in IAM that are drift
+func V1Drift(desired polv1.Policy, actual polv1.Policy) []drift.Difference {
+	return Drift(polv2.ConvertV1Policy(desired), polv2.ConvertV1Policy(actual))
+}
+
+// Drift returns the differences between a desired v2 policy and the one in IAM that are drift. A policy
+// whose description lacks the ownership marker, e.g. one created before the operator marked its policies,
+// has not drifted: the marker is stamped once when the policy is adopted, see Unmarked.
+func Drift(desired polv2.Policy, actual polv2.Policy) []drift.Difference {
+	if !ownership.Owned(actual.Description) {
+		desired.Description = actual.Description
+	}
+	return Policies(desired, actual)
+}
+
+// V1Unmarked returns the difference of the description of a v1 policy in IAM without the ownership marker
+func V1Unmarked(desired polv1.Policy, actual polv1.Policy) []drift.Difference {
+	return Unmarked(polv2.ConvertV1Policy(desired), polv2.ConvertV1Policy(actual))
+}
+
+// Unmarked returns the difference of the description of a v2 policy in IAM that lacks the ownership marker,
+// nil if the policy has it. The operator stamps the marker on such a policy without changing the rest of it.
+func Unmarked(desired polv2.Policy, actual polv2.Policy) []drift.Difference {
+	if ownership.Owned(actual.Description) {
+		return nil
+	}
+	return description(desired, actual)
+}
+
+// description compares the descriptions of policies
+func description(desired polv2.Policy, actual polv2.Policy) []drift.Difference {
+	if desired.Description == actual.Description {
+		return nil
+	}
+	diff := drift.Difference{Field: "description"}
+	if desired.Description != "" {
+		diff.Desired = desired.Description
+	}
+	if actual.Description != "" {
+		diff.Actual = actual.Description
+	}
+	return []drift.Difference{diff}
+}
+
+// attributes compares attributes by key, missing attributes are reported as null
+func attributes(field string, desired []polv2.Attribute, actual []polv2.Attribute) []drift.Difference {
+	desiredByKey := attributesByKey(desired)
+	actualByKey := attributesByKey(actual)
+
+	var keys []string
+	for key := range desiredByKey {
+		keys = append(keys, key)
+	}
+	for key := range actualByKey {
+		if _, ok := desiredByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []drift.Difference
+	for _, key := range keys {
+		d, dok := desiredByKey[key]
+		a, aok := actualByKey[key]
+		if dok && aok && d == a {
+			continue
+		}
+		diff := drift.Difference{Field: fmt.Sprintf("%s[%s]", field, key)}
+		if dok {
+			diff.Desired = d
+		}
+		if aok {
+			diff.Actual = a
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// attributesByKey indexes attributes as "operator:value" strings
+func attributesByKey(attributes []polv2.Attribute) map[string]string {
+	result := map[string]string{}
+	for _, a := range attributes {
+		operator := a.Operator
+		if operator == "" {
+			operator = polv1.OperatorStringEquals
+		}
+		result[a.Key] = operator + ":" + a.Value
+	}
+	return result
+}
+
+// tags compares tags as sets, since a key can have several values: the desired
+// tags missing from IAM and the tags in IAM that are not desired are reported
+func tags(desired []polv2.Attribute, actual []polv2.Attribute) []drift.Difference {
+	desiredTags := tagSet(desired)
+	actualTags := tagSet(actual)
+
+	var diffs []drift.Difference
+	for _, t := range sortedTags(desired) {
+		if !actualTags[t] {
+			diffs = append(diffs, drift.Difference{Field: fmt.Sprintf("resource.tags[%s]", t.key), Desired: t.value})
+		}
+	}
+	for _, t := range sortedTags(actual) {
+		if !desiredTags[t] {
+			diffs = append(diffs, drift.Difference{Field: fmt.Sprintf("resource.tags[%s]", t.key), Actual: t.value})
+		}
+	}
+	return diffs
+}
+
+// tag is a tag key with its "operator:value" string
+type tag struct {
+	key   string
+	value string
+}
+
+func sortedTags(attributes []polv2.Attribute) []tag {
+	var result []tag
+	for _, a := range attributes {
+		operator := a.Operator
+		if operator == "" {
+			operator = polv1.OperatorStringEquals
+		}
+		result = append(result, tag{key: a.Key, value: operator + ":" + a.Value})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].key != result[j].key {
+			return result[i].key < result[j].key
+		}
+		return result[i].value < result[j].value
+	})
+	return result
+}
+
+func tagSet(attributes []polv2.Attribute) map[tag]bool {
+	result := map[tag]bool{}
+	for _, t := range sortedTags(attributes) {
+		result[t] = true
+	}
+	return result
+}
+
+// roles reports the desired roles missing from IAM and the roles in IAM that are not desired
+func roles(desired []polv2.Role, actual []polv2.Role) []drift.Difference {
+	var diffs []drift.Difference
+	for _, d := range desired {
+		if !containsRole(actual, d) {
+			diffs = append(diffs, drift.Difference{Field: "roles", Desired: d.RoleID})
+		}
+	}
+	for _, a := range actual {
+		if !containsRole(desired, a) {
+			diffs = append(diffs, drift.Difference{Field: "roles", Actual: a.RoleID})
+		}
+	}
+	return diffs
+}
+
+func containsRole(roles []polv2.Role, role polv2.Role) bool {
+	for _, r := range roles {
+		if sameRole(r.RoleID, role.RoleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRole matches role CRNs, or a role name with the name at the end of a role CRN
+func sameRole(a string, b string) bool {
+	if a == b {
+		return true
+	}
+	if isCRN(a) && isCRN(b) {
+		return false
+	}
+	return roleName(a) == roleName(b)
+}
+
+func isCRN(role string) bool {
+	return strings.HasPrefix(role, "crn:")
+}
+
+// roleName returns the name of a role from its CRN, e.g. Viewer for crn:v1:bluemix:public:iam::::role:Viewer
+func roleName(role string) string {
+	return role[strings.LastIndex(role, ":")+1:]
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+const viewer = "crn:v1:bluemix:public:iam::::role:Viewer"
+const writer = "crn:v1:bluemix:public:iam::::serviceRole:Writer"
+
+func testPolicy(roles ...string) polv1.Policy {
+	resource := polv1.Resource{}
+	resource.SetAttribute("serviceName", "cloud-object-storage")
+	resource.SetAccountID("12345")
+	subject := polv1.Subject{}
+	subject.SetAttribute("iam_id", "iam-ServiceId-123")
+	policy := polv1.Policy{Type: "access", Subjects: []polv1.Subject{subject}, Resources: []polv1.Resource{resource}}
+	for _, role := range roles {
+		policy.Roles = append(policy.Roles, iampapv1.Role{RoleID: role})
+	}
+	return policy
+}
+
+func TestV1PoliciesEqual(t *testing.T) {
+	desired := testPolicy(viewer, writer)
+	actual := testPolicy(writer, viewer)
+	// Attribute order and the default operator don't matter
+	actual.Resources[0].Attributes = []polv1.Attribute{
+		{Name: "accountId", Value: "12345", Operator: polv1.OperatorStringEquals},
+		{Name: "serviceName", Value: "cloud-object-storage"},
+	}
+	assert.Empty(t, V1Policies(desired, actual))
+}
+
+func TestV1PoliciesRoleNames(t *testing.T) {
+	assert.Empty(t, V1Policies(testPolicy("Viewer"), testPolicy(viewer)))
+	assert.Equal(t, []drift.Difference{
+		{Field: "roles", Desired: viewer},
+		{Field: "roles", Actual: "crn:v1:bluemix:public:iam::12345:customRole:Viewer"},
+	}, V1Policies(testPolicy(viewer), testPolicy("crn:v1:bluemix:public:iam::12345:customRole:Viewer")))
+}
+
+func TestV1PoliciesMissingRole(t *testing.T) {
+	assert.Equal(t, []drift.Difference{{Field: "roles", Desired: writer}}, V1Policies(testPolicy(viewer, writer), testPolicy(viewer)))
+	assert.Equal(t, []drift.Difference{{Field: "roles", Actual: writer}}, V1Policies(testPolicy(viewer), testPolicy(viewer, writer)))
+}
+
+func TestV1PoliciesDescription(t *testing.T) {
+	desired := testPolicy(viewer)
+	desired.Description = "OPERATOR OWNED: AccessPolicy default/readers"
+	// A policy adopted without the ownership marker gets it
+	assert.Equal(t, []drift.Difference{{Field: "description", Desired: desired.Description}}, V1Policies(desired, testPolicy(viewer)))
+
+	actual := testPolicy(viewer)
+	actual.Description = "Edited in the console"
+	assert.Equal(t, []drift.Difference{{Field: "description", Desired: desired.Description, Actual: actual.Description}}, V1Policies(desired, actual))
+}
+
+func TestV1DriftUnmarked(t *testing.T) {
+	desired := testPolicy(viewer, writer)
+	desired.Description = "OPERATOR OWNED: AccessPolicy default/readers"
+	// A policy created before the operator marked its policies hasn't drifted, it only misses the marker
+	actual := testPolicy(viewer)
+	actual.Description = "Created before the ownership marker"
+	assert.Equal(t, []drift.Difference{{Field: "roles", Desired: writer}}, V1Drift(desired, actual))
+	assert.Equal(t, []drift.Difference{{Field: "description", Desired: desired.Description, Actual: actual.Description}}, V1Unmarked(desired, actual))
+
+	actual = testPolicy(viewer, writer)
+	assert.Empty(t, V1Drift(desired, actual))
+	assert.Equal(t, []drift.Difference{{Field: "description", Desired: desired.Description}}, V1Unmarked(desired, actual))
+
+	// The description of a marked policy is compared
+	actual.Description = "OPERATOR OWNED: AccessPolicy default/writers"
+	assert.Equal(t, []drift.Difference{{Field: "description", Desired: desired.Description, Actual: actual.Description}}, V1Drift(desired, actual))
+	assert.Empty(t, V1Unmarked(desired, actual))
+}
+
+func TestV1PoliciesAttributes(t *testing.T) {
+	desired := testPolicy(viewer)
+	desired.Resources[0].AddAttribute("prefix", "logs/*", polv1.OperatorStringMatch)
+	desired.Resources[0].AddTag("env", "dev", "")
+	actual := testPolicy(viewer)
+	actual.Resources[0].SetAttribute("serviceName", "kms")
+	actual.Resources[0].AddAttribute("prefix", "logs/*", "")
+
+	assert.Equal(t, []drift.Difference{
+		{Field: "resource.attributes[prefix]", Desired: "stringMatch:logs/*", Actual: "stringEquals:logs/*"},
+		{Field: "resource.attributes[serviceName]", Desired: "stringEquals:cloud-object-storage", Actual: "stringEquals:kms"},
+		{Field: "resource.tags[env]", Desired: "stringEquals:dev"},
+	}, V1Policies(desired, actual))
+}
+
+func TestV1PoliciesTags(t *testing.T) {
+	desired := testPolicy(viewer)
+	desired.Resources[0].AddTag("env", "dev", "")
+	desired.Resources[0].AddTag("env", "test", "")
+	actual := testPolicy(viewer)
+	actual.Resources[0].AddTag("env", "test", "")
+	actual.Resources[0].AddTag("env", "prod", "")
+	assert.Equal(t, []drift.Difference{
+		{Field: "resource.tags[env]", Desired: "stringEquals:dev"},
+		{Field: "resource.tags[env]", Actual: "stringEquals:prod"},
+	}, V1Policies(desired, actual))
+
+	actual = testPolicy(viewer)
+	actual.Resources[0].AddTag("env", "test", "")
+	actual.Resources[0].AddTag("env", "dev", "")
+	assert.Empty(t, V1Policies(desired, actual))
+}
+
+func TestPoliciesRule(t *testing.T) {
+	desired := polv2.ConvertV1Policy(testPolicy(viewer))
+	desired.Pattern = polv2.PatternOnce
+	desired.Rule = &polv2.Rule{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeLessThanOrEquals, Value: "2020-12-31T23:59:59Z"}
+	actual := polv2.ConvertV1Policy(testPolicy(viewer))
+
+	diffs := Policies(desired, actual)
+	assert.Len(t, diffs, 2)
+	assert.Equal(t, "pattern", diffs[0].Field)
+	assert.Equal(t, "rule", diffs[1].Field)
+}
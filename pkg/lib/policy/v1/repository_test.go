@@ -0,0 +1,52 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	bluemix "github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPages(t *testing.T) {
+	var queries []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("start") == "" {
+			fmt.Fprintf(w, `{"policies": [{"id": "policy-1"}], "next": {"href": "%s/v1/policies?account_id=12345&start=page-2", "start": "page-2"}}`, server.URL)
+			return
+		}
+		fmt.Fprint(w, `{"policies": [{"id": "policy-2"}]}`)
+	}))
+	defer server.Close()
+
+	maxRetries := 0
+	repository := NewPolicyRepository(client.New(&bluemix.Config{Endpoint: &server.URL, MaxRetries: &maxRetries}, bluemix.IAMPAPService, nil))
+	policies, err := repository.List(SearchParams{AccountID: "12345"})
+	assert.NoError(t, err)
+	if assert.Len(t, policies, 2) {
+		assert.Equal(t, "policy-1", policies[0].ID)
+		assert.Equal(t, "policy-2", policies[1].ID)
+	}
+	assert.Equal(t, []string{"account_id=12345", "account_id=12345&start=page-2"}, queries)
+}
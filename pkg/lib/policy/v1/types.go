@@ -0,0 +1,194 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+)
+
+const (
+	// OperatorStringEquals matches an attribute value exactly. This is the IAM default.
+	OperatorStringEquals = "stringEquals"
+	// OperatorStringMatch matches an attribute value with the wildcards '*' and '?'
+	OperatorStringMatch = "stringMatch"
+)
+
+// Policy is the model of an IAM policy
+type Policy struct {
+	ID               string          `json:"id,omitempty"`
+	Type             string          `json:"type"`
+	Description      string          `json:"description,omitempty"`
+	Subjects         []Subject       `json:"subjects"`
+	Roles            []iampapv1.Role `json:"roles"`
+	Resources        []Resource      `json:"resources"`
+	Href             string          `json:"href,omitempty"`
+	CreatedAt        string          `json:"created_at,omitempty"`
+	CreatedByID      string          `json:"created_by_id,omitempty"`
+	LastModifiedAt   string          `json:"last_modified_at,omitempty"`
+	LastModifiedByID string          `json:"last_modified_by_id,omitempty"`
+	Version          string          `json:"-"`
+}
+
+// Attribute is part of policy subject and resource
+type Attribute struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// Subject is the target to which is assigned policy
+type Subject struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Resource is the object controlled by the policy
+type Resource struct {
+	Attributes []Attribute `json:"attributes"`
+	Tags       []Attribute `json:"tags,omitempty"`
+}
+
+// SetAttribute sets value of an attribute of policy subject, matched with stringEquals
+func (s *Subject) SetAttribute(name string, value string) {
+	s.Attributes = setAttribute(s.Attributes, name, value, "")
+}
+
+// AddAttribute sets value and operator of an attribute of policy subject
+func (s *Subject) AddAttribute(name string, value string, operator string) {
+	s.Attributes = setAttribute(s.Attributes, name, value, operator)
+}
+
+// GetAttribute returns an attribute of policy subject
+func (s *Subject) GetAttribute(name string) string {
+	return getAttribute(s.Attributes, name)
+}
+
+// SetResourceGroupID sets value of resource group ID attribute of policy subject
+func (s *Subject) SetResourceGroupID(value string) {
+	s.SetAttribute(iampapv1.ResourceGroupIDAttribute, value)
+}
+
+// SetAttribute sets value of an attribute of policy resource, matched with stringEquals
+func (r *Resource) SetAttribute(name string, value string) {
+	r.Attributes = setAttribute(r.Attributes, name, value, "")
+}
+
+// AddAttribute sets value and operator of an attribute of policy resource
+func (r *Resource) AddAttribute(name string, value string, operator string) {
+	r.Attributes = setAttribute(r.Attributes, name, value, operator)
+}
+
+// GetAttribute returns an attribute of policy resource
+func (r *Resource) GetAttribute(name string) string {
+	return getAttribute(r.Attributes, name)
+}
+
+// AddTag adds an access tag condition to the policy resource. Unlike attributes,
+// IAM requires an operator on tags so stringEquals is set when none is given. A
+// key can be added with several values, e.g. env:dev and env:test.
+func (r *Resource) AddTag(name string, value string, operator string) {
+	if operator == "" {
+		operator = OperatorStringEquals
+	}
+	for i := range r.Tags {
+		if r.Tags[i].Name == name && r.Tags[i].Value == value {
+			r.Tags[i].Operator = operator
+			return
+		}
+	}
+	r.Tags = append(r.Tags, Attribute{
+		Name:     name,
+		Value:    value,
+		Operator: operator,
+	})
+}
+
+// OperatorWellFormed returns whether IAM supports an attribute or tag operator
+func OperatorWellFormed(operator string) bool {
+	return operator == "" || operator == OperatorStringEquals || operator == OperatorStringMatch
+}
+
+// AttributesWellFormed returns whether custom attributes can be added to a policy subject or
+// resource with the attributes set: each has a name, a value and a supported operator, and none
+// overrides an attribute already set, another custom attribute or the account ID, which is set last
+func AttributesWellFormed(set []Attribute, custom []Attribute) bool {
+	names := map[string]bool{iampapv1.AccountIDAttribute: true}
+	for _, a := range set {
+		names[a.Name] = true
+	}
+	for _, a := range custom {
+		if a.Name == "" || a.Value == "" || !OperatorWellFormed(a.Operator) || names[a.Name] {
+			return false
+		}
+		names[a.Name] = true
+	}
+	return true
+}
+
+// SetAccountID sets value of account ID attribute of policy resource
+func (r *Resource) SetAccountID(value string) {
+	r.SetAttribute(iampapv1.AccountIDAttribute, value)
+}
+
+// SetResourceGroupID sets value of resource group ID attribute of policy resource
+func (r *Resource) SetResourceGroupID(value string) {
+	r.SetAttribute(iampapv1.ResourceGroupIDAttribute, value)
+}
+
+func getAttribute(attributes []Attribute, name string) string {
+	for _, a := range attributes {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func setAttribute(attributes []Attribute, name string, value string, operator string) []Attribute {
+	if operator == OperatorStringEquals {
+		operator = "" // stringEquals is the default, omit it so policies read back from IAM compare equal
+	}
+	for i := range attributes {
+		if attributes[i].Name == name {
+			attributes[i].Value = value
+			attributes[i].Operator = operator
+			return attributes
+		}
+	}
+	return append(attributes, Attribute{
+		Name:     name,
+		Value:    value,
+		Operator: operator,
+	})
+}
+
+// normalize drops the default operator from all attributes of the policy
+func normalize(policy *Policy) {
+	for i := range policy.Subjects {
+		normalizeAttributes(policy.Subjects[i].Attributes)
+	}
+	for i := range policy.Resources {
+		normalizeAttributes(policy.Resources[i].Attributes)
+	}
+}
+
+func normalizeAttributes(attributes []Attribute) {
+	for i := range attributes {
+		if attributes[i].Operator == OperatorStringEquals {
+			attributes[i].Operator = ""
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAttribute(t *testing.T) {
+	resource := Resource{}
+	resource.SetAttribute("serviceName", "cloud-object-storage")
+	resource.AddAttribute("prefix", "logs/*", OperatorStringMatch)
+	resource.AddAttribute("region", "us-south", OperatorStringEquals)
+	assert.Equal(t, []Attribute{
+		{Name: "serviceName", Value: "cloud-object-storage"},
+		{Name: "prefix", Value: "logs/*", Operator: OperatorStringMatch},
+		{Name: "region", Value: "us-south"},
+	}, resource.Attributes)
+
+	resource.AddAttribute("prefix", "data/*", OperatorStringMatch)
+	assert.Equal(t, "data/*", resource.GetAttribute("prefix"))
+	assert.Equal(t, 3, len(resource.Attributes))
+}
+
+func TestNormalize(t *testing.T) {
+	var policy Policy
+	if err := json.Unmarshal([]byte(`{"resources":[{"attributes":[{"name":"serviceName","value":"kms","operator":"stringEquals"},{"name":"resource","value":"key-*","operator":"stringMatch"}]}]}`), &policy); err != nil {
+		t.Fail()
+	}
+	normalize(&policy)
+
+	expected := Resource{}
+	expected.SetAttribute("serviceName", "kms")
+	expected.AddAttribute("resource", "key-*", OperatorStringMatch)
+	assert.Equal(t, []Resource{expected}, policy.Resources)
+}
+
+func TestAddTag(t *testing.T) {
+	resource := Resource{}
+	resource.AddTag("env", "dev", "")
+	resource.AddTag("team", "payments-*", OperatorStringMatch)
+	resource.AddTag("env", "test", "")
+	resource.AddTag("team", "payments-*", OperatorStringEquals)
+	assert.Equal(t, []Attribute{
+		{Name: "env", Value: "dev", Operator: OperatorStringEquals},
+		{Name: "team", Value: "payments-*", Operator: OperatorStringEquals},
+		{Name: "env", Value: "test", Operator: OperatorStringEquals},
+	}, resource.Tags)
+
+	data, err := json.Marshal(Resource{})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"attributes":null}`, string(data))
+}
+
+func TestAttributesWellFormed(t *testing.T) {
+	set := []Attribute{{Name: "serviceName", Value: "messagehub"}, {Name: "serviceInstance", Value: "instance-1"}}
+	assert.True(t, AttributesWellFormed(set, []Attribute{
+		{Name: "resourceType", Value: "topic"},
+		{Name: "resource", Value: "orders-*", Operator: OperatorStringMatch},
+	}))
+	assert.True(t, AttributesWellFormed(nil, []Attribute{{Name: "serviceName", Value: "messagehub"}}))
+
+	assert.False(t, AttributesWellFormed(set, []Attribute{{Name: "serviceName", Value: "kms"}}), "overrides an attribute set")
+	assert.False(t, AttributesWellFormed(nil, []Attribute{{Name: "accountId", Value: "12345"}}), "overrides the account ID")
+	assert.False(t, AttributesWellFormed(nil, []Attribute{{Name: "region", Value: "us-*"}, {Name: "region", Value: "eu-*"}}), "duplicated")
+	assert.False(t, AttributesWellFormed(nil, []Attribute{{Name: "region", Value: "us-*", Operator: "stringExists"}}), "unsupported operator")
+	assert.False(t, AttributesWellFormed(nil, []Attribute{{Name: "region"}}), "no value")
+}
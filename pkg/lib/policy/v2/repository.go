@@ -0,0 +1,187 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v2
+
+import (
+	gohttp "net/http"
+
+	bluemix "github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/authentication"
+	"github.com/IBM-Cloud/bluemix-go/client"
+	"github.com/IBM-Cloud/bluemix-go/http"
+	"github.com/IBM-Cloud/bluemix-go/rest"
+	"github.com/IBM-Cloud/bluemix-go/session"
+)
+
+// SearchParams are the query parameters used to list policies
+type SearchParams struct {
+	AccountID     string
+	IAMID         string
+	AccessGroupID string
+	Type          string
+	ServiceType   string
+	State         string
+}
+
+func (p SearchParams) buildRequest(r *rest.Request) {
+	if p.AccountID != "" {
+		r.Query("account_id", p.AccountID)
+	}
+	if p.IAMID != "" {
+		r.Query("iam_id", p.IAMID)
+	}
+	if p.AccessGroupID != "" {
+		r.Query("access_group_id", p.AccessGroupID)
+	}
+	if p.Type != "" {
+		r.Query("type", p.Type)
+	}
+	if p.ServiceType != "" {
+		r.Query("service_type", p.ServiceType)
+	}
+	if p.State != "" {
+		r.Query("state", p.State)
+	}
+}
+
+// PolicyRepository manages IAM v2 access policies
+type PolicyRepository interface {
+	List(params SearchParams) ([]Policy, error)
+	Get(policyID string) (Policy, error)
+	Create(policy Policy) (Policy, error)
+	Update(policyID string, policy Policy, version string) (Policy, error)
+	Delete(policyID string) error
+}
+
+type policyRepository struct {
+	client *client.Client
+}
+
+// New creates a v2 policy repository for the IAM policy management endpoint of the session
+func New(sess *session.Session) (PolicyRepository, error) {
+	config := sess.Config.Copy()
+	err := config.ValidateConfigForService(bluemix.IAMPAPService)
+	if err != nil {
+		return nil, err
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.NewHTTPClient(config)
+	}
+	tokenRefresher, err := authentication.NewIAMAuthRepository(config, &rest.Client{
+		DefaultHeader: gohttp.Header{
+			"User-Agent": []string{http.UserAgent()},
+		},
+		HTTPClient: config.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config.IAMAccessToken == "" {
+		err := authentication.PopulateTokens(tokenRefresher, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Endpoint == nil {
+		ep, err := config.EndpointLocator.IAMPAPEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		config.Endpoint = &ep
+	}
+	return NewPolicyRepository(client.New(config, bluemix.IAMPAPService, tokenRefresher)), nil
+}
+
+// NewPolicyRepository creates a policy repository on top of an existing client
+func NewPolicyRepository(c *client.Client) PolicyRepository {
+	return &policyRepository{
+		client: c,
+	}
+}
+
+func (r *policyRepository) List(params SearchParams) ([]Policy, error) {
+	request := rest.GetRequest(*r.client.Config.Endpoint + "/v2/policies")
+	params.buildRequest(request)
+
+	// IAM returns the policies in pages, each linking to the next one
+	var policies []Policy
+	for request != nil {
+		var response page
+		_, err := r.client.SendRequest(request, &response)
+		if err != nil {
+			return []Policy{}, err
+		}
+		policies = append(policies, response.Policies...)
+		request = nil
+		if response.Next != nil && response.Next.Href != "" {
+			request = rest.GetRequest(response.Next.Href)
+		}
+	}
+	return policies, nil
+}
+
+// page is a page of policies listed by IAM
+type page struct {
+	Policies []Policy `json:"policies"`
+	Next     *link    `json:"next,omitempty"`
+}
+
+// link is the URL of a page of a list
+type link struct {
+	Href string `json:"href"`
+}
+
+func (r *policyRepository) Get(policyID string) (Policy, error) {
+	var response Policy
+	resp, err := r.client.Get("/v2/policies/"+policyID, &response)
+	if err != nil {
+		return Policy{}, err
+	}
+	response.Version = resp.Header.Get("ETag")
+	return response, nil
+}
+
+func (r *policyRepository) Create(policy Policy) (Policy, error) {
+	var response Policy
+	resp, err := r.client.Post("/v2/policies", &policy, &response)
+	if err != nil {
+		return Policy{}, err
+	}
+	response.Version = resp.Header.Get("ETag")
+	return response, nil
+}
+
+func (r *policyRepository) Update(policyID string, policy Policy, version string) (Policy, error) {
+	var response Policy
+	request := rest.PutRequest(*r.client.Config.Endpoint + "/v2/policies/" + policyID)
+	request = request.Set("If-Match", version).Body(&policy)
+
+	resp, err := r.client.SendRequest(request, &response)
+	if err != nil {
+		return Policy{}, err
+	}
+	response.Version = resp.Header.Get("ETag")
+	return response, nil
+}
+
+func (r *policyRepository) Delete(policyID string) error {
+	_, err := r.client.Delete("/v2/policies/" + policyID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v2
+
+import (
+	"encoding/json"
+	"reflect"
+
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+)
+
+const (
+	// PatternOnce grants access between two points in time
+	PatternOnce = "time-based-conditions:once"
+	// PatternWeeklyAllDay grants access on some days of the week
+	PatternWeeklyAllDay = "time-based-conditions:weekly:all-day"
+	// PatternWeeklyCustomHours grants access on some days of the week between two times of the day
+	PatternWeeklyCustomHours = "time-based-conditions:weekly:custom-hours"
+
+	// CurrentDateTimeKey is the environment attribute compared by date-time conditions
+	CurrentDateTimeKey = "{{environment.attributes.current_date_time}}"
+	// DayOfWeekKey is the environment attribute compared by day of week conditions
+	DayOfWeekKey = "{{environment.attributes.day_of_week}}"
+	// CurrentTimeKey is the environment attribute compared by time of day conditions
+	CurrentTimeKey = "{{environment.attributes.current_time}}"
+
+	OperatorAnd                         = "and"
+	OperatorDateTimeGreaterThanOrEquals = "dateTimeGreaterThanOrEquals"
+	OperatorDateTimeLessThanOrEquals    = "dateTimeLessThanOrEquals"
+	OperatorDayOfWeekAnyOf              = "dayOfWeekAnyOf"
+	OperatorTimeGreaterThanOrEquals     = "timeGreaterThanOrEquals"
+	OperatorTimeLessThanOrEquals        = "timeLessThanOrEquals"
+)
+
+// Policy is the model of an IAM v2 policy
+type Policy struct {
+	ID               string   `json:"id,omitempty"`
+	Type             string   `json:"type"`
+	Description      string   `json:"description,omitempty"`
+	Subject          Subject  `json:"subject"`
+	Control          Control  `json:"control"`
+	Resource         Resource `json:"resource"`
+	Rule             *Rule    `json:"rule,omitempty"`
+	Pattern          string   `json:"pattern,omitempty"`
+	Href             string   `json:"href,omitempty"`
+	CreatedAt        string   `json:"created_at,omitempty"`
+	CreatedByID      string   `json:"created_by_id,omitempty"`
+	LastModifiedAt   string   `json:"last_modified_at,omitempty"`
+	LastModifiedByID string   `json:"last_modified_by_id,omitempty"`
+	State            string   `json:"state,omitempty"`
+	Version          string   `json:"-"`
+}
+
+// Attribute is part of policy subject and resource
+type Attribute struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// Subject is the target to which is assigned policy
+type Subject struct {
+	Attributes []Attribute `json:"attributes"`
+}
+
+// Resource is the object controlled by the policy
+type Resource struct {
+	Attributes []Attribute `json:"attributes"`
+	Tags       []Attribute `json:"tags,omitempty"`
+}
+
+// Role is a role granted by the policy
+type Role struct {
+	RoleID string `json:"role_id"`
+}
+
+// Grant lists the roles granted by the policy
+type Grant struct {
+	Roles []Role `json:"roles"`
+}
+
+// Control is the access granted by the policy
+type Control struct {
+	Grant Grant `json:"grant"`
+}
+
+// Rule is a condition evaluated by IAM when the policy is used. A rule either
+// compares Key with Value, or combines Conditions with Operator.
+type Rule struct {
+	Key        string      `json:"key,omitempty"`
+	Operator   string      `json:"operator"`
+	Value      interface{} `json:"value,omitempty"`
+	Conditions []Rule      `json:"conditions,omitempty"`
+}
+
+// ConvertV1Policy transforms a v1 policy into the equivalent v2 policy, without rule
+func ConvertV1Policy(policy polv1.Policy) Policy {
+	result := Policy{
+		ID:          policy.ID,
+		Type:        policy.Type,
+		Description: policy.Description,
+	}
+	for _, subject := range policy.Subjects {
+		result.Subject.Attributes = append(result.Subject.Attributes, convertV1Attributes(subject.Attributes)...)
+	}
+	for _, role := range policy.Roles {
+		result.Control.Grant.Roles = append(result.Control.Grant.Roles, Role{RoleID: role.RoleID})
+	}
+	for _, resource := range policy.Resources {
+		result.Resource.Attributes = append(result.Resource.Attributes, convertV1Attributes(resource.Attributes)...)
+		result.Resource.Tags = append(result.Resource.Tags, convertV1Attributes(resource.Tags)...)
+	}
+	return result
+}
+
+func convertV1Attributes(attributes []polv1.Attribute) []Attribute {
+	var results []Attribute
+	for _, a := range attributes {
+		operator := a.Operator
+		if operator == "" {
+			operator = polv1.OperatorStringEquals
+		}
+		results = append(results, Attribute{
+			Key:      a.Name,
+			Operator: operator,
+			Value:    a.Value,
+		})
+	}
+	return results
+}
+
+// RulesEqual compares two rules by their JSON representation, since rule values
+// read back from IAM are decoded as generic JSON values.
+func RulesEqual(a *Rule, b *Rule) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	adata, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bdata, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	var avalue, bvalue interface{}
+	if json.Unmarshal(adata, &avalue) != nil || json.Unmarshal(bdata, &bvalue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(avalue, bvalue)
+}
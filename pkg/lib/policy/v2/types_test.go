@@ -0,0 +1,74 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v2
+
+import (
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/stretchr/testify/assert"
+
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+)
+
+func TestConvertV1Policy(t *testing.T) {
+	resource := polv1.Resource{}
+	resource.SetAttribute("serviceName", "cloud-object-storage")
+	resource.AddAttribute("prefix", "logs/*", polv1.OperatorStringMatch)
+	resource.AddTag("env", "dev", "")
+	subject := polv1.Subject{}
+	subject.SetAttribute("iam_id", "iam-ServiceId-123")
+
+	policy := ConvertV1Policy(polv1.Policy{
+		Type:      "access",
+		Subjects:  []polv1.Subject{subject},
+		Roles:     []iampapv1.Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Viewer"}},
+		Resources: []polv1.Resource{resource},
+	})
+
+	assert.Equal(t, "access", policy.Type)
+	assert.Equal(t, []Attribute{{Key: "iam_id", Operator: polv1.OperatorStringEquals, Value: "iam-ServiceId-123"}}, policy.Subject.Attributes)
+	assert.Equal(t, []Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Viewer"}}, policy.Control.Grant.Roles)
+	assert.Equal(t, []Attribute{
+		{Key: "serviceName", Operator: polv1.OperatorStringEquals, Value: "cloud-object-storage"},
+		{Key: "prefix", Operator: polv1.OperatorStringMatch, Value: "logs/*"},
+	}, policy.Resource.Attributes)
+	assert.Equal(t, []Attribute{{Key: "env", Operator: polv1.OperatorStringEquals, Value: "dev"}}, policy.Resource.Tags)
+	assert.Nil(t, policy.Rule)
+}
+
+func TestRulesEqual(t *testing.T) {
+	rule := &Rule{
+		Operator: OperatorAnd,
+		Conditions: []Rule{
+			{Key: DayOfWeekKey, Operator: OperatorDayOfWeekAnyOf, Value: []string{"1+00:00", "5+00:00"}},
+		},
+	}
+	// values read back from IAM are decoded as generic JSON values
+	retrieved := &Rule{
+		Operator: OperatorAnd,
+		Conditions: []Rule{
+			{Key: DayOfWeekKey, Operator: OperatorDayOfWeekAnyOf, Value: []interface{}{"1+00:00", "5+00:00"}},
+		},
+	}
+	assert.True(t, RulesEqual(rule, retrieved))
+	assert.True(t, RulesEqual(nil, nil))
+	assert.False(t, RulesEqual(rule, nil))
+
+	retrieved.Conditions[0].Value = []interface{}{"1+00:00"}
+	assert.False(t, RulesEqual(rule, retrieved))
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package registry shares an object per account and credential between the controllers,
+// such as a cache, so that namespaces configured with different API keys of the same
+// account never read IAM through each other's credentials
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// Registry holds an object per account and credential
+type Registry struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is the object of a key, ready once its connection ends
+type entry struct {
+	ready chan struct{}
+	obj   interface{}
+	err   error
+}
+
+// New creates an empty registry
+func New() *Registry {
+	return &Registry{entries: map[string]*entry{}}
+}
+
+// Key identifies an account and the API key it is accessed with, without holding the API key
+func Key(accountID string, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return accountID + "/" + hex.EncodeToString(sum[:])
+}
+
+// Get returns the object of an account and API key. When there is none, connect is
+// called and returns the object to register. Only the callers of the same key wait
+// for connect, which may call IAM, and get its error if it fails.
+func (r *Registry) Get(accountID string, apiKey string, connect func() (interface{}, error)) (interface{}, error) {
+	key := Key(accountID, apiKey)
+	r.mu.Lock()
+	e, ok := r.entries[key]
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		r.entries[key] = e
+	}
+	r.mu.Unlock()
+
+	if ok {
+		<-e.ready
+		return e.obj, e.err
+	}
+	e.obj, e.err = connect()
+	if e.err != nil { // The next call connects again
+		r.mu.Lock()
+		if r.entries[key] == e {
+			delete(r.entries, key)
+		}
+		r.mu.Unlock()
+	}
+	close(e.ready)
+	return e.obj, e.err
+}
+
+// Evict drops the object of a key, e.g. when the API key was rotated, so that the next
+// call of Get with that key connects again
+func (r *Registry) Evict(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, key)
+}
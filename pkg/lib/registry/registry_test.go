@@ -0,0 +1,109 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type object struct {
+	apiKey string
+}
+
+func TestGet(t *testing.T) {
+	r := New()
+	connects := 0
+	get := func(accountID string, apiKey string) *object {
+		obj, err := r.Get(accountID, apiKey, func() (interface{}, error) {
+			connects++
+			return &object{apiKey: apiKey}, nil
+		})
+		assert.NoError(t, err)
+		return obj.(*object)
+	}
+
+	first := get("12345", "key-1")
+	assert.Same(t, first, get("12345", "key-1"))
+	assert.Equal(t, 1, connects)
+
+	// Another API key of the same account gets its own object
+	second := get("12345", "key-2")
+	assert.NotSame(t, first, second)
+	assert.Equal(t, "key-2", second.apiKey)
+	assert.Same(t, first, get("12345", "key-1"))
+	assert.NotSame(t, second, get("67890", "key-2"))
+	assert.Equal(t, 3, connects)
+
+	// An object that fails to connect isn't registered
+	_, err := r.Get("00000", "key-1", func() (interface{}, error) { return nil, errors.New("invalid API key") })
+	assert.Error(t, err)
+	get("00000", "key-1")
+	assert.Equal(t, 4, connects)
+}
+
+func TestGetConnectsPerKey(t *testing.T) {
+	r := New()
+	connecting := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan interface{})
+	go func() {
+		obj, _ := r.Get("12345", "key-1", func() (interface{}, error) {
+			close(connecting)
+			<-release // A token exchange that hangs
+			return &object{apiKey: "key-1"}, nil
+		})
+		done <- obj
+	}()
+	<-connecting
+
+	// Another key doesn't wait for the connection
+	obj, err := r.Get("67890", "key-2", func() (interface{}, error) { return &object{apiKey: "key-2"}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "key-2", obj.(*object).apiKey)
+
+	// The same key waits for it and shares its object
+	waiting := make(chan interface{})
+	go func() {
+		obj, _ := r.Get("12345", "key-1", func() (interface{}, error) { return nil, errors.New("connected twice") })
+		waiting <- obj
+	}()
+	close(release)
+	first := <-done
+	assert.Same(t, first, <-waiting)
+}
+
+func TestEvict(t *testing.T) {
+	r := New()
+	connect := func() (interface{}, error) { return &object{apiKey: "key-1"}, nil }
+	first, err := r.Get("12345", "key-1", connect)
+	assert.NoError(t, err)
+
+	r.Evict(Key("12345", "key-1"))
+	second, err := r.Get("12345", "key-1", connect)
+	assert.NoError(t, err)
+	assert.NotSame(t, first, second)
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("12345", "key-1"), Key("12345", "key-1"))
+	assert.NotEqual(t, Key("12345", "key-1"), Key("12345", "key-2"))
+	assert.NotEqual(t, Key("12345", "key-1"), Key("67890", "key-1"))
+	assert.NotContains(t, Key("12345", "key-1"), "key-1")
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package requeue classifies the errors of reconciliations as transient, permanent or waiting for a
+// dependency, and decides from the class whether and when a resource is reconciled again.
+package requeue
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/expiry"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+// Class tells how a reconciliation that failed is requeued
+type Class string
+
+const (
+	// Transient errors, such as IAM being unavailable, are retried with exponential backoff
+	Transient Class = "Transient"
+	// Permanent errors, such as a spec IAM rejects, are not retried until the spec changes
+	Permanent Class = "Permanent"
+	// Dependency errors, such as a referenced resource that is not ready, are retried when the dependency changes
+	Dependency Class = "Dependency"
+)
+
+// ConditionType is the type of the status condition reporting that reconciliation is stalled
+const ConditionType = "Stalled"
+
+const (
+	reasonReconciled = "Reconciled"
+	reasonRetrying   = "Retrying"
+	reasonPermanent  = "PermanentError"
+	reasonDependency = "WaitingForDependency"
+)
+
+// Error is an error with the class that decides how it is requeued
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the classified error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// AsPermanent classifies err as permanent, it is not retried until the spec changes
+func AsPermanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: Permanent, Err: err}
+}
+
+// AsDependency classifies err as waiting for a dependency, it is retried when the dependency changes
+func AsDependency(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: Dependency, Err: err}
+}
+
+// Classify returns the class of err. Errors that were not classified are permanent if IAM or Kubernetes
+// rejected the request as invalid, and transient otherwise.
+func Classify(err error) Class {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	var failure bmxerror.RequestFailure
+	if errors.As(err, &failure) {
+		switch failure.StatusCode() {
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return Permanent
+		}
+	}
+	if kerror.IsInvalid(err) || kerror.IsBadRequest(err) {
+		return Permanent
+	}
+	return Transient
+}
+
+// Result returns the result of a reconciliation that failed with err: transient errors are returned so the
+// request is retried with backoff, while permanent and dependency errors are not requeued
+func Result(err error) (reconcile.Result, error) {
+	if err == nil || Classify(err) != Transient {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{}, err
+}
+
+// ResultUntil is Result for resources with a deadline, such as an expiry: permanent and dependency errors
+// are requeued when the deadline passes, so that it is still handled
+func ResultUntil(err error, deadline *metav1.Time, now time.Time) (reconcile.Result, error) {
+	if result, err := Result(err); err != nil {
+		return result, err
+	}
+	return Until(deadline, now), nil
+}
+
+// Until returns the result of a reconciliation that is only requeued when deadline passes, if there is one
+func Until(deadline *metav1.Time, now time.Time) reconcile.Result {
+	if deadline == nil {
+		return reconcile.Result{}
+	}
+	until := deadline.Sub(now)
+	if until < expiry.MinRequeue {
+		until = expiry.MinRequeue
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: until}
+}
+
+// Report sets the Stalled condition of obj from the outcome of its reconciliation, and returns true if
+// the condition changed. A nil err means obj was reconciled.
+func Report(obj runtime.Object, err error) bool {
+	condition := &resv1.Condition{Type: ConditionType, Status: corev1.ConditionFalse, Reason: reasonReconciled}
+	if err != nil {
+		switch Classify(err) {
+		case Permanent:
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = reasonPermanent
+		case Dependency:
+			condition.Status = corev1.ConditionTrue
+			condition.Reason = reasonDependency
+		default:
+			condition.Reason = reasonRetrying
+		}
+		condition.Message = err.Error()
+		condition.ObservedGeneration = resv1.ObjectMeta(obj).GetGeneration()
+	}
+
+	current := resv1.GetCondition(obj, ConditionType)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return false
+	}
+	if current == nil && condition.Status == corev1.ConditionFalse { // No need for a condition until reconciliation stalls
+		return false
+	}
+	resv1.SetCondition(obj, condition)
+	return true
+}
+
+// Stalled returns true if the reconciliation of obj failed permanently for its current spec
+func Stalled(obj runtime.Object) bool {
+	current := resv1.GetCondition(obj, ConditionType)
+	return current != nil && current.Reason == reasonPermanent && current.ObservedGeneration == resv1.ObjectMeta(obj).GetGeneration()
+}
+
+// Waiting returns true if the reconciliation of obj is waiting for a dependency
+func Waiting(obj runtime.Object) bool {
+	current := resv1.GetCondition(obj, ConditionType)
+	return current != nil && current.Reason == reasonDependency
+}
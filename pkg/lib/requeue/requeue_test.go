@@ -0,0 +1,118 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package requeue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/expiry"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, Transient, Classify(errors.New("connection reset by peer")))
+	assert.Equal(t, Permanent, Classify(AsPermanent(errors.New("The spec is not well-formed"))))
+	assert.Equal(t, Dependency, Classify(AsDependency(errors.New("access group is not ready"))))
+	assert.Equal(t, Permanent, Classify(fmt.Errorf("creating policy: %w", AsPermanent(errors.New("invalid")))))
+
+	assert.Equal(t, Permanent, Classify(bmxerror.NewRequestFailure("invalid_body", "Invalid role", 400)))
+	assert.Equal(t, Transient, Classify(bmxerror.NewRequestFailure("too_many_requests", "Rate limit exceeded", 429)))
+	assert.Equal(t, Transient, Classify(bmxerror.NewRequestFailure("internal_error", "Internal error", 500)))
+
+	assert.Equal(t, Permanent, Classify(kerror.NewInvalid(schema.GroupKind{Group: "ibmcloud.ibm.com", Kind: "AccessPolicy"}, "viewer", nil)))
+	assert.Equal(t, Transient, Classify(kerror.NewConflict(schema.GroupResource{Group: "ibmcloud.ibm.com", Resource: "accesspolicies"}, "viewer", errors.New("modified"))))
+
+	assert.Nil(t, AsPermanent(nil))
+	assert.Nil(t, AsDependency(nil))
+}
+
+func TestResult(t *testing.T) {
+	transient := errors.New("connection reset by peer")
+	result, err := Result(transient)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.Equal(t, transient, err)
+
+	result, err = Result(AsPermanent(errors.New("The spec is not well-formed")))
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.NoError(t, err)
+
+	result, err = Result(AsDependency(errors.New("access group is not ready")))
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.NoError(t, err)
+}
+
+func TestResultUntil(t *testing.T) {
+	now := time.Now()
+	deadline := metav1.NewTime(now.Add(time.Hour))
+
+	result, err := ResultUntil(AsPermanent(errors.New("invalid")), &deadline, now)
+	assert.Equal(t, reconcile.Result{Requeue: true, RequeueAfter: time.Hour}, result)
+	assert.NoError(t, err)
+
+	result, err = ResultUntil(AsPermanent(errors.New("invalid")), nil, now)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.NoError(t, err)
+
+	transient := errors.New("connection reset by peer")
+	result, err = ResultUntil(transient, &deadline, now)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.Equal(t, transient, err)
+
+	passed := metav1.NewTime(now.Add(-time.Minute))
+	assert.Equal(t, reconcile.Result{Requeue: true, RequeueAfter: expiry.MinRequeue}, Until(&passed, now))
+}
+
+func TestReport(t *testing.T) {
+	policy := &ibmcloudv1alpha1.AccessPolicy{}
+	policy.Generation = 1
+	assert.False(t, Report(policy, nil))
+	assert.False(t, Report(policy, errors.New("connection reset by peer")))
+	assert.Nil(t, resv1.GetCondition(policy, ConditionType))
+
+	assert.True(t, Report(policy, AsPermanent(errors.New("The spec is not well-formed"))))
+	condition := resv1.GetCondition(policy, ConditionType)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, int64(1), condition.ObservedGeneration)
+	assert.True(t, Stalled(policy))
+	assert.False(t, Waiting(policy))
+	assert.False(t, Report(policy, AsPermanent(errors.New("The spec is not well-formed"))))
+
+	// A new spec is reconciled again, even if it fails the same way
+	policy.Generation = 2
+	assert.False(t, Stalled(policy))
+	assert.True(t, Report(policy, AsPermanent(errors.New("The spec is not well-formed"))))
+	assert.True(t, Stalled(policy))
+
+	assert.True(t, Report(policy, AsDependency(errors.New("access group is not ready"))))
+	assert.False(t, Stalled(policy))
+	assert.True(t, Waiting(policy))
+
+	assert.True(t, Report(policy, nil))
+	assert.Equal(t, corev1.ConditionFalse, resv1.GetCondition(policy, ConditionType).Status)
+	assert.False(t, Waiting(policy))
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+// ConditionType is the type of the status condition reporting that IAM is unavailable
+const ConditionType = "Degraded"
+
+const (
+	reasonAvailable   = "IAMAvailable"
+	reasonUnavailable = "IAMUnavailable"
+)
+
+// Report sets the Degraded condition of obj while IAM is unavailable, and returns true if the condition changed
+func Report(obj runtime.Object, unavailable time.Duration) bool {
+	condition := &resv1.Condition{Type: ConditionType, Status: corev1.ConditionFalse, Reason: reasonAvailable}
+	if unavailable > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = reasonUnavailable
+		condition.Message = "IAM requests are paused after repeated transient errors"
+	}
+
+	current := resv1.GetCondition(obj, ConditionType)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message {
+		return false
+	}
+	if current == nil && unavailable == 0 { // No need for a condition until IAM is unavailable
+		return false
+	}
+	resv1.SetCondition(obj, condition)
+	return true
+}
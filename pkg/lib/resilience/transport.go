@@ -0,0 +1,362 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resilience protects IAM from the operator and the operator from IAM outages. It wraps
+// the HTTP transport of the bluemix-go clients of an account with a rate limiter, retries of
+// transient errors with backoff, and a circuit breaker.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	bxhttp "github.com/IBM-Cloud/bluemix-go/http"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"golang.org/x/time/rate"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/registry"
+)
+
+// ErrUnavailable is returned without calling IAM while the circuit breaker of an account is open
+var ErrUnavailable = errors.New("IAM is unavailable, waiting for it to recover")
+
+// Options tune the rate limiter, retries and circuit breaker of an account
+type Options struct {
+	// RequestsPerSecond and Burst size the token bucket of requests to IAM
+	RequestsPerSecond float64
+	Burst             int
+	// MaxRetries of a request failing with a transient error
+	MaxRetries int
+	// BaseDelay doubles at each retry up to MaxDelay, which also caps the Retry-After of IAM
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// FailureThreshold consecutive requests failing with transient errors, once retried, open the circuit
+	// breaker for Cooldown
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// DefaultOptions are used for all accounts
+var DefaultOptions = Options{
+	RequestsPerSecond: 10,
+	Burst:             20,
+	MaxRetries:        4,
+	BaseDelay:         time.Millisecond * 500,
+	MaxDelay:          time.Second * 30,
+	FailureThreshold:  5,
+	Cooldown:          time.Minute,
+}
+
+var (
+	transportsLock sync.Mutex
+	// transports of each account, by registry key of the account and API key
+	transports = map[string]map[string]*Transport{}
+)
+
+// Install routes the requests of clients created from a session through the transport of an account
+// and the API key of the session
+func Install(sess *session.Session, accountID string) {
+	sess.Config.HTTPClient = &http.Client{
+		Transport: ForAccount(sess, accountID),
+		Timeout:   sess.Config.HTTPTimeout,
+	}
+}
+
+// ForAccount returns the transport shared by all clients of an account using the API key of the
+// session. Each API key of an account has its own rate limiter and circuit breaker, so that one
+// namespace with an invalid or throttled API key doesn't break the others.
+func ForAccount(sess *session.Session, accountID string) *Transport {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	key := registry.Key(accountID, sess.Config.BluemixAPIKey)
+	transport, ok := transports[accountID][key]
+	if !ok {
+		// Count every attempt, including retries, in the IAM request metrics
+		transport = NewTransport(&metrics.Transport{Base: bxhttp.NewHTTPClient(sess.Config).Transport}, DefaultOptions)
+		if transports[accountID] == nil {
+			transports[accountID] = map[string]*Transport{}
+		}
+		transports[accountID][key] = transport
+	}
+	return transport
+}
+
+// Evict drops the transport of an account and registry key, e.g. when its API key is no longer configured
+// for any namespace. Sessions that still hold the transport keep using it.
+func Evict(accountID string, key string) {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	delete(transports[accountID], key)
+	if len(transports[accountID]) == 0 {
+		delete(transports, accountID)
+	}
+}
+
+// Unavailable returns how long IAM is expected to stay unavailable for an account, the longest
+// of its API keys, 0 if it is available
+func Unavailable(accountID string) time.Duration {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	return unavailable(accountID)
+}
+
+func unavailable(accountID string) time.Duration {
+	var longest time.Duration
+	for _, transport := range transports[accountID] {
+		if wait := transport.Breaker.Unavailable(); wait > longest {
+			longest = wait
+		}
+	}
+	return longest
+}
+
+// UnavailableAccounts returns the accounts IAM is unavailable for, sorted
+func UnavailableAccounts() []string {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	var accounts []string
+	for accountID := range transports {
+		if unavailable(accountID) > 0 {
+			accounts = append(accounts, accountID)
+		}
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// Transport is an http.RoundTripper limiting, retrying and breaking requests to IAM
+type Transport struct {
+	Breaker *Breaker
+
+	base    http.RoundTripper
+	options Options
+	limiter *rate.Limiter
+	sleep   func(ctx context.Context, delay time.Duration) error
+}
+
+// NewTransport wraps a transport
+func NewTransport(base http.RoundTripper, options Options) *Transport {
+	return &Transport{
+		Breaker: NewBreaker(options.FailureThreshold, options.Cooldown),
+		base:    base,
+		options: options,
+		limiter: rate.NewLimiter(rate.Limit(options.RequestsPerSecond), options.Burst),
+		sleep:   sleep,
+	}
+}
+
+// RoundTrip sends a request to IAM, retrying it while it fails with a transient error. The
+// circuit breaker counts the request once, whatever its number of attempts.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	allowed, probe := t.Breaker.Allow()
+	if !allowed {
+		return nil, ErrUnavailable
+	}
+	counted := false
+	if probe { // A probe left without an outcome, e.g. canceled, lets the next request probe
+		defer func() {
+			if !counted {
+				t.Breaker.Release()
+			}
+		}()
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && t.Breaker.Unavailable() > 0 { // Other requests opened the breaker meanwhile
+			return nil, ErrUnavailable
+		}
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		sent := req
+		if attempt > 0 { // A RoundTripper must not modify the request, a retry sends a copy with a new body
+			sent = req.Clone(req.Context())
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				sent.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(sent)
+		if !Transient(resp, err) {
+			t.Breaker.Success()
+			counted = true
+			return resp, err
+		}
+		if attempt >= t.options.MaxRetries || !retryable(req, resp) {
+			t.Breaker.Failure()
+			counted = true
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for a delay, or returns the error of the context if it is done first
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Transient returns true for responses and errors that may not happen again: throttling, server errors and network errors
+func Transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryable returns true if a request can be sent again. Throttled requests were not
+// processed, other requests are only sent again if doing so twice has the same effect.
+func retryable(req *http.Request, resp *http.Response) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before a retry: the Retry-After of IAM if set, up to MaxDelay, else an
+// exponential delay with jitter
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return t.capDelay(time.Duration(seconds) * time.Second)
+		}
+		if at, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+			if delay := time.Until(at); delay > 0 {
+				return t.capDelay(delay)
+			}
+			return 0
+		}
+	}
+	delay := t.options.BaseDelay << uint(attempt)
+	if delay > t.options.MaxDelay || delay <= 0 {
+		delay = t.options.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (t *Transport) capDelay(delay time.Duration) time.Duration {
+	if delay > t.options.MaxDelay {
+		return t.options.MaxDelay
+	}
+	return delay
+}
+
+// Breaker stops requests to IAM after consecutive transient errors, then lets one
+// request through after a cooldown to probe whether IAM recovered
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker creates a closed circuit breaker
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow returns true if a request can be sent, and whether it probes the open circuit breaker. A probe
+// must end with Success, Failure or Release, or no other request is let through.
+func (b *Breaker) Allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if b.now().Sub(b.openedAt) < b.cooldown || b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// Release ends a probe that got no answer from IAM, so that the next request probes instead
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Success closes the circuit breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure counts a request that failed with a transient error, and opens the circuit breaker at the threshold
+// or when a probe fails
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
+
+// Unavailable returns the time left before the open circuit breaker lets a probe through, 0 if it is closed
+func (b *Breaker) Unavailable() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return 0
+	}
+	if left := b.cooldown - b.now().Sub(b.openedAt); left > 0 {
+		return left
+	}
+	return 0
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resilience
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	ibmcloudv1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	resv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/resource/v1"
+)
+
+// fakeIAM answers requests with the given status codes in turn, then 200
+type fakeIAM struct {
+	codes    []int
+	headers  http.Header
+	requests int
+	bodies   []string
+}
+
+func (f *fakeIAM) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests++
+	if req.Body != nil {
+		body, _ := ioutil.ReadAll(req.Body)
+		f.bodies = append(f.bodies, string(body))
+	}
+	code := http.StatusOK
+	if len(f.codes) > 0 {
+		code, f.codes = f.codes[0], f.codes[1:]
+	}
+	if code == 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+	header := http.Header{}
+	if code != http.StatusOK {
+		header = f.headers
+	}
+	return &http.Response{StatusCode: code, Header: header, Body: ioutil.NopCloser(bytes.NewBufferString(""))}, nil
+}
+
+// failures returns the status codes of n requests failing with a transient error
+func failures(n int) []int {
+	codes := make([]int, n)
+	for i := range codes {
+		codes[i] = http.StatusServiceUnavailable
+	}
+	return codes
+}
+
+func newTestTransport(iam *fakeIAM, delays *[]time.Duration) *Transport {
+	transport := NewTransport(iam, Options{RequestsPerSecond: 1000, Burst: 1000, MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Second * 4, FailureThreshold: 5, Cooldown: time.Minute})
+	transport.sleep = func(ctx context.Context, delay time.Duration) error {
+		*delays = append(*delays, delay)
+		return nil
+	}
+	return transport
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	var delays []time.Duration
+	iam := &fakeIAM{codes: []int{http.StatusServiceUnavailable, 0, http.StatusBadGateway}}
+	transport := newTestTransport(iam, &delays)
+
+	req, _ := http.NewRequest(http.MethodPut, "https://iam.cloud.ibm.com/v1/policies/policy-1", bytes.NewBufferString(`{"type":"access"}`))
+	body := req.Body
+	resp, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 4, iam.requests)
+	assert.Equal(t, []string{`{"type":"access"}`, `{"type":"access"}`, `{"type":"access"}`, `{"type":"access"}`}, iam.bodies)
+	assert.True(t, req.Body == body, "the request of the caller was modified")
+
+	// Exponential backoff with jitter
+	assert.Len(t, delays, 3)
+	for i, max := range []time.Duration{time.Second, time.Second * 2, time.Second * 4} {
+		assert.True(t, delays[i] >= max/2 && delays[i] <= max, delays[i])
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var delays []time.Duration
+	iam := &fakeIAM{codes: []int{500, 500, 500, 500, 500}}
+	transport := newTestTransport(iam, &delays)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.cloud.ibm.com/v1/policies/policy-1", nil)
+	resp, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Equal(t, 4, iam.requests)
+}
+
+func TestNoRetry(t *testing.T) {
+	var delays []time.Duration
+	iam := &fakeIAM{codes: []int{http.StatusBadRequest, http.StatusInternalServerError}}
+	transport := newTestTransport(iam, &delays)
+
+	// Permanent errors are not retried
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.cloud.ibm.com/v1/policies/policy-1", nil)
+	resp, _ := transport.RoundTrip(req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, 1, iam.requests)
+
+	// A create that failed on the server side might have been processed
+	req, _ = http.NewRequest(http.MethodPost, "https://iam.cloud.ibm.com/v1/policies", bytes.NewBufferString("{}"))
+	resp, _ = transport.RoundTrip(req)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, 2, iam.requests)
+	assert.Len(t, delays, 0)
+}
+
+func TestRetryAfter(t *testing.T) {
+	var delays []time.Duration
+	iam := &fakeIAM{codes: []int{http.StatusTooManyRequests}, headers: http.Header{"Retry-After": []string{"3"}}}
+	transport := newTestTransport(iam, &delays)
+
+	// Throttled creates were not processed and are retried
+	req, _ := http.NewRequest(http.MethodPost, "https://iam.cloud.ibm.com/v1/policies", bytes.NewBufferString("{}"))
+	resp, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second * 3}, delays)
+
+	// A Retry-After longer than the maximum delay is capped
+	iam.codes = []int{http.StatusTooManyRequests}
+	iam.headers = http.Header{"Retry-After": []string{"3600"}}
+	_, err = transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*4, delays[1])
+}
+
+func TestRetryCanceled(t *testing.T) {
+	iam := &fakeIAM{codes: []int{http.StatusServiceUnavailable}, headers: http.Header{"Retry-After": []string{"3"}}}
+	transport := NewTransport(iam, Options{RequestsPerSecond: 1000, Burst: 1000, MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Second * 4, FailureThreshold: 5, Cooldown: time.Minute})
+
+	// The retry doesn't outlive the request
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.cloud.ibm.com/v1/policies/policy-1", nil)
+	start := time.Now()
+	_, err := transport.RoundTrip(req.WithContext(ctx))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, 1, iam.requests)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var delays []time.Duration
+	iam := &fakeIAM{codes: failures(20)}
+	transport := newTestTransport(iam, &delays)
+	now := time.Now()
+	transport.Breaker.now = func() time.Time { return now }
+
+	// Failed requests are counted once, with their retries
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.cloud.ibm.com/v1/policies/policy-1", nil)
+	for i := 0; i < 4; i++ {
+		transport.RoundTrip(req)
+	}
+	assert.Equal(t, 16, iam.requests)
+	assert.Equal(t, time.Duration(0), transport.Breaker.Unavailable())
+	transport.RoundTrip(req)
+	assert.Equal(t, 20, iam.requests)
+	assert.Equal(t, time.Minute, transport.Breaker.Unavailable())
+
+	// IAM is not called while the breaker is open
+	_, err := transport.RoundTrip(req)
+	assert.Equal(t, ErrUnavailable, err)
+	assert.Equal(t, 20, iam.requests)
+
+	// After the cooldown a probe closes the breaker
+	now = now.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), transport.Breaker.Unavailable())
+	resp, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, allowed(transport.Breaker))
+}
+
+func TestBreakerProbe(t *testing.T) {
+	breaker := NewBreaker(2, time.Minute)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	assert.True(t, allowed(breaker))
+	breaker.Failure()
+	assert.False(t, allowed(breaker))
+
+	// A single probe is let through, and opens the breaker again if it fails
+	now = now.Add(time.Minute)
+	ok, probe := breaker.Allow()
+	assert.True(t, ok)
+	assert.True(t, probe)
+	assert.False(t, allowed(breaker))
+	breaker.Failure()
+	assert.Equal(t, time.Minute, breaker.Unavailable())
+	assert.False(t, allowed(breaker))
+
+	// A released probe lets the next request probe
+	now = now.Add(time.Minute)
+	assert.True(t, allowed(breaker))
+	breaker.Release()
+	assert.True(t, allowed(breaker))
+}
+
+func TestProbeCanceled(t *testing.T) {
+	var delays []time.Duration
+	iam := &fakeIAM{codes: failures(21)}
+	transport := newTestTransport(iam, &delays)
+	now := time.Now()
+	transport.Breaker.now = func() time.Time { return now }
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.cloud.ibm.com/v1/policies/policy-1", nil)
+	for i := 0; i < 5; i++ {
+		transport.RoundTrip(req)
+	}
+	require.Equal(t, time.Minute, transport.Breaker.Unavailable())
+
+	// The probe is canceled while it waits to be retried
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	transport.sleep = func(ctx context.Context, delay time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	_, err := transport.RoundTrip(req.WithContext(ctx))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 21, iam.requests)
+
+	// The next request probes IAM, which recovered
+	_, err = transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 22, iam.requests)
+	assert.Equal(t, time.Duration(0), transport.Breaker.Unavailable())
+	assert.True(t, allowed(transport.Breaker))
+}
+
+// allowed returns true if the breaker lets a request through
+func allowed(breaker *Breaker) bool {
+	ok, _ := breaker.Allow()
+	return ok
+}
+
+func TestReport(t *testing.T) {
+	group := &ibmcloudv1alpha1.AccessGroup{}
+	assert.False(t, Report(group, 0))
+	assert.Nil(t, resv1.GetCondition(group, ConditionType))
+
+	assert.True(t, Report(group, time.Minute))
+	assert.Equal(t, corev1.ConditionTrue, resv1.GetCondition(group, ConditionType).Status)
+	assert.False(t, Report(group, time.Second*30))
+
+	assert.True(t, Report(group, 0))
+	assert.Equal(t, corev1.ConditionFalse, resv1.GetCondition(group, ConditionType).Status)
+}
+
+func TestUnavailableAccounts(t *testing.T) {
+	defer func() { transports = map[string]map[string]*Transport{} }()
+	var delays []time.Duration
+	available := newTestTransport(&fakeIAM{}, &delays)
+	unavailable := newTestTransport(&fakeIAM{codes: failures(20)}, &delays)
+	transports = map[string]map[string]*Transport{
+		"12345": {"12345/a": available},
+		"67890": {"67890/a": available, "67890/b": unavailable},
+	}
+	assert.Empty(t, UnavailableAccounts())
+
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.cloud.ibm.com/v1/policies/policy-1", nil)
+	for i := 0; i < 5; i++ {
+		unavailable.RoundTrip(req)
+	}
+	assert.Equal(t, []string{"67890"}, UnavailableAccounts())
+	assert.Equal(t, time.Duration(0), Unavailable("12345"))
+	assert.InDelta(t, float64(time.Minute), float64(Unavailable("67890")), float64(time.Second))
+}
+
+func TestForAccount(t *testing.T) {
+	defer func() { transports = map[string]map[string]*Transport{} }()
+	sess := func(apiKey string) *session.Session {
+		return &session.Session{Config: &bluemix.Config{BluemixAPIKey: apiKey}}
+	}
+
+	first := ForAccount(sess("key-1"), "12345")
+	assert.Same(t, first, ForAccount(sess("key-1"), "12345"))
+	assert.NotSame(t, first, ForAccount(sess("key-2"), "12345"))
+	assert.NotSame(t, first, ForAccount(sess("key-1"), "67890"))
+}
@@ -42,6 +42,8 @@ const (
 	ResourceStateRetrying string = "Retrying"
 	// ResourceStateBinding indicates a resource such as a cloud service is being bound
 	ResourceStateBinding string = "Binding"
+	// ResourceStateExpired indicates a temporary resource has been revoked after its deadline
+	ResourceStateExpired string = "Expired"
 )
 
 // Resource is the base struct for custom resources
@@ -72,6 +74,20 @@ type Spec struct {
 type ResourceStatus struct {
 	State   string `json:"state,omitempty"`
 	Message string `json:"message,omitempty"`
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []Condition `json:"conditions,omitempty"`
+	// AcceptedDrift lists the values changed in IAM outside of the operator that the Ignore drift policy accepted
+	// +optional
+	AcceptedDrift []AcceptedValue `json:"acceptedDrift,omitempty"`
+}
+
+// AcceptedValue is a value of an IAM object changed outside of the operator and accepted by its drift policy
+type AcceptedValue struct {
+	Field string `json:"field"`
+	// Value is the accepted value, formatted as JSON
+	Value string `json:"value"`
 }
 
 // Condition is the base struct for representing resource conditions
@@ -89,4 +105,7 @@ type Condition struct {
 	// A human readable message indicating details about the transition.
 	// +optional
 	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
+	// The generation of the resource the condition was set for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
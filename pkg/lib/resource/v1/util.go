@@ -95,9 +95,17 @@ func SeedGeneration(obj runtime.Object) int64 {
 	return 0
 }
 
+// conditionsHolder returns the struct with the conditions field: the status of the resource if it has one
+func conditionsHolder(obj runtime.Object) interface{} {
+	if accessor, ok := obj.(StatusAccessor); ok {
+		return accessor.GetStatus()
+	}
+	return obj
+}
+
 // Conditions returns resource list of conditions
 func Conditions(obj runtime.Object) []Condition {
-	if conditions := util.GetField(obj, "Conditions"); conditions != nil {
+	if conditions := util.GetField(conditionsHolder(obj), "Conditions"); conditions != nil {
 		return conditions.([]Condition)
 	}
 	return make([]Condition, 0)
@@ -116,20 +124,40 @@ func GetCondition(obj runtime.Object, condType string) *Condition {
 }
 
 // SetCondition updates the resource condition to include the provided condition. If the condition that
-// we are about to add already exists and has the same status then we are not going to update.
+// we are about to add already exists and has the same status then we only update its reason and message.
 func SetCondition(obj runtime.Object, condition *Condition) runtime.Object {
 	currentCond := GetCondition(obj, condition.Type)
 	if currentCond != nil && currentCond.Status == condition.Status {
-		return obj
+		if currentCond.Reason == condition.Reason && currentCond.Message == condition.Message && currentCond.ObservedGeneration == condition.ObservedGeneration {
+			return obj
+		}
+		condition.LastTransitionTime = currentCond.LastTransitionTime
+	}
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
 	}
 	newConditions := filterOutCondition(Conditions(obj), condition.Type)
-	util.SetField(obj, "Conditions", append(newConditions, *condition))
+	util.SetField(conditionsHolder(obj), "Conditions", append(newConditions, *condition))
+	return obj
+}
+
+// AcceptedDrift returns the values changed in IAM that the resource accepted
+func AcceptedDrift(obj runtime.Object) []AcceptedValue {
+	if values := util.GetField(conditionsHolder(obj), "AcceptedDrift"); values != nil {
+		return values.([]AcceptedValue)
+	}
+	return nil
+}
+
+// SetAcceptedDrift records the values changed in IAM that the resource accepted
+func SetAcceptedDrift(obj runtime.Object, values []AcceptedValue) runtime.Object {
+	util.SetField(conditionsHolder(obj), "AcceptedDrift", values)
 	return obj
 }
 
 // RemoveCondition removes the condition with the provided type.
 func RemoveCondition(obj runtime.Object, condType string) runtime.Object {
-	util.SetField(obj, "Conditions", filterOutCondition(Conditions(obj), condType))
+	util.SetField(conditionsHolder(obj), "Conditions", filterOutCondition(Conditions(obj), condType))
 	return obj
 }
 
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type MyStatusType struct {
+	Resource `json:",inline"`
+	Status   ResourceStatus `json:"status"`
+}
+
+func (s *MyStatusType) GetStatus() Status {
+	return &s.Status
+}
+
+func TestSetCondition(t *testing.T) {
+	obj := &MyStatusType{}
+	SetCondition(obj, &Condition{Type: "Ready", Status: corev1.ConditionFalse, Reason: "Pending"})
+	assert.Equal(t, 1, len(obj.Status.Conditions))
+	transition := obj.Status.Conditions[0].LastTransitionTime
+	assert.False(t, transition.IsZero())
+
+	// same status only updates the reason and message
+	SetCondition(obj, &Condition{Type: "Ready", Status: corev1.ConditionFalse, Reason: "Waiting", Message: "waiting for IAM"})
+	condition := GetCondition(obj, "Ready")
+	assert.Equal(t, "Waiting", condition.Reason)
+	assert.Equal(t, "waiting for IAM", condition.Message)
+	assert.Equal(t, transition, condition.LastTransitionTime)
+
+	SetCondition(obj, &Condition{Type: "Synced", Status: corev1.ConditionTrue})
+	assert.Equal(t, 2, len(Conditions(obj)))
+	RemoveCondition(obj, "Ready")
+	assert.Nil(t, GetCondition(obj, "Ready"))
+	assert.Equal(t, 1, len(obj.Status.Conditions))
+}
@@ -0,0 +1,454 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package rolecatalog caches the IAM roles of services, and resolves the names,
+// display names and CRNs of service roles and of the custom roles of an account,
+// which the IAM cache of the account holds
+package rolecatalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/IBM-Cloud/bluemix-go/session"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/registry"
+)
+
+// DefaultTTL is how long service roles are cached before they are listed again
+const DefaultTTL = time.Minute * 10
+
+// minRelist avoids listing roles again on every lookup of an unknown role name
+const minRelist = time.Second * 30
+
+// Role is a service, system or custom IAM role
+type Role struct {
+	Name        string
+	DisplayName string
+	CRN         string
+	ServiceName string
+	Custom      bool
+}
+
+// CustomRoleSource holds the custom roles of an account, such as the IAM cache of the account
+type CustomRoleSource interface {
+	// CustomRoles returns the custom roles of a service, or of all services if serviceName is ""
+	CustomRoles(serviceName string) ([]iampapv2.Role, error)
+	// ListCustomRoles lists the custom roles of a service, or of all services, from IAM again
+	ListCustomRoles(serviceName string) ([]iampapv2.Role, error)
+}
+
+// Clients are the IAM APIs the catalog reads service roles from, and the source of the custom roles
+type Clients struct {
+	ServiceRoles iamv1.ServiceRoleRepository
+	CustomRoles  CustomRoleSource
+}
+
+// NewClients creates the IAM APIs the catalog reads service roles from
+func NewClients(sess *session.Session, customRoles CustomRoleSource) (Clients, error) {
+	iamClient, err := iamv1.New(sess)
+	if err != nil {
+		return Clients{}, err
+	}
+	return Clients{
+		ServiceRoles: iamClient.ServiceRoles(),
+		CustomRoles:  customRoles,
+	}, nil
+}
+
+var catalogs = registry.New()
+
+// ForAccount returns the catalog shared by all controllers for an account and the
+// API key of the session, which resolves custom roles among those of customRoles.
+func ForAccount(sess *session.Session, accountID string, customRoles CustomRoleSource) (*Catalog, error) {
+	catalog, err := catalogs.Get(accountID, sess.Config.BluemixAPIKey, func() (interface{}, error) {
+		clients, err := NewClients(sess, customRoles)
+		if err != nil {
+			return nil, err
+		}
+		return New(accountID, clients, DefaultTTL), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return catalog.(*Catalog), nil
+}
+
+// Evict drops the catalog of a registry key, e.g. when its API key is no longer configured for any namespace
+func Evict(key string) {
+	catalogs.Evict(key)
+}
+
+// Catalog caches the roles of services per service class
+type Catalog struct {
+	accountID string
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	clients Clients
+	entries map[string]entry
+	// customListed is when the custom roles were last listed again for an unknown name
+	customListed time.Time
+}
+
+// entry is a cached list of roles
+type entry struct {
+	roles  []Role
+	listed time.Time
+}
+
+// New creates a role catalog for an account
+func New(accountID string, clients Clients, ttl time.Duration) *Catalog {
+	return &Catalog{
+		accountID: accountID,
+		ttl:       ttl,
+		clients:   clients,
+		entries:   map[string]entry{},
+	}
+}
+
+// ServiceRoles returns the roles of a service class, or the system defined roles if serviceClass is ""
+func (c *Catalog) ServiceRoles(serviceClass string) ([]Role, error) {
+	return c.serviceRoles(serviceClass, c.ttl)
+}
+
+// AuthorizationRoles returns the roles a source service can be granted on a target service
+func (c *Catalog) AuthorizationRoles(source string, target string) ([]Role, error) {
+	return c.authorizationRoles(source, target, c.ttl)
+}
+
+// CustomRoles returns the custom roles of the account for a service class, or for all services if serviceClass is ""
+func (c *Catalog) CustomRoles(serviceClass string) ([]Role, error) {
+	roles, err := c.customRoles(false)
+	if err != nil {
+		return nil, err
+	}
+	return filterService(roles, serviceClass), nil
+}
+
+// ResolveServiceRoles returns the roles of a service class, or the system defined roles, with the given names
+func (c *Catalog) ResolveServiceRoles(serviceClass string, names []string) ([]Role, error) {
+	return c.resolve(names, func(relist bool) ([]Role, error) {
+		return c.serviceRoles(serviceClass, c.relistTTL(relist))
+	})
+}
+
+// ResolveAuthorizationRoles returns the authorization roles between two services with the given names
+func (c *Catalog) ResolveAuthorizationRoles(source string, target string, names []string) ([]Role, error) {
+	return c.resolve(names, func(relist bool) ([]Role, error) {
+		return c.authorizationRoles(source, target, c.relistTTL(relist))
+	})
+}
+
+// ResolveCustomRoles returns the custom roles of the account with the given names, for a
+// service class or for all services if serviceClass is "". Custom roles are listed again
+// if a name is unknown, since they may have been created since they were cached.
+func (c *Catalog) ResolveCustomRoles(serviceClass string, names []string) ([]Role, error) {
+	return c.resolve(names, func(relist bool) ([]Role, error) {
+		roles, err := c.customRoles(relist)
+		return filterService(roles, serviceClass), err
+	})
+}
+
+// Lookup returns the cached service role with a CRN
+func (c *Catalog) Lookup(crn string) (Role, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range c.entries {
+		for _, role := range entry.roles {
+			if role.CRN == crn {
+				return role, true
+			}
+		}
+	}
+	return Role{}, false
+}
+
+// Invalidate lets the next unknown custom role name list the custom roles of the account again right
+// away, e.g. after one was created or deleted
+func (c *Catalog) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.customListed = time.Time{}
+}
+
+// Expire drops all cached service roles, so they are listed again when next resolved, e.g. on a forced resync
+func (c *Catalog) Expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]entry{}
+	c.customListed = time.Time{}
+}
+
+// relistTTL returns the age of the cached roles to list again
+func (c *Catalog) relistTTL(relist bool) time.Duration {
+	if relist {
+		return minRelist
+	}
+	return c.ttl
+}
+
+func (c *Catalog) serviceRoles(serviceClass string, ttl time.Duration) ([]Role, error) {
+	return c.list("service/"+serviceClass, ttl, func(clients Clients) ([]Role, error) {
+		var roles []models.PolicyRole
+		var err error
+		if serviceClass == "" {
+			roles, err = clients.ServiceRoles.ListSystemDefinedRoles()
+		} else {
+			roles, err = clients.ServiceRoles.ListServiceRoles(serviceClass)
+		}
+		return fromPolicyRoles(roles, serviceClass), err
+	})
+}
+
+func (c *Catalog) authorizationRoles(source string, target string, ttl time.Duration) ([]Role, error) {
+	return c.list("authorization/"+source+"/"+target, ttl, func(clients Clients) ([]Role, error) {
+		roles, err := clients.ServiceRoles.ListAuthorizationRoles(source, target)
+		return fromPolicyRoles(roles, target), err
+	})
+}
+
+// customRoles returns the custom roles of the account from their source, listed from IAM again to relist
+// them unless they were less than minRelist ago
+func (c *Catalog) customRoles(relist bool) ([]Role, error) {
+	c.mu.Lock()
+	source := c.clients.CustomRoles
+	relist = relist && time.Since(c.customListed) >= minRelist
+	if relist {
+		c.customListed = time.Now()
+	}
+	c.mu.Unlock()
+
+	var roles []iampapv2.Role
+	var err error
+	if relist {
+		roles, err = source.ListCustomRoles("")
+	} else {
+		roles, err = source.CustomRoles("")
+	}
+	return fromCustomRoles(roles), err
+}
+
+// list returns the roles cached with a key if they are more recent than ttl, else lists them
+func (c *Catalog) list(key string, ttl time.Duration, lister func(Clients) ([]Role, error)) ([]Role, error) {
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	clients := c.clients
+	c.mu.Unlock()
+	hit := ok && time.Since(cached.listed) < ttl
+	metrics.CacheLookup("roles", strings.SplitN(key, "/", 2)[0], hit)
+	if hit {
+		return cached.roles, nil
+	}
+
+	roles, err := lister(clients)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = entry{roles: roles, listed: time.Now()}
+	c.mu.Unlock()
+	return roles, nil
+}
+
+// resolve finds roles by name in the cached roles, then in roles listed again if some are unknown
+func (c *Catalog) resolve(names []string, lister func(relist bool) ([]Role, error)) ([]Role, error) {
+	roles, err := lister(false)
+	if err != nil {
+		return nil, err
+	}
+	resolved, unknown := find(roles, names)
+	if len(unknown) == 0 {
+		return resolved, nil
+	}
+
+	roles, err = lister(true)
+	if err != nil {
+		return nil, err
+	}
+	resolved, unknown = find(roles, names)
+	if len(unknown) > 0 {
+		return nil, newUnknownRolesError(unknown, roles)
+	}
+	return resolved, nil
+}
+
+// find returns the roles with the given display names, names or CRNs, and the names that match no role
+func find(roles []Role, names []string) ([]Role, []string) {
+	var resolved []Role
+	var unknown []string
+	for _, name := range names {
+		role, ok := match(roles, name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		resolved = append(resolved, role)
+	}
+	return resolved, unknown
+}
+
+func match(roles []Role, name string) (Role, bool) {
+	for _, role := range roles { // Display names take precedence, as they did before roles were cached
+		if role.DisplayName == name {
+			return role, true
+		}
+	}
+	for _, role := range roles {
+		if role.Name == name || role.CRN == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+// PolicyRoles returns roles as they are set in policies
+func PolicyRoles(roles []Role) []iampapv1.Role {
+	var policyRoles []iampapv1.Role
+	for _, role := range roles {
+		policyRoles = append(policyRoles, iampapv1.Role{RoleID: role.CRN})
+	}
+	return policyRoles
+}
+
+func fromPolicyRoles(roles []models.PolicyRole, serviceClass string) []Role {
+	var result []Role
+	for _, role := range roles {
+		result = append(result, Role{
+			Name:        role.ID.Resource,
+			DisplayName: role.DisplayName,
+			CRN:         role.ID.String(),
+			ServiceName: serviceClass,
+		})
+	}
+	return result
+}
+
+func fromCustomRoles(roles []iampapv2.Role) []Role {
+	var result []Role
+	for _, role := range roles {
+		result = append(result, Role{
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			CRN:         role.Crn,
+			ServiceName: role.ServiceName,
+			Custom:      true,
+		})
+	}
+	return result
+}
+
+func filterService(roles []Role, serviceClass string) []Role {
+	if serviceClass == "" {
+		return roles
+	}
+	var result []Role
+	for _, role := range roles {
+		if role.ServiceName == serviceClass {
+			result = append(result, role)
+		}
+	}
+	return result
+}
+
+// UnknownRolesError reports role names that match no role, with the closest role names as suggestions
+type UnknownRolesError struct {
+	Unknown     []string
+	Suggestions map[string]string
+	Valid       []string
+}
+
+func newUnknownRolesError(unknown []string, roles []Role) *UnknownRolesError {
+	err := &UnknownRolesError{Unknown: unknown, Suggestions: map[string]string{}}
+	for _, role := range roles {
+		err.Valid = append(err.Valid, role.DisplayName)
+	}
+	sort.Strings(err.Valid)
+	for _, name := range unknown {
+		if suggestion := Suggest(name, err.Valid); suggestion != "" {
+			err.Suggestions[name] = suggestion
+		}
+	}
+	return err
+}
+
+func (e *UnknownRolesError) Error() string {
+	var messages []string
+	for _, name := range e.Unknown {
+		if suggestion, ok := e.Suggestions[name]; ok {
+			messages = append(messages, fmt.Sprintf("unknown role %q, did you mean %q?", name, suggestion))
+		} else {
+			messages = append(messages, fmt.Sprintf("unknown role %q, valid roles are %s", name, strings.Join(e.Valid, ", ")))
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Suggest returns the candidate closest to a name, ignoring case, or "" if none is close enough to be a typo
+func Suggest(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name) / 3
+	if bestDistance < 2 {
+		bestDistance = 2
+	}
+	for _, candidate := range candidates {
+		if distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); distance <= bestDistance && (best == "" || distance < bestDistance) {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the number of single character edits between two strings
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rolecatalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeServiceRoles struct {
+	iamv1.ServiceRoleRepository
+	lists int
+}
+
+func serviceRole(resourceType string, name string) models.PolicyRole {
+	id, _ := crn.Parse("crn:v1:bluemix:public:iam::::" + resourceType + ":" + name)
+	return models.PolicyRole{ID: id, DisplayName: name}
+}
+
+func (f *fakeServiceRoles) ListServiceRoles(serviceName string) ([]models.PolicyRole, error) {
+	f.lists++
+	return []models.PolicyRole{serviceRole("serviceRole", "Reader"), serviceRole("serviceRole", "Writer"), serviceRole("role", "Viewer")}, nil
+}
+
+func (f *fakeServiceRoles) ListSystemDefinedRoles() ([]models.PolicyRole, error) {
+	f.lists++
+	return []models.PolicyRole{serviceRole("role", "Viewer"), serviceRole("role", "Administrator")}, nil
+}
+
+// fakeCustomRoles is a cache of the custom roles of an account
+type fakeCustomRoles struct {
+	roles  []iampapv2.Role
+	cached []iampapv2.Role
+	lists  int
+}
+
+func newFakeCustomRoles(roles ...iampapv2.Role) *fakeCustomRoles {
+	return &fakeCustomRoles{roles: roles, cached: roles}
+}
+
+func (f *fakeCustomRoles) CustomRoles(serviceName string) ([]iampapv2.Role, error) {
+	return f.cached, nil
+}
+
+func (f *fakeCustomRoles) ListCustomRoles(serviceName string) ([]iampapv2.Role, error) {
+	f.lists++
+	f.cached = f.roles
+	return f.roles, nil
+}
+
+func customRole(name string, displayName string, serviceName string) iampapv2.Role {
+	role := iampapv2.Role{ID: name, Crn: "crn:v1:bluemix:public:iam-access-management::a/12345::customRole:" + name}
+	role.Name = name
+	role.DisplayName = displayName
+	role.ServiceName = serviceName
+	return role
+}
+
+func TestResolveServiceRoles(t *testing.T) {
+	serviceRoles := &fakeServiceRoles{}
+	catalog := New("12345", Clients{ServiceRoles: serviceRoles}, time.Hour)
+
+	roles, err := catalog.ResolveServiceRoles("kms", []string{"Writer", "crn:v1:bluemix:public:iam::::role:Viewer"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Role{
+		{Name: "Writer", DisplayName: "Writer", CRN: "crn:v1:bluemix:public:iam::::serviceRole:Writer", ServiceName: "kms"},
+		{Name: "Viewer", DisplayName: "Viewer", CRN: "crn:v1:bluemix:public:iam::::role:Viewer", ServiceName: "kms"},
+	}, roles)
+	assert.Equal(t, "crn:v1:bluemix:public:iam::::serviceRole:Writer", PolicyRoles(roles)[0].RoleID)
+
+	roles, err = catalog.ResolveServiceRoles("", []string{"Administrator"})
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+
+	// Service roles are cached per service class
+	_, err = catalog.ResolveServiceRoles("kms", []string{"Reader"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, serviceRoles.lists)
+
+	role, ok := catalog.Lookup("crn:v1:bluemix:public:iam::::serviceRole:Reader")
+	assert.True(t, ok)
+	assert.Equal(t, "Reader", role.DisplayName)
+	_, ok = catalog.Lookup("crn:v1:bluemix:public:iam::::serviceRole:Manager")
+	assert.False(t, ok)
+}
+
+func TestResolveUnknownRoles(t *testing.T) {
+	serviceRoles := &fakeServiceRoles{}
+	catalog := New("12345", Clients{ServiceRoles: serviceRoles}, time.Hour)
+
+	_, err := catalog.ResolveServiceRoles("kms", []string{"Writter", "reader", "Owner"})
+	assert.Error(t, err)
+	unknown, ok := err.(*UnknownRolesError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Writter", "reader", "Owner"}, unknown.Unknown)
+	assert.Equal(t, map[string]string{"Writter": "Writer", "reader": "Reader"}, unknown.Suggestions)
+	assert.Equal(t, `unknown role "Writter", did you mean "Writer"?; unknown role "reader", did you mean "Reader"?; unknown role "Owner", valid roles are Reader, Viewer, Writer`, err.Error())
+}
+
+func TestResolveCustomRoles(t *testing.T) {
+	customRoles := newFakeCustomRoles(customRole("KeyReader", "Key Reader", "kms"))
+	catalog := New("12345", Clients{CustomRoles: customRoles}, time.Hour)
+
+	// Custom roles are resolved among the cached ones
+	roles, err := catalog.ResolveCustomRoles("", []string{"Key Reader"})
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	assert.True(t, roles[0].Custom)
+	roles, err = catalog.ResolveCustomRoles("kms", []string{"KeyReader"})
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	assert.Equal(t, 0, customRoles.lists)
+
+	// An unknown name lists them again, at most every minRelist
+	_, err = catalog.ResolveCustomRoles("cloud-object-storage", []string{"Key Reader"})
+	assert.Error(t, err)
+	_, err = catalog.ResolveCustomRoles("cloud-object-storage", []string{"Key Reader"})
+	assert.Error(t, err)
+	assert.Equal(t, 1, customRoles.lists)
+
+	// A custom role created since the roles were listed is found once they are listed again
+	customRoles.roles = append(customRoles.roles, customRole("KeyWriter", "Key Writer", "kms"))
+	catalog.Invalidate()
+	roles, err = catalog.ResolveCustomRoles("kms", []string{"Key Writer"})
+	assert.NoError(t, err)
+	assert.Equal(t, "KeyWriter", roles[0].Name)
+	assert.Equal(t, 2, customRoles.lists)
+
+	all, err := catalog.CustomRoles("")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"Reader", "Writer", "Manager", "Key Purge"}
+	assert.Equal(t, "Writer", Suggest("Writter", candidates))
+	assert.Equal(t, "Manager", Suggest("manager", candidates))
+	assert.Equal(t, "Key Purge", Suggest("KeyPurge", candidates))
+	assert.Equal(t, "", Suggest("Administrator", candidates))
+}
+
+func TestExpire(t *testing.T) {
+	serviceRoles := &fakeServiceRoles{}
+	customRoles := newFakeCustomRoles()
+	catalog := New("12345", Clients{ServiceRoles: serviceRoles, CustomRoles: customRoles}, time.Hour)
+
+	_, err := catalog.ResolveServiceRoles("kms", []string{"Writer"})
+	assert.NoError(t, err)
+	_, err = catalog.ResolveCustomRoles("kms", []string{"KeyReader"})
+	assert.Error(t, err)
+
+	// Service roles are listed again after the catalog expired, and so are custom roles for an unknown name
+	catalog.Expire()
+	_, err = catalog.ResolveServiceRoles("kms", []string{"Writer"})
+	assert.NoError(t, err)
+	_, err = catalog.ResolveCustomRoles("kms", []string{"KeyReader"})
+	assert.Error(t, err)
+	assert.Equal(t, 2, serviceRoles.lists)
+	assert.Equal(t, 2, customRoles.lists)
+}
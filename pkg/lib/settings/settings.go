@@ -0,0 +1,261 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package settings tunes the reconciles of the operator for large clusters: the resync period,
+// the concurrency of the controllers and the watched namespaces. They are set with command line
+// flags, or the ibmcloud-iam-operator ConfigMap of the operator namespace.
+package settings
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	v1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/drift"
+)
+
+const (
+	// ConfigMapName is the ConfigMap of the operator namespace holding the settings
+	ConfigMapName = "ibmcloud-iam-operator"
+	// SyncPeriodAnnotation overrides the resync period of a resource, e.g. "10m"
+	SyncPeriodAnnotation = "ibmcloud.ibm.com/sync-period"
+	// MinSyncPeriod bounds the resync period to protect IAM from too many requests
+	MinSyncPeriod = time.Second * 10
+)
+
+// ConfigMap keys of the settings, the concurrency of a controller is set with the key
+// of MaxConcurrentReconciles followed by a dot and the kind, e.g. maxConcurrentReconciles.AccessPolicy
+const (
+	syncPeriodKey              = "syncPeriod"
+	syncJitterKey              = "syncJitter"
+	maxConcurrentReconcilesKey = "maxConcurrentReconciles"
+	watchNamespacesKey         = "watchNamespaces"
+	dryRunKey                  = "dryRun"
+	instanceIDKey              = "instanceID"
+	driftPolicyKey             = "driftPolicy"
+)
+
+// Settings tune the reconciles of the operator
+type Settings struct {
+	// SyncPeriod is the period resources are reconciled at to detect drift
+	SyncPeriod time.Duration
+	// SyncJitter is the maximum fraction of the sync period randomly added to it, to spread the reconciles
+	SyncJitter float64
+	// MaxConcurrentReconciles is the number of resources each controller reconciles at the same time
+	MaxConcurrentReconciles int
+	// Concurrency overrides MaxConcurrentReconciles for the controllers of some kinds
+	Concurrency map[string]int
+	// Namespaces are the watched namespaces, all when empty
+	Namespaces []string
+	// DryRun plans the changes to IAM of the resources without making them, unless a resource opts out
+	DryRun bool
+	// InstanceID identifies the operator instance in the description of the IAM objects it owns, so that
+	// several instances share an account and only sweep their own orphaned objects
+	InstanceID string
+	// DriftPolicy is how changes made to IAM outside of the operator are handled for the resources
+	// without their own drift policy: Enforce when empty, Detect or Ignore
+	DriftPolicy string
+}
+
+// Defaults are the settings without flags nor ConfigMap
+var Defaults = Settings{
+	SyncPeriod:              time.Second * 150,
+	SyncJitter:              0.1,
+	MaxConcurrentReconciles: 1,
+}
+
+// Current are the settings of the operator, set on start
+var Current = Defaults
+
+// FlagSet returns the flags of the settings, parsed into s
+func FlagSet(s *Settings) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("settings", pflag.ExitOnError)
+	flags.DurationVar(&s.SyncPeriod, "sync-period", s.SyncPeriod, "Period resources are reconciled at to detect drift")
+	flags.Float64Var(&s.SyncJitter, "sync-jitter", s.SyncJitter, "Maximum fraction of the sync period randomly added to it")
+	flags.IntVar(&s.MaxConcurrentReconciles, "max-concurrent-reconciles", s.MaxConcurrentReconciles, "Number of resources each controller reconciles at the same time")
+	flags.StringToIntVar(&s.Concurrency, "controller-concurrency", s.Concurrency, "Concurrent reconciles of the controllers of some kinds, e.g. AccessPolicy=8,AccessGroup=2")
+	flags.StringSliceVar(&s.Namespaces, "watch-namespaces", s.Namespaces, "Namespaces to watch, all when empty (default WATCH_NAMESPACE)")
+	flags.BoolVar(&s.DryRun, "dry-run", s.DryRun, "Plan the changes to IAM in the status and events of the resources without making them")
+	flags.StringVar(&s.InstanceID, "instance-id", s.InstanceID, "Identifier of the operator instance marked on the IAM objects it owns, required to delete orphaned objects")
+	flags.StringVar(&s.DriftPolicy, "drift-policy", s.DriftPolicy, "Drift policy of the resources without their own: Enforce (default), Detect or Ignore")
+	return flags
+}
+
+// WatchNamespaces returns the namespaces of a comma separated list, such as the WATCH_NAMESPACE variable
+func WatchNamespaces(list string) []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(list, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// Load returns the settings of the ConfigMap of a namespace, if any, overridden by the flags set on the command line
+func Load(r client.Reader, namespace string, flags *pflag.FlagSet, flagged Settings) (Settings, error) {
+	s := Defaults
+	s.Namespaces = WatchNamespaces(os.Getenv("WATCH_NAMESPACE"))
+
+	if namespace != "" {
+		cm := &v1.ConfigMap{}
+		err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: ConfigMapName}, cm)
+		if err != nil && !kerror.IsNotFound(err) {
+			return s, err
+		}
+		if err == nil {
+			if err := s.apply(cm.Data); err != nil {
+				return s, fmt.Errorf("ConfigMap %s/%s: %v", namespace, ConfigMapName, err)
+			}
+		}
+	}
+
+	if flags.Changed("sync-period") {
+		s.SyncPeriod = flagged.SyncPeriod
+	}
+	if flags.Changed("sync-jitter") {
+		s.SyncJitter = flagged.SyncJitter
+	}
+	if flags.Changed("max-concurrent-reconciles") {
+		s.MaxConcurrentReconciles = flagged.MaxConcurrentReconciles
+	}
+	if flags.Changed("controller-concurrency") {
+		s.Concurrency = flagged.Concurrency
+	}
+	if flags.Changed("watch-namespaces") {
+		s.Namespaces = flagged.Namespaces
+	}
+	if flags.Changed("dry-run") {
+		s.DryRun = flagged.DryRun
+	}
+	if flags.Changed("instance-id") {
+		s.InstanceID = flagged.InstanceID
+	}
+	if flags.Changed("drift-policy") {
+		s.DriftPolicy = flagged.DriftPolicy
+	}
+	return s, s.validate()
+}
+
+// apply sets the settings found in the data of a ConfigMap
+func (s *Settings) apply(data map[string]string) error {
+	var err error
+	if value, ok := data[syncPeriodKey]; ok {
+		if s.SyncPeriod, err = time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s: %v", syncPeriodKey, err)
+		}
+	}
+	if value, ok := data[syncJitterKey]; ok {
+		if s.SyncJitter, err = strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s: %v", syncJitterKey, err)
+		}
+	}
+	for key, value := range data {
+		if key != maxConcurrentReconcilesKey && !strings.HasPrefix(key, maxConcurrentReconcilesKey+".") {
+			continue
+		}
+		concurrency, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if key == maxConcurrentReconcilesKey {
+			s.MaxConcurrentReconciles = concurrency
+			continue
+		}
+		if s.Concurrency == nil {
+			s.Concurrency = map[string]int{}
+		}
+		s.Concurrency[strings.TrimPrefix(key, maxConcurrentReconcilesKey+".")] = concurrency
+	}
+	if value, ok := data[watchNamespacesKey]; ok {
+		s.Namespaces = WatchNamespaces(value)
+	}
+	if value, ok := data[dryRunKey]; ok {
+		if s.DryRun, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: %v", dryRunKey, err)
+		}
+	}
+	if value, ok := data[instanceIDKey]; ok {
+		s.InstanceID = value
+	}
+	if value, ok := data[driftPolicyKey]; ok {
+		s.DriftPolicy = value
+	}
+	return nil
+}
+
+// validate checks the settings are within their bounds
+func (s Settings) validate() error {
+	if s.SyncPeriod < MinSyncPeriod {
+		return fmt.Errorf("sync period %v is shorter than %v", s.SyncPeriod, MinSyncPeriod)
+	}
+	if s.SyncJitter < 0 {
+		return fmt.Errorf("sync jitter %v is negative", s.SyncJitter)
+	}
+	if strings.ContainsAny(s.InstanceID, "[] ") {
+		return fmt.Errorf("instance ID %q contains a bracket or a space", s.InstanceID)
+	}
+	switch s.DriftPolicy {
+	case "", drift.Enforce, drift.Detect, drift.Ignore:
+	default:
+		return fmt.Errorf("drift policy %q is not Enforce, Detect or Ignore", s.DriftPolicy)
+	}
+	if s.MaxConcurrentReconciles < 1 {
+		return fmt.Errorf("max concurrent reconciles %d is less than 1", s.MaxConcurrentReconciles)
+	}
+	for kind, concurrency := range s.Concurrency {
+		if concurrency < 1 {
+			return fmt.Errorf("max concurrent reconciles %d of %s is less than 1", concurrency, kind)
+		}
+	}
+	return nil
+}
+
+// SyncPeriod returns when to reconcile a resource again, its SyncPeriodAnnotation or the
+// current sync period, plus a random jitter
+func SyncPeriod(obj metav1.Object) time.Duration {
+	period := Current.SyncPeriod
+	if value, ok := obj.GetAnnotations()[SyncPeriodAnnotation]; ok {
+		if override, err := time.ParseDuration(value); err == nil {
+			period = override
+		}
+		if period < MinSyncPeriod {
+			period = MinSyncPeriod
+		}
+	}
+	if Current.SyncJitter > 0 {
+		return wait.Jitter(period, Current.SyncJitter)
+	}
+	return period
+}
+
+// MaxConcurrentReconciles returns the number of resources of a kind reconciled at the same time
+func MaxConcurrentReconciles(kind string) int {
+	if concurrency, ok := Current.Concurrency[kind]; ok {
+		return concurrency
+	}
+	return Current.MaxConcurrentReconciles
+}
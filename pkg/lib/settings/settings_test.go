@@ -0,0 +1,131 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package settings
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func configMap(data map[string]string) *v1.ConfigMap {
+	return &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ibmcloud-iam-operator", Name: ConfigMapName}, Data: data}
+}
+
+func TestLoad(t *testing.T) {
+	defer os.Unsetenv("WATCH_NAMESPACE")
+	os.Setenv("WATCH_NAMESPACE", "team-a, team-b")
+
+	flagged := Defaults
+	flags := FlagSet(&flagged)
+	assert.NoError(t, flags.Parse([]string{"--sync-period=5m", "--controller-concurrency=AccessGroup=2", "--drift-policy=Detect"}))
+
+	// Without ConfigMap, the flags set on the command line override the defaults and WATCH_NAMESPACE
+	reader := fake.NewFakeClientWithScheme(scheme.Scheme)
+	s, err := Load(reader, "ibmcloud-iam-operator", flags, flagged)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute*5, s.SyncPeriod)
+	assert.Equal(t, Defaults.SyncJitter, s.SyncJitter)
+	assert.Equal(t, 1, s.MaxConcurrentReconciles)
+	assert.Equal(t, map[string]int{"AccessGroup": 2}, s.Concurrency)
+	assert.Equal(t, []string{"team-a", "team-b"}, s.Namespaces)
+	assert.False(t, s.DryRun)
+	assert.Equal(t, "Detect", s.DriftPolicy)
+
+	// The ConfigMap overrides the defaults, not the flags
+	reader = fake.NewFakeClientWithScheme(scheme.Scheme, configMap(map[string]string{
+		"namespace":                            "safe",
+		"syncPeriod":                           "10m",
+		"syncJitter":                           "0.5",
+		"maxConcurrentReconciles":              "4",
+		"maxConcurrentReconciles.AccessPolicy": "8",
+		"watchNamespaces":                      "team-c",
+		"dryRun":                               "true",
+		"instanceID":                           "cluster-a",
+		"driftPolicy":                          "Ignore",
+	}))
+	s, err = Load(reader, "ibmcloud-iam-operator", flags, flagged)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute*5, s.SyncPeriod)
+	assert.Equal(t, 0.5, s.SyncJitter)
+	assert.Equal(t, 4, s.MaxConcurrentReconciles)
+	assert.Equal(t, map[string]int{"AccessGroup": 2}, s.Concurrency)
+	assert.Equal(t, []string{"team-c"}, s.Namespaces)
+	assert.True(t, s.DryRun)
+	assert.Equal(t, "cluster-a", s.InstanceID)
+	assert.Equal(t, "Detect", s.DriftPolicy)
+
+	// Without flags, the ConfigMap concurrency of a kind is kept
+	s, err = Load(reader, "ibmcloud-iam-operator", FlagSet(&Settings{}), Settings{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute*10, s.SyncPeriod)
+	assert.Equal(t, map[string]int{"AccessPolicy": 8}, s.Concurrency)
+	assert.Equal(t, "Ignore", s.DriftPolicy)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, data := range []map[string]string{
+		{"syncPeriod": "often"},
+		{"syncPeriod": "1s"},
+		{"syncJitter": "-0.1"},
+		{"maxConcurrentReconciles": "0"},
+		{"maxConcurrentReconciles.AccessGroup": "many"},
+		{"dryRun": "maybe"},
+		{"instanceID": "cluster a"},
+		{"driftPolicy": "enforce"},
+	} {
+		reader := fake.NewFakeClientWithScheme(scheme.Scheme, configMap(data))
+		_, err := Load(reader, "ibmcloud-iam-operator", FlagSet(&Settings{}), Settings{})
+		assert.Error(t, err, "%v", data)
+	}
+}
+
+func TestSyncPeriod(t *testing.T) {
+	defer func(previous Settings) { Current = previous }(Current)
+	Current = Settings{SyncPeriod: time.Minute, MaxConcurrentReconciles: 1}
+
+	obj := &metav1.ObjectMeta{}
+	assert.Equal(t, time.Minute, SyncPeriod(obj))
+
+	obj.Annotations = map[string]string{SyncPeriodAnnotation: "1h"}
+	assert.Equal(t, time.Hour, SyncPeriod(obj))
+
+	// A period too short to protect IAM is bounded, an invalid one ignored
+	obj.Annotations[SyncPeriodAnnotation] = "1s"
+	assert.Equal(t, MinSyncPeriod, SyncPeriod(obj))
+	obj.Annotations[SyncPeriodAnnotation] = "hourly"
+	assert.Equal(t, time.Minute, SyncPeriod(obj))
+
+	Current.SyncJitter = 0.5
+	for i := 0; i < 100; i++ {
+		period := SyncPeriod(obj)
+		assert.True(t, period >= time.Minute && period < time.Second*90, "%v", period)
+	}
+}
+
+func TestMaxConcurrentReconciles(t *testing.T) {
+	defer func(previous Settings) { Current = previous }(Current)
+	Current = Settings{MaxConcurrentReconciles: 2, Concurrency: map[string]int{"AccessPolicy": 8}}
+
+	assert.Equal(t, 8, MaxConcurrentReconciles("AccessPolicy"))
+	assert.Equal(t, 2, MaxConcurrentReconciles("AccessGroup"))
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package tracing starts the OpenTelemetry spans of the reconciles and of their IAM requests,
+// and exports them through OTLP when a collector is configured
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ServiceName identifies the operator in the exported traces
+const ServiceName = "ibmcloud-iam-operator"
+
+// tracerName is the instrumentation name of the operator spans
+const tracerName = "github.com/IBM/ibmcloud-iam-operator"
+
+// endpointVars are the environment variables that configure the OTLP collector, tracing is disabled when none is set
+var endpointVars = []string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"}
+
+// Enabled tells whether an OTLP collector is configured
+func Enabled() bool {
+	for _, name := range endpointVars {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Setup exports the spans to the OTLP collector configured by the OTEL_EXPORTER_OTLP_* variables,
+// and returns the function flushing them on shutdown. Spans are dropped when no collector is configured.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span of a context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartReconcile starts the root span of the reconcile of a custom resource
+func StartReconcile(kind string, name types.NamespacedName) (context.Context, trace.Span) {
+	return Start(context.Background(), "Reconcile "+kind,
+		attribute.String("k8s.kind", kind),
+		attribute.String("k8s.namespace", name.Namespace),
+		attribute.String("k8s.name", name.Name))
+}
+
+// End records the error of a span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Instrument starts a span for each request of the clients created from a session, as a child of the span of the
+// request context
+func Instrument(sess *session.Session) {
+	wrap(sess, func(base http.RoundTripper) http.RoundTripper { return &Transport{Base: base} })
+}
+
+// WithContext returns a copy of a session sending its requests within the span of a context, e.g. the one of a
+// reconcile, so that their spans are children of its span. The requests keep their own deadline and cancellation.
+// The clients outliving the context, e.g. shared between reconciles, are created from the original session.
+func WithContext(ctx context.Context, sess *session.Session) *session.Session {
+	bound := sess.Copy()
+	wrap(bound, func(base http.RoundTripper) http.RoundTripper { return &contextTransport{base: base, ctx: ctx} })
+	return bound
+}
+
+// wrap replaces the transport of the HTTP client of a session
+func wrap(sess *session.Session, transport func(base http.RoundTripper) http.RoundTripper) {
+	client := *http.DefaultClient
+	if sess.Config.HTTPClient != nil {
+		client = *sess.Config.HTTPClient
+	}
+	client.Transport = transport(client.Transport)
+	sess.Config.HTTPClient = &client
+}
+
+// contextTransport sends the requests within the span of a context, as the IBM Cloud clients don't take one
+type contextTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req.WithContext(trace.ContextWithSpan(req.Context(), trace.SpanFromContext(t.ctx))))
+}
+
+// Transport starts a span for each request it sends, as a child of the span of the request context
+type Transport struct {
+	// Base sends the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// RoundTrip sends a request within a span named after its operation
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	service, operation := metrics.Operation(req)
+	_, span := otel.Tracer(tracerName).Start(req.Context(), operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("iam.service", service),
+		semconv.HTTPMethodKey.String(req.Method),
+		semconv.HTTPHostKey.String(req.URL.Host),
+		semconv.HTTPTargetKey.String(req.URL.Path)))
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode))
+	}
+	End(span, err)
+	return resp, err
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// record records the spans until the returned function restores the previous provider
+func record() (*tracetest.SpanRecorder, func()) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder, func() { otel.SetTracerProvider(previous) }
+}
+
+func TestTransport(t *testing.T) {
+	recorder, restore := record()
+	defer restore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx, reconcile := StartReconcile("AccessGroup", types.NamespacedName{Namespace: "default", Name: "admins"})
+	client := &http.Client{Transport: &Transport{}}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v2/groups/AccessGroup-1234", nil)
+	resp, err := client.Do(req.WithContext(ctx))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	End(reconcile, nil)
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	request, root := spans[0], spans[1]
+	assert.Equal(t, "GET /v2/groups/{id}", request.Name())
+	assert.Equal(t, root.SpanContext().SpanID(), request.Parent().SpanID())
+	assert.Equal(t, codes.Error, request.Status().Code)
+	assert.Equal(t, "Reconcile AccessGroup", root.Name())
+	assert.Equal(t, codes.Unset, root.Status().Code)
+	attrs := map[string]string{}
+	for _, attr := range root.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, map[string]string{"k8s.kind": "AccessGroup", "k8s.namespace": "default", "k8s.name": "admins"}, attrs)
+}
+
+func TestWithContext(t *testing.T) {
+	recorder, restore := record()
+	defer restore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	sess := &session.Session{Config: &bluemix.Config{}}
+	Instrument(sess)
+	ctx, reconcile := StartReconcile("AccessPolicy", types.NamespacedName{Namespace: "default", Name: "viewers"})
+	reconcileSess := WithContext(ctx, sess)
+	for _, s := range []*session.Session{reconcileSess, sess} {
+		resp, err := s.Config.HTTPClient.Get(server.URL + "/v1/policies")
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	End(reconcile, nil)
+
+	// Only the requests of the reconcile session are children of the reconcile
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 3) {
+		return
+	}
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.False(t, spans[1].Parent().IsValid())
+	assert.NotSame(t, sess.Config, reconcileSess.Config)
+
+	// The requests keep their own cancellation
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/policies", nil)
+	_, err := reconcileSess.Config.HTTPClient.Do(req.WithContext(canceled))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestEnd(t *testing.T) {
+	recorder, restore := record()
+	defer restore()
+	_, span := StartReconcile("CustomRole", types.NamespacedName{Namespace: "default", Name: "viewer"})
+	End(span, errors.New("role not found"))
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 1) {
+		return
+	}
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "role not found", spans[0].Status().Description)
+	assert.Len(t, spans[0].Events(), 1)
+}
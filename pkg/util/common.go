@@ -21,10 +21,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/endpoints"
 	icv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/ibmcloud/v1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/metrics"
 	
 	bx "github.com/IBM-Cloud/bluemix-go"
-	bxendpoints "github.com/IBM-Cloud/bluemix-go/endpoints"
 	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
 	"github.com/IBM-Cloud/bluemix-go/api/mccp/mccpv2"
 	"github.com/IBM-Cloud/bluemix-go/session"
@@ -47,24 +48,28 @@ func GetIAMAccountInfo(r client.Client, namespace string) (*session.Session, *ac
 	bxConfig, err := getBxConfig(r, namespace)
 	if err != nil {
 		logc.Info("Error getting Bluemix config")
+		metrics.CredentialLookupFailed(namespace, "config")
 		return nil, nil, err
 	}
 	
 	ibmCloudContext, err := getIBMCloudDefaultContext(r, namespace)
 	if err != nil {
 		logc.Info("Error getting IBM Cloud context")
+		metrics.CredentialLookupFailed(namespace, "context")
 		return nil, nil, err
 	}
 
 	sess, err := session.New(&bxConfig)
 	if err != nil {
 		logc.Info("Error creating new session")
+		metrics.CredentialLookupFailed(namespace, "session")
 		return nil, nil, err
 	}
 
 	client, err := mccpv2.New(sess)
 	if err != nil {
 		logc.Info("Error creating new client")
+		metrics.CredentialLookupFailed(namespace, "org")
 		return nil, nil, err
 	}
 
@@ -72,12 +77,14 @@ func GetIAMAccountInfo(r client.Client, namespace string) (*session.Session, *ac
 	myorg, err := orgAPI.FindByName(ibmCloudContext.Org, sess.Config.Region)
 	if err != nil {
 		logc.Info("Error getting my org")
+		metrics.CredentialLookupFailed(namespace, "org")
 		return nil, nil, err
 	}
 
 	accClient, err := accountv2.New(sess)
 	if err != nil {
 		logc.Info("Error getting account Client")
+		metrics.CredentialLookupFailed(namespace, "account")
 		return nil, nil, err
 	}
 
@@ -85,6 +92,7 @@ func GetIAMAccountInfo(r client.Client, namespace string) (*session.Session, *ac
 	myAccount, err := accountAPI.FindByOrg(myorg.GUID, sess.Config.Region)
 	if err != nil {
 		logc.Info("Error getting my account")
+		metrics.CredentialLookupFailed(namespace, "account")
 		return nil, nil, err
 	}
 
@@ -93,7 +101,7 @@ func GetIAMAccountInfo(r client.Client, namespace string) (*session.Session, *ac
 
 func getBxConfig(r client.Client, secretNS string) (bx.Config, error) {
 	config := bx.Config{
-		EndpointLocator: bxendpoints.NewEndpointLocator("us-south"), // TODO: hard wired to us-south!!
+		EndpointLocator: endpoints.Locator("us-south"), // TODO: hard wired to us-south!!
 		//Debug: true,
 	}
 
@@ -150,6 +158,15 @@ func getDefaultNamespace(r client.Client) string {
 }
 
 func getIBMCloudDefaultContext(r client.Client, configmapNS string) (icv1.ResourceContext, error) {
+	cm, err := getDefaultsConfigMap(r, configmapNS)
+	if err != nil {
+		return icv1.ResourceContext{}, err
+	}
+	ibmCloudContext := getIBMCloudContext(cm)
+	return ibmCloudContext, nil
+}
+
+func getDefaultsConfigMap(r client.Client, configmapNS string) (*v1.ConfigMap, error) {
 	cm := &v1.ConfigMap{}
 	cmName := seedDefaults
 	cmNameSpace := configmapNS
@@ -164,16 +181,15 @@ func getIBMCloudDefaultContext(r client.Client, configmapNS string) (icv1.Resour
 			err = r.Get(context.TODO(), types.NamespacedName{Name: cmName, Namespace: namespace}, cm)
 			if err != nil {
 				logc.Info("Failed to find ConfigMap in namespace (in Service)", namespace, err)
-				return icv1.ResourceContext{}, err
+				return nil, err
 			}
 		} else {
 			logc.Info("Failed to find ConfigMap in namespace (in Service)", cmNameSpace, err)
-			return icv1.ResourceContext{}, err
+			return nil, err
 		}
 
 	}
-	ibmCloudContext := getIBMCloudContext(cm)
-	return ibmCloudContext, nil
+	return cm, nil
 }
 
 func getIBMCloudContext(cm *v1.ConfigMap) icv1.ResourceContext {
@@ -18,12 +18,17 @@ package test
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"os/user"
+	"sync"
 	"time"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/endpoints"
+	"github.com/IBM/ibmcloud-iam-operator/test/fakeiam"
 )
 
 var (
@@ -40,9 +45,31 @@ var (
 	ts              = time.Now().Unix()
 )
 
-func init() {
+// FakeIAM is the fake IAM server the tests run against when FAKE_IAM is set, nil otherwise
+var FakeIAM *fakeiam.Server
+
+var (
+	configure    sync.Once
+	configureErr error
+)
+
+// Configure reads the IBM Cloud target of the controller tests, or starts the fake IAM server when FAKE_IAM
+// is set, and returns why the tests can't run, e.g. so that a suite is skipped without credentials. It is
+// called by the test suites rather than on import, so that the other tests of their packages run anyway.
+func Configure() error {
+	configure.Do(func() { configureErr = readTarget() })
+	return configureErr
+}
+
+// readTarget reads the IBM Cloud target from the environment and the IBM Cloud CLI configuration
+func readTarget() error {
+	if os.Getenv("FAKE_IAM") != "" {
+		startFakeIAM()
+		return nil
+	}
+
 	if apikey == "" {
-		panic("set BLUEMIX_API_KEY to run tests")
+		return errors.New("set BLUEMIX_API_KEY, or FAKE_IAM to run offline, to run tests")
 	}
 
 	usr, err := user.Current()
@@ -61,7 +88,25 @@ func init() {
 	}
 
 	if org == "" || region == "" {
-		panic("set current bx target to run tests")
+		return errors.New("set current bx target to run tests")
 	}
+	return nil
+}
+
+// UseExistingCluster tells whether the controller tests run in the cluster of the current kubeconfig. With
+// FAKE_IAM, envtest starts its own control plane instead, so that the tests need no cluster either.
+func UseExistingCluster() bool {
+	return FakeIAM == nil
+}
+
+// startFakeIAM redirects the operator to a fake IAM server knowing the users and service IDs
+// of the controller test data, so tests run offline
+func startFakeIAM() {
+	FakeIAM = fakeiam.NewServer()
+	endpoints.Override(FakeIAM.URL)
+	apikey, org, region = fakeiam.APIKey, fakeiam.Org, fakeiam.Region
 
+	FakeIAM.AddUser("avarghese@us.ibm.com")
+	FakeIAM.AddServiceID("ServiceId-3b9f026a-eb6e-495f-b104-95232d0c4a59", "test-service-id-1")
+	FakeIAM.AddServiceID("ServiceId-fa27c539-a6cf-41d2-8cb0-2916da5f8e8a", "test-service-id-2")
 }
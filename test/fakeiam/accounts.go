@@ -0,0 +1,190 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeiam
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv2"
+	"github.com/IBM-Cloud/bluemix-go/api/mccp/mccpv2"
+)
+
+// AddUser adds an active user to the account
+func (s *Server) AddUser(email string) accountv1.AccountUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.createUser(email, "ACTIVE")
+}
+
+// Users returns the users of the account sorted by email
+func (s *Server) Users() []accountv1.AccountUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := []accountv1.AccountUser{}
+	for _, user := range s.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users
+}
+
+func (s *Server) createUser(email string, state string) *accountv1.AccountUser {
+	id := s.newID()
+	user := &accountv1.AccountUser{
+		UserId:      email,
+		Email:       email,
+		State:       state,
+		Id:          id,
+		IbmUniqueId: "IBMid-" + id,
+		AccountId:   AccountID,
+		Role:        "MEMBER",
+		CreatedOn:   now(),
+		InvitedOn:   now(),
+	}
+	if state == "ACTIVE" {
+		user.VerifiedOn = now()
+	}
+	s.users[email] = user
+	return user
+}
+
+// serveUsers serves the users API of the account, segments being the path after users
+func (s *Server) serveUsers(w http.ResponseWriter, r *http.Request, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		userID := r.URL.Query().Get("user_id")
+		response := accountv1.AccountUserQueryResponse{AccountUsers: []accountv1.AccountUserResource{}}
+		for _, user := range s.users {
+			if userID == "" || strings.EqualFold(userID, user.UserId) {
+				response.AccountUsers = append(response.AccountUsers, userResource(user))
+			}
+		}
+		writeJSON(w, http.StatusOK, response)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		var request struct {
+			Users []struct {
+				Email string `json:"email"`
+			} `json:"users"`
+		}
+		if !decode(w, r, &request) {
+			return
+		}
+		if len(request.Users) == 0 {
+			writeError(w, http.StatusBadRequest, "invalid_body", "No user to invite")
+			return
+		}
+		email := request.Users[0].Email
+		user, ok := s.users[email]
+		if !ok { // Invited users are pending until they accept the invitation
+			user = s.createUser(email, "PENDING")
+		}
+		writeJSON(w, http.StatusAccepted, accountv1.AccountInviteResponse{Id: user.Id, Email: user.Email, State: user.State})
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		for email, user := range s.users {
+			if user.Id == segments[0] {
+				delete(s.users, email)
+				for _, g := range s.groups { // IAM removes the user from all access groups
+					for i, member := range g.members {
+						if member.ID == user.IbmUniqueId {
+							g.members = append(g.members[:i], g.members[i+1:]...)
+							break
+						}
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		notFound(w, "User", segments[0])
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func userResource(user *accountv1.AccountUser) accountv1.AccountUserResource {
+	return accountv1.AccountUserResource{
+		Metadata: accountv1.AccountUserMetadata{
+			Guid:       user.Id,
+			CreatedAt:  user.CreatedOn,
+			VerifiedAt: user.VerifiedOn,
+			Identity: accountv1.Identity{
+				Id:       user.IbmUniqueId,
+				UserName: user.UserId,
+			},
+		},
+		Entity: accountv1.AccountUserEntity{
+			AccountId: user.AccountId,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			State:     user.State,
+			Email:     user.Email,
+			Role:      user.Role,
+		},
+	}
+}
+
+// serveAccounts serves the account owning the organization of the query
+func (s *Server) serveAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+	var request struct {
+		OrganizationsRegion []accountv2.AccountOrganization `json:"organizations_region"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+	for _, org := range request.OrganizationsRegion {
+		if org.GUID == OrgGUID {
+			account := accountv2.AccountResource{
+				Resource: accountv2.Resource{Metadata: accountv2.Metadata{GUID: AccountID}},
+				Entity: accountv2.AccountEntity{
+					Name:          AccountName,
+					Type:          "PAYG",
+					State:         "ACTIVE",
+					Organizations: []accountv2.AccountOrganization{{GUID: OrgGUID, Region: Region}},
+				},
+			}
+			writeJSON(w, http.StatusOK, accountv2.AccountQueryResponse{Accounts: []accountv2.AccountResource{account}})
+			return
+		}
+	}
+	notFound(w, "Account of organization", "")
+}
+
+// serveOrganizations serves the organization of the account when queried by name
+func (s *Server) serveOrganizations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	resources := []mccpv2.OrgResource{}
+	if r.URL.Query().Get("q") == "name:"+Org {
+		resources = append(resources, mccpv2.OrgResource{
+			Resource: mccpv2.Resource{Metadata: mccpv2.Metadata{GUID: OrgGUID}},
+			Entity:   mccpv2.OrgEntity{Name: Org, Region: Region, Status: "active"},
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_results": len(resources),
+		"total_pages":   1,
+		"resources":     resources,
+	})
+}
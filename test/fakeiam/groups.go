@@ -0,0 +1,216 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeiam
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+)
+
+// AddAccessGroup adds an access group to the account, e.g. one created by hand before the operator
+func (s *Server) AddAccessGroup(name string, description string) models.AccessGroupV2 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createGroup(name, description).AccessGroupV2
+}
+
+// AccessGroups returns the access groups of the account sorted by name
+func (s *Server) AccessGroups() []models.AccessGroupV2 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listGroups()
+}
+
+// Members returns the members of an access group
+func (s *Server) Members(groupID string) []models.AccessGroupMemberV2 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.groups[groupID]; ok {
+		return append([]models.AccessGroupMemberV2{}, g.members...)
+	}
+	return nil
+}
+
+func (s *Server) createGroup(name string, description string) *group {
+	g := &group{
+		AccessGroupV2: models.AccessGroupV2{
+			AccessGroup: models.AccessGroup{
+				ID:          "AccessGroupId-" + s.newID(),
+				Name:        name,
+				Description: description,
+			},
+			AccountID:        AccountID,
+			CreatedAt:        now(),
+			CreatedByID:      "iam-" + APIKey,
+			LastModifiedAt:   now(),
+			LastModifiedByID: "iam-" + APIKey,
+		},
+		version: 1,
+	}
+	s.groups[g.ID] = g
+	return g
+}
+
+func (s *Server) listGroups() []models.AccessGroupV2 {
+	groups := []models.AccessGroupV2{}
+	for _, g := range s.groups {
+		groups = append(groups, g.AccessGroupV2)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// serveGroups serves the access groups API, segments being the path after /v2/groups
+func (s *Server) serveGroups(w http.ResponseWriter, r *http.Request, segments []string) {
+	if len(segments) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, iamuumv2.Groups{Groups: s.listGroups()})
+		case http.MethodPost:
+			var request models.AccessGroupV2
+			if !decode(w, r, &request) {
+				return
+			}
+			for _, g := range s.groups {
+				if g.Name == request.Name {
+					writeError(w, http.StatusConflict, "group_name_conflict", "Group name "+request.Name+" already exists")
+					return
+				}
+			}
+			g := s.createGroup(request.Name, request.Description)
+			w.Header().Set("ETag", etag(g.version))
+			writeJSON(w, http.StatusCreated, g.AccessGroupV2)
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+
+	g, ok := s.groups[segments[0]]
+	if !ok {
+		notFound(w, "Access group", segments[0])
+		return
+	}
+	if len(segments) > 1 && segments[1] == "members" {
+		s.serveMembers(w, r, g, segments[2:])
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("ETag", etag(g.version))
+		writeJSON(w, http.StatusOK, g.AccessGroupV2)
+	case http.MethodPatch:
+		if !ifMatch(w, r, g.version) {
+			return
+		}
+		var request iamuumv2.AccessGroupUpdateRequest
+		if !decode(w, r, &request) {
+			return
+		}
+		if request.Name != "" {
+			g.Name = request.Name
+		}
+		if request.Description != "" {
+			g.Description = request.Description
+		}
+		g.LastModifiedAt = now()
+		g.version++
+		w.Header().Set("ETag", etag(g.version))
+		writeJSON(w, http.StatusOK, g.AccessGroupV2)
+	case http.MethodDelete:
+		if len(g.members) > 0 && r.URL.Query().Get("force") != "true" {
+			writeError(w, http.StatusConflict, "request_not_processed", "Access group has members, delete it with force")
+			return
+		}
+		delete(s.groups, g.ID)
+		for id, p := range s.policies { // IAM deletes the policies of a deleted group
+			if subjectValue(p.Subject, "access_group_id") == g.ID {
+				delete(s.policies, id)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// serveMembers serves the members API of an access group, segments being the path after members
+func (s *Server) serveMembers(w http.ResponseWriter, r *http.Request, g *group, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, iamuumv2.GroupMembers{Members: g.members})
+	case len(segments) == 0 && r.Method == http.MethodPut:
+		var request iamuumv2.AddGroupMemberRequestV2
+		if !decode(w, r, &request) {
+			return
+		}
+		response := iamuumv2.AddGroupMemberResponseV2{}
+		for _, member := range request.Members {
+			added := iamuumv2.AddedGroupMemberV2{ID: member.ID, Type: member.Type, StatusCode: http.StatusOK, CreatedAt: now()}
+			if !s.iamIDExists(member.ID, member.Type) {
+				added.StatusCode = http.StatusNotFound
+				added.Errors = []iamuumv2.Error{{Code: "not_found", Message: "IAM ID " + member.ID + " not found"}}
+			} else if !hasMember(g, member.ID) {
+				member.CreatedAt = now()
+				g.members = append(g.members, member)
+			}
+			response.Members = append(response.Members, added)
+		}
+		writeJSON(w, http.StatusMultiStatus, response)
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		for i, member := range g.members {
+			if member.ID == segments[0] {
+				g.members = append(g.members[:i], g.members[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		notFound(w, "Member", segments[0])
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func hasMember(g *group, iamID string) bool {
+	for _, member := range g.members {
+		if member.ID == iamID {
+			return true
+		}
+	}
+	return false
+}
+
+// iamIDExists returns true if the account has a user or service ID with an IAM ID
+func (s *Server) iamIDExists(iamID string, memberType string) bool {
+	if memberType == iamuumv2.AccessGroupMemberService {
+		for _, serviceID := range s.serviceIDs {
+			if serviceID.IAMID == iamID {
+				return true
+			}
+		}
+		return false
+	}
+	for _, user := range s.users {
+		if user.IbmUniqueId == iamID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeiam
+
+import (
+	"net/http"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+	"github.com/IBM-Cloud/bluemix-go/models"
+)
+
+const refreshToken = "fake-refresh-token"
+
+// platformRoles are the system defined roles, granted on any service
+var platformRoles = []string{"Viewer", "Operator", "Editor", "Administrator"}
+
+// defaultServiceRoles are the roles of the services without roles set by SetServiceRoles
+var defaultServiceRoles = []string{"Reader", "Writer", "Manager"}
+
+// AddServiceID adds a service ID to the account, with a new UUID if uuid is empty
+func (s *Server) AddServiceID(uuid string, name string) models.ServiceID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	boundTo := crn.New("bluemix", "public")
+	boundTo.ScopeType = crn.ScopeAccount
+	boundTo.Scope = AccountID
+	if uuid == "" {
+		uuid = "ServiceId-" + s.newID()
+	}
+	serviceID := &models.ServiceID{
+		UUID:       uuid,
+		IAMID:      "iam-" + uuid,
+		CRN:        "crn:v1:bluemix:public:iam-identity::a/" + AccountID + "::serviceid:" + uuid,
+		Version:    "1-" + uuid,
+		BoundTo:    boundTo.String(),
+		Name:       name,
+		CreatedAt:  now(),
+		ModifiedAt: now(),
+	}
+	s.serviceIDs[uuid] = serviceID
+	return *serviceID
+}
+
+// SetServiceRoles sets the names of the service roles of a service, Reader, Writer and Manager by default
+func (s *Server) SetServiceRoles(serviceName string, roles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serviceRoles[serviceName] = roles
+}
+
+func (s *Server) serveIAMToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.Form.Get("apikey") != APIKey && r.Form.Get("refresh_token") != refreshToken {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"errorCode":    "BXNIM0415E",
+			"errorMessage": "Provided API key could not be found",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  iamToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+func (s *Server) serveUAAToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.Form.Get("password") != APIKey && r.Form.Get("refresh_token") != refreshToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{
+			"error":             "unauthorized",
+			"error_description": "Bad credentials",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  uaaToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+// serveServiceIDs serves the service IDs of the account, which are read only
+func (s *Server) serveServiceIDs(w http.ResponseWriter, r *http.Request, segments []string) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	if len(segments) == 0 || segments[0] == "" {
+		items := []iamv1.ServiceIDResource{}
+		for _, serviceID := range s.serviceIDs {
+			items = append(items, serviceIDResource(serviceID))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+		return
+	}
+	serviceID, ok := s.serviceIDs[segments[0]]
+	if !ok {
+		notFound(w, "Service ID", segments[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, serviceIDResource(serviceID))
+}
+
+func serviceIDResource(serviceID *models.ServiceID) iamv1.ServiceIDResource {
+	return iamv1.ServiceIDResource{
+		Metadata: iamv1.IAMMetadata{
+			UUID:       serviceID.UUID,
+			IAMID:      serviceID.IAMID,
+			Version:    serviceID.Version,
+			CRN:        serviceID.CRN,
+			CreatedAt:  serviceID.CreatedAt,
+			ModifiedAt: serviceID.ModifiedAt,
+		},
+		Entity: iamv1.ServiceIDEntity{
+			BoundTo:     serviceID.BoundTo,
+			Name:        serviceID.Name,
+			Description: serviceID.Description,
+		},
+	}
+}
+
+// serveServiceRoles serves the system defined roles, or the roles of the service named by the query
+func (s *Server) serveServiceRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	serviceName := r.URL.Query().Get("serviceName")
+	if serviceName == "" {
+		roles := []models.Role{}
+		for _, name := range platformRoles {
+			roles = append(roles, models.Role{CRN: platformRole(name).ID, Name: name})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"systemDefinedRoles": roles})
+		return
+	}
+
+	names, ok := s.serviceRoles[serviceName]
+	if !ok {
+		names = defaultServiceRoles
+	}
+	response := struct {
+		ServiceSpecificRoles []models.PolicyRole `json:"supportedRoles"`
+		PlatformExtensions   struct {
+			Roles []models.PolicyRole `json:"supportedRoles"`
+		} `json:"platformExtensions"`
+	}{}
+	for _, name := range names {
+		response.ServiceSpecificRoles = append(response.ServiceSpecificRoles, models.PolicyRole{
+			ID:          roleCRN(serviceName, "serviceRole", name),
+			DisplayName: name,
+		})
+	}
+	if r.URL.Query().Get("policyType") != "authorization" {
+		for _, name := range platformRoles {
+			response.PlatformExtensions.Roles = append(response.PlatformExtensions.Roles, platformRole(name))
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func platformRole(name string) models.PolicyRole {
+	return models.PolicyRole{
+		ID:          roleCRN(crn.ServiceIAM, crn.ResourceTypeRole, name),
+		DisplayName: name,
+	}
+}
+
+func roleCRN(serviceName string, resourceType string, name string) crn.CRN {
+	id := crn.New("bluemix", "public")
+	id.ServiceName = serviceName
+	id.ResourceType = resourceType
+	id.Resource = name
+	return id
+}
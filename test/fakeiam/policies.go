@@ -0,0 +1,255 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeiam
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+// AddPolicy adds a policy to the account, e.g. one created by hand before the operator
+func (s *Server) AddPolicy(p polv2.Policy) polv2.Policy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createPolicy(p, "/v2/policies/").Policy
+}
+
+// Policies returns the policies of the account in the order they were created. Policies are
+// stored as v2 policies, policies created with the v1 API are converted.
+func (s *Server) Policies() []polv2.Policy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listPolicies(polv2.SearchParams{AccountID: AccountID}, false)
+}
+
+func (s *Server) createPolicy(p polv2.Policy, path string) *policy {
+	p.ID = s.newID()
+	p.Href = s.URL + path + p.ID
+	p.CreatedAt = now()
+	p.CreatedByID = "iam-" + APIKey
+	p.LastModifiedAt = p.CreatedAt
+	p.LastModifiedByID = p.CreatedByID
+	if p.State == "" {
+		p.State = "active"
+	}
+	created := &policy{Policy: p, version: 1}
+	s.policies[p.ID] = created
+	return created
+}
+
+func (s *Server) listPolicies(params polv2.SearchParams, v1 bool) []polv2.Policy {
+	policies := []polv2.Policy{}
+	for _, p := range s.policies {
+		if v1 && !v1Visible(p.Policy) {
+			continue
+		}
+		if params.AccountID != resourceValue(p.Resource, "accountId") ||
+			params.IAMID != "" && params.IAMID != subjectValue(p.Subject, "iam_id") ||
+			params.AccessGroupID != "" && params.AccessGroupID != subjectValue(p.Subject, "access_group_id") ||
+			params.Type != "" && params.Type != p.Type ||
+			params.State != "" && params.State != p.State {
+			continue
+		}
+		policies = append(policies, p.Policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	return policies
+}
+
+// validPolicy returns false and replies 400 or 403 if a policy cannot be created in the account
+func (s *Server) validPolicy(w http.ResponseWriter, p polv2.Policy) bool {
+	if p.Type != "access" && p.Type != "authorization" {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Policy type "+p.Type+" is not supported")
+		return false
+	}
+	if len(p.Control.Grant.Roles) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_body", "Policy must grant roles")
+		return false
+	}
+	if accountID := resourceValue(p.Resource, "accountId"); accountID != AccountID {
+		writeError(w, http.StatusForbidden, "forbidden", "Account "+accountID+" is not accessible")
+		return false
+	}
+	if groupID := subjectValue(p.Subject, "access_group_id"); groupID != "" {
+		if _, ok := s.groups[groupID]; !ok {
+			writeError(w, http.StatusBadRequest, "invalid_subject", "Access group "+groupID+" not found")
+			return false
+		}
+	}
+	return true
+}
+
+// serveV2Policies serves the v2 policies API, segments being the path after /v2/policies
+func (s *Server) serveV2Policies(w http.ResponseWriter, r *http.Request, segments []string) {
+	s.servePolicies(w, r, segments, false)
+}
+
+// serveV1Policies serves the v1 policies API, segments being the path after /v1/policies.
+// Policies with conditions are only visible to the v2 API.
+func (s *Server) serveV1Policies(w http.ResponseWriter, r *http.Request, segments []string) {
+	s.servePolicies(w, r, segments, true)
+}
+
+func (s *Server) servePolicies(w http.ResponseWriter, r *http.Request, segments []string, v1 bool) {
+	path := "/v2/policies/"
+	if v1 {
+		path = "/v1/policies/"
+	}
+	write := func(status int, p *policy) {
+		w.Header().Set("ETag", etag(p.version))
+		if v1 {
+			writeJSON(w, status, toV1(p.Policy))
+		} else {
+			writeJSON(w, status, p.Policy)
+		}
+	}
+	read := func(p *polv2.Policy) bool {
+		if !v1 {
+			return decode(w, r, p)
+		}
+		var request polv1.Policy
+		if !decode(w, r, &request) {
+			return false
+		}
+		*p = polv2.ConvertV1Policy(request)
+		return true
+	}
+
+	if len(segments) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			query := r.URL.Query()
+			params := polv2.SearchParams{
+				AccountID:     query.Get("account_id"),
+				IAMID:         query.Get("iam_id"),
+				AccessGroupID: query.Get("access_group_id"),
+				Type:          query.Get("type"),
+				State:         query.Get("state"),
+			}
+			policies := s.listPolicies(params, v1)
+			if v1 {
+				response := struct {
+					Policies []polv1.Policy `json:"policies"`
+				}{[]polv1.Policy{}}
+				for _, p := range policies {
+					response.Policies = append(response.Policies, toV1(p))
+				}
+				writeJSON(w, http.StatusOK, response)
+			} else {
+				writeJSON(w, http.StatusOK, struct {
+					Policies []polv2.Policy `json:"policies"`
+				}{policies})
+			}
+		case http.MethodPost:
+			var p polv2.Policy
+			if !read(&p) || !s.validPolicy(w, p) {
+				return
+			}
+			write(http.StatusCreated, s.createPolicy(p, path))
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+
+	existing, ok := s.policies[segments[0]]
+	if !ok || v1 && !v1Visible(existing.Policy) {
+		notFound(w, "Policy", segments[0])
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		write(http.StatusOK, existing)
+	case http.MethodPut:
+		if !ifMatch(w, r, existing.version) {
+			return
+		}
+		var p polv2.Policy
+		if !read(&p) || !s.validPolicy(w, p) {
+			return
+		}
+		existing.Type = p.Type
+		existing.Description = p.Description
+		existing.Subject = p.Subject
+		existing.Control = p.Control
+		existing.Resource = p.Resource
+		existing.Rule = p.Rule
+		existing.Pattern = p.Pattern
+		existing.LastModifiedAt = now()
+		existing.version++
+		write(http.StatusOK, existing)
+	case http.MethodDelete:
+		delete(s.policies, existing.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// v1Visible returns true if a policy can be represented with the v1 API
+func v1Visible(p polv2.Policy) bool {
+	return p.Rule == nil && p.Pattern == ""
+}
+
+// toV1 converts a v2 policy without conditions to the v1 representation
+func toV1(p polv2.Policy) polv1.Policy {
+	result := polv1.Policy{
+		ID:               p.ID,
+		Type:             p.Type,
+		Subjects:         []polv1.Subject{{Attributes: toV1Attributes(p.Subject.Attributes)}},
+		Roles:            []iampapv1.Role{},
+		Resources:        []polv1.Resource{{Attributes: toV1Attributes(p.Resource.Attributes), Tags: toV1Attributes(p.Resource.Tags)}},
+		Href:             p.Href,
+		CreatedAt:        p.CreatedAt,
+		CreatedByID:      p.CreatedByID,
+		LastModifiedAt:   p.LastModifiedAt,
+		LastModifiedByID: p.LastModifiedByID,
+	}
+	for _, r := range p.Control.Grant.Roles {
+		result.Roles = append(result.Roles, iampapv1.Role{RoleID: r.RoleID})
+	}
+	return result
+}
+
+func toV1Attributes(attributes []polv2.Attribute) []polv1.Attribute {
+	var results []polv1.Attribute
+	for _, a := range attributes {
+		results = append(results, polv1.Attribute{Name: a.Key, Value: a.Value, Operator: a.Operator})
+	}
+	return results
+}
+
+func subjectValue(subject polv2.Subject, key string) string {
+	return attributeValue(subject.Attributes, key)
+}
+
+func resourceValue(resource polv2.Resource, key string) string {
+	return attributeValue(resource.Attributes, key)
+}
+
+func attributeValue(attributes []polv2.Attribute, key string) string {
+	for _, a := range attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeiam
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/crn"
+)
+
+// AddCustomRole adds a custom role to the account, e.g. one created by hand before the operator
+func (s *Server) AddCustomRole(request iampapv2.CreateRoleRequest) iampapv2.Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createRole(request).Role
+}
+
+// CustomRoles returns the custom roles of the account sorted by name
+func (s *Server) CustomRoles() []iampapv2.Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listRoles("")
+}
+
+func (s *Server) createRole(request iampapv2.CreateRoleRequest) *role {
+	id := s.newID()
+	roleCRN := roleCRN(request.ServiceName, "customRole", request.Name)
+	roleCRN.ScopeType = crn.ScopeAccount
+	roleCRN.Scope = AccountID
+	request.AccountID = AccountID
+	r := &role{
+		Role: iampapv2.Role{
+			CreateRoleRequest: request,
+			ID:                id,
+			Crn:               roleCRN.String(),
+			CreatedAt:         now(),
+			CreatedByID:       "iam-" + APIKey,
+			LastModifiedAt:    now(),
+			LastModifiedByID:  "iam-" + APIKey,
+		},
+		version: 1,
+	}
+	s.roles[id] = r
+	return r
+}
+
+func (s *Server) listRoles(serviceName string) []iampapv2.Role {
+	roles := []iampapv2.Role{}
+	for _, r := range s.roles {
+		if serviceName == "" || r.ServiceName == serviceName {
+			roles = append(roles, r.Role)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+// serveRoles serves the roles API, segments being the path after /v2/roles
+func (s *Server) serveRoles(w http.ResponseWriter, r *http.Request, segments []string) {
+	if len(segments) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			s.serveRoleList(w, r)
+		case http.MethodPost:
+			var request iampapv2.CreateRoleRequest
+			if !decode(w, r, &request) {
+				return
+			}
+			if request.AccountID != AccountID {
+				writeError(w, http.StatusForbidden, "forbidden", "Account "+request.AccountID+" is not accessible")
+				return
+			}
+			for _, existing := range s.roles {
+				if existing.Name == request.Name {
+					writeError(w, http.StatusConflict, "role_conflict_error", "Role "+request.Name+" already exists")
+					return
+				}
+			}
+			created := s.createRole(request)
+			w.Header().Set("ETag", etag(created.version))
+			writeJSON(w, http.StatusCreated, created.Role)
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+
+	existing, ok := s.roles[segments[0]]
+	if !ok {
+		notFound(w, "Role", segments[0])
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("ETag", etag(existing.version))
+		writeJSON(w, http.StatusOK, existing.Role)
+	case http.MethodPut:
+		if !ifMatch(w, r, existing.version) {
+			return
+		}
+		var request iampapv2.UpdateRoleRequest
+		if !decode(w, r, &request) {
+			return
+		}
+		existing.DisplayName = request.DisplayName
+		existing.Description = request.Description
+		existing.Actions = request.Actions
+		existing.LastModifiedAt = now()
+		existing.version++
+		w.Header().Set("ETag", etag(existing.version))
+		writeJSON(w, http.StatusOK, existing.Role)
+	case http.MethodDelete:
+		delete(s.roles, existing.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// serveRoleList lists the custom roles of the account, the roles of a service and the system defined roles
+func (s *Server) serveRoleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	response := iampapv2.ListResponse{
+		CustomRoles:  []iampapv2.Role{},
+		ServiceRoles: []iampapv2.Role{},
+		SystemRoles:  []iampapv2.Role{},
+	}
+	if accountID := query.Get("account_id"); accountID == AccountID {
+		response.CustomRoles = s.listRoles(query.Get("service_name"))
+	}
+	if serviceName := query.Get("service_name"); serviceName != "" {
+		names, ok := s.serviceRoles[serviceName]
+		if !ok {
+			names = defaultServiceRoles
+		}
+		for _, name := range names {
+			response.ServiceRoles = append(response.ServiceRoles, systemRole(roleCRN(serviceName, "serviceRole", name), name))
+		}
+	}
+	for _, name := range platformRoles {
+		response.SystemRoles = append(response.SystemRoles, systemRole(roleCRN(crn.ServiceIAM, crn.ResourceTypeRole, name), name))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func systemRole(id crn.CRN, name string) iampapv2.Role {
+	return iampapv2.Role{
+		CreateRoleRequest: iampapv2.CreateRoleRequest{
+			Name:        name,
+			ServiceName: id.ServiceName,
+			DisplayName: name,
+		},
+		ID:  id.String(),
+		Crn: id.String(),
+	}
+}
@@ -0,0 +1,272 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package fakeiam is an in-memory IBM Cloud server implementing the parts of the IAM identity,
+// access groups, policy management and account APIs called by the operator. Redirecting the
+// operator to it with endpoints.Override lets controller tests run offline. The server keeps
+// state across requests and can be told to fail some of them to exercise error handling.
+package fakeiam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/models"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+)
+
+// Identity of the single account served by a fake server
+const (
+	APIKey      = "fake-api-key"
+	AccountID   = "fa4e0000000000000000000000000001"
+	AccountName = "Fake Account"
+	Org         = "fake-org"
+	OrgGUID     = "fa4e0000-0000-4000-8000-000000000001"
+	Region      = "us-south"
+
+	iamToken = "fake-iam-token"
+	uaaToken = "fake-uaa-token"
+)
+
+// Fault makes the server fail requests instead of serving them
+type Fault struct {
+	// Method of the failing requests, or any method if empty
+	Method string
+	// Path prefix of the failing requests, or any path if empty
+	Path string
+	// Status is the HTTP status code of the failures, e.g. 403 or 503. Note that bluemix-go
+	// retries requests failing with 408, 429, 500, 502 and 504 after the session RetryDelay.
+	Status int
+	// Count is the number of requests to fail, or all of them if 0
+	Count int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// Server is a fake IBM Cloud server for the account AccountID
+type Server struct {
+	// URL of the server, to pass to endpoints.Override
+	URL string
+
+	server *httptest.Server
+
+	mu           sync.Mutex
+	seq          int
+	requests     []string
+	faults       []*Fault
+	users        map[string]*accountv1.AccountUser // by email
+	serviceIDs   map[string]*models.ServiceID      // by UUID
+	serviceRoles map[string][]string               // role names by service
+	groups       map[string]*group                 // by ID
+	roles        map[string]*role                  // by ID
+	policies     map[string]*policy                // by ID
+}
+
+type group struct {
+	models.AccessGroupV2
+	members []models.AccessGroupMemberV2
+	version int
+}
+
+type role struct {
+	iampapv2.Role
+	version int
+}
+
+type policy struct {
+	polv2.Policy
+	version int
+}
+
+// NewServer starts a fake server with an empty account. Close must be called when done.
+func NewServer() *Server {
+	s := &Server{
+		users:        map[string]*accountv1.AccountUser{},
+		serviceIDs:   map[string]*models.ServiceID{},
+		serviceRoles: map[string][]string{},
+		groups:       map[string]*group{},
+		roles:        map[string]*role{},
+		policies:     map[string]*policy{},
+	}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	return s
+}
+
+// Credentials returns the operator secret and config map of a namespace selecting the account
+// of the server, to seed the Kubernetes client of reconcilers
+func Credentials(namespace string) (*corev1.Secret, *corev1.ConfigMap) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "secret-ibmcloud-iam-operator"},
+		Data:       map[string][]byte{"api-key": []byte(APIKey), "region": []byte(Region)},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "config-ibmcloud-iam-operator"},
+		Data:       map[string]string{"org": Org, "region": Region},
+	}
+	return secret, configMap
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Inject makes the server fail the requests matching fault
+func (s *Server) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults makes the server serve all requests again
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the method and path of the requests served so far, e.g. "POST /v2/groups"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP serves IBM Cloud API requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	for i, fault := range s.faults {
+		if fault.matches(r) {
+			if fault.Count == 1 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			} else if fault.Count > 1 {
+				fault.Count--
+			}
+			writeError(w, fault.Status, "injected_fault", "Fault injected by the fake IAM server")
+			return
+		}
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "identity/token":
+		s.serveIAMToken(w, r)
+		return
+	case path == "oauth/token":
+		s.serveUAAToken(w, r)
+		return
+	}
+
+	if auth := r.Header.Get("Authorization"); auth != "Bearer "+iamToken && auth != "Bearer "+uaaToken {
+		writeError(w, http.StatusUnauthorized, "BXNIM0407E", "Invalid token")
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	switch {
+	case strings.HasPrefix(path, "serviceids"):
+		s.serveServiceIDs(w, r, segments[1:])
+	case strings.HasPrefix(path, "acms/v1/roles"):
+		s.serveServiceRoles(w, r)
+	case strings.HasPrefix(path, "v2/groups"):
+		s.serveGroups(w, r, segments[2:])
+	case strings.HasPrefix(path, "v2/roles"):
+		s.serveRoles(w, r, segments[2:])
+	case strings.HasPrefix(path, "v1/policies"):
+		s.serveV1Policies(w, r, segments[2:])
+	case strings.HasPrefix(path, "v2/policies"):
+		s.serveV2Policies(w, r, segments[2:])
+	case strings.HasPrefix(path, "v1/accounts/"+AccountID+"/users"):
+		s.serveUsers(w, r, segments[4:])
+	case path == "coe/v2/getaccounts":
+		s.serveAccounts(w, r)
+	case path == "v2/organizations":
+		s.serveOrganizations(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "Unknown path "+r.URL.Path)
+	}
+}
+
+// newID returns a new unique ID shaped like an IAM UUID
+func (s *Server) newID() string {
+	s.seq++
+	return fmt.Sprintf("fa4e0000-0000-4000-8000-%012x", s.seq)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// etag is the entity tag of the version of a resource
+func etag(version int) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// ifMatch returns false and replies 412 if the request does not match the version of a resource
+func ifMatch(w http.ResponseWriter, r *http.Request, version int) bool {
+	if match := r.Header.Get("If-Match"); match != "*" && match != etag(version) {
+		writeError(w, http.StatusPreconditionFailed, "precondition_failed", "The revision does not match the current one")
+		return false
+	}
+	return true
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError replies with the error body shared by the IAM APIs
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors":      []map[string]string{{"code": code, "message": message}},
+		"status_code": status,
+		"trace":       "fake-iam",
+	})
+}
+
+func notFound(w http.ResponseWriter, kind string, id string) {
+	writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s %s not found", kind, id))
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" "+r.URL.Path+" is not supported")
+}
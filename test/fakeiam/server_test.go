@@ -0,0 +1,278 @@
+/*
+ * Copyright 2019 IBM Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fakeiam
+
+import (
+	"testing"
+
+	"github.com/IBM-Cloud/bluemix-go/api/account/accountv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iam/iamv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv1"
+	"github.com/IBM-Cloud/bluemix-go/api/iampap/iampapv2"
+	"github.com/IBM-Cloud/bluemix-go/api/iamuum/iamuumv2"
+	"github.com/IBM-Cloud/bluemix-go/bmxerror"
+	"github.com/IBM-Cloud/bluemix-go/models"
+	"github.com/IBM-Cloud/bluemix-go/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/endpoints"
+	polv1 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v1"
+	polv2 "github.com/IBM/ibmcloud-iam-operator/pkg/lib/policy/v2"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/util"
+)
+
+// connect logs in the fake server the way reconcilers do
+func connect(t *testing.T, s *Server) *session.Session {
+	secret, configMap := Credentials("default")
+	client := fake.NewFakeClientWithScheme(scheme.Scheme, secret, configMap)
+	sess, account, err := util.GetIAMAccountInfo(client, "default")
+	require.NoError(t, err)
+	require.Equal(t, AccountID, account.GUID)
+	return sess
+}
+
+func statusCode(err error) int {
+	if failure, ok := err.(bmxerror.RequestFailure); ok {
+		return failure.StatusCode()
+	}
+	return 0
+}
+
+func TestCredentials(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	defer endpoints.Override(s.URL)()
+
+	connect(t, s)
+
+	secret, configMap := Credentials("default")
+	secret.Data["api-key"] = []byte("revoked")
+	client := fake.NewFakeClientWithScheme(scheme.Scheme, secret, configMap)
+	_, _, err := util.GetIAMAccountInfo(client, "default")
+	assert.Error(t, err)
+}
+
+func TestAccessGroups(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	defer endpoints.Override(s.URL)()
+	user := s.AddUser("jane@example.com")
+	serviceID := s.AddServiceID("", "deployer")
+
+	sess := connect(t, s)
+	uum, err := iamuumv2.New(sess)
+	require.NoError(t, err)
+	groups := uum.AccessGroup()
+	members := uum.AccessGroupMember()
+
+	created, err := groups.Create(models.AccessGroupV2{AccessGroup: models.AccessGroup{Name: "developers"}}, AccountID)
+	require.NoError(t, err)
+	_, err = groups.Create(models.AccessGroupV2{AccessGroup: models.AccessGroup{Name: "developers"}}, AccountID)
+	assert.Equal(t, 409, statusCode(err))
+
+	found, err := groups.FindByName("developers", AccountID)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	_, revision, err := groups.Get(created.ID)
+	require.NoError(t, err)
+	_, err = groups.Update(created.ID, iamuumv2.AccessGroupUpdateRequest{Description: "Developers"}, revision)
+	require.NoError(t, err)
+	_, err = groups.Update(created.ID, iamuumv2.AccessGroupUpdateRequest{Description: "Stale"}, revision)
+	assert.Equal(t, 412, statusCode(err))
+	assert.Equal(t, "Developers", s.AccessGroups()[0].Description)
+
+	response, err := members.Add(created.ID, iamuumv2.AddGroupMemberRequestV2{Members: []models.AccessGroupMemberV2{
+		{ID: user.IbmUniqueId, Type: iamuumv2.AccessGroupMemberUser},
+		{ID: serviceID.IAMID, Type: iamuumv2.AccessGroupMemberService},
+		{ID: "IBMid-unknown", Type: iamuumv2.AccessGroupMemberUser},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 404, response.Members[2].StatusCode)
+	listed, err := members.List(created.ID)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+	require.NoError(t, members.Remove(created.ID, serviceID.IAMID))
+	assert.Len(t, s.Members(created.ID), 1)
+
+	s.AddPolicy(groupPolicy(created.ID))
+	assert.Equal(t, 409, statusCode(groups.Delete(created.ID, false)))
+	require.NoError(t, groups.Delete(created.ID, true))
+	assert.Empty(t, s.AccessGroups())
+	assert.Empty(t, s.Policies())
+}
+
+func groupPolicy(groupID string) polv2.Policy {
+	return polv2.Policy{
+		Type:     "access",
+		Subject:  polv2.Subject{Attributes: []polv2.Attribute{{Key: "access_group_id", Operator: "stringEquals", Value: groupID}}},
+		Control:  polv2.Control{Grant: polv2.Grant{Roles: []polv2.Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Viewer"}}}},
+		Resource: polv2.Resource{Attributes: []polv2.Attribute{{Key: "accountId", Operator: "stringEquals", Value: AccountID}}},
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	defer endpoints.Override(s.URL)()
+	user := s.AddUser("jane@example.com")
+
+	sess := connect(t, s)
+	v1API, err := polv1.New(sess)
+	require.NoError(t, err)
+	v2API, err := polv2.New(sess)
+	require.NoError(t, err)
+
+	var subject polv1.Subject
+	subject.SetAttribute("iam_id", user.IbmUniqueId)
+	var resource polv1.Resource
+	resource.SetAccountID(AccountID)
+	resource.SetAttribute("serviceName", "cloud-object-storage")
+	policy := polv1.Policy{
+		Type:      "access",
+		Subjects:  []polv1.Subject{subject},
+		Roles:     []iampapv1.Role{{RoleID: "crn:v1:bluemix:public:iam::::role:Viewer"}},
+		Resources: []polv1.Resource{resource},
+	}
+	created, err := v1API.Create(policy)
+	require.NoError(t, err)
+	assert.Equal(t, policy.Subjects, created.Subjects)
+	assert.Equal(t, policy.Resources, created.Resources)
+
+	read, err := v2API.Get(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, polv2.ConvertV1Policy(policy).Resource, read.Resource)
+
+	policy.Roles[0].RoleID = "crn:v1:bluemix:public:iam::::role:Editor"
+	_, err = v1API.Update(created.ID, policy, created.Version)
+	require.NoError(t, err)
+	_, err = v1API.Update(created.ID, policy, created.Version)
+	assert.Equal(t, 412, statusCode(err))
+
+	conditional := polv2.ConvertV1Policy(policy)
+	conditional.Rule = &polv2.Rule{Key: polv2.CurrentDateTimeKey, Operator: polv2.OperatorDateTimeLessThanOrEquals, Value: "2030-01-01T00:00:00Z"}
+	created2, err := v2API.Create(conditional)
+	require.NoError(t, err)
+	_, err = v1API.Get(created2.ID)
+	assert.Equal(t, 404, statusCode(err))
+
+	listed, err := v1API.List(polv1.SearchParams{AccountID: AccountID, IAMID: user.IbmUniqueId})
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+	listed2, err := v2API.List(polv2.SearchParams{AccountID: AccountID, IAMID: user.IbmUniqueId})
+	require.NoError(t, err)
+	assert.Len(t, listed2, 2)
+
+	require.NoError(t, v2API.Delete(created2.ID))
+	assert.Len(t, s.Policies(), 1)
+
+	resource.SetAccountID("another-account")
+	policy.Resources = []polv1.Resource{resource}
+	_, err = v1API.Create(policy)
+	assert.Equal(t, 403, statusCode(err))
+}
+
+func TestRoles(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	defer endpoints.Override(s.URL)()
+	s.SetServiceRoles("kms", "Reader", "ReaderPlus")
+
+	sess := connect(t, s)
+	pap, err := iampapv2.New(sess)
+	require.NoError(t, err)
+	roles := pap.IAMRoles()
+
+	created, err := roles.Create(iampapv2.CreateRoleRequest{Name: "Auditor", ServiceName: "kms", AccountID: AccountID, DisplayName: "Auditor", Actions: []string{"kms.secrets.list"}})
+	require.NoError(t, err)
+	assert.Equal(t, "crn:v1:bluemix:public:kms::a/"+AccountID+"::customRole:Auditor", created.Crn)
+
+	custom, err := roles.ListCustomRoles(AccountID, "kms")
+	require.NoError(t, err)
+	assert.Len(t, custom, 1)
+
+	_, revision, err := roles.Get(created.ID)
+	require.NoError(t, err)
+	_, err = roles.Update(iampapv2.UpdateRoleRequest{DisplayName: "Auditor", Actions: []string{"kms.secrets.read"}}, created.ID, revision)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kms.secrets.read"}, s.CustomRoles()[0].Actions)
+	require.NoError(t, roles.Delete(created.ID))
+	assert.Empty(t, s.CustomRoles())
+
+	iam, err := iamv1.New(sess)
+	require.NoError(t, err)
+	serviceRoles, err := iam.ServiceRoles().ListServiceRoles("kms")
+	require.NoError(t, err)
+	assert.Len(t, serviceRoles, 6)
+	assert.Equal(t, "crn:v1:bluemix:public:kms::::serviceRole:ReaderPlus", serviceRoles[1].ID.String())
+	systemRoles, err := iam.ServiceRoles().ListSystemDefinedRoles()
+	require.NoError(t, err)
+	assert.Len(t, systemRoles, 4)
+}
+
+func TestUsers(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	defer endpoints.Override(s.URL)()
+	user := s.AddUser("jane@example.com")
+
+	sess := connect(t, s)
+	account, err := accountv1.New(sess)
+	require.NoError(t, err)
+	users := account.Accounts()
+
+	found, err := users.FindAccountUserByUserId(AccountID, "jane@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.IbmUniqueId, found.IbmUniqueId)
+	assert.Equal(t, "ACTIVE", found.State)
+
+	invited, err := users.InviteAccountUser(AccountID, "john@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING", invited.State)
+	require.NoError(t, users.DeleteAccountUser(AccountID, invited.Id))
+	assert.Len(t, s.Users(), 1)
+}
+
+func TestFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	defer endpoints.Override(s.URL)()
+
+	sess := connect(t, s)
+	uum, err := iamuumv2.New(sess)
+	require.NoError(t, err)
+
+	s.Inject(Fault{Method: "GET", Path: "/v2/groups", Status: 503, Count: 1})
+	_, err = uum.AccessGroup().List(AccountID)
+	assert.Equal(t, 503, statusCode(err))
+	_, err = uum.AccessGroup().List(AccountID)
+	assert.NoError(t, err)
+
+	s.Inject(Fault{Path: "/v2/groups", Status: 403})
+	_, err = uum.AccessGroup().List(AccountID)
+	assert.Equal(t, 403, statusCode(err))
+	_, err = uum.AccessGroup().List(AccountID)
+	assert.Equal(t, 403, statusCode(err))
+	s.ClearFaults()
+	_, err = uum.AccessGroup().List(AccountID)
+	assert.NoError(t, err)
+
+	assert.Contains(t, s.Requests(), "POST /identity/token")
+	assert.Contains(t, s.Requests(), "GET /v2/groups")
+}
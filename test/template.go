@@ -17,14 +17,13 @@
 package test
 
 import (
-	"io/ioutil"
 	"time"
 
-	yaml2 "github.com/ghodss/yaml"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	v1alpha1 "github.com/IBM/ibmcloud-iam-operator/pkg/apis/ibmcloud/v1alpha1"
+	"github.com/IBM/ibmcloud-iam-operator/pkg/lib/manifest"
 
 	rcontext "github.com/IBM/ibmcloud-iam-operator/pkg/context"
 )
@@ -93,12 +92,20 @@ func LoadCustomRole(filename string) v1alpha1.CustomRole {
 	return *LoadObject(filename, &v1alpha1.CustomRole{}).(*v1alpha1.CustomRole)
 }
 
+// LoadAccessRequest loads the YAML spec into obj
+func LoadAccessRequest(filename string) v1alpha1.AccessRequest {
+	return *LoadObject(filename, &v1alpha1.AccessRequest{}).(*v1alpha1.AccessRequest)
+}
+
+// LoadIAMOrphanReport loads the YAML spec into obj
+func LoadIAMOrphanReport(filename string) v1alpha1.IAMOrphanReport {
+	return *LoadObject(filename, &v1alpha1.IAMOrphanReport{}).(*v1alpha1.IAMOrphanReport)
+}
+
 // LoadObject loads the YAML spec into obj
 func LoadObject(filename string, obj runtime.Object) runtime.Object {
-	bytes, err := ioutil.ReadFile(filename)
-	if err != nil {
+	if err := manifest.LoadObject(filename, obj, ""); err != nil {
 		panic(err)
 	}
-	yaml2.Unmarshal(bytes, obj)
 	return obj
 }